@@ -1,80 +1,269 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
-	"log"
+	"net"
 	"net/http"
 	"os"
-	"os/signal"
-	"syscall"
-	"time"
 
+	"github.com/go-kit/kit/log"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/paulbes/go-pedal/pedal/client"
+	"google.golang.org/grpc"
 
 	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/alert"
+	"github.com/paulbes/go-pedal/pedal/forecast"
+	"github.com/paulbes/go-pedal/pedal/history"
+	"github.com/paulbes/go-pedal/pedal/rebalance"
+	"github.com/paulbes/go-pedal/pedal/stream"
+	"github.com/paulbes/go-pedal/pedal/trip"
+	"github.com/paulbes/go-pedal/pkg/api/pb"
 	api "github.com/paulbes/go-pedal/pkg/api/server"
 	store "github.com/paulbes/go-pedal/pkg/api/store/http"
+	"github.com/paulbes/go-pedal/pkg/config"
+	"github.com/paulbes/go-pedal/pkg/logging"
 	"github.com/paulbes/go-pedal/pkg/md"
+	"github.com/paulbes/go-pedal/pkg/metrics"
 )
 
-var clientIdentifier string
+func main() {
+	// Read the settings from the flags, the environment and the
+	// config file, and print them instead of serving if asked to,
+	// before validating them, so invalid settings can be inspected
+	cfg := config.NewAPI(flag.CommandLine)
+	err := cfg.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cfg.PrintConfig {
+		err = cfg.Print(os.Stdout)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		return
+	}
+	err = cfg.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
 
-func init() {
-	flag.StringVar(&clientIdentifier, "client-identifier", "", "Oslo City Bike Client Identifier")
-	flag.Parse()
-}
+	// Log structured entries to the standard error
+	logger, err := logging.NewLogger(cfg.Server.LogFormat, os.Stderr)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
+	// Collect the metrics of the API and its upstream calls
+	registry := metrics.NewRegistry()
 
-func main() {
 	// Create an HTTP client for interacting with the city bike API
-	cli, err := client.NewHTTPClient(clientIdentifier, 5)
+	client.BaseURL = cfg.Upstream.URL
+	cli, err := client.NewHTTPClient(cfg.Upstream.ClientIdentifier, int(cfg.Upstream.Timeout.Seconds()))
 	if err != nil {
-		log.Fatalf("failed to create an API client: %s", err)
+		fatal(logger, "failed to create an API client", err)
 	}
-	pedlar := pedal.New(cli)
+	cli = client.Logging(instrumentClient(registry, cli), logger)
+
+	// Update the gauges of the stations on every refresh
+	options := []pedal.Option{
+		pedal.WithLogger(logger),
+		pedal.OnRefresh(observeSnapshots(registry)),
+	}
+
+	// Persist the latest stations, if requested, so we
+	// have something to serve after a restart
+	if len(cfg.Cache.SnapshotFile) > 0 {
+		options = append(options, pedal.WithSnapshotFile(cfg.Cache.SnapshotFile))
+	}
+
+	// Record the availability history, if requested, and
+	// use it to forecast the availability
+	var historyStore history.Store
+	var forecaster pedal.Forecaster
+	if len(cfg.History.Dir) > 0 {
+		historyStore, err = history.Open(cfg.History.Dir, 0, cfg.History.Retention.Duration)
+		if err != nil {
+			fatal(logger, "failed to open history", err)
+		}
+		forecaster = forecast.New(historyStore)
+		options = append(options,
+			pedal.OnRefresh(history.Recorder(historyStore, logger)),
+			pedal.WithForecaster(forecaster),
+		)
+	}
+
+	// Evaluate the alert rules on every refresh, if requested
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var alerter *alert.Alerter
+	if len(cfg.AlertConfig) > 0 {
+		config, err := alert.LoadConfig(cfg.AlertConfig)
+		if err != nil {
+			fatal(logger, "failed to load alert config", err)
+		}
+		rules, err := config.ParseRules()
+		if err != nil {
+			fatal(logger, "failed to parse alert rules", err)
+		}
+		alerter = alert.New(rules, alert.NewWebhook(config.Webhook, 5), logger)
+		go alerter.Run(ctx)
+		options = append(options, pedal.OnRefresh(alerter.Observe))
+	}
+
+	// Publish the changes to the stations to the streams
+	broker := stream.New()
+	options = append(options, pedal.OnRefresh(broker.Observe))
+	pedlar := pedal.New(cli, options...)
+	observeAge(registry, pedlar)
+
+	// Refresh the stations in the background, so the history is
+	// recorded, alerts fire and changes are streamed even when
+	// nobody is asking
+	polled := make(chan struct{})
+	go func() {
+		pedal.Poll(ctx, pedlar, cfg.Upstream.PollInterval.Duration, logger)
+		close(polled)
+	}()
 
 	// Create a store that uses the pedlar interface
 	stationStore := store.NewStationStore(pedlar)
 
-	// Create a service that reads from the store
+	// Create a store that plans trips using the pedlar interface
+	tripStore := store.NewTripStore(trip.New(pedlar))
+
+	// Create a store that reads the recorded history, if any
+	historyStationStore := store.NewHistoryStore(pedlar, historyStore)
+
+	// Create a store that plans rebalancing, using the
+	// forecasted availability when history is recorded
+	rebalanceStore := store.NewRebalanceStore(rebalance.New(pedlar, forecaster))
+
+	// Create a store that aggregates the stations
+	statsStore := store.NewStatsStore(pedlar)
+
+	// Create a store that streams the changes to the stations
+	streamStore := store.NewStreamStore(pedlar, broker)
+
+	// Create a store that reports how up to date the stations are
+	statusStore := store.NewStatusStore(pedlar)
+
+	// Create services that read from the stores
 	stationService := api.NewStationService(stationStore)
+	tripService := api.NewTripService(tripStore)
+	historyService := api.NewHistoryService(historyStationStore)
+	rebalanceService := api.NewRebalanceService(rebalanceStore)
+	statsService := api.NewStatsService(statsStore)
+	streamService := api.NewStreamService(streamStore)
+	statusService := api.NewStatusService(statusStore)
 
 	// Create the endpoints that interact with the known services
 	services := api.Services{
-		Station: stationService,
+		Station:   stationService,
+		Trip:      tripService,
+		History:   historyService,
+		Rebalance: rebalanceService,
+		Stats:     statsService,
+		Stream:    streamService,
+		Status:    statusService,
 	}
-	endpoints := api.MakeEndpoints(services)
+	apiMetrics := api.NewMetrics(registry)
+	endpoints := api.InstrumentEndpoints(api.MakeEndpoints(services), apiMetrics)
 
 	// Create HTTP handlers and attach them to routes so they
-	// can be queried
-	handlers := api.AttachRoutes(api.MakeHandlers(endpoints))
+	// can be queried, every request and failure is logged
+	apiHandlers := api.MakeHandlers(endpoints, kithttp.ServerErrorLogger(logger))
+	middlewares := []func(http.Handler) http.Handler{api.InstrumentRoutes(apiMetrics)}
+	var grpcOptions []grpc.ServerOption
+
+	// Authenticate the API keys and limit the requests, if requested,
+	// the rejected requests are still counted by the metrics
+	if len(cfg.AccessConfig) > 0 {
+		config, err := api.LoadAccessConfig(cfg.AccessConfig)
+		if err != nil {
+			fatal(logger, "failed to load access config", err)
+		}
+		middlewares = append(middlewares, api.RateLimit(config))
+		grpcOptions = append(grpcOptions, api.RateLimitGRPC(config)...)
+	}
+	handlers := api.AttachRoutes(apiHandlers, logger, middlewares...)
+	probes := api.AttachProbes(apiHandlers)
 
-	// Create an entry point
+	// Create an entry point, rather than using the default
+	// serve mux, so only the known routes are served
+	mux := http.NewServeMux()
 	router := http.NewServeMux()
 	// Ensure that cross origin requests are accepted
-	http.Handle("/", md.Cors(router))
+	md.AllowedOrigins = cfg.CORS.AllowedOrigins
+	mux.Handle("/", md.Cors(router))
 	// Add the known routes to the primary router
 	router.Handle("/v1/", handlers)
+	router.Handle("/v2/", handlers)
+	router.Handle("/graphql", handlers)
+	// The probes are not cross origin resources, and not logged
+	mux.Handle("/healthz", probes)
+	mux.Handle("/readyz", probes)
+	mux.Handle("/metrics", registry)
 
-	// Create an HTTP server
+	// Create an HTTP server, streams are ended before the write
+	// timeout, the clients reconnect and resume where they left off
+	api.MaxStreamDuration = cfg.Server.WriteTimeout.Duration - config.StreamMargin
 	server := &http.Server{
-		ReadTimeout:  5 * time.Second,
-		WriteTimeout: 300 * time.Second,
-		Addr:         fmt.Sprintf(":%d", 8080),
+		Handler:      mux,
+		ReadTimeout:  cfg.Server.ReadTimeout.Duration,
+		WriteTimeout: cfg.Server.WriteTimeout.Duration,
+		Addr:         fmt.Sprintf(":%d", cfg.Server.Port),
 	}
 
-	// Start serving incoming HTTP requests
-	errs := make(chan error, 2)
-	go func() {
-		log.Printf("server is listening on port %d", 8080)
-		errs <- server.ListenAndServe()
-	}()
+	// Stop polling and end the streams once the server starts
+	// shutting down, so the requests in flight can complete
+	server.RegisterOnShutdown(cancel)
+	server.RegisterOnShutdown(broker.Close)
 
-	go func() {
-		c := make(chan os.Signal, 1)
-		signal.Notify(c, syscall.SIGINT)
-		errs <- fmt.Errorf("%s", <-c)
-	}()
+	// Serve the stations over gRPC as well, if requested, from
+	// the same endpoints, so the calls are logged and measured
+	runOptions := []api.RunOption{api.WithSessions(apiHandlers.Sessions)}
+	if cfg.Server.GRPCPort > 0 {
+		grpcServer := grpc.NewServer(grpcOptions...)
+		pb.RegisterStationServiceServer(grpcServer, api.MakeGRPCServer(endpoints, kitgrpc.ServerErrorLogger(logger)))
+		grpcListener, err := net.Listen("tcp", fmt.Sprintf(":%d", cfg.Server.GRPCPort))
+		if err != nil {
+			fatal(logger, "failed to listen for grpc", err)
+		}
+		runOptions = append(runOptions, api.WithGRPC(grpcServer, grpcListener))
+	}
+
+	// Serve incoming HTTP requests until SIGINT or SIGTERM
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		fatal(logger, "failed to listen", err)
+	}
+	err = api.Run(context.Background(), server, listener, cfg.Server.ShutdownGrace.Duration, logger, runOptions...)
+
+	// The history is closed even when the requests did not
+	// complete in time, so what was recorded is kept, but only
+	// once nothing is refreshed, so nothing is recorded after
+	cancel()
+	<-polled
+	pedal.Wait(context.Background(), pedlar)
+	if historyStore != nil {
+		historyStore.Close()
+	}
+	if err != nil {
+		fatal(logger, "terminated", err)
+	}
+	logger.Log("msg", "terminated")
+}
 
-	log.Println("terminated", <-errs)
+// fatal logs the error and exits
+func fatal(logger log.Logger, msg string, err error) {
+	logger.Log("msg", msg, "err", err)
+	os.Exit(1)
 }
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"math"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/stats"
+	"github.com/paulbes/go-pedal/pkg/metrics"
+)
+
+// instrumentClient counts and times the calls to the upstream API
+func instrumentClient(r *metrics.Registry, cli client.Client) client.Client {
+	return client.Instrument(cli,
+		r.NewCounter("pedal_upstream_requests_total", "The number of calls to the upstream API, by endpoint.", "endpoint"),
+		r.NewCounter("pedal_upstream_errors_total", "The number of failed calls to the upstream API, by endpoint.", "endpoint"),
+		r.NewHistogram("pedal_upstream_request_duration_seconds", "The duration of the calls to the upstream API in seconds, by endpoint.", nil, "endpoint"),
+	)
+}
+
+// observeSnapshots creates a function for pedal.OnRefresh, that
+// updates the gauges with the stations and their availability
+func observeSnapshots(r *metrics.Registry) func(model.Snapshot) {
+	stations := r.NewGauge("pedal_stations", "The number of stations, by state.", "state")
+	bikes := r.NewGauge("pedal_bikes_available", "The number of bikes available at the open stations.")
+	locks := r.NewGauge("pedal_locks_available", "The number of locks available at the open stations.")
+
+	return func(snapshot model.Snapshot) {
+		computed := stats.Compute(snapshot)
+		stations.With("state", "open").Set(float64(computed.Open))
+		stations.With("state", "closed").Set(float64(computed.Closed))
+		bikes.Set(float64(computed.Bikes))
+		locks.Set(float64(computed.Locks))
+	}
+}
+
+// observeAge reports the age of the stations whenever the metrics
+// are scraped, without refreshing them, it is NaN until they are loaded
+func observeAge(r *metrics.Registry, pedlar pedal.Pedlar) {
+	r.NewGaugeFunc("pedal_snapshot_age_seconds", "The number of seconds since the availability of the stations was updated.", func() float64 {
+		health := pedlar.Health()
+		if !health.Loaded {
+			return math.NaN()
+		}
+		return time.Since(health.UpdatedAt).Seconds()
+	})
+}
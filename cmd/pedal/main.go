@@ -1,37 +1,92 @@
 package main
 
 import (
+	"context"
 	"flag"
 	"fmt"
 	"log"
+	"math"
 	"os"
 	"strconv"
+	"strings"
 	"text/tabwriter"
 
 	"github.com/paulbes/go-pedal/pedal/client"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/rebalance"
+	"github.com/paulbes/go-pedal/pedal/stats"
+	"github.com/paulbes/go-pedal/pedal/trip"
+	"github.com/paulbes/go-pedal/pkg/config"
 
 	"github.com/fatih/color"
 	"github.com/paulbes/go-pedal/pedal"
 )
 
-var clientIdentifier string
-
-func init() {
-	flag.StringVar(&clientIdentifier, "client-identifier", "", "Oslo City Bike Client Identifier")
-	flag.Parse()
-}
-
 func main() {
+	// Read the settings of the upstream API from the flags,
+	// the environment and the config file
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [stations|trip|rebalance|stats] [command flags]\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	cfg := config.NewCLI(flag.CommandLine)
+	err := cfg.Load(os.Args[1:], os.Getenv)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+	if cfg.PrintConfig {
+		err = cfg.Print(os.Stdout)
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	err = cfg.Validate()
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(2)
+	}
+
 	// Create an http API client
-	cli, err := client.NewHTTPClient(clientIdentifier, 5)
+	client.BaseURL = cfg.Upstream.URL
+	cli, err := client.NewHTTPClient(cfg.Upstream.ClientIdentifier, int(cfg.Upstream.Timeout.Seconds()))
 	if err != nil {
 		log.Fatalf("failed to create an API client: %s", err)
 	}
+	pedlar := pedal.New(cli)
+	ctx := context.Background()
 
+	// Run the requested command, listing the
+	// stations is the default
+	command, args := flag.Arg(0), flag.Args()
+	if len(args) > 0 {
+		args = args[1:]
+	}
+	switch command {
+	case "", "stations":
+		err = printStations(ctx, pedlar)
+	case "trip":
+		err = printTrip(ctx, pedlar, args)
+	case "rebalance":
+		err = printRebalance(ctx, pedlar, args)
+	case "stats":
+		err = printStats(ctx, pedlar)
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// printStations pretty prints all stations
+func printStations(ctx context.Context, pedlar pedal.Pedlar) error {
 	// Read all stations
-	stations, err := pedal.New(cli).Stations()
+	stations, err := pedlar.Stations(ctx)
 	if err != nil {
-		log.Fatalf("failed to get stations: %s", err)
+		return fmt.Errorf("failed to get stations: %s", err)
 	}
 
 	// Pretty print stations
@@ -47,11 +102,168 @@ func main() {
 		))
 		_, err := w.Write(out)
 		if err != nil {
-			log.Fatalf("failed to write station information")
+			return fmt.Errorf("failed to write station information")
 		}
 	}
 	err = w.Flush()
 	if err != nil {
-		log.Fatalf("failed to flush stations: %s", err)
+		return fmt.Errorf("failed to flush stations: %s", err)
+	}
+	return nil
+}
+
+// printTrip pretty prints the suggested trips between
+// the two coordinates provided as arguments
+func printTrip(ctx context.Context, pedlar pedal.Pedlar, args []string) error {
+	var from, to string
+	var limit int
+	flags := flag.NewFlagSet("trip", flag.ExitOnError)
+	flags.StringVar(&from, "from", "", "Origin as latitude,longitude")
+	flags.StringVar(&to, "to", "", "Destination as latitude,longitude")
+	flags.IntVar(&limit, "limit", 3, "Number of alternatives to suggest")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	origin, err := parseCoord(from)
+	if err != nil {
+		return fmt.Errorf("failed to parse origin: %s", err)
+	}
+	destination, err := parseCoord(to)
+	if err != nil {
+		return fmt.Errorf("failed to parse destination: %s", err)
+	}
+
+	suggestions, err := trip.New(pedlar).Suggest(ctx, origin, destination, limit)
+	if err != nil {
+		return fmt.Errorf("failed to suggest trips: %s", err)
+	}
+	if len(suggestions) == 0 {
+		fmt.Println("no trips found, there are no open stations with bikes and free locks")
+		return nil
+	}
+
+	for i, suggestion := range suggestions {
+		fmt.Printf("%d. %s -> %s (%s)\n",
+			i+1,
+			color.GreenString(suggestion.Pickup.Title),
+			color.CyanString(suggestion.Dropoff.Title),
+			suggestion.Duration,
+		)
+		for _, reason := range suggestion.Reasons {
+			fmt.Printf("   - %s\n", reason)
+		}
+	}
+	return nil
+}
+
+// printRebalance pretty prints the stations that are out of
+// balance and the transfers suggested to even them out
+func printRebalance(ctx context.Context, pedlar pedal.Pedlar, args []string) error {
+	var capacity int
+	flags := flag.NewFlagSet("rebalance", flag.ExitOnError)
+	flags.IntVar(&capacity, "capacity", 20, "Number of bikes the truck can carry")
+	err := flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	plan, err := rebalance.New(pedlar, nil).Plan(ctx, capacity)
+	if err != nil {
+		return fmt.Errorf("failed to plan rebalancing: %s", err)
+	}
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 2, '\t', tabwriter.AlignRight)
+	w.Write([]byte(fmt.Sprintf("%-30s%-10s%-10s%-10s%s\n", "Station", "Locks", "Bikes", "Target", "Level")))
+	for _, status := range plan.Stations {
+		if status.Level == rebalance.Balanced {
+			continue
+		}
+		out := []byte(fmt.Sprintf("%-30s%-10s%-10s%-10d%s\n",
+			status.Station.Title,
+			color.CyanString(strconv.Itoa(status.Station.Availability.Locks)),
+			color.GreenString(strconv.Itoa(status.Station.Availability.Bikes)),
+			status.Target,
+			color.RedString(status.Level.String()),
+		))
+		_, err := w.Write(out)
+		if err != nil {
+			return fmt.Errorf("failed to write station information")
+		}
+	}
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush stations: %s", err)
+	}
+
+	if len(plan.Transfers) == 0 {
+		fmt.Println("\nno transfers needed, the stations are balanced")
+		return nil
+	}
+
+	fmt.Println()
+	for i, transfer := range plan.Transfers {
+		fmt.Printf("%d. move %d bikes from %s to %s (%.0f m)\n",
+			i+1,
+			transfer.Count,
+			color.GreenString(transfer.From.Title),
+			color.CyanString(transfer.To.Title),
+			transfer.Distance,
+		)
+	}
+	return nil
+}
+
+// printStats pretty prints the aggregate statistics
+// of all stations
+func printStats(ctx context.Context, pedlar pedal.Pedlar) error {
+	snapshot, err := pedlar.Snapshot(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to get stations: %s", err)
+	}
+	s := stats.Compute(snapshot)
+
+	w := new(tabwriter.Writer)
+	w.Init(os.Stdout, 0, 8, 2, '\t', 0)
+	fmt.Fprintf(w, "Updated\t%s\n", s.UpdatedAt.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(w, "Stations\t%d open, %s\n", s.Open, color.RedString("%d closed", s.Closed))
+	fmt.Fprintf(w, "Bikes\t%s available, about %d in use\n", color.GreenString(strconv.Itoa(s.Bikes)), s.BikesInUseEstimate)
+	fmt.Fprintf(w, "Locks\t%s available, %d in total\n", color.CyanString(strconv.Itoa(s.Locks)), s.Capacity)
+	fmt.Fprintf(w, "Empty\t%d stations\n", s.Empty)
+	fmt.Fprintf(w, "Full\t%d stations\n", s.Full)
+	for _, p := range s.Utilisation {
+		fmt.Fprintf(w, "Utilisation p%.0f\t%.0f%%\n", p.Percentile, p.Value*100)
+	}
+	err = w.Flush()
+	if err != nil {
+		return fmt.Errorf("failed to flush stats: %s", err)
+	}
+	return nil
+}
+
+// parseCoord converts a latitude,longitude string
+// to a coordinate that is within range
+func parseCoord(s string) (model.Coord, error) {
+	parts := strings.Split(s, ",")
+	if len(parts) != 2 {
+		return model.Coord{}, fmt.Errorf("expected latitude,longitude, got: %q", s)
+	}
+	lat, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil {
+		return model.Coord{}, err
+	}
+	lon, err := strconv.ParseFloat(strings.TrimSpace(parts[1]), 64)
+	if err != nil {
+		return model.Coord{}, err
+	}
+	// NaN is parsed, but is neither in nor out of range
+	if math.IsNaN(lat) || math.IsNaN(lon) || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return model.Coord{}, fmt.Errorf("expected a latitude between -90 and 90 and a longitude between -180 and 180, got: %q", s)
 	}
+	return model.Coord{
+		Latitude:  lat,
+		Longitude: lon,
+	}, nil
 }
@@ -0,0 +1,233 @@
+package alert
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// QueueSize is the number of notifications that can be waiting
+// for delivery, before new notifications are dropped
+var QueueSize = 100
+
+// Status of an alert
+const (
+	Firing   = "firing"
+	Resolved = "resolved"
+)
+
+// Alert describes a rule that started or
+// stopped firing for a station
+type Alert struct {
+	Rule      string     `json:"rule"`
+	Status    string     `json:"status"`
+	StationID int        `json:"station_id,omitempty"`
+	Station   string     `json:"station,omitempty"`
+	Value     int        `json:"value"`
+	StartsAt  time.Time  `json:"starts_at"`
+	EndsAt    *time.Time `json:"ends_at,omitempty"`
+}
+
+// Notifier delivers alerts to someone who cares, until
+// the context is cancelled
+type Notifier interface {
+	Notify(ctx context.Context, alerts []Alert) error
+}
+
+// Config contains the rules to evaluate and
+// where to deliver the alerts
+type Config struct {
+	Webhook string   `json:"webhook"`
+	Rules   []string `json:"rules"`
+}
+
+// LoadConfig reads a JSON config from the provided path
+func LoadConfig(path string) (*Config, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &Config{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse alert config %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// ParseRules parses every rule in the config
+func (c *Config) ParseRules() ([]Rule, error) {
+	rules := make([]Rule, 0, len(c.Rules))
+	for _, text := range c.Rules {
+		rule, err := Parse(text)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// key identifies an alert, so it is only delivered once
+type key struct {
+	rule      int
+	stationID int
+}
+
+// state keeps track of a condition that holds
+type state struct {
+	since  time.Time
+	firing bool
+	alert  Alert
+}
+
+// Alerter evaluates the rules against every snapshot and
+// notifies when an alert starts firing or is resolved
+type Alerter struct {
+	mutex    sync.Mutex
+	rules    []Rule
+	states   map[key]*state
+	notifier Notifier
+	queue    chan []Alert
+	logger   log.Logger
+}
+
+// New creates an alerter that evaluates the rules and
+// delivers the alerts to the notifier, the alerts that
+// could not be delivered are logged to the logger
+func New(rules []Rule, notifier Notifier, logger log.Logger) *Alerter {
+	return &Alerter{
+		rules:    rules,
+		states:   map[key]*state{},
+		notifier: notifier,
+		queue:    make(chan []Alert, QueueSize),
+		logger:   logger,
+	}
+}
+
+// Observe evaluates the snapshot and queues the resulting
+// alerts for delivery, it is meant to be registered with
+// pedal.OnRefresh
+func (a *Alerter) Observe(snapshot model.Snapshot) {
+	alerts := a.Evaluate(snapshot)
+	if len(alerts) == 0 {
+		return
+	}
+	select {
+	case a.queue <- alerts:
+	default:
+		a.logger.Log("msg", "delivery queue is full, dropping alerts", "alerts", len(alerts))
+	}
+}
+
+// Run delivers the queued alerts until the context is
+// cancelled, the alerts are delivered in order
+func (a *Alerter) Run(ctx context.Context) {
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case alerts := <-a.queue:
+			err := a.notifier.Notify(ctx, alerts)
+			if err != nil {
+				a.logger.Log("msg", "failed to deliver alerts", "alerts", len(alerts), "err", err)
+			}
+		}
+	}
+}
+
+// Evaluate compares the snapshot with the rules and returns the
+// alerts that started firing, or were resolved, by it. The time
+// of the snapshot is used as the clock, so a condition must hold
+// in every snapshot for the duration of the rule.
+func (a *Alerter) Evaluate(snapshot model.Snapshot) []Alert {
+	a.mutex.Lock()
+	defer a.mutex.Unlock()
+
+	now := snapshot.UpdatedAt
+	seen := map[key]bool{}
+	var alerts []Alert
+
+	hold := func(k key, alert Alert) {
+		seen[k] = true
+		s, hasKey := a.states[k]
+		if !hasKey {
+			s = &state{since: now}
+			a.states[k] = s
+		}
+		if s.firing || now.Sub(s.since) < a.rules[k.rule].For {
+			return
+		}
+		alert.Status = Firing
+		alert.StartsAt = s.since
+		s.firing, s.alert = true, alert
+		alerts = append(alerts, alert)
+	}
+
+	for i, rule := range a.rules {
+		switch rule.Scope {
+		case AllStations:
+			if snapshot.AllStationsClosed {
+				hold(key{rule: i}, Alert{Rule: rule.Text})
+			}
+		case OneStation:
+			station, hasKey := snapshot.Stations[rule.StationID]
+			if !hasKey {
+				continue
+			}
+			if ok, value := rule.matches(station); ok {
+				hold(key{rule: i, stationID: station.ID}, newAlert(rule, station, value))
+			}
+		case AnyStation:
+			for _, station := range snapshot.Stations {
+				if ok, value := rule.matches(station); ok {
+					hold(key{rule: i, stationID: station.ID}, newAlert(rule, station, value))
+				}
+			}
+		}
+	}
+
+	// Everything that no longer holds is resolved, if it fired
+	for k, s := range a.states {
+		if seen[k] {
+			continue
+		}
+		delete(a.states, k)
+		if !s.firing {
+			continue
+		}
+		resolved := s.alert
+		resolved.Status = Resolved
+		resolved.EndsAt = &now
+		if station, hasKey := snapshot.Stations[k.stationID]; hasKey {
+			_, resolved.Value = a.rules[k.rule].matches(station)
+		}
+		alerts = append(alerts, resolved)
+	}
+
+	sort.Slice(alerts, func(i, j int) bool {
+		if alerts[i].Rule == alerts[j].Rule {
+			return alerts[i].StationID < alerts[j].StationID
+		}
+		return alerts[i].Rule < alerts[j].Rule
+	})
+
+	return alerts
+}
+
+// newAlert creates an alert for the rule at the station
+func newAlert(rule Rule, station *model.Station, value int) Alert {
+	return Alert{
+		Rule:      rule.Text,
+		StationID: station.ID,
+		Station:   station.Title,
+		Value:     value,
+	}
+}
@@ -0,0 +1,276 @@
+package alert_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/alert"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// step is the state of the system at a single refresh
+type step struct {
+	bikes  [2]int
+	status model.Status
+}
+
+// scripted is a client that moves one step forward, five
+// minutes apart, every time the availability is read
+type scripted struct {
+	steps []step
+	pos   int
+}
+
+func (s *scripted) Stations(context.Context) (*model.Stations, error) {
+	return &model.Stations{
+		Stations: []*model.Station{
+			{ID: 1, InService: true, Title: "Antarctica", NumberOfLocks: 10},
+			{ID: 2, InService: true, Title: "Arctic", NumberOfLocks: 10},
+		},
+	}, nil
+}
+
+func (s *scripted) Availability(context.Context) (*model.StationAvailability, error) {
+	s.pos++
+	res := &model.StationAvailability{
+		UpdatedAt: epoch.Add(time.Duration(s.pos-1) * 5 * time.Minute),
+	}
+	for i, bikes := range s.steps[s.pos-1].bikes {
+		res.Stations = append(res.Stations, struct {
+			ID           int                `json:"ID"`
+			Availability model.Availability `json:"availability"`
+		}{
+			ID:           i + 1,
+			Availability: model.Availability{Bikes: bikes, Locks: 10 - bikes},
+		})
+	}
+	return res, nil
+}
+
+func (s *scripted) Status(context.Context) (*model.Status, error) {
+	status := s.steps[s.pos-1].status
+	return &status, nil
+}
+
+// receiver records the alerts posted to it, the first
+// delivery fails, so it has to be retried
+type receiver struct {
+	mutex    sync.Mutex
+	attempts int
+	received [][]alert.Alert
+}
+
+func (r *receiver) ServeHTTP(w http.ResponseWriter, req *http.Request) {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.attempts++
+	if r.attempts == 1 {
+		w.WriteHeader(http.StatusBadGateway)
+		return
+	}
+
+	var body struct {
+		Alerts []alert.Alert `json:"alerts"`
+	}
+	err := json.NewDecoder(req.Body).Decode(&body)
+	if err != nil {
+		w.WriteHeader(http.StatusBadRequest)
+		return
+	}
+	r.received = append(r.received, body.Alerts)
+}
+
+func (r *receiver) deliveries() [][]alert.Alert {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+	return r.received
+}
+
+// summary is the part of an alert that is compared
+type summary struct {
+	Rule      string
+	Status    string
+	StationID int
+	Value     int
+	StartsAt  time.Duration
+	EndsAt    time.Duration
+}
+
+func summarise(alerts []alert.Alert) []summary {
+	var res []summary
+	for _, a := range alerts {
+		s := summary{
+			Rule:      a.Rule,
+			Status:    a.Status,
+			StationID: a.StationID,
+			Value:     a.Value,
+			StartsAt:  a.StartsAt.Sub(epoch),
+		}
+		if a.EndsAt != nil {
+			s.EndsAt = a.EndsAt.Sub(epoch)
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+func TestAlerter_Webhook(t *testing.T) {
+	alert.Backoff = time.Millisecond
+
+	r := &receiver{}
+	server := httptest.NewServer(r)
+	defer server.Close()
+
+	var rules []alert.Rule
+	for _, text := range []string{
+		"station 1 bikes < 2 for 10 minutes",
+		"any station closed",
+		"all stations closed",
+	} {
+		rule, err := alert.Parse(text)
+		assert.Nil(t, err)
+		rules = append(rules, rule)
+	}
+
+	alerter := alert.New(rules, alert.NewWebhook(server.URL, 5), log.NewNopLogger())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go alerter.Run(ctx)
+
+	client := &scripted{
+		steps: []step{
+			// Station 1 runs low, but recovers before 10 minutes
+			{bikes: [2]int{1, 5}},
+			{bikes: [2]int{5, 5}},
+			// Station 1 runs low for 10 minutes
+			{bikes: [2]int{1, 5}},
+			{bikes: [2]int{1, 5}},
+			{bikes: [2]int{0, 5}},
+			// Station 2 closes, station 1 is still firing
+			{bikes: [2]int{0, 5}, status: model.Status{StationsClosed: []int{2}}},
+			{bikes: [2]int{0, 5}, status: model.Status{StationsClosed: []int{2}}},
+			// Everything recovers
+			{bikes: [2]int{5, 5}},
+			// Everything closes
+			{bikes: [2]int{5, 5}, status: model.Status{AllStationsClosed: true}},
+		},
+	}
+	pedlar := pedal.New(client, pedal.OnRefresh(alerter.Observe))
+	for range client.steps {
+		_, err := pedlar.Stations(context.Background())
+		assert.Nil(t, err)
+	}
+
+	expect := [][]summary{
+		{
+			{Rule: "station 1 bikes < 2 for 10 minutes", Status: alert.Firing, StationID: 1, Value: 0, StartsAt: 10 * time.Minute},
+		},
+		{
+			{Rule: "any station closed", Status: alert.Firing, StationID: 2, StartsAt: 25 * time.Minute},
+		},
+		{
+			{Rule: "any station closed", Status: alert.Resolved, StationID: 2, StartsAt: 25 * time.Minute, EndsAt: 35 * time.Minute},
+			{Rule: "station 1 bikes < 2 for 10 minutes", Status: alert.Resolved, StationID: 1, Value: 5, StartsAt: 10 * time.Minute, EndsAt: 35 * time.Minute},
+		},
+		{
+			{Rule: "all stations closed", Status: alert.Firing, StartsAt: 40 * time.Minute},
+			{Rule: "any station closed", Status: alert.Firing, StationID: 1, StartsAt: 40 * time.Minute},
+			{Rule: "any station closed", Status: alert.Firing, StationID: 2, StartsAt: 40 * time.Minute},
+		},
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for len(r.deliveries()) < len(expect) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	var got [][]summary
+	for _, alerts := range r.deliveries() {
+		got = append(got, summarise(alerts))
+	}
+	assert.Equal(t, expect, got)
+	r.mutex.Lock()
+	assert.Equal(t, len(expect)+1, r.attempts)
+	r.mutex.Unlock()
+	assert.Equal(t, "Antarctica", r.deliveries()[0][0].Station)
+}
+
+func TestWebhook_Notify(t *testing.T) {
+	alert.Backoff = time.Millisecond
+
+	testCases := []struct {
+		Name        string
+		Status      int
+		ExpectErr   bool
+		ExpectCalls int
+	}{
+		{
+			Name:        "Delivered",
+			Status:      http.StatusNoContent,
+			ExpectCalls: 1,
+		},
+		{
+			Name:        "Server errors are retried",
+			Status:      http.StatusServiceUnavailable,
+			ExpectErr:   true,
+			ExpectCalls: alert.Retries + 1,
+		},
+		{
+			Name:        "Client errors are not retried",
+			Status:      http.StatusBadRequest,
+			ExpectErr:   true,
+			ExpectCalls: 1,
+		},
+	}
+
+	for _, tc := range testCases {
+		calls := 0
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			calls++
+			w.WriteHeader(tc.Status)
+		}))
+
+		err := alert.NewWebhook(server.URL, 5).Notify(context.Background(), []alert.Alert{{Rule: "any station closed", Status: alert.Firing}})
+		server.Close()
+
+		assert.Equal(t, tc.ExpectErr, err != nil, tc.Name)
+		assert.Equal(t, tc.ExpectCalls, calls, tc.Name)
+	}
+}
+
+func TestWebhook_Notify_Cancelled(t *testing.T) {
+	backoff := alert.Backoff
+	alert.Backoff = time.Hour
+	defer func() { alert.Backoff = backoff }()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		// Cancel while the delivery is waiting to be retried
+		cancel()
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- alert.NewWebhook(server.URL, 5).Notify(ctx, []alert.Alert{{Rule: "any station closed", Status: alert.Firing}})
+	}()
+
+	select {
+	case err := <-done:
+		assert.Equal(t, context.Canceled, err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the retry did not stop when cancelled")
+	}
+}
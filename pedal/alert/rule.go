@@ -0,0 +1,176 @@
+package alert
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// Scope determines which stations a rule applies to
+type Scope int
+
+// The available scopes
+const (
+	// OneStation applies the rule to a single station
+	OneStation Scope = iota
+	// AnyStation applies the rule to every station separately
+	AnyStation
+	// AllStations applies the rule to the system as a whole
+	AllStations
+)
+
+// Metric is what a rule compares
+type Metric string
+
+// The available metrics
+const (
+	Bikes  Metric = "bikes"
+	Locks  Metric = "locks"
+	Closed Metric = "closed"
+)
+
+// Rule describes a condition that fires an alert when it
+// has held for at least the provided duration
+type Rule struct {
+	Text      string
+	Scope     Scope
+	StationID int
+	Metric    Metric
+	Operator  string
+	Threshold int
+	For       time.Duration
+}
+
+// Parse converts a textual rule to a rule, the
+// following forms are understood:
+//
+//	station 183 bikes < 2 for 10 minutes
+//	any station locks <= 1 for 5m
+//	station 183 closed
+//	any station closed
+//	all stations closed for 1h
+func Parse(text string) (Rule, error) {
+	rule := Rule{Text: text}
+	tokens := strings.Fields(strings.ToLower(text))
+	next := func() string {
+		if len(tokens) == 0 {
+			return ""
+		}
+		token := tokens[0]
+		tokens = tokens[1:]
+		return token
+	}
+
+	switch token := next(); token {
+	case "station":
+		id, err := strconv.Atoi(next())
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: expected a station id", text)
+		}
+		rule.Scope, rule.StationID = OneStation, id
+	case "any":
+		if next() != "station" {
+			return Rule{}, fmt.Errorf("rule %q: expected 'any station'", text)
+		}
+		rule.Scope = AnyStation
+	case "all":
+		if next() != "stations" {
+			return Rule{}, fmt.Errorf("rule %q: expected 'all stations'", text)
+		}
+		rule.Scope = AllStations
+	default:
+		return Rule{}, fmt.Errorf("rule %q: expected station, any station or all stations, got: %q", text, token)
+	}
+
+	switch metric := Metric(next()); metric {
+	case Closed:
+		rule.Metric = metric
+	case Bikes, Locks:
+		if rule.Scope == AllStations {
+			return Rule{}, fmt.Errorf("rule %q: all stations only supports closed", text)
+		}
+		rule.Metric = metric
+		rule.Operator = next()
+		if _, known := operators[rule.Operator]; !known {
+			return Rule{}, fmt.Errorf("rule %q: unknown operator: %q", text, rule.Operator)
+		}
+		threshold, err := strconv.Atoi(next())
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: expected a threshold", text)
+		}
+		rule.Threshold = threshold
+	default:
+		return Rule{}, fmt.Errorf("rule %q: expected bikes, locks or closed, got: %q", text, metric)
+	}
+
+	if len(tokens) > 0 {
+		if next() != "for" {
+			return Rule{}, fmt.Errorf("rule %q: expected 'for' followed by a duration", text)
+		}
+		d, err := parseDuration(tokens)
+		if err != nil {
+			return Rule{}, fmt.Errorf("rule %q: %s", text, err)
+		}
+		rule.For = d
+	}
+
+	return rule, nil
+}
+
+// operators contains the comparisons a rule can make
+var operators = map[string]func(a, b int) bool{
+	"<":  func(a, b int) bool { return a < b },
+	"<=": func(a, b int) bool { return a <= b },
+	">":  func(a, b int) bool { return a > b },
+	">=": func(a, b int) bool { return a >= b },
+	"==": func(a, b int) bool { return a == b },
+	"!=": func(a, b int) bool { return a != b },
+}
+
+// units maps the spelled out units to durations
+var units = map[string]time.Duration{
+	"second":  time.Second,
+	"seconds": time.Second,
+	"minute":  time.Minute,
+	"minutes": time.Minute,
+	"hour":    time.Hour,
+	"hours":   time.Hour,
+}
+
+// parseDuration understands both 10m and 10 minutes
+func parseDuration(tokens []string) (time.Duration, error) {
+	switch len(tokens) {
+	case 1:
+		return time.ParseDuration(tokens[0])
+	case 2:
+		n, err := strconv.Atoi(tokens[0])
+		unit, known := units[tokens[1]]
+		if err != nil || !known {
+			return 0, fmt.Errorf("could not parse duration: %q", strings.Join(tokens, " "))
+		}
+		return time.Duration(n) * unit, nil
+	default:
+		return 0, fmt.Errorf("could not parse duration: %q", strings.Join(tokens, " "))
+	}
+}
+
+// matches determines if the station satisfies the
+// condition of the rule, and the value compared
+func (r Rule) matches(station *model.Station) (bool, int) {
+	switch r.Metric {
+	case Bikes:
+		return operators[r.Operator](station.Availability.Bikes, r.Threshold), station.Availability.Bikes
+	case Locks:
+		return operators[r.Operator](station.Availability.Locks, r.Threshold), station.Availability.Locks
+	default:
+		return station.Closed, 0
+	}
+}
+
+// String returns the rule as it was written
+func (r Rule) String() string {
+	return r.Text
+}
@@ -0,0 +1,103 @@
+package alert_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/alert"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Text      string
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name: "Station bikes for minutes",
+			Text: "station 183 bikes < 2 for 10 minutes",
+			Expect: alert.Rule{
+				Text:      "station 183 bikes < 2 for 10 minutes",
+				Scope:     alert.OneStation,
+				StationID: 183,
+				Metric:    alert.Bikes,
+				Operator:  "<",
+				Threshold: 2,
+				For:       10 * time.Minute,
+			},
+		},
+		{
+			Name: "Any station locks with short duration",
+			Text: "Any station locks <= 1 for 5m",
+			Expect: alert.Rule{
+				Text:      "Any station locks <= 1 for 5m",
+				Scope:     alert.AnyStation,
+				Metric:    alert.Locks,
+				Operator:  "<=",
+				Threshold: 1,
+				For:       5 * time.Minute,
+			},
+		},
+		{
+			Name: "Any station closed",
+			Text: "any station closed",
+			Expect: alert.Rule{
+				Text:   "any station closed",
+				Scope:  alert.AnyStation,
+				Metric: alert.Closed,
+			},
+		},
+		{
+			Name: "All stations closed",
+			Text: "all stations closed for 1 hour",
+			Expect: alert.Rule{
+				Text:   "all stations closed for 1 hour",
+				Scope:  alert.AllStations,
+				Metric: alert.Closed,
+				For:    time.Hour,
+			},
+		},
+		{
+			Name:      "Unknown scope",
+			Text:      "some stations closed",
+			ExpectErr: true,
+			Expect:    `rule "some stations closed": expected station, any station or all stations, got: "some"`,
+		},
+		{
+			Name:      "Bad station id",
+			Text:      "station abc closed",
+			ExpectErr: true,
+			Expect:    `rule "station abc closed": expected a station id`,
+		},
+		{
+			Name:      "Unknown operator",
+			Text:      "station 1 bikes ~ 2",
+			ExpectErr: true,
+			Expect:    `rule "station 1 bikes ~ 2": unknown operator: "~"`,
+		},
+		{
+			Name:      "All stations with bikes",
+			Text:      "all stations bikes < 2",
+			ExpectErr: true,
+			Expect:    `rule "all stations bikes < 2": all stations only supports closed`,
+		},
+		{
+			Name:      "Bad duration",
+			Text:      "any station closed for a while",
+			ExpectErr: true,
+			Expect:    `rule "any station closed for a while": could not parse duration: "a while"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := alert.Parse(tc.Text)
+		if tc.ExpectErr {
+			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
+			continue
+		}
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, tc.Expect, got, tc.Name)
+	}
+}
@@ -0,0 +1,93 @@
+package alert
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// Retries is the number of times a failed
+// delivery is retried
+var Retries = 3
+
+// Backoff is how long to wait before the first retry,
+// the wait is doubled for every following retry
+var Backoff = time.Second
+
+// webhook delivers alerts by posting them as JSON to a URL
+type webhook struct {
+	url    string
+	client *http.Client
+}
+
+// payload is the body posted to the webhook
+type payload struct {
+	Alerts []Alert `json:"alerts"`
+}
+
+// NewWebhook creates a notifier that posts the alerts to the URL
+func NewWebhook(url string, timeoutInSec int) Notifier {
+	return &webhook{
+		url: url,
+		client: &http.Client{
+			Timeout: time.Duration(timeoutInSec) * time.Second,
+		},
+	}
+}
+
+// Notify posts the alerts to the webhook, retrying server errors
+// and failed connections, until the context is cancelled
+func (w *webhook) Notify(ctx context.Context, alerts []Alert) error {
+	body, err := json.Marshal(payload{Alerts: alerts})
+	if err != nil {
+		return err
+	}
+
+	wait := Backoff
+	for attempt := 0; ; attempt++ {
+		retry, err := w.post(ctx, body)
+		if err == nil {
+			return nil
+		}
+		if !retry || attempt >= Retries {
+			return fmt.Errorf("webhook %s failed after %d attempts: %s", w.url, attempt+1, err)
+		}
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-time.After(wait):
+		}
+		wait *= 2
+	}
+}
+
+// post performs a single delivery and reports
+// if a failure is worth retrying
+func (w *webhook) post(ctx context.Context, body []byte) (bool, error) {
+	req, err := http.NewRequest(http.MethodPost, w.url, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := w.client.Do(req.WithContext(ctx))
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	// Drain the body, so the connection can be reused
+	io.Copy(ioutil.Discard, resp.Body)
+
+	switch {
+	case resp.StatusCode >= 200 && resp.StatusCode < 300:
+		return false, nil
+	case resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests:
+		return true, fmt.Errorf("unexpected status: %s", resp.Status)
+	default:
+		return false, fmt.Errorf("unexpected status: %s", resp.Status)
+	}
+}
@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"io/ioutil"
@@ -8,14 +9,15 @@ import (
 	"time"
 
 	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pkg/logging"
 )
 
 // Client defines the interface that
 // an API client must implement
 type Client interface {
-	Stations() (*model.Stations, error)
-	Availability() (*model.StationAvailability, error)
-	Status() (*model.Status, error)
+	Stations(ctx context.Context) (*model.Stations, error)
+	Availability(ctx context.Context) (*model.StationAvailability, error)
+	Status(ctx context.Context) (*model.Status, error)
 }
 
 // BaseURL provides the base for performing queries
@@ -43,12 +45,12 @@ func NewHTTPClient(clientID string, timeoutInSec int) (Client, error) {
 }
 
 // Status loads the status of the stations
-func (c *httpClient) Status() (*model.Status, error) {
+func (c *httpClient) Status(ctx context.Context) (*model.Status, error) {
 	var status = struct {
 		Status model.Status `json:"status"`
 	}{}
 
-	err := c.do("status", &status)
+	err := c.do(ctx, "status", &status)
 	if err != nil {
 		return nil, err
 	}
@@ -57,10 +59,10 @@ func (c *httpClient) Status() (*model.Status, error) {
 }
 
 // Stations loads all known stations from the API
-func (c *httpClient) Stations() (*model.Stations, error) {
+func (c *httpClient) Stations(ctx context.Context) (*model.Stations, error) {
 	var stations model.Stations
 
-	err := c.do("stations", &stations)
+	err := c.do(ctx, "stations", &stations)
 	if err != nil {
 		return nil, err
 	}
@@ -70,10 +72,10 @@ func (c *httpClient) Stations() (*model.Stations, error) {
 
 // Availability fetches the availability of bikes and locks at all
 // locations.
-func (c *httpClient) Availability() (*model.StationAvailability, error) {
+func (c *httpClient) Availability(ctx context.Context) (*model.StationAvailability, error) {
 	var stationAvailability model.StationAvailability
 
-	err := c.do("stations/availability", &stationAvailability)
+	err := c.do(ctx, "stations/availability", &stationAvailability)
 	if err != nil {
 		return nil, err
 	}
@@ -81,13 +83,18 @@ func (c *httpClient) Availability() (*model.StationAvailability, error) {
 	return &stationAvailability, nil
 }
 
-// do executes a request towards the oslo city bike API
-func (c *httpClient) do(endpoint string, to interface{}) error {
+// do executes a request towards the oslo city bike API, the
+// request ID of the context, if any, is forwarded to the API
+func (c *httpClient) do(ctx context.Context, endpoint string, to interface{}) error {
 	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%s", c.baseURL, endpoint), nil)
 	if err != nil {
 		return err
 	}
+	req = req.WithContext(ctx)
 	req.Header.Add("Client-Identifier", c.clientIdentifier)
+	if id := logging.RequestID(ctx); len(id) > 0 {
+		req.Header.Add(logging.RequestIDHeader, id)
+	}
 
 	resp, err := c.client.Do(req)
 	if err != nil {
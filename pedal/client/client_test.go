@@ -1,6 +1,7 @@
 package client
 
 import (
+	"context"
 	"net/http"
 	"testing"
 	"time"
@@ -69,7 +70,7 @@ func TestHttpClient_Stations(t *testing.T) {
 		gock.Clean()
 		tc.Mock()
 
-		stations, err := cli.Stations()
+		stations, err := cli.Stations(context.Background())
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
@@ -136,7 +137,7 @@ func TestHttpClient_Availability(t *testing.T) {
 	for _, tc := range testCases {
 		gock.Clean()
 		tc.Mock()
-		avail, err := cli.Availability()
+		avail, err := cli.Availability(context.Background())
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
@@ -191,7 +192,7 @@ func TestHttpClient_Status(t *testing.T) {
 	for _, tc := range testCases {
 		gock.Clean()
 		tc.Mock()
-		avail, err := cli.Status()
+		avail, err := cli.Status(context.Background())
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
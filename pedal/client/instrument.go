@@ -0,0 +1,63 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pkg/metrics"
+)
+
+// The endpoints of the API, as they are labelled in the metrics
+const (
+	EndpointStations     = "stations"
+	EndpointAvailability = "availability"
+	EndpointStatus       = "status"
+)
+
+// instrumentedClient counts and times the calls to the API
+type instrumentedClient struct {
+	next     Client
+	requests metrics.Counter
+	failures metrics.Counter
+	duration metrics.Histogram
+}
+
+// Instrument wraps the client, so the calls to every endpoint of the
+// API are counted and timed, the failed calls are counted separately.
+// The metrics are labelled with the endpoint.
+func Instrument(next Client, requests, failures metrics.Counter, duration metrics.Histogram) Client {
+	return &instrumentedClient{
+		next:     next,
+		requests: requests,
+		failures: failures,
+		duration: duration,
+	}
+}
+
+// Status loads the status of the stations
+func (c *instrumentedClient) Status(ctx context.Context) (status *model.Status, err error) {
+	defer c.observe(EndpointStatus, time.Now(), &err)
+	return c.next.Status(ctx)
+}
+
+// Stations loads all known stations from the API
+func (c *instrumentedClient) Stations(ctx context.Context) (stations *model.Stations, err error) {
+	defer c.observe(EndpointStations, time.Now(), &err)
+	return c.next.Stations(ctx)
+}
+
+// Availability fetches the availability of bikes and locks at all
+// locations.
+func (c *instrumentedClient) Availability(ctx context.Context) (availability *model.StationAvailability, err error) {
+	defer c.observe(EndpointAvailability, time.Now(), &err)
+	return c.next.Availability(ctx)
+}
+
+func (c *instrumentedClient) observe(endpoint string, begin time.Time, err *error) {
+	c.requests.With("endpoint", endpoint).Add(1)
+	c.duration.With("endpoint", endpoint).Observe(time.Since(begin).Seconds())
+	if *err != nil {
+		c.failures.With("endpoint", endpoint).Add(1)
+	}
+}
@@ -0,0 +1,61 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+// stubClient fails every call to the status endpoint
+type stubClient struct{}
+
+func (stubClient) Status(context.Context) (*model.Status, error) {
+	return nil, fmt.Errorf("nope")
+}
+
+func (stubClient) Stations(context.Context) (*model.Stations, error) {
+	return &model.Stations{}, nil
+}
+
+func (stubClient) Availability(context.Context) (*model.StationAvailability, error) {
+	return &model.StationAvailability{}, nil
+}
+
+func TestInstrument(t *testing.T) {
+	r := metrics.NewRegistry()
+	cli := Instrument(stubClient{},
+		r.NewCounter("requests_total", "", "endpoint"),
+		r.NewCounter("errors_total", "", "endpoint"),
+		r.NewHistogram("duration_seconds", "", []float64{60}, "endpoint"),
+	)
+
+	stations, err := cli.Stations(context.Background())
+	assert.Nil(t, err)
+	assert.NotNil(t, stations)
+	_, err = cli.Stations(context.Background())
+	assert.Nil(t, err)
+	_, err = cli.Availability(context.Background())
+	assert.Nil(t, err)
+	_, err = cli.Status(context.Background())
+	assert.Equal(t, "nope", err.Error())
+
+	var b bytes.Buffer
+	r.WriteTo(&b)
+	for _, line := range []string{
+		`requests_total{endpoint="availability"} 1`,
+		`requests_total{endpoint="stations"} 2`,
+		`requests_total{endpoint="status"} 1`,
+		`errors_total{endpoint="status"} 1`,
+		`duration_seconds_count{endpoint="stations"} 2`,
+		`duration_seconds_bucket{endpoint="status",le="60"} 1`,
+	} {
+		assert.Contains(t, strings.Split(b.String(), "\n"), line)
+	}
+	assert.NotContains(t, b.String(), `errors_total{endpoint="stations"}`)
+}
@@ -0,0 +1,54 @@
+package client
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pkg/logging"
+)
+
+// loggingClient logs the calls to the API
+type loggingClient struct {
+	next   Client
+	logger log.Logger
+}
+
+// Logging wraps the client, so every call to the API is logged
+// with its endpoint, duration and error, together with the ID of
+// the request that caused it
+func Logging(next Client, logger log.Logger) Client {
+	return &loggingClient{
+		next:   next,
+		logger: logger,
+	}
+}
+
+// Status loads the status of the stations
+func (c *loggingClient) Status(ctx context.Context) (status *model.Status, err error) {
+	defer c.log(ctx, EndpointStatus, time.Now(), &err)
+	return c.next.Status(ctx)
+}
+
+// Stations loads all known stations from the API
+func (c *loggingClient) Stations(ctx context.Context) (stations *model.Stations, err error) {
+	defer c.log(ctx, EndpointStations, time.Now(), &err)
+	return c.next.Stations(ctx)
+}
+
+// Availability fetches the availability of bikes and locks at all
+// locations.
+func (c *loggingClient) Availability(ctx context.Context) (availability *model.StationAvailability, err error) {
+	defer c.log(ctx, EndpointAvailability, time.Now(), &err)
+	return c.next.Availability(ctx)
+}
+
+func (c *loggingClient) log(ctx context.Context, endpoint string, begin time.Time, err *error) {
+	logging.With(ctx, c.logger).Log(
+		"msg", "called upstream API",
+		"endpoint", endpoint,
+		"took", time.Since(begin),
+		"err", *err,
+	)
+}
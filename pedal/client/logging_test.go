@@ -0,0 +1,31 @@
+package client
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pkg/logging"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLogging(t *testing.T) {
+	var b bytes.Buffer
+	cli := Logging(stubClient{}, log.NewLogfmtLogger(&b))
+
+	ctx := logging.WithRequestID(context.Background(), "abc123")
+	_, err := cli.Stations(ctx)
+	assert.Nil(t, err)
+	_, err = cli.Status(ctx)
+	assert.Equal(t, "nope", err.Error())
+
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	assert.Len(t, lines, 2)
+	assert.Contains(t, lines[0], "request_id=abc123")
+	assert.Contains(t, lines[0], "endpoint=stations")
+	assert.Contains(t, lines[0], "err=null")
+	assert.Contains(t, lines[1], "endpoint=status")
+	assert.Contains(t, lines[1], "err=nope")
+}
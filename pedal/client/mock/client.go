@@ -1,41 +1,43 @@
 package mock
 
 import (
+	"context"
+
 	cli "github.com/paulbes/go-pedal/pedal/client"
 	"github.com/paulbes/go-pedal/pedal/model"
 )
 
 type client struct {
-	StationsFn     func() (*model.Stations, error)
-	AvailabilityFn func() (*model.StationAvailability, error)
-	StatusFn       func() (*model.Status, error)
+	StationsFn     func(ctx context.Context) (*model.Stations, error)
+	AvailabilityFn func(ctx context.Context) (*model.StationAvailability, error)
+	StatusFn       func(ctx context.Context) (*model.Status, error)
 }
 
 // Stations returns the output of the mocked stations function
-func (c *client) Stations() (*model.Stations, error) {
-	return c.StationsFn()
+func (c *client) Stations(ctx context.Context) (*model.Stations, error) {
+	return c.StationsFn(ctx)
 }
 
 // Availability returns the output of the mocked availability function
-func (c *client) Availability() (*model.StationAvailability, error) {
-	return c.AvailabilityFn()
+func (c *client) Availability(ctx context.Context) (*model.StationAvailability, error) {
+	return c.AvailabilityFn(ctx)
 }
 
 // Status returns the output of the mocked status function
-func (c *client) Status() (*model.Status, error) {
-	return c.StatusFn()
+func (c *client) Status(ctx context.Context) (*model.Status, error) {
+	return c.StatusFn(ctx)
 }
 
 // NewClient creates a new mock that returns the provided arguments
 func NewClient(stations *model.Stations, availability *model.StationAvailability, status *model.Status, err error) cli.Client {
 	return &client{
-		StationsFn: func() (*model.Stations, error) {
+		StationsFn: func(context.Context) (*model.Stations, error) {
 			return stations, err
 		},
-		AvailabilityFn: func() (*model.StationAvailability, error) {
+		AvailabilityFn: func(context.Context) (*model.StationAvailability, error) {
 			return availability, err
 		},
-		StatusFn: func() (*model.Status, error) {
+		StatusFn: func(context.Context) (*model.Status, error) {
 			return status, err
 		},
 	}
@@ -0,0 +1,251 @@
+package forecast
+
+import (
+	"math"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/history"
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// Weeks is the number of previous weeks used to
+// determine the seasonal baseline
+var Weeks = 4
+
+// Slot is the width of the time of day window that
+// is considered the same time in previous periods
+var Slot = 15 * time.Minute
+
+// TrendWindow is how far back the current trend
+// is determined from
+var TrendWindow = time.Hour
+
+// TrendDecay determines how quickly the current trend
+// flattens out, and the forecast moves to the seasonal
+// baseline, as the horizon grows
+var TrendDecay = 30 * time.Minute
+
+// Source provides the recorded history a forecast is based on
+type Source interface {
+	Query(stationID int, from, to time.Time) ([]history.Sample, error)
+}
+
+// Forecaster predicts the availability of
+// bikes and locks at a station
+type Forecaster struct {
+	source Source
+}
+
+// New creates a forecaster that reads the history from source
+func New(source Source) *Forecaster {
+	return &Forecaster{
+		source: source,
+	}
+}
+
+// Forecast predicts the availability at the station the provided
+// horizon after now. The prediction starts from the current
+// availability, smoothed by the recent history, and blends the
+// current trend with the change that is usually seen on the same
+// weekday and time of day. The trend dominates short horizons
+// and the seasonal change long ones.
+func (f *Forecaster) Forecast(station *model.Station, now time.Time, horizon time.Duration) (*model.Forecast, error) {
+	target := now.Add(horizon)
+
+	trend, err := f.trend(station.ID, now)
+	if err != nil {
+		return nil, err
+	}
+
+	season, err := f.season(station.ID, now, target)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := float64(station.NumberOfLocks)
+	if capacity == 0 {
+		capacity = float64(station.Availability.Bikes + station.Availability.Locks)
+	}
+
+	// The trend is damped, so it flattens out as the horizon grows
+	weight := math.Exp(-horizon.Seconds() / TrendDecay.Seconds())
+	damped := TrendDecay.Seconds() * (1 - weight)
+	predict := func(current, slope, change, probability float64, seasonal bool) (float64, float64) {
+		trended := clamp(current+slope*damped, capacity)
+		if !seasonal {
+			return trended, atLeastOne(trended)
+		}
+		expected := weight*trended + (1-weight)*clamp(current+change, capacity)
+		return expected, weight*atLeastOne(trended) + (1-weight)*probability
+	}
+
+	res := &model.Forecast{
+		StationID: station.ID,
+		At:        target,
+		Horizon:   horizon,
+	}
+	bikes, locks := float64(station.Availability.Bikes), float64(station.Availability.Locks)
+	if trend.found {
+		bikes, locks = trend.bikesLevel, trend.locksLevel
+	}
+
+	res.Bikes, res.BikeProbability = predict(
+		bikes,
+		trend.bikes,
+		season.bikes,
+		season.bikeProbability,
+		season.found,
+	)
+	res.Locks, res.LockProbability = predict(
+		locks,
+		trend.locks,
+		season.locks,
+		season.lockProbability,
+		season.found,
+	)
+
+	return res, nil
+}
+
+// slope is the level at now, and the rate of change per
+// second, of the bikes and locks
+type slope struct {
+	found      bool
+	bikes      float64
+	locks      float64
+	bikesLevel float64
+	locksLevel float64
+}
+
+// trend determines the current level and rate of change using
+// a least squares fit of the samples within the trend window
+func (f *Forecaster) trend(stationID int, now time.Time) (slope, error) {
+	samples, err := f.source.Query(stationID, now.Add(-TrendWindow), now.Add(1))
+	if err != nil {
+		return slope{}, err
+	}
+	if len(samples) < 3 {
+		return slope{}, nil
+	}
+
+	var sx, sxx, sb, sxb, sl, sxl float64
+	for _, sample := range samples {
+		x := sample.Time.Sub(now).Seconds()
+		sx += x
+		sxx += x * x
+		sb += float64(sample.Bikes)
+		sxb += x * float64(sample.Bikes)
+		sl += float64(sample.Locks)
+		sxl += x * float64(sample.Locks)
+	}
+
+	n := float64(len(samples))
+	d := n*sxx - sx*sx
+	if d == 0 {
+		return slope{}, nil
+	}
+
+	res := slope{
+		found: true,
+		bikes: (n*sxb - sx*sb) / d,
+		locks: (n*sxl - sx*sl) / d,
+	}
+	res.bikesLevel = (sb - res.bikes*sx) / n
+	res.locksLevel = (sl - res.locks*sx) / n
+	return res, nil
+}
+
+// seasonal describes the change usually seen between now
+// and the target, and how often there was at least one
+// bike or lock available at the target
+type seasonal struct {
+	found           bool
+	bikes           float64
+	locks           float64
+	bikeProbability float64
+	lockProbability float64
+}
+
+// season compares the availability at the time of now and the
+// target in previous weeks, if there are no previous weeks the
+// previous days are used instead
+func (f *Forecaster) season(stationID int, now, target time.Time) (seasonal, error) {
+	s, err := f.seasonOver(stationID, now, target, 7*24*time.Hour, Weeks)
+	if err != nil || s.found {
+		return s, err
+	}
+	return f.seasonOver(stationID, now, target, 24*time.Hour, 7)
+}
+
+func (f *Forecaster) seasonOver(stationID int, now, target time.Time, period time.Duration, periods int) (seasonal, error) {
+	var res seasonal
+	var count, bikesAvailable, locksAvailable, targetSamples int
+	for i := 1; i <= periods; i++ {
+		lag := time.Duration(i) * period
+		before, err := f.slot(stationID, now.Add(-lag))
+		if err != nil {
+			return seasonal{}, err
+		}
+		after, err := f.slot(stationID, target.Add(-lag))
+		if err != nil {
+			return seasonal{}, err
+		}
+		if len(before) == 0 || len(after) == 0 {
+			continue
+		}
+
+		bikesBefore, locksBefore := mean(before)
+		bikesAfter, locksAfter := mean(after)
+		res.bikes += bikesAfter - bikesBefore
+		res.locks += locksAfter - locksBefore
+		count++
+
+		for _, sample := range after {
+			if sample.Bikes > 0 {
+				bikesAvailable++
+			}
+			if sample.Locks > 0 {
+				locksAvailable++
+			}
+		}
+		targetSamples += len(after)
+	}
+
+	if count == 0 {
+		return seasonal{}, nil
+	}
+
+	res.found = true
+	res.bikes /= float64(count)
+	res.locks /= float64(count)
+	res.bikeProbability = float64(bikesAvailable) / float64(targetSamples)
+	res.lockProbability = float64(locksAvailable) / float64(targetSamples)
+	return res, nil
+}
+
+// slot returns the samples within the slot centred at the time
+func (f *Forecaster) slot(stationID int, at time.Time) ([]history.Sample, error) {
+	return f.source.Query(stationID, at.Add(-Slot/2), at.Add(Slot/2))
+}
+
+// mean returns the average bikes and locks of the samples
+func mean(samples []history.Sample) (float64, float64) {
+	var bikes, locks int
+	for _, sample := range samples {
+		bikes += sample.Bikes
+		locks += sample.Locks
+	}
+	return float64(bikes) / float64(len(samples)), float64(locks) / float64(len(samples))
+}
+
+// clamp keeps the value between zero and the capacity
+func clamp(value, capacity float64) float64 {
+	return math.Max(0, math.Min(capacity, value))
+}
+
+// atLeastOne returns the probability of at least one
+// occurrence when the expected number is provided,
+// assuming a poisson distribution
+func atLeastOne(expected float64) float64 {
+	return 1 - math.Exp(-expected)
+}
@@ -0,0 +1,137 @@
+package forecast_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/forecast"
+	"github.com/paulbes/go-pedal/pedal/history"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+const capacity = 20
+
+// epoch is a monday
+var epoch = time.Date(2018, 9, 3, 0, 0, 0, 0, time.UTC)
+
+// memory is an in-memory history source, ordered by time
+type memory []history.Sample
+
+func (m memory) Query(stationID int, from, to time.Time) ([]history.Sample, error) {
+	i := sort.Search(len(m), func(i int) bool { return !m[i].Time.Before(from) })
+	j := sort.Search(len(m), func(i int) bool { return !m[i].Time.Before(to) })
+	var res []history.Sample
+	for _, sample := range m[i:j] {
+		if sample.StationID == stationID {
+			res = append(res, sample)
+		}
+	}
+	return res, nil
+}
+
+// at returns the sample recorded at, or just before, the time
+func (m memory) at(t time.Time) history.Sample {
+	i := sort.Search(len(m), func(i int) bool { return m[i].Time.After(t) })
+	return m[i-1]
+}
+
+// synthetic creates five weeks of history every five minutes for a
+// station that slowly fills up during the day, is emptied by
+// commuters every weekday morning and refilled in the afternoon
+func synthetic() memory {
+	r := rand.New(rand.NewSource(42))
+	var m memory
+	for t := epoch; t.Before(epoch.Add(5 * 7 * 24 * time.Hour)); t = t.Add(5 * time.Minute) {
+		hours := float64(t.Hour()) + float64(t.Minute())/60
+		bikes := 10 + 6*math.Sin(2*math.Pi*(hours-6)/24)
+		if t.Weekday() != time.Saturday && t.Weekday() != time.Sunday && hours >= 7.5 && hours < 17 {
+			bikes -= 14 * math.Min(1, math.Min((hours-7.5)*2, 17-hours))
+		}
+		bikes += r.NormFloat64() * 0.7
+
+		b := int(math.Max(0, math.Min(capacity, math.Round(bikes))))
+		m = append(m, history.Sample{
+			Time:      t,
+			StationID: 1,
+			Bikes:     b,
+			Locks:     capacity - b,
+			InService: true,
+		})
+	}
+	return m
+}
+
+func TestForecaster_Backtest(t *testing.T) {
+	m := synthetic()
+	f := forecast.New(m)
+	start := epoch.Add(4 * 7 * 24 * time.Hour)
+
+	for _, horizon := range []time.Duration{20 * time.Minute, time.Hour} {
+		var forecastErr, persistenceErr float64
+		var empty, available []float64
+		var n int
+		for now := start; now.Before(start.Add(7*24*time.Hour - horizon)); now = now.Add(30 * time.Minute) {
+			current, actual := m.at(now), m.at(now.Add(horizon))
+			station := &model.Station{
+				ID:            1,
+				NumberOfLocks: capacity,
+				Availability:  model.Availability{Bikes: current.Bikes, Locks: current.Locks},
+			}
+
+			got, err := f.Forecast(station, now, horizon)
+			assert.Nil(t, err)
+			assert.Equal(t, now.Add(horizon), got.At)
+			assert.InDelta(t, capacity, got.Bikes+got.Locks, 0.5)
+
+			forecastErr += math.Abs(got.Bikes - float64(actual.Bikes))
+			persistenceErr += math.Abs(float64(current.Bikes - actual.Bikes))
+			if actual.Bikes == 0 {
+				empty = append(empty, got.BikeProbability)
+			} else if actual.Bikes > 3 {
+				available = append(available, got.BikeProbability)
+			}
+			n++
+		}
+
+		forecastErr /= float64(n)
+		persistenceErr /= float64(n)
+		t.Logf("horizon %s: forecast MAE %.2f, persistence MAE %.2f", horizon, forecastErr, persistenceErr)
+		assert.True(t, forecastErr < persistenceErr, "forecast must beat persistence at %s", horizon)
+		assert.True(t, forecastErr < 1.5, "forecast MAE too large at %s", horizon)
+		assert.True(t, average(empty) < 0.5, "empty station must be unlikely to have bikes at %s", horizon)
+		assert.True(t, average(available) > 0.9, "full station must be likely to have bikes at %s", horizon)
+	}
+}
+
+func TestForecaster_WithoutHistory(t *testing.T) {
+	f := forecast.New(memory{})
+	station := &model.Station{
+		ID:            1,
+		NumberOfLocks: 10,
+		Availability:  model.Availability{Bikes: 4, Locks: 6},
+	}
+
+	got, err := f.Forecast(station, epoch, 20*time.Minute)
+	assert.Nil(t, err)
+	assert.Equal(t, &model.Forecast{
+		StationID:       1,
+		At:              epoch.Add(20 * time.Minute),
+		Horizon:         20 * time.Minute,
+		Bikes:           4,
+		Locks:           6,
+		BikeProbability: 1 - math.Exp(-4),
+		LockProbability: 1 - math.Exp(-6),
+	}, got)
+}
+
+func average(values []float64) float64 {
+	var sum float64
+	for _, v := range values {
+		sum += v
+	}
+	return sum / float64(len(values))
+}
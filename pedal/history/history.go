@@ -0,0 +1,61 @@
+package history
+
+import (
+	"sort"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// Sample represents the state of a single
+// station at a point in time
+type Sample struct {
+	Time      time.Time
+	StationID int
+	Bikes     int
+	Locks     int
+	Closed    bool
+	InService bool
+}
+
+// Store defines the methods a storage of
+// historical samples must implement
+type Store interface {
+	Append(samples ...Sample) error
+	Query(stationID int, from, to time.Time) ([]Sample, error)
+	At(at time.Time) ([]Sample, error)
+	Close() error
+}
+
+// Samples converts a snapshot into one sample per station,
+// ordered by station ID
+func Samples(snapshot model.Snapshot) []Sample {
+	samples := make([]Sample, 0, len(snapshot.Stations))
+	for _, station := range snapshot.Stations {
+		samples = append(samples, Sample{
+			Time:      snapshot.UpdatedAt,
+			StationID: station.ID,
+			Bikes:     station.Availability.Bikes,
+			Locks:     station.Availability.Locks,
+			Closed:    station.Closed,
+			InService: station.InService,
+		})
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].StationID < samples[j].StationID
+	})
+	return samples
+}
+
+// Recorder returns a function that appends every snapshot
+// it receives to the store, it is meant to be registered
+// with pedal.OnRefresh, failures are logged to the logger
+func Recorder(store Store, logger log.Logger) func(snapshot model.Snapshot) {
+	return func(snapshot model.Snapshot) {
+		err := store.Append(Samples(snapshot)...)
+		if err != nil {
+			logger.Log("msg", "failed to record snapshot", "updated_at", snapshot.UpdatedAt, "err", err)
+		}
+	}
+}
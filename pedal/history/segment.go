@@ -0,0 +1,412 @@
+package history
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// A segment is an append-only file containing fixed size
+// records, each record is laid out as follows (little endian):
+//
+//	time       int64   unix nanoseconds
+//	station    uint32
+//	bikes      uint16
+//	locks      uint16
+//	flags      uint8   closed, in service
+//	checksum   uint32  crc32 of the preceding bytes
+//
+// Once a segment is sealed an index file is written next to
+// it, listing the record offsets of every station.
+const (
+	recordSize    = 21
+	checksumStart = 17
+
+	flagClosed    = 1 << 0
+	flagInService = 1 << 1
+
+	segmentExt = ".seg"
+	indexExt   = ".idx"
+)
+
+// segment keeps track of a single segment file
+type segment struct {
+	path  string
+	start time.Time
+	// file and index are only set for the active segment
+	file  segmentFile
+	size  int64
+	index map[int][]uint32
+}
+
+// segmentFile is the file of the active segment, it
+// is implemented by *os.File
+type segmentFile interface {
+	io.Writer
+	io.ReaderAt
+	io.Seeker
+	io.Closer
+	Truncate(size int64) error
+	Sync() error
+}
+
+// segmentName returns the file name of the segment starting at
+// the provided time, the name sorts in chronological order
+func segmentName(start time.Time) string {
+	return fmt.Sprintf("%020d%s", start.UnixNano(), segmentExt)
+}
+
+// listSegments returns all segments in the directory,
+// ordered from oldest to newest
+func listSegments(dir string) ([]*segment, error) {
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var segments []*segment
+	for _, file := range files {
+		name := file.Name()
+		if file.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		nanos, err := strconv.ParseInt(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, &segment{
+			path:  filepath.Join(dir, name),
+			start: time.Unix(0, nanos).UTC(),
+		})
+	}
+
+	sort.Slice(segments, func(i, j int) bool {
+		return segments[i].start.Before(segments[j].start)
+	})
+
+	return segments, nil
+}
+
+// indexPath returns the path of the index for the segment
+func (s *segment) indexPath() string {
+	return strings.TrimSuffix(s.path, segmentExt) + indexExt
+}
+
+// open opens the segment for appending, the index is rebuilt
+// from the content and a partially written record at the end
+// is discarded
+func (s *segment) open() error {
+	file, err := os.OpenFile(s.path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return err
+	}
+
+	data, err := ioutil.ReadAll(file)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.index = map[int][]uint32{}
+	var size int64
+	for ; size+recordSize <= int64(len(data)); size += recordSize {
+		sample, err := decodeRecord(data[size : size+recordSize])
+		if err != nil {
+			break
+		}
+		s.index[sample.StationID] = append(s.index[sample.StationID], uint32(size))
+	}
+
+	if size != int64(len(data)) {
+		err = file.Truncate(size)
+		if err != nil {
+			file.Close()
+			return err
+		}
+	}
+
+	_, err = file.Seek(size, io.SeekStart)
+	if err != nil {
+		file.Close()
+		return err
+	}
+
+	s.file = file
+	s.size = size
+	return nil
+}
+
+// append writes the samples to the end of the segment
+func (s *segment) append(samples []Sample) error {
+	buf := make([]byte, 0, len(samples)*recordSize)
+	for _, sample := range samples {
+		buf = append(buf, encodeRecord(sample)...)
+	}
+
+	n, err := s.file.Write(buf)
+	if err != nil {
+		// Discard what was written, so the records and the
+		// index still line up when the next samples are appended
+		if n > 0 {
+			err = s.discard(err)
+		}
+		return err
+	}
+
+	for i, sample := range samples {
+		offset := uint32(s.size + int64(i*recordSize))
+		s.index[sample.StationID] = append(s.index[sample.StationID], offset)
+	}
+	s.size += int64(len(buf))
+
+	return nil
+}
+
+// discard truncates the segment back to the records in the index,
+// after the write failed with the error
+func (s *segment) discard(writeErr error) error {
+	err := s.file.Truncate(s.size)
+	if err == nil {
+		_, err = s.file.Seek(s.size, io.SeekStart)
+	}
+	if err != nil {
+		return fmt.Errorf("%s, and failed to discard the partial write: %s", writeErr, err)
+	}
+	return writeErr
+}
+
+// seal flushes the segment to disk, writes the index
+// and closes the file
+func (s *segment) seal() error {
+	err := s.file.Sync()
+	if err != nil {
+		return err
+	}
+
+	err = writeIndex(s.indexPath(), s.index)
+	if err != nil {
+		return err
+	}
+
+	err = s.file.Close()
+	s.file = nil
+	s.index = nil
+	return err
+}
+
+// query returns the samples for the station within the time range
+func (s *segment) query(stationID int, from, to time.Time) ([]Sample, error) {
+	var offsets []uint32
+	var reader io.ReaderAt
+	if s.file != nil {
+		offsets, reader = s.index[stationID], s.file
+	} else {
+		var err error
+		offsets, err = readIndex(s.indexPath(), stationID)
+		if os.IsNotExist(err) {
+			// We were stopped before the index could be
+			// written, so we rebuild it
+			err = s.open()
+			if err == nil {
+				err = s.seal()
+			}
+			if err != nil {
+				return nil, err
+			}
+			offsets, err = readIndex(s.indexPath(), stationID)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		file, err := os.Open(s.path)
+		if err != nil {
+			return nil, err
+		}
+		defer file.Close()
+		reader = file
+	}
+
+	var samples []Sample
+	record := make([]byte, recordSize)
+	for _, offset := range offsets {
+		_, err := reader.ReadAt(record, int64(offset))
+		if err != nil {
+			return nil, err
+		}
+		sample, err := decodeRecord(record)
+		if err != nil {
+			return nil, err
+		}
+		if !sample.Time.Before(from) && sample.Time.Before(to) {
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples, nil
+}
+
+// scan returns the samples of all stations within the time range
+func (s *segment) scan(from, to time.Time) ([]Sample, error) {
+	var data []byte
+	if s.file != nil {
+		data = make([]byte, s.size)
+		_, err := s.file.ReadAt(data, 0)
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		var err error
+		data, err = ioutil.ReadFile(s.path)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	var samples []Sample
+	for offset := 0; offset+recordSize <= len(data); offset += recordSize {
+		sample, err := decodeRecord(data[offset : offset+recordSize])
+		if err != nil {
+			return nil, err
+		}
+		if !sample.Time.Before(from) && sample.Time.Before(to) {
+			samples = append(samples, sample)
+		}
+	}
+
+	return samples, nil
+}
+
+// remove deletes the segment and its index
+func (s *segment) remove() error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	err := os.Remove(s.indexPath())
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return os.Remove(s.path)
+}
+
+// encodeRecord converts a sample to its binary representation
+func encodeRecord(sample Sample) []byte {
+	record := make([]byte, recordSize)
+	binary.LittleEndian.PutUint64(record[0:8], uint64(sample.Time.UnixNano()))
+	binary.LittleEndian.PutUint32(record[8:12], uint32(sample.StationID))
+	binary.LittleEndian.PutUint16(record[12:14], uint16(sample.Bikes))
+	binary.LittleEndian.PutUint16(record[14:16], uint16(sample.Locks))
+	var flags byte
+	if sample.Closed {
+		flags |= flagClosed
+	}
+	if sample.InService {
+		flags |= flagInService
+	}
+	record[16] = flags
+	binary.LittleEndian.PutUint32(record[checksumStart:], crc32.ChecksumIEEE(record[:checksumStart]))
+	return record
+}
+
+// decodeRecord converts the binary representation to a sample
+func decodeRecord(record []byte) (Sample, error) {
+	if crc32.ChecksumIEEE(record[:checksumStart]) != binary.LittleEndian.Uint32(record[checksumStart:]) {
+		return Sample{}, fmt.Errorf("record checksum mismatch")
+	}
+	return Sample{
+		Time:      time.Unix(0, int64(binary.LittleEndian.Uint64(record[0:8]))).UTC(),
+		StationID: int(binary.LittleEndian.Uint32(record[8:12])),
+		Bikes:     int(binary.LittleEndian.Uint16(record[12:14])),
+		Locks:     int(binary.LittleEndian.Uint16(record[14:16])),
+		Closed:    record[16]&flagClosed != 0,
+		InService: record[16]&flagInService != 0,
+	}, nil
+}
+
+// writeIndex stores the offsets of every station, the index is laid
+// out as a station count, followed by the station ID, the number of
+// offsets and the offsets themselves for each station
+func writeIndex(path string, index map[int][]uint32) error {
+	ids := make([]int, 0, len(index))
+	for id := range index {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	buf := new(bytes.Buffer)
+	write := func(v uint32) {
+		binary.Write(buf, binary.LittleEndian, v)
+	}
+	write(uint32(len(ids)))
+	for _, id := range ids {
+		write(uint32(id))
+		write(uint32(len(index[id])))
+		for _, offset := range index[id] {
+			write(offset)
+		}
+	}
+
+	tmp := path + ".tmp"
+	err := ioutil.WriteFile(tmp, buf.Bytes(), 0644)
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// readIndex returns the offsets of the station from the index
+func readIndex(path string, stationID int) ([]uint32, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	pos := 0
+	read := func() (uint32, error) {
+		if pos+4 > len(data) {
+			return 0, fmt.Errorf("index %s is truncated", path)
+		}
+		v := binary.LittleEndian.Uint32(data[pos:])
+		pos += 4
+		return v, nil
+	}
+
+	count, err := read()
+	if err != nil {
+		return nil, err
+	}
+	for i := uint32(0); i < count; i++ {
+		id, err := read()
+		if err != nil {
+			return nil, err
+		}
+		n, err := read()
+		if err != nil {
+			return nil, err
+		}
+		if int(id) != stationID {
+			pos += int(n) * 4
+			continue
+		}
+		offsets := make([]uint32, n)
+		for j := range offsets {
+			offsets[j], err = read()
+			if err != nil {
+				return nil, err
+			}
+		}
+		return offsets, nil
+	}
+
+	return nil, nil
+}
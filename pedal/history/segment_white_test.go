@@ -0,0 +1,76 @@
+package history
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// failingFile writes part of what it is given and then fails
+type failingFile struct {
+	segmentFile
+	written int
+}
+
+func (f *failingFile) Write(p []byte) (int, error) {
+	n, _ := f.segmentFile.Write(p[:f.written])
+	return n, fmt.Errorf("disk is full")
+}
+
+func TestSegment_append(t *testing.T) {
+	epoch := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+	sample := func(minute int) Sample {
+		return Sample{Time: epoch.Add(time.Duration(minute) * time.Minute), StationID: 1, Bikes: minute, InService: true}
+	}
+
+	testCases := []struct {
+		Name    string
+		Written int
+	}{
+		{
+			Name:    "Nothing written",
+			Written: 0,
+		},
+		{
+			Name:    "Part of a record written",
+			Written: recordSize / 2,
+		},
+		{
+			Name:    "Some of the records written",
+			Written: recordSize,
+		},
+	}
+
+	for _, tc := range testCases {
+		dir, err := ioutil.TempDir("", "history")
+		assert.Nil(t, err, tc.Name)
+
+		s := &segment{path: filepath.Join(dir, segmentName(epoch)), start: epoch}
+		assert.Nil(t, s.open(), tc.Name)
+		assert.Nil(t, s.append([]Sample{sample(0)}), tc.Name)
+
+		// The failed write is discarded, and the next append
+		// lines up with the records that were written
+		file := s.file
+		s.file = &failingFile{segmentFile: file, written: tc.Written}
+		assert.EqualError(t, s.append([]Sample{sample(1), sample(2)}), "disk is full", tc.Name)
+		s.file = file
+		assert.Equal(t, int64(recordSize), s.size, tc.Name)
+		info, err := os.Stat(s.path)
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, int64(recordSize), info.Size(), tc.Name)
+
+		assert.Nil(t, s.append([]Sample{sample(3)}), tc.Name)
+		got, err := s.query(1, epoch, epoch.Add(time.Hour))
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, []Sample{sample(0), sample(3)}, got, tc.Name)
+
+		assert.Nil(t, s.seal(), tc.Name)
+		os.RemoveAll(dir)
+	}
+}
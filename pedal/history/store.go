@@ -0,0 +1,232 @@
+package history
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultSegmentDuration is the time span covered by a
+// segment file when nothing else is provided
+var DefaultSegmentDuration = 1 * time.Hour
+
+// DefaultRetention is how long samples are kept when
+// nothing else is provided
+var DefaultRetention = 7 * 24 * time.Hour
+
+// fileStore is an append-only time-series store that keeps
+// samples in segment files, each covering a fixed time span
+type fileStore struct {
+	mutex           sync.Mutex
+	dir             string
+	segmentDuration time.Duration
+	retention       time.Duration
+	segments        []*segment
+	active          *segment
+}
+
+// Open creates or opens a store in the provided directory, samples
+// are written to segment files covering segmentDuration each, and
+// segments older than the retention are removed. A zero duration
+// selects the default.
+func Open(dir string, segmentDuration, retention time.Duration) (Store, error) {
+	if segmentDuration <= 0 {
+		segmentDuration = DefaultSegmentDuration
+	}
+	if retention <= 0 {
+		retention = DefaultRetention
+	}
+
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return nil, err
+	}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	s := &fileStore{
+		dir:             dir,
+		segmentDuration: segmentDuration,
+		retention:       retention,
+		segments:        segments,
+	}
+
+	// Continue appending to the newest segment
+	if len(segments) > 0 {
+		active := segments[len(segments)-1]
+		err = active.open()
+		if err != nil {
+			return nil, err
+		}
+		s.active = active
+
+		err = s.enforceRetention(active.start.Add(segmentDuration))
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return s, nil
+}
+
+// Append writes the samples to the segments covering their
+// time, samples must not be older than the active segment
+func (s *fileStore) Append(samples ...Sample) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	for len(samples) > 0 {
+		start := samples[0].Time.UTC().Truncate(s.segmentDuration)
+
+		// Collect the samples that belong to the same segment
+		n := 1
+		for n < len(samples) && samples[n].Time.UTC().Truncate(s.segmentDuration).Equal(start) {
+			n++
+		}
+
+		seg, err := s.segmentFor(start)
+		if err != nil {
+			return err
+		}
+		err = seg.append(samples[:n])
+		if err != nil {
+			return err
+		}
+		samples = samples[n:]
+	}
+
+	return nil
+}
+
+// segmentFor returns the segment samples starting at the
+// provided time should be appended to, rotating the active
+// segment when required
+func (s *fileStore) segmentFor(start time.Time) (*segment, error) {
+	if s.active != nil {
+		if s.active.start.Equal(start) {
+			return s.active, nil
+		}
+		if start.Before(s.active.start) {
+			return nil, fmt.Errorf("samples from %s are older than the active segment from %s", start, s.active.start)
+		}
+		err := s.active.seal()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	seg := &segment{
+		path:  filepath.Join(s.dir, segmentName(start)),
+		start: start,
+	}
+	err := seg.open()
+	if err != nil {
+		return nil, err
+	}
+	s.segments = append(s.segments, seg)
+	s.active = seg
+
+	return seg, s.enforceRetention(start.Add(s.segmentDuration))
+}
+
+// enforceRetention removes the segments that only contain
+// samples older than the retention, relative to now
+func (s *fileStore) enforceRetention(now time.Time) error {
+	cutoff := now.Add(-s.retention)
+	var kept []*segment
+	for _, seg := range s.segments {
+		if seg != s.active && !seg.start.Add(s.segmentDuration).After(cutoff) {
+			err := seg.remove()
+			if err != nil {
+				return err
+			}
+			continue
+		}
+		kept = append(kept, seg)
+	}
+	s.segments = kept
+	return nil
+}
+
+// Query returns the samples of the station in the time
+// range [from, to), ordered by time
+func (s *fileStore) Query(stationID int, from, to time.Time) ([]Sample, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	var samples []Sample
+	for _, seg := range s.segments {
+		if !seg.start.Before(to) || !seg.start.Add(s.segmentDuration).After(from) {
+			continue
+		}
+		found, err := seg.query(stationID, from, to)
+		if err != nil {
+			return nil, err
+		}
+		samples = append(samples, found...)
+	}
+
+	sort.SliceStable(samples, func(i, j int) bool {
+		return samples[i].Time.Before(samples[j].Time)
+	})
+
+	return samples, nil
+}
+
+// At returns the most recent sample of every station at, or
+// before, the provided time, ordered by station ID. Samples
+// older than one segment duration are not considered.
+func (s *fileStore) At(at time.Time) ([]Sample, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	from, to := at.Add(-s.segmentDuration), at.Add(1)
+	latest := map[int]Sample{}
+	for _, seg := range s.segments {
+		if !seg.start.Before(to) || !seg.start.Add(s.segmentDuration).After(from) {
+			continue
+		}
+		found, err := seg.scan(from, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, sample := range found {
+			if current, hasKey := latest[sample.StationID]; !hasKey || !sample.Time.Before(current.Time) {
+				latest[sample.StationID] = sample
+			}
+		}
+	}
+
+	samples := make([]Sample, 0, len(latest))
+	for _, sample := range latest {
+		samples = append(samples, sample)
+	}
+	sort.Slice(samples, func(i, j int) bool {
+		return samples[i].StationID < samples[j].StationID
+	})
+
+	return samples, nil
+}
+
+// Close flushes and closes the active segment
+func (s *fileStore) Close() error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	if s.active == nil {
+		return nil
+	}
+	err := s.active.file.Sync()
+	if err == nil {
+		err = s.active.file.Close()
+	}
+	s.active.file = nil
+	s.active = nil
+	return err
+}
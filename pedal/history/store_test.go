@@ -0,0 +1,242 @@
+package history_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pedal/history"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+
+// samples creates one sample for each of the stations every
+// minute, starting at the epoch
+func samples(minutes int, stations ...int) []history.Sample {
+	var res []history.Sample
+	for m := 0; m < minutes; m++ {
+		for _, id := range stations {
+			res = append(res, history.Sample{
+				Time:      epoch.Add(time.Duration(m) * time.Minute),
+				StationID: id,
+				Bikes:     m % 10,
+				Locks:     10 - m%10,
+				Closed:    m%7 == 0,
+				InService: true,
+			})
+		}
+	}
+	return res
+}
+
+func openStore(t *testing.T, dir string, retention time.Duration) history.Store {
+	store, err := history.Open(dir, 30*time.Minute, retention)
+	assert.Nil(t, err)
+	return store
+}
+
+func TestStore_Query(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := openStore(t, dir, 0)
+	for _, sample := range samples(120, 1, 2) {
+		assert.Nil(t, store.Append(sample))
+	}
+
+	testCases := []struct {
+		Name      string
+		StationID int
+		From      time.Time
+		To        time.Time
+		Expect    int
+	}{
+		{
+			Name:      "Everything",
+			StationID: 1,
+			From:      epoch,
+			To:        epoch.Add(2 * time.Hour),
+			Expect:    120,
+		},
+		{
+			Name:      "Across segments",
+			StationID: 2,
+			From:      epoch.Add(20 * time.Minute),
+			To:        epoch.Add(40 * time.Minute),
+			Expect:    20,
+		},
+		{
+			Name:      "Unknown station",
+			StationID: 3,
+			From:      epoch,
+			To:        epoch.Add(2 * time.Hour),
+			Expect:    0,
+		},
+		{
+			Name:      "Before anything",
+			StationID: 1,
+			From:      epoch.Add(-time.Hour),
+			To:        epoch,
+			Expect:    0,
+		},
+	}
+
+	check := func(store history.Store) {
+		for _, tc := range testCases {
+			got, err := store.Query(tc.StationID, tc.From, tc.To)
+			assert.Nil(t, err, tc.Name)
+			assert.Len(t, got, tc.Expect, tc.Name)
+			for i, sample := range got {
+				assert.Equal(t, tc.StationID, sample.StationID, tc.Name)
+				assert.False(t, sample.Time.Before(tc.From), tc.Name)
+				assert.True(t, sample.Time.Before(tc.To), tc.Name)
+				if i > 0 {
+					assert.True(t, got[i-1].Time.Before(sample.Time), tc.Name)
+				}
+			}
+		}
+	}
+
+	check(store)
+	got, err := store.Query(1, epoch.Add(7*time.Minute), epoch.Add(8*time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []history.Sample{samples(120, 1)[7]}, got)
+
+	// Everything must survive a restart
+	assert.Nil(t, store.Close())
+	store = openStore(t, dir, 0)
+	check(store)
+	assert.Nil(t, store.Close())
+}
+
+func TestStore_Append(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := openStore(t, dir, 0)
+	assert.Nil(t, store.Append(samples(40, 1)...))
+
+	// Samples older than the active segment are refused
+	err = store.Append(samples(1, 1)...)
+	assert.NotNil(t, err)
+
+	// A partially written record is discarded on open
+	assert.Nil(t, store.Close())
+	segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	assert.Nil(t, err)
+	assert.Len(t, segments, 2)
+	f, err := os.OpenFile(segments[1], os.O_APPEND|os.O_WRONLY, 0644)
+	assert.Nil(t, err)
+	_, err = f.Write([]byte{1, 2, 3})
+	assert.Nil(t, err)
+	assert.Nil(t, f.Close())
+
+	store = openStore(t, dir, 0)
+	got, err := store.Query(1, epoch, epoch.Add(time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, got, 40)
+	assert.Nil(t, store.Close())
+}
+
+func TestStore_Retention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := openStore(t, dir, time.Hour)
+	assert.Nil(t, store.Append(samples(180, 1)...))
+
+	got, err := store.Query(1, epoch, epoch.Add(3*time.Hour))
+	assert.Nil(t, err)
+	assert.Len(t, got, 60)
+	assert.Equal(t, epoch.Add(2*time.Hour), got[0].Time)
+
+	segments, err := filepath.Glob(filepath.Join(dir, "*.seg"))
+	assert.Nil(t, err)
+	assert.Len(t, segments, 2)
+	assert.Nil(t, store.Close())
+}
+
+func TestStore_At(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := openStore(t, dir, 0)
+	assert.Nil(t, store.Append(samples(90, 1, 2)...))
+
+	testCases := []struct {
+		Name   string
+		At     time.Time
+		Expect []history.Sample
+	}{
+		{
+			Name:   "Exact",
+			At:     epoch.Add(35 * time.Minute),
+			Expect: []history.Sample{samples(90, 1, 2)[70], samples(90, 1, 2)[71]},
+		},
+		{
+			Name:   "In between",
+			At:     epoch.Add(35*time.Minute + 30*time.Second),
+			Expect: []history.Sample{samples(90, 1, 2)[70], samples(90, 1, 2)[71]},
+		},
+		{
+			Name:   "After everything",
+			At:     epoch.Add(100 * time.Minute),
+			Expect: []history.Sample{samples(90, 1, 2)[178], samples(90, 1, 2)[179]},
+		},
+		{
+			Name:   "Too long after",
+			At:     epoch.Add(3 * time.Hour),
+			Expect: []history.Sample{},
+		},
+		{
+			Name:   "Before anything",
+			At:     epoch.Add(-time.Minute),
+			Expect: []history.Sample{},
+		},
+	}
+
+	for _, tc := range testCases {
+		got, err := store.At(tc.At)
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, tc.Expect, got, tc.Name)
+	}
+	assert.Nil(t, store.Close())
+}
+
+func TestRecorder(t *testing.T) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	store := openStore(t, dir, 0)
+	record := history.Recorder(store, log.NewNopLogger())
+	record(model.Snapshot{
+		UpdatedAt: epoch,
+		Stations: map[int]*model.Station{
+			2: {ID: 2, InService: true, Availability: model.Availability{Bikes: 3, Locks: 7}},
+			1: {ID: 1, Closed: true},
+		},
+	})
+
+	got, err := store.Query(2, epoch, epoch.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []history.Sample{
+		{Time: epoch, StationID: 2, Bikes: 3, Locks: 7, InService: true},
+	}, got)
+
+	got, err = store.Query(1, epoch, epoch.Add(time.Minute))
+	assert.Nil(t, err)
+	assert.Equal(t, []history.Sample{
+		{Time: epoch, StationID: 1, Closed: true},
+	}, got)
+	assert.Nil(t, store.Close())
+}
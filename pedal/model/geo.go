@@ -0,0 +1,169 @@
+package model
+
+import "math"
+
+// EarthRadius is the mean radius of the earth in metres
+const EarthRadius = 6371008.8
+
+// Polygon represents a closed area described by its
+// corners, the last corner is implicitly connected to
+// the first one
+type Polygon []Coord
+
+// Distance returns the great-circle distance in metres
+// between two coordinates, using the haversine formula
+func (c Coord) Distance(to Coord) float64 {
+	lat1, lat2 := radians(c.Latitude), radians(to.Latitude)
+	dLat := lat2 - lat1
+	dLon := radians(to.Longitude - c.Longitude)
+
+	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(lat1)*math.Cos(lat2)*math.Sin(dLon/2)*math.Sin(dLon/2)
+
+	return 2 * EarthRadius * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
+}
+
+// Contains returns true if the coordinate is inside the
+// polygon, points exactly on an edge may go either way
+func (p Polygon) Contains(c Coord) bool {
+	if len(p) < 3 {
+		return false
+	}
+
+	inside := false
+	for i, j := 0, len(p)-1; i < len(p); j, i = i, i+1 {
+		a, b := p[i], p[j]
+		if (a.Latitude > c.Latitude) != (b.Latitude > c.Latitude) {
+			lon := (b.Longitude-a.Longitude)*(c.Latitude-a.Latitude)/(b.Latitude-a.Latitude) + a.Longitude
+			if c.Longitude < lon {
+				inside = !inside
+			}
+		}
+	}
+
+	return inside
+}
+
+// Area returns the area of the polygon in square metres
+func (p Polygon) Area() float64 {
+	if len(p) < 3 {
+		return 0
+	}
+	return math.Abs(signedArea(p.project(p[0])))
+}
+
+// Centroid returns the geometric centre of the polygon, if the
+// polygon has no area the average of the corners is returned
+func (p Polygon) Centroid() Coord {
+	if len(p) == 0 {
+		return Coord{}
+	}
+
+	ref := p[0]
+	points := p.project(ref)
+	area := signedArea(points)
+	if len(p) < 3 || area == 0 {
+		var sum point
+		for _, pt := range points {
+			sum.x += pt.x
+			sum.y += pt.y
+		}
+		return unproject(ref, point{x: sum.x / float64(len(points)), y: sum.y / float64(len(points))})
+	}
+
+	var cx, cy float64
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		cross := points[j].x*points[i].y - points[i].x*points[j].y
+		cx += (points[j].x + points[i].x) * cross
+		cy += (points[j].y + points[i].y) * cross
+	}
+
+	return unproject(ref, point{x: cx / (6 * area), y: cy / (6 * area)})
+}
+
+// DistanceToEdge returns the shortest distance in metres from
+// the coordinate to the outline of the polygon, regardless of
+// whether the coordinate is inside or outside the polygon
+func (p Polygon) DistanceToEdge(c Coord) float64 {
+	if len(p) == 0 {
+		return math.Inf(1)
+	}
+
+	points := p.project(c)
+	if len(points) == 1 {
+		return math.Hypot(points[0].x, points[0].y)
+	}
+
+	shortest := math.Inf(1)
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		d := distanceToSegment(points[j], points[i])
+		if d < shortest {
+			shortest = d
+		}
+	}
+
+	return shortest
+}
+
+// point is a position in metres on a flat plane
+type point struct {
+	x, y float64
+}
+
+// project maps the polygon onto a flat plane centred on the
+// reference coordinate, this is accurate enough for areas the
+// size of a bike station
+func (p Polygon) project(ref Coord) []point {
+	scale := math.Cos(radians(ref.Latitude))
+	points := make([]point, len(p))
+	for i, c := range p {
+		points[i] = point{
+			x: radians(c.Longitude-ref.Longitude) * scale * EarthRadius,
+			y: radians(c.Latitude-ref.Latitude) * EarthRadius,
+		}
+	}
+	return points
+}
+
+// unproject maps a point on the plane centred on the reference
+// coordinate back to a coordinate
+func unproject(ref Coord, pt point) Coord {
+	scale := math.Cos(radians(ref.Latitude))
+	return Coord{
+		Latitude:  ref.Latitude + degrees(pt.y/EarthRadius),
+		Longitude: ref.Longitude + degrees(pt.x/(EarthRadius*scale)),
+	}
+}
+
+// signedArea returns the area of the points using the
+// shoelace formula, the sign depends on the winding order
+func signedArea(points []point) float64 {
+	var sum float64
+	for i, j := 0, len(points)-1; i < len(points); j, i = i, i+1 {
+		sum += points[j].x*points[i].y - points[i].x*points[j].y
+	}
+	return sum / 2
+}
+
+// distanceToSegment returns the distance from the origin
+// to the line segment between a and b
+func distanceToSegment(a, b point) float64 {
+	dx, dy := b.x-a.x, b.y-a.y
+	length := dx*dx + dy*dy
+	if length == 0 {
+		return math.Hypot(a.x, a.y)
+	}
+
+	t := -(a.x*dx + a.y*dy) / length
+	t = math.Max(0, math.Min(1, t))
+
+	return math.Hypot(a.x+t*dx, a.y+t*dy)
+}
+
+func radians(deg float64) float64 {
+	return deg * math.Pi / 180
+}
+
+func degrees(rad float64) float64 {
+	return rad * 180 / math.Pi
+}
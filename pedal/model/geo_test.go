@@ -0,0 +1,158 @@
+package model_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+// square is roughly a 100 by 100 metres square close
+// to Oslo Central Station
+var square = model.Polygon{
+	{Latitude: 59.9100, Longitude: 10.7500},
+	{Latitude: 59.9100, Longitude: 10.7518},
+	{Latitude: 59.9109, Longitude: 10.7518},
+	{Latitude: 59.9109, Longitude: 10.7500},
+}
+
+func TestCoord_Distance(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		From   model.Coord
+		To     model.Coord
+		Expect float64
+	}{
+		{
+			Name:   "Same coordinate",
+			From:   model.Coord{Latitude: 59.91, Longitude: 10.75},
+			To:     model.Coord{Latitude: 59.91, Longitude: 10.75},
+			Expect: 0,
+		},
+		{
+			Name:   "One degree latitude",
+			From:   model.Coord{Latitude: 59, Longitude: 10},
+			To:     model.Coord{Latitude: 60, Longitude: 10},
+			Expect: 111195,
+		},
+		{
+			Name:   "Oslo to Bergen",
+			From:   model.Coord{Latitude: 59.9139, Longitude: 10.7522},
+			To:     model.Coord{Latitude: 60.3913, Longitude: 5.3221},
+			Expect: 305000,
+		},
+	}
+
+	for _, tc := range testCases {
+		got := tc.From.Distance(tc.To)
+		assert.InDelta(t, tc.Expect, got, tc.Expect*0.01+1, tc.Name)
+	}
+}
+
+func TestPolygon_Contains(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Polygon model.Polygon
+		Coord   model.Coord
+		Expect  bool
+	}{
+		{
+			Name:    "Inside",
+			Polygon: square,
+			Coord:   model.Coord{Latitude: 59.9105, Longitude: 10.7510},
+			Expect:  true,
+		},
+		{
+			Name:    "Outside",
+			Polygon: square,
+			Coord:   model.Coord{Latitude: 59.9115, Longitude: 10.7510},
+			Expect:  false,
+		},
+		{
+			Name:    "Closed polygon",
+			Polygon: append(append(model.Polygon{}, square...), square[0]),
+			Coord:   model.Coord{Latitude: 59.9105, Longitude: 10.7510},
+			Expect:  true,
+		},
+		{
+			Name:    "Too few corners",
+			Polygon: square[:2],
+			Coord:   model.Coord{Latitude: 59.9105, Longitude: 10.7510},
+			Expect:  false,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.Expect, tc.Polygon.Contains(tc.Coord), tc.Name)
+	}
+}
+
+func TestPolygon_Area(t *testing.T) {
+	assert.InDelta(t, 10000, square.Area(), 100)
+	assert.Equal(t, 0.0, square[:2].Area())
+}
+
+func TestPolygon_Centroid(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Polygon model.Polygon
+		Expect  model.Coord
+	}{
+		{
+			Name:    "Square",
+			Polygon: square,
+			Expect:  model.Coord{Latitude: 59.91045, Longitude: 10.7509},
+		},
+		{
+			Name:    "Line",
+			Polygon: square[:2],
+			Expect:  model.Coord{Latitude: 59.9100, Longitude: 10.7509},
+		},
+		{
+			Name:    "Empty",
+			Polygon: model.Polygon{},
+			Expect:  model.Coord{},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := tc.Polygon.Centroid()
+		assert.InDelta(t, tc.Expect.Latitude, got.Latitude, 0.00001, tc.Name)
+		assert.InDelta(t, tc.Expect.Longitude, got.Longitude, 0.00001, tc.Name)
+	}
+}
+
+func TestPolygon_DistanceToEdge(t *testing.T) {
+	testCases := []struct {
+		Name    string
+		Polygon model.Polygon
+		Coord   model.Coord
+		Expect  float64
+	}{
+		{
+			Name:    "Inside",
+			Polygon: square,
+			Coord:   model.Coord{Latitude: 59.91045, Longitude: 10.7509},
+			Expect:  50,
+		},
+		{
+			Name:    "Outside",
+			Polygon: square,
+			Coord:   model.Coord{Latitude: 59.9118, Longitude: 10.7509},
+			Expect:  100,
+		},
+		{
+			Name:    "Single corner",
+			Polygon: square[:1],
+			Coord:   model.Coord{Latitude: 59.9109, Longitude: 10.7500},
+			Expect:  100,
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.InDelta(t, tc.Expect, tc.Polygon.DistanceToEdge(tc.Coord), 2, tc.Name)
+	}
+
+	assert.True(t, math.IsInf(model.Polygon{}.DistanceToEdge(model.Coord{}), 1))
+}
@@ -35,11 +35,64 @@ type Station struct {
 	Subtitle      string       `json:"subtitle"`
 	NumberOfLocks int          `json:"number_of_locks"`
 	Center        Coord        `json:"center"`
-	Bounds        []Coord      `json:"bounds"`
+	Bounds        Polygon      `json:"bounds"`
 	Availability  Availability `json:"-"`
 	Closed        bool         `json:"-"`
 }
 
+// Snapshot represents a consistent view of all stations
+// after their availability and status has been refreshed,
+// a stale snapshot could not be refreshed and might be
+// out of date
+type Snapshot struct {
+	Stations          map[int]*Station `json:"stations"`
+	AllStationsClosed bool             `json:"all_stations_closed"`
+	UpdatedAt         time.Time        `json:"updated_at"`
+	RefreshRate       float32          `json:"refresh_rate"`
+	Stale             bool             `json:"stale"`
+	// Version is derived from the content of the snapshot, so it
+	// only changes when the stations, their availability or their
+	// status change, and is the same for every instance
+	Version string `json:"version"`
+}
+
+// The feeds of the Oslo City Bike API
+const (
+	FeedStations     = "stations"
+	FeedAvailability = "availability"
+	FeedStatus       = "status"
+)
+
+// Feed describes the latest calls to one of the feeds of
+// the API, the times are zero until it has happened
+type Feed struct {
+	LastSuccess time.Time `json:"last_success"`
+	LastError   string    `json:"last_error"`
+	LastErrorAt time.Time `json:"last_error_at"`
+}
+
+// Health describes how well the stations are kept up to
+// date, loaded is false until there is a snapshot to serve
+type Health struct {
+	Loaded            bool            `json:"loaded"`
+	Stale             bool            `json:"stale"`
+	UpdatedAt         time.Time       `json:"updated_at"`
+	AllStationsClosed bool            `json:"all_stations_closed"`
+	Feeds             map[string]Feed `json:"feeds"`
+}
+
+// Forecast describes the expected availability of
+// bikes and locks at a station in the future
+type Forecast struct {
+	StationID       int           `json:"station_id"`
+	At              time.Time     `json:"at"`
+	Horizon         time.Duration `json:"horizon"`
+	Bikes           float64       `json:"bikes"`
+	Locks           float64       `json:"locks"`
+	BikeProbability float64       `json:"bike_probability"`
+	LockProbability float64       `json:"lock_probability"`
+}
+
 // StationAvailability represents the availability of
 // bikes and locks, with refresh rate, etc.
 type StationAvailability struct {
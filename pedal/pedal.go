@@ -1,67 +1,519 @@
 package pedal
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"hash/fnv"
+	"math"
+	"os"
+	"sort"
+	"sync"
 	"time"
 
+	"github.com/go-kit/kit/log"
 	"github.com/paulbes/go-pedal/pedal/client"
 	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pkg/logging"
 )
 
 // Pedlar defines the available methods for
 // interacting with the Oslo City Bike API
 type Pedlar interface {
-	Stations() (map[int]*model.Station, error)
+	Stations(ctx context.Context) (map[int]*model.Station, error)
+	Snapshot(ctx context.Context) (model.Snapshot, error)
+	Last() (model.Snapshot, bool)
+	StationAt(ctx context.Context, coord model.Coord) (*model.Station, bool, error)
+	Forecast(ctx context.Context, id int, horizon time.Duration) (*model.Forecast, bool, error)
+	Health() model.Health
 }
 
+// Forecaster defines the methods required for
+// predicting the availability at a station
+type Forecaster interface {
+	Forecast(station *model.Station, now time.Time, horizon time.Duration) (*model.Forecast, error)
+}
+
+// ErrForecastDisabled is returned when a forecast is
+// requested, but no forecaster has been provided
+var ErrForecastDisabled = fmt.Errorf("forecasting is not enabled")
+
+// StationAtTolerance is the distance in metres a coordinate
+// may be from the bounds of a station and still be considered
+// at the station, this makes up for inaccurate positioning
+var StationAtTolerance = 10.0
+
 // pedlar contains some basic data that
 // is required to load oslo city bike data
 type pedlar struct {
-	client      client.Client
-	stations    map[int]*model.Station
-	refreshRate time.Duration
-	lastUpdate  time.Time
+	client client.Client
+	// stations, allClosed, refreshRate and lastUpdate are
+	// only used by the refresh in flight, or on creation
+	stations     map[int]*model.Station
+	allClosed    bool
+	refreshRate  time.Duration
+	lastUpdate   time.Time
+	onRefresh    []func(model.Snapshot)
+	forecaster   Forecaster
+	logger       log.Logger
+	snapshotFile string
+	// mutex guards the last snapshot and the refresh in flight,
+	// it is never held while waiting for the API
+	mutex sync.Mutex
+	// last is the latest consistent snapshot, it is served
+	// as stale when the API cannot be reached
+	last  model.Snapshot
+	stale bool
+	// flight is the refresh in progress, if any
+	flight *flight
+	// flights counts the refreshes until they are done, the flight
+	// is gone before the registered functions are notified
+	flights sync.WaitGroup
+	// polling counts the running pollers, the reads are served
+	// from the last snapshot while the stations are polled
+	polling int
+	// health is guarded by its own mutex, so it can be
+	// reported while the stations are being refreshed
+	healthMutex sync.Mutex
+	health      model.Health
+	// saveMutex serialises the writes of the snapshot file, which
+	// are made outside of mutex, saved is the last one written
+	saveMutex sync.Mutex
+	saved     time.Time
+}
+
+// Option configures optional behaviour of pedlar
+type Option func(p *pedlar)
+
+// OnRefresh registers a function that is invoked with a
+// snapshot of all stations every time the availability
+// and status has been refreshed from the API, the snapshot
+// is shared and must not be modified
+func OnRefresh(fn func(snapshot model.Snapshot)) Option {
+	return func(p *pedlar) {
+		p.onRefresh = append(p.onRefresh, fn)
+	}
+}
+
+// WithForecaster enables forecasting the availability
+// at stations using the provided forecaster
+func WithForecaster(forecaster Forecaster) Option {
+	return func(p *pedlar) {
+		p.forecaster = forecaster
+	}
+}
+
+// WithSnapshotFile persists the latest snapshot to the file
+// after every refresh, and loads it when pedlar is created.
+// The loaded snapshot is served as stale until the first
+// successful refresh, so we have something to offer while
+// the API cannot be reached.
+func WithSnapshotFile(path string) Option {
+	return func(p *pedlar) {
+		p.snapshotFile = path
+	}
+}
+
+// WithLogger logs the refreshes and the problems with the
+// data from the API to the provided logger, instead of the
+// standard error
+func WithLogger(logger log.Logger) Option {
+	return func(p *pedlar) {
+		p.logger = logger
+	}
 }
 
 // New creates a new client for interacting
 // with the Oslo City Bike API
-func New(client client.Client) Pedlar {
-	return &pedlar{
+func New(client client.Client, options ...Option) Pedlar {
+	p := &pedlar{
 		client:      client,
 		stations:    map[int]*model.Station{},
 		refreshRate: 0 * time.Second,
 		lastUpdate:  time.Now().Add(-1 * time.Hour),
+		logger:      log.NewLogfmtLogger(log.NewSyncWriter(os.Stderr)),
+	}
+	for _, option := range options {
+		option(p)
 	}
+	if len(p.snapshotFile) > 0 {
+		err := p.load()
+		if err != nil && !os.IsNotExist(err) {
+			p.logger.Log("msg", "failed to load snapshot, starting empty", "file", p.snapshotFile, "err", err)
+		}
+	}
+	return p
 }
 
 // Stations returns a list of all stations
 // and their availability and status
-func (p *pedlar) Stations() (map[int]*model.Station, error) {
+func (p *pedlar) Stations(ctx context.Context) (map[int]*model.Station, error) {
+	snapshot, err := p.latest(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return snapshot.Stations, nil
+}
+
+// Snapshot returns all stations and their availability
+// and status, together with the time of the last refresh
+func (p *pedlar) Snapshot(ctx context.Context) (model.Snapshot, error) {
+	return p.latest(ctx)
+}
+
+// Last returns the last snapshot held, without refreshing it
+// from the API, false is returned when nothing has been loaded
+// yet. The snapshot is shared and must not be modified.
+func (p *pedlar) Last() (model.Snapshot, bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if p.last.Stations == nil {
+		return model.Snapshot{}, false
+	}
+	res := p.last
+	res.Stale = p.stale
+	return res, true
+}
+
+// flight is a refresh in progress, those that need
+// its result wait for it to be done
+type flight struct {
+	done     chan struct{}
+	snapshot model.Snapshot
+	err      error
+}
+
+// latest returns the last snapshot while it is up to date, or
+// while the stations are polled, otherwise the stations are
+// refreshed. A refresh is shared by everyone that needs it, and
+// the last snapshot is served while somebody else refreshes.
+func (p *pedlar) latest(ctx context.Context) (model.Snapshot, error) {
+	p.mutex.Lock()
+	if p.last.Stations != nil && (p.polling > 0 || p.flight != nil || !p.due(time.Now())) {
+		res := p.served()
+		p.mutex.Unlock()
+		return res, nil
+	}
+	p.mutex.Unlock()
+
+	return p.refresh(ctx)
+}
+
+// due returns true if the API might have something new,
+// i.e., the refresh rate has passed since the last update
+func (p *pedlar) due(now time.Time) bool {
+	refreshRate := time.Duration(float64(p.last.RefreshRate) * float64(time.Second))
+	return p.last.Stations == nil || !p.last.UpdatedAt.Add(refreshRate).After(now)
+}
+
+// served returns a copy of the last snapshot, so it can be
+// handed out without being modified by the receiver
+func (p *pedlar) served() model.Snapshot {
+	res := p.last
+	res.Stations = make(map[int]*model.Station, len(p.last.Stations))
+	for id, station := range p.last.Stations {
+		s := *station
+		res.Stations[id] = &s
+	}
+	res.Stale = p.stale
+	return res
+}
+
+// poll refreshes the stations if the refresh rate has passed,
+// it is invoked by Poll, which also marks pedlar as polled
+func (p *pedlar) poll(ctx context.Context) error {
+	p.mutex.Lock()
+	due := p.due(time.Now())
+	p.mutex.Unlock()
+	if !due {
+		return nil
+	}
+
+	_, err := p.refresh(ctx)
+	return err
+}
+
+// setPolling marks pedlar as polled, or no longer polled
+func (p *pedlar) setPolling(polling bool) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	if polling {
+		p.polling++
+	} else {
+		p.polling--
+	}
+}
+
+// wait blocks until the refreshes that have started are done
+func (p *pedlar) wait(ctx context.Context) error {
+	done := make(chan struct{})
+	go func() {
+		p.flights.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// refresh joins the refresh in flight, or starts one. The refresh
+// is shared, so it is not cancelled when the caller goes away, the
+// caller only stops waiting for it.
+func (p *pedlar) refresh(ctx context.Context) (model.Snapshot, error) {
+	p.mutex.Lock()
+	f := p.flight
+	if f == nil {
+		f = &flight{done: make(chan struct{})}
+		p.flight = f
+		p.flights.Add(1)
+		go p.doFlight(detach(ctx), f)
+	}
+	p.mutex.Unlock()
+
+	select {
+	case <-f.done:
+		return f.snapshot, f.err
+	case <-ctx.Done():
+		return model.Snapshot{}, ctx.Err()
+	}
+}
+
+// detach returns a context that is never cancelled, it only
+// carries the request ID, so the calls to the API are still
+// logged with the request that caused them
+func detach(ctx context.Context) context.Context {
+	return logging.WithRequestID(context.Background(), logging.RequestID(ctx))
+}
+
+// doFlight updates the stations, availability and status from
+// the API, without holding the lock, and stores the result in
+// the flight. The registered functions are notified before the
+// flight is done, if anything was refreshed.
+func (p *pedlar) doFlight(ctx context.Context, f *flight) {
+	defer p.flights.Done()
+	defer close(f.done)
+
+	// Remember when we were last updated, so we can
+	// determine if anything was refreshed
+	lastUpdate := p.lastUpdate
+	stations, err := p.doRefresh(ctx)
+
+	logger := logging.With(ctx, p.logger)
+	p.mutex.Lock()
+	p.flight = nil
+	if err != nil {
+		defer p.mutex.Unlock()
+		// Serve the last consistent snapshot, if we have one,
+		// rather than failing
+		if p.last.Stations == nil {
+			f.err = err
+			return
+		}
+		logger.Log("msg", "serving stale snapshot", "updated_at", p.last.UpdatedAt, "err", err)
+		p.stale = true
+		f.snapshot = p.served()
+		p.recordSnapshot(f.snapshot)
+		return
+	}
+
+	p.stations = stations
+	p.last = p.snapshot()
+	p.stale = false
+	p.recordSnapshot(p.last)
+	refreshed := !p.lastUpdate.Equal(lastUpdate)
+	last := p.last
+	f.snapshot = p.served()
+	p.mutex.Unlock()
+
+	// Save outside of the lock, so a slow disk does
+	// not hold up the readers of the snapshot
+	if refreshed && len(p.snapshotFile) > 0 {
+		err = p.save(last)
+		if err != nil {
+			logger.Log("msg", "failed to save snapshot", "file", p.snapshotFile, "err", err)
+		}
+	}
+
+	// Notify outside of the lock, so the registered
+	// functions are free to use pedlar
+	if refreshed {
+		for _, fn := range p.onRefresh {
+			fn(f.snapshot)
+		}
+	}
+}
+
+// doRefresh updates the stations, availability and status
+func (p *pedlar) doRefresh(ctx context.Context) (map[int]*model.Station, error) {
 	// Populate the stations, if we have new stations,
 	// lets force an update
-	newStations, stations, err := p.doPopulateStations(p.stations)
+	newStations, stations, err := p.doPopulateStations(ctx, p.stations)
 	if err != nil {
 		return nil, err
 	}
 
-	updated, stations, err := p.doUpdateAvailability(newStations, stations)
+	updated, stations, err := p.doUpdateAvailability(ctx, newStations, stations)
 	if err != nil {
 		return nil, err
 	}
 
 	if updated {
-		stations, err = p.doUpdateStatus(stations)
+		stations, err = p.doUpdateStatus(ctx, stations)
 		if err != nil {
 			return nil, err
 		}
 	}
 
-	p.stations = stations
-	return p.stations, nil
+	return stations, nil
+}
+
+// snapshot copies the current state of the stations, so
+// they can be handed out without being modified by the
+// next refresh
+func (p *pedlar) snapshot() model.Snapshot {
+	stations := make(map[int]*model.Station, len(p.stations))
+	for id, station := range p.stations {
+		s := *station
+		stations[id] = &s
+	}
+	return model.Snapshot{
+		Stations:          stations,
+		AllStationsClosed: p.allClosed,
+		UpdatedAt:         p.lastUpdate,
+		RefreshRate:       float32(p.refreshRate.Seconds()),
+		Version:           version(stations, p.allClosed, p.lastUpdate),
+	}
+}
+
+// version hashes everything that is part of a snapshot, in the
+// order of the station IDs, so equal snapshots get equal versions
+func version(stations map[int]*model.Station, allClosed bool, updatedAt time.Time) string {
+	ids := make([]int, 0, len(stations))
+	for id := range stations {
+		ids = append(ids, id)
+	}
+	sort.Ints(ids)
+
+	h := fnv.New64a()
+	fmt.Fprintf(h, "%t %d\n", allClosed, updatedAt.UnixNano())
+	for _, id := range ids {
+		s := stations[id]
+		fmt.Fprintf(h, "%d %t %q %q %d %v %v %v %t\n", s.ID, s.InService, s.Title, s.Subtitle, s.NumberOfLocks, s.Center, s.Bounds, s.Availability, s.Closed)
+	}
+	return fmt.Sprintf("%x", h.Sum64())
+}
+
+// Health reports how well the stations are kept up to date,
+// it does not refresh them, so it never waits for the API
+func (p *pedlar) Health() model.Health {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+
+	res := p.health
+	res.Feeds = make(map[string]model.Feed, len(p.health.Feeds))
+	for name, feed := range p.health.Feeds {
+		res.Feeds[name] = feed
+	}
+	return res
+}
+
+// recordSnapshot records the state of the snapshot being served
+func (p *pedlar) recordSnapshot(snapshot model.Snapshot) {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+
+	p.health.Loaded = snapshot.Stations != nil
+	p.health.Stale = snapshot.Stale
+	p.health.UpdatedAt = snapshot.UpdatedAt
+	p.health.AllStationsClosed = snapshot.AllStationsClosed
+}
+
+// recordFeed records the outcome of a call to a feed of the API
+func (p *pedlar) recordFeed(name string, err error) {
+	p.healthMutex.Lock()
+	defer p.healthMutex.Unlock()
+
+	if p.health.Feeds == nil {
+		p.health.Feeds = map[string]model.Feed{}
+	}
+	feed := p.health.Feeds[name]
+	if err != nil {
+		feed.LastError = err.Error()
+		feed.LastErrorAt = time.Now()
+	} else {
+		feed.LastSuccess = time.Now()
+	}
+	p.health.Feeds[name] = feed
+}
+
+// StationAt returns the station whose bounds contain the
+// provided coordinate, if more than one station contains
+// the coordinate, the one with the nearest center wins
+func (p *pedlar) StationAt(ctx context.Context, coord model.Coord) (*model.Station, bool, error) {
+	stations, err := p.Stations(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	var found *model.Station
+	shortest := math.Inf(1)
+	for _, station := range stations {
+		if !station.Bounds.Contains(coord) {
+			continue
+		}
+		if d := station.Center.Distance(coord); d < shortest || (d == shortest && found != nil && station.ID < found.ID) {
+			found, shortest = station, d
+		}
+	}
+	if found != nil {
+		return found, true, nil
+	}
+
+	// Nothing contains the coordinate, so we look for the
+	// station with the closest edge within the tolerance
+	shortest = StationAtTolerance
+	for _, station := range stations {
+		if d := station.Bounds.DistanceToEdge(coord); d < shortest || (d == shortest && found != nil && station.ID < found.ID) {
+			found, shortest = station, d
+		}
+	}
+
+	return found, found != nil, nil
 }
 
-func (p *pedlar) doPopulateStations(s map[int]*model.Station) (bool, map[int]*model.Station, error) {
-	stations, err := p.client.Stations()
+// Forecast predicts the availability at the station with the
+// provided id, the horizon is counted from the last refresh
+func (p *pedlar) Forecast(ctx context.Context, id int, horizon time.Duration) (*model.Forecast, bool, error) {
+	if p.forecaster == nil {
+		return nil, false, ErrForecastDisabled
+	}
+
+	snapshot, err := p.latest(ctx)
+	if err != nil {
+		return nil, false, err
+	}
+
+	station, hasKey := snapshot.Stations[id]
+	if !hasKey {
+		return nil, false, nil
+	}
+
+	forecast, err := p.forecaster.Forecast(station, snapshot.UpdatedAt, horizon)
+	if err != nil {
+		return nil, false, err
+	}
+
+	return forecast, true, nil
+}
+
+func (p *pedlar) doPopulateStations(ctx context.Context, s map[int]*model.Station) (bool, map[int]*model.Station, error) {
+	stations, err := p.client.Stations(ctx)
+	p.recordFeed(model.FeedStations, err)
 	if err != nil {
 		return false, nil, err
 	}
@@ -77,42 +529,48 @@ func (p *pedlar) doPopulateStations(s map[int]*model.Station) (bool, map[int]*mo
 	return newStations, s, nil
 }
 
-func (p *pedlar) doUpdateAvailability(force bool, s map[int]*model.Station) (bool, map[int]*model.Station, error) {
+func (p *pedlar) doUpdateAvailability(ctx context.Context, force bool, s map[int]*model.Station) (bool, map[int]*model.Station, error) {
 	// Determine if we should update the availability of bikes and locks
-	if p.lastUpdate.Add(p.refreshRate).Before(time.Now()) || force {
-		availability, err := p.client.Availability()
-		if err != nil {
-			return false, nil, err
-		}
-		p.refreshRate = time.Duration(availability.RefreshRate) * time.Second
-		p.lastUpdate = availability.UpdatedAt
-		for _, station := range availability.Stations {
-			if s, hasKey := s[station.ID]; hasKey {
-				s.Availability = station.Availability
-			} else {
-				log.Printf("availability: could not find station with ID: %d, skipping", station.ID)
-			}
+	if !p.lastUpdate.Add(p.refreshRate).Before(time.Now()) && !force {
+		return false, s, nil
+	}
+
+	availability, err := p.client.Availability(ctx)
+	p.recordFeed(model.FeedAvailability, err)
+	if err != nil {
+		return false, nil, err
+	}
+	p.refreshRate = time.Duration(availability.RefreshRate) * time.Second
+	p.lastUpdate = availability.UpdatedAt
+	for _, station := range availability.Stations {
+		if s, hasKey := s[station.ID]; hasKey {
+			s.Availability = station.Availability
+		} else {
+			logging.With(ctx, p.logger).Log("msg", "could not find station for availability, skipping", "station_id", station.ID)
 		}
 	}
 
 	return true, s, nil
 }
 
-func (p *pedlar) doUpdateStatus(s map[int]*model.Station) (map[int]*model.Station, error) {
-	status, err := p.client.Status()
+func (p *pedlar) doUpdateStatus(ctx context.Context, s map[int]*model.Station) (map[int]*model.Station, error) {
+	status, err := p.client.Status(ctx)
+	p.recordFeed(model.FeedStatus, err)
 	if err != nil {
 		return s, err
 	}
-	if status.AllStationsClosed {
-		for _, station := range s {
-			station.Closed = true
-		}
-	} else {
+	p.allClosed = status.AllStationsClosed
+
+	// Stations that are no longer listed as closed have reopened
+	for _, station := range s {
+		station.Closed = status.AllStationsClosed
+	}
+	if !status.AllStationsClosed {
 		for _, closedID := range status.StationsClosed {
 			if station, hasKey := s[closedID]; hasKey {
 				station.Closed = true
 			} else {
-				log.Printf("close station: could not find station with id: %d", closedID)
+				logging.With(ctx, p.logger).Log("msg", "could not find station to close", "station_id", closedID)
 			}
 		}
 	}
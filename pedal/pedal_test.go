@@ -1,13 +1,22 @@
 package pedal_test
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-kit/kit/log"
 	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client"
 	"github.com/paulbes/go-pedal/pedal/client/mock"
 	"github.com/paulbes/go-pedal/pedal/model"
 	modmock "github.com/paulbes/go-pedal/pedal/model/mock"
+	"github.com/paulbes/go-pedal/pkg/logging"
 	"github.com/stretchr/testify/assert"
 )
 
@@ -44,7 +53,7 @@ func TestPedlar_Stations(t *testing.T) {
 	for _, tc := range testCases {
 		client := mock.NewClient(tc.Stations, tc.Availability, tc.Status, tc.Err)
 		p := pedal.New(client)
-		got, err := p.Stations()
+		got, err := p.Stations(context.Background())
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
@@ -53,3 +62,534 @@ func TestPedlar_Stations(t *testing.T) {
 		}
 	}
 }
+
+func TestPedlar_StationAt(t *testing.T) {
+	bounds := model.Polygon{
+		{Latitude: 59.9100, Longitude: 10.7500},
+		{Latitude: 59.9100, Longitude: 10.7518},
+		{Latitude: 59.9109, Longitude: 10.7518},
+		{Latitude: 59.9109, Longitude: 10.7500},
+	}
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			{ID: 1, Center: bounds.Centroid(), Bounds: bounds},
+			{ID: 2, Center: model.Coord{Latitude: 59.95, Longitude: 10.80}},
+		},
+	}
+
+	testCases := []struct {
+		Name        string
+		Coord       model.Coord
+		Err         error
+		ExpectErr   bool
+		ExpectFound bool
+		Expect      interface{}
+	}{
+		{
+			Name:        "Inside the bounds",
+			Coord:       model.Coord{Latitude: 59.9105, Longitude: 10.7510},
+			ExpectFound: true,
+			Expect:      1,
+		},
+		{
+			Name:        "Within the tolerance",
+			Coord:       model.Coord{Latitude: 59.91095, Longitude: 10.7510},
+			ExpectFound: true,
+			Expect:      1,
+		},
+		{
+			Name:        "Nowhere near",
+			Coord:       model.Coord{Latitude: 59.92, Longitude: 10.76},
+			ExpectFound: false,
+		},
+		{
+			Name:      "With error fails",
+			Err:       fmt.Errorf("nope"),
+			ExpectErr: true,
+			Expect:    "nope",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(stations, modmock.NewStationAvailability(), modmock.NewStatus(), tc.Err)
+		p := pedal.New(client)
+		got, found, err := p.StationAt(context.Background(), tc.Coord)
+		if tc.ExpectErr {
+			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.ExpectFound, found, tc.Name)
+			if tc.ExpectFound {
+				assert.Equal(t, tc.Expect, got.ID, tc.Name)
+			}
+		}
+	}
+}
+
+func TestPedlar_OnRefresh(t *testing.T) {
+	var snapshots []model.Snapshot
+	client := mock.NewClient(modmock.NewStations(), modmock.NewStationAvailability(), modmock.NewStatus(), nil)
+	p := pedal.New(client, pedal.OnRefresh(func(snapshot model.Snapshot) {
+		snapshots = append(snapshots, snapshot)
+	}))
+
+	// The second call gets the same availability, so
+	// nothing was refreshed
+	for i := 0; i < 2; i++ {
+		_, err := p.Stations(context.Background())
+		assert.Nil(t, err)
+	}
+
+	assert.Len(t, snapshots, 1)
+	assert.NotEmpty(t, snapshots[0].Version)
+	assert.Equal(t, []model.Snapshot{
+		{
+			Stations:    map[int]*model.Station{1: modmock.NewStation()},
+			UpdatedAt:   modmock.NewStationAvailability().UpdatedAt,
+			RefreshRate: 10,
+			Version:     snapshots[0].Version,
+		},
+	}, snapshots)
+}
+
+func TestPedlar_SnapshotVersion(t *testing.T) {
+	status := &model.Status{}
+	client := mock.NewClient(modmock.NewStations(), modmock.NewStationAvailability(), status, nil)
+	p := pedal.New(client)
+
+	first, err := p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	second, err := pedal.New(client).Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, first.Version, second.Version, "Equal snapshots have equal versions")
+
+	// Closing a station does not change the availability,
+	// but it does change the version
+	status.StationsClosed = []int{1}
+	closed, err := p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, closed.Stations[1].Closed)
+	assert.NotEqual(t, first.Version, closed.Version)
+}
+
+// flaky is a client that fails while it is down
+type flaky struct {
+	client.Client
+	down bool
+}
+
+func (f *flaky) Stations(ctx context.Context) (*model.Stations, error) {
+	if f.down {
+		return nil, fmt.Errorf("down")
+	}
+	return f.Client.Stations(ctx)
+}
+
+// counting is a client that counts the calls to the API,
+// and holds them until the hold is closed or the context
+// is cancelled
+type counting struct {
+	client.Client
+	mutex      sync.Mutex
+	calls      int
+	requestIDs []string
+	hold       chan struct{}
+}
+
+func (c *counting) call(ctx context.Context) error {
+	c.mutex.Lock()
+	c.calls++
+	c.requestIDs = append(c.requestIDs, logging.RequestID(ctx))
+	hold := c.hold
+	c.mutex.Unlock()
+	if hold == nil {
+		return nil
+	}
+	select {
+	case <-hold:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (c *counting) RequestIDs() []string {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.requestIDs
+}
+
+func (c *counting) Calls() int {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	return c.calls
+}
+
+func (c *counting) Stations(ctx context.Context) (*model.Stations, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return c.Client.Stations(ctx)
+}
+
+func (c *counting) Availability(ctx context.Context) (*model.StationAvailability, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return c.Client.Availability(ctx)
+}
+
+func (c *counting) Status(ctx context.Context) (*model.Status, error) {
+	if err := c.call(ctx); err != nil {
+		return nil, err
+	}
+	return c.Client.Status(ctx)
+}
+
+// newCounting creates a counting client, whose availability
+// was updated at the provided time
+func newCounting(updatedAt time.Time) *counting {
+	availability := modmock.NewStationAvailability()
+	availability.UpdatedAt = updatedAt
+	return &counting{Client: mock.NewClient(modmock.NewStations(), availability, modmock.NewStatus(), nil)}
+}
+
+func TestPedlar_RefreshRate(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		UpdatedAt   time.Time
+		ExpectCalls int
+	}{
+		{
+			Name:        "Up to date, the second read is served from the last snapshot",
+			UpdatedAt:   time.Now(),
+			ExpectCalls: 3,
+		},
+		{
+			Name:        "Refresh rate has passed, the second read refreshes",
+			UpdatedAt:   time.Now().Add(-time.Minute),
+			ExpectCalls: 6,
+		},
+	}
+
+	for _, tc := range testCases {
+		c := newCounting(tc.UpdatedAt)
+		p := pedal.New(c)
+		for i := 0; i < 2; i++ {
+			got, err := p.Stations(context.Background())
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, map[int]*model.Station{1: modmock.NewStation()}, got, tc.Name)
+		}
+		assert.Equal(t, tc.ExpectCalls, c.Calls(), tc.Name)
+	}
+}
+
+func TestPedlar_SharedRefresh(t *testing.T) {
+	c := newCounting(time.Now())
+	c.hold = make(chan struct{})
+	p := pedal.New(c)
+
+	// Everyone waits for the same refresh
+	var wg sync.WaitGroup
+	for i := 0; i < 5; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := p.Stations(context.Background())
+			assert.Nil(t, err)
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+	close(c.hold)
+	wg.Wait()
+	assert.Equal(t, 3, c.Calls())
+
+	// A reader that gives up does not wait for the API
+	c = newCounting(time.Now())
+	c.hold = make(chan struct{})
+	defer close(c.hold)
+	p = pedal.New(c)
+	go p.Stations(context.Background())
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	for c.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	_, err := p.Stations(ctx)
+	assert.Equal(t, context.DeadlineExceeded, err)
+}
+
+func TestPedlar_Last(t *testing.T) {
+	c := newCounting(time.Now().Add(-time.Minute))
+	p := pedal.New(c)
+
+	_, ok := p.Last()
+	assert.False(t, ok, "Nothing has been loaded")
+
+	snapshot, err := p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	calls := c.Calls()
+	last, ok := p.Last()
+	assert.True(t, ok)
+	assert.Equal(t, snapshot, last)
+	assert.Equal(t, calls, c.Calls(), "The API is not called, even though the refresh rate has passed")
+}
+
+func TestPedlar_DetachedRefresh(t *testing.T) {
+	c := newCounting(time.Now())
+	c.hold = make(chan struct{})
+	p := pedal.New(c)
+
+	// The reader that started the refresh goes away
+	ctx, cancel := context.WithCancel(logging.WithRequestID(context.Background(), "abc"))
+	done := make(chan error, 1)
+	go func() {
+		_, err := p.Stations(ctx)
+		done <- err
+	}()
+	for c.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+	assert.Equal(t, context.Canceled, <-done)
+
+	// The refresh completes for everyone else, and is
+	// not recorded as a failure
+	close(c.hold)
+	got, err := p.Stations(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, map[int]*model.Station{1: modmock.NewStation()}, got)
+	assert.Equal(t, 3, c.Calls())
+	assert.Equal(t, []string{"abc", "abc", "abc"}, c.RequestIDs())
+	for name, feed := range p.Health().Feeds {
+		assert.Empty(t, feed.LastError, name)
+	}
+}
+
+func TestWait(t *testing.T) {
+	c := newCounting(time.Now())
+	c.hold = make(chan struct{})
+	notified := make(chan struct{}, 1)
+	p := pedal.New(c, pedal.OnRefresh(func(model.Snapshot) {
+		notified <- struct{}{}
+	}))
+	assert.Nil(t, pedal.Wait(context.Background(), p), "Nothing is in flight")
+
+	// The reader that started the refresh goes away
+	ctx, cancel := context.WithCancel(context.Background())
+	go p.Stations(ctx)
+	for c.Calls() == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	cancel()
+
+	timeout, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.Equal(t, context.DeadlineExceeded, pedal.Wait(timeout, p))
+
+	// The refresh is waited for, until everyone is notified
+	close(c.hold)
+	assert.Nil(t, pedal.Wait(context.Background(), p))
+	select {
+	case <-notified:
+	default:
+		t.Error("returned before the registered functions were notified")
+	}
+}
+
+func TestPoll(t *testing.T) {
+	// The refresh rate has always passed, so every
+	// read would refresh if nobody was polling
+	c := newCounting(time.Now().Add(-time.Minute))
+	refreshed := make(chan struct{}, 1)
+	p := pedal.New(c, pedal.OnRefresh(func(model.Snapshot) {
+		select {
+		case refreshed <- struct{}{}:
+		default:
+		}
+	}))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		pedal.Poll(ctx, p, time.Hour, log.NewNopLogger())
+		close(done)
+	}()
+	<-refreshed
+	assert.Equal(t, 3, c.Calls())
+
+	// The reads are served from the last snapshot while polled
+	for i := 0; i < 3; i++ {
+		got, err := p.Stations(context.Background())
+		assert.Nil(t, err)
+		assert.Equal(t, map[int]*model.Station{1: modmock.NewStation()}, got)
+	}
+	assert.Equal(t, 3, c.Calls())
+
+	// And refresh again once the poller is gone
+	cancel()
+	<-done
+	_, err := p.Stations(context.Background())
+	assert.Nil(t, err)
+	assert.Equal(t, 6, c.Calls())
+}
+
+func TestPedlar_SnapshotFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pedal")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "snapshot.json")
+
+	c := &flaky{Client: mock.NewClient(modmock.NewStations(), modmock.NewStationAvailability(), &model.Status{StationsClosed: []int{1}}, nil)}
+	closed := modmock.NewStation()
+	closed.Closed = true
+
+	// Nothing has been persisted, so we fail while the API is down
+	c.down = true
+	_, err = pedal.New(c, pedal.WithSnapshotFile(path)).Snapshot(context.Background())
+	assert.Equal(t, "down", err.Error())
+
+	// A successful refresh is persisted
+	c.down = false
+	p := pedal.New(c, pedal.WithSnapshotFile(path))
+	got, err := p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, got.Stale)
+	_, err = os.Stat(path)
+	assert.Nil(t, err)
+
+	// The last snapshot is served as stale when the API goes down
+	c.down = true
+	got, err = p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, got.Stale)
+	assert.Equal(t, map[int]*model.Station{1: closed}, got.Stations)
+
+	// A restart while the API is down loads the persisted snapshot
+	got, err = pedal.New(c, pedal.WithSnapshotFile(path)).Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.True(t, got.Stale)
+	assert.Equal(t, map[int]*model.Station{1: closed}, got.Stations)
+	assert.True(t, modmock.NewStationAvailability().UpdatedAt.Equal(got.UpdatedAt))
+	assert.Equal(t, float32(10), got.RefreshRate)
+
+	// Until the API is back
+	c.down = false
+	got, err = pedal.New(c, pedal.WithSnapshotFile(path)).Snapshot(context.Background())
+	assert.Nil(t, err)
+	assert.False(t, got.Stale)
+
+	// Leftovers from the atomic write are cleaned up
+	files, err := ioutil.ReadDir(dir)
+	assert.Nil(t, err)
+	assert.Len(t, files, 1)
+}
+
+func TestPedlar_Health(t *testing.T) {
+	c := &flaky{Client: mock.NewClient(modmock.NewStations(), modmock.NewStationAvailability(), &model.Status{AllStationsClosed: true}, nil)}
+	p := pedal.New(c)
+
+	// Nothing has been loaded or called yet
+	health := p.Health()
+	assert.False(t, health.Loaded)
+	assert.Empty(t, health.Feeds)
+
+	// Every feed is called on the first refresh
+	_, err := p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	health = p.Health()
+	assert.True(t, health.Loaded)
+	assert.False(t, health.Stale)
+	assert.True(t, health.AllStationsClosed)
+	assert.True(t, modmock.NewStationAvailability().UpdatedAt.Equal(health.UpdatedAt))
+	for _, name := range []string{model.FeedStations, model.FeedAvailability, model.FeedStatus} {
+		assert.False(t, health.Feeds[name].LastSuccess.IsZero(), name)
+		assert.Empty(t, health.Feeds[name].LastError, name)
+	}
+
+	// A failing feed is recorded, and the snapshot goes stale
+	c.down = true
+	_, err = p.Snapshot(context.Background())
+	assert.Nil(t, err)
+	health = p.Health()
+	assert.True(t, health.Loaded)
+	assert.True(t, health.Stale)
+	assert.Equal(t, "down", health.Feeds[model.FeedStations].LastError)
+	assert.False(t, health.Feeds[model.FeedStations].LastErrorAt.IsZero())
+	assert.False(t, health.Feeds[model.FeedStations].LastSuccess.IsZero())
+}
+
+type forecaster struct{}
+
+func (forecaster) Forecast(station *model.Station, now time.Time, horizon time.Duration) (*model.Forecast, error) {
+	return &model.Forecast{
+		StationID: station.ID,
+		At:        now.Add(horizon),
+		Horizon:   horizon,
+		Bikes:     float64(station.Availability.Bikes),
+		Locks:     float64(station.Availability.Locks),
+	}, nil
+}
+
+func TestPedlar_Forecast(t *testing.T) {
+	updatedAt := modmock.NewStationAvailability().UpdatedAt
+
+	testCases := []struct {
+		Name        string
+		ID          int
+		Options     []pedal.Option
+		Err         error
+		ExpectErr   bool
+		ExpectFound bool
+		Expect      interface{}
+	}{
+		{
+			Name:        "Forecasting works",
+			ID:          1,
+			Options:     []pedal.Option{pedal.WithForecaster(forecaster{})},
+			ExpectFound: true,
+			Expect: &model.Forecast{
+				StationID: 1,
+				At:        updatedAt.Add(20 * time.Minute),
+				Horizon:   20 * time.Minute,
+				Bikes:     5,
+				Locks:     5,
+			},
+		},
+		{
+			Name:        "No such station",
+			ID:          1000,
+			Options:     []pedal.Option{pedal.WithForecaster(forecaster{})},
+			ExpectFound: false,
+		},
+		{
+			Name:      "Without forecaster fails",
+			ID:        1,
+			ExpectErr: true,
+			Expect:    "forecasting is not enabled",
+		},
+		{
+			Name:      "With error fails",
+			ID:        1,
+			Options:   []pedal.Option{pedal.WithForecaster(forecaster{})},
+			Err:       fmt.Errorf("nope"),
+			ExpectErr: true,
+			Expect:    "nope",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(modmock.NewStations(), modmock.NewStationAvailability(), modmock.NewStatus(), tc.Err)
+		p := pedal.New(client, tc.Options...)
+		got, found, err := p.Forecast(context.Background(), tc.ID, 20*time.Minute)
+		if tc.ExpectErr {
+			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.ExpectFound, found, tc.Name)
+			if tc.ExpectFound {
+				assert.Equal(t, tc.Expect, got, tc.Name)
+			}
+		}
+	}
+}
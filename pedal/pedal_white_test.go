@@ -1,10 +1,15 @@
 package pedal
 
 import (
+	"context"
 	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
+	"github.com/go-kit/kit/log"
 	modmock "github.com/paulbes/go-pedal/pedal/model/mock"
 
 	"github.com/paulbes/go-pedal/pedal/client/mock"
@@ -48,8 +53,9 @@ func TestPedlar_doPopulateStations(t *testing.T) {
 		client := mock.NewClient(tc.Stations, nil, nil, tc.Err)
 		p := pedlar{
 			client: client,
+			logger: log.NewNopLogger(),
 		}
-		gotNew, got, err := p.doPopulateStations(tc.Initial)
+		gotNew, got, err := p.doPopulateStations(context.Background(), tc.Initial)
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
@@ -125,7 +131,7 @@ func TestPedlar_doUpdateAvailability(t *testing.T) {
 					return s
 				}(),
 			},
-			ExpectUpdate: true,
+			ExpectUpdate: false,
 		},
 	}
 
@@ -134,8 +140,9 @@ func TestPedlar_doUpdateAvailability(t *testing.T) {
 		p := pedlar{
 			client:     client,
 			lastUpdate: tc.LastUpdate,
+			logger:     log.NewNopLogger(),
 		}
-		updated, got, err := p.doUpdateAvailability(tc.Force, tc.Initial)
+		updated, got, err := p.doUpdateAvailability(context.Background(), tc.Force, tc.Initial)
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
@@ -173,6 +180,12 @@ func TestPedlar_doUpdateStatus(t *testing.T) {
 			Expect:  map[int]*model.Station{1: {ID: 1, Closed: true}},
 			Initial: map[int]*model.Station{1: {ID: 1}},
 		},
+		{
+			Name:    "Reopening works",
+			Status:  &model.Status{AllStationsClosed: false, StationsClosed: []int{2}},
+			Expect:  map[int]*model.Station{1: {ID: 1}, 2: {ID: 2, Closed: true}},
+			Initial: map[int]*model.Station{1: {ID: 1, Closed: true}, 2: {ID: 2}},
+		},
 		{
 			Name:    "Closing none works",
 			Status:  &model.Status{AllStationsClosed: false, StationsClosed: []int{}},
@@ -185,8 +198,9 @@ func TestPedlar_doUpdateStatus(t *testing.T) {
 		client := mock.NewClient(nil, nil, tc.Status, tc.Err)
 		p := pedlar{
 			client: client,
+			logger: log.NewNopLogger(),
 		}
-		got, err := p.doUpdateStatus(tc.Initial)
+		got, err := p.doUpdateStatus(context.Background(), tc.Initial)
 		if tc.ExpectErr {
 			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
 		} else {
@@ -195,3 +209,28 @@ func TestPedlar_doUpdateStatus(t *testing.T) {
 		}
 	}
 }
+
+func TestPedlar_save(t *testing.T) {
+	dir, err := ioutil.TempDir("", "pedal")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	p := &pedlar{snapshotFile: filepath.Join(dir, "snapshot.json")}
+	newer := model.Snapshot{
+		Stations:  map[int]*model.Station{1: {ID: 1, Title: "newer"}},
+		UpdatedAt: time.Unix(20, 0).UTC(),
+	}
+	older := model.Snapshot{
+		Stations:  map[int]*model.Station{1: {ID: 1, Title: "older"}},
+		UpdatedAt: time.Unix(10, 0).UTC(),
+	}
+
+	// The older snapshot is skipped, when saved
+	// after the newer one, e.g., by a slow flight
+	assert.Nil(t, p.save(newer))
+	assert.Nil(t, p.save(older))
+
+	assert.Nil(t, p.load())
+	assert.Equal(t, newer.UpdatedAt, p.last.UpdatedAt)
+	assert.Equal(t, "newer", p.last.Stations[1].Title)
+}
@@ -0,0 +1,124 @@
+package pedal
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// snapshotVersion is increased whenever the
+// persisted format changes
+const snapshotVersion = 1
+
+// persisted is the on disk representation of a snapshot, the
+// availability and closed state of the stations are not part
+// of their JSON representation, so they are stored separately
+type persisted struct {
+	Version           int                `json:"version"`
+	UpdatedAt         time.Time          `json:"updated_at"`
+	RefreshRate       float32            `json:"refresh_rate"`
+	AllStationsClosed bool               `json:"all_stations_closed"`
+	Stations          []persistedStation `json:"stations"`
+}
+
+type persistedStation struct {
+	Station      *model.Station     `json:"station"`
+	Availability model.Availability `json:"availability"`
+	Closed       bool               `json:"closed"`
+}
+
+// save writes the snapshot to the snapshot file, the file is
+// written next to the destination and renamed, so a crash never
+// leaves a partially written snapshot behind. The saves are made
+// in turn, and a snapshot older than the saved one is skipped.
+func (p *pedlar) save(snapshot model.Snapshot) error {
+	p.saveMutex.Lock()
+	defer p.saveMutex.Unlock()
+	if !snapshot.UpdatedAt.After(p.saved) {
+		return nil
+	}
+
+	data := persisted{
+		Version:           snapshotVersion,
+		UpdatedAt:         snapshot.UpdatedAt,
+		RefreshRate:       snapshot.RefreshRate,
+		AllStationsClosed: snapshot.AllStationsClosed,
+	}
+	for _, station := range snapshot.Stations {
+		data.Stations = append(data.Stations, persistedStation{
+			Station:      station,
+			Availability: station.Availability,
+			Closed:       station.Closed,
+		})
+	}
+
+	content, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(filepath.Dir(p.snapshotFile), filepath.Base(p.snapshotFile)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Sync()
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+
+	err = os.Rename(tmp.Name(), p.snapshotFile)
+	if err != nil {
+		return err
+	}
+	p.saved = snapshot.UpdatedAt
+	return nil
+}
+
+// load reads the snapshot file and uses it as the stale
+// starting point, until the stations are refreshed
+func (p *pedlar) load() error {
+	content, err := ioutil.ReadFile(p.snapshotFile)
+	if err != nil {
+		return err
+	}
+
+	data := persisted{}
+	err = json.Unmarshal(content, &data)
+	if err != nil {
+		return err
+	}
+	if data.Version != snapshotVersion {
+		return fmt.Errorf("unsupported snapshot version: %d", data.Version)
+	}
+
+	stations := map[int]*model.Station{}
+	for _, s := range data.Stations {
+		if s.Station == nil {
+			continue
+		}
+		s.Station.Availability = s.Availability
+		s.Station.Closed = s.Closed
+		stations[s.Station.ID] = s.Station
+	}
+
+	p.stations = stations
+	p.allClosed = data.AllStationsClosed
+	p.refreshRate = time.Duration(data.RefreshRate) * time.Second
+	p.lastUpdate = data.UpdatedAt
+	p.last = p.snapshot()
+	p.stale = true
+	p.recordSnapshot(p.served())
+	return nil
+}
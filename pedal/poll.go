@@ -0,0 +1,65 @@
+package pedal
+
+import (
+	"context"
+	"time"
+
+	"github.com/go-kit/kit/log"
+)
+
+// poller is implemented by pedlar, which serves the reads
+// from the last snapshot while it is being polled
+type poller interface {
+	poll(ctx context.Context) error
+	setPolling(polling bool)
+	wait(ctx context.Context) error
+}
+
+// Poll refreshes the stations at the provided interval until
+// the context is cancelled, this ensures that the functions
+// registered with OnRefresh are invoked even when nobody is
+// asking for the stations, failures are logged to the logger.
+// While pedlar is polled, the reads never wait for the API,
+// and the API is only called once the refresh rate has passed.
+func Poll(ctx context.Context, p Pedlar, interval time.Duration, logger log.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	refresh := func() error {
+		_, err := p.Stations(ctx)
+		return err
+	}
+	if pl, ok := p.(poller); ok {
+		pl.setPolling(true)
+		defer pl.setPolling(false)
+		refresh = func() error {
+			return pl.poll(ctx)
+		}
+	}
+
+	for {
+		err := refresh()
+		if err != nil {
+			logger.Log("msg", "failed to refresh stations", "err", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Wait blocks until the refresh in flight, if any, is done and the
+// functions registered with OnRefresh have returned, or until the
+// context is done. The refreshes outlive the readers that started
+// them, so stop polling and serving first, e.g., before the
+// resources the registered functions use are closed.
+func Wait(ctx context.Context, p Pedlar) error {
+	pl, ok := p.(poller)
+	if !ok {
+		return nil
+	}
+	return pl.wait(ctx)
+}
@@ -0,0 +1,250 @@
+package rebalance
+
+import (
+	"context"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// NearlyEmptyRatio is the share of the locks with a bike at,
+// or below, which a station is considered nearly empty
+var NearlyEmptyRatio = 0.2
+
+// NearlyFullRatio is the share of the locks with a bike at,
+// or above, which a station is considered nearly full
+var NearlyFullRatio = 0.8
+
+// TargetRatio is the share of the locks a rebalanced
+// station should have a bike in
+var TargetRatio = 0.5
+
+// Horizon is how far into the future the availability is
+// forecasted, when a forecaster is available
+var Horizon = time.Hour
+
+// Level describes how full a station is
+type Level int
+
+// The levels a station can be at
+const (
+	Balanced Level = iota
+	Empty
+	NearlyEmpty
+	NearlyFull
+	Full
+)
+
+// String returns the name of the level
+func (l Level) String() string {
+	switch l {
+	case Empty:
+		return "empty"
+	case NearlyEmpty:
+		return "nearly_empty"
+	case NearlyFull:
+		return "nearly_full"
+	case Full:
+		return "full"
+	default:
+		return "balanced"
+	}
+}
+
+// Classify determines the level of the station relative
+// to its number of locks
+func Classify(station *model.Station) Level {
+	return classify(float64(station.Availability.Bikes), station)
+}
+
+func classify(bikes float64, station *model.Station) Level {
+	capacity := float64(station.NumberOfLocks)
+	switch {
+	case bikes <= 0:
+		return Empty
+	case bikes >= capacity:
+		return Full
+	case bikes <= NearlyEmptyRatio*capacity:
+		return NearlyEmpty
+	case bikes >= NearlyFullRatio*capacity:
+		return NearlyFull
+	default:
+		return Balanced
+	}
+}
+
+// Status describes the balance of a single station, the
+// projected number of bikes is the forecasted number, or
+// the current number when no forecast is available
+type Status struct {
+	Station   *model.Station
+	Level     Level
+	Projected float64
+	Target    int
+}
+
+// Transfer describes moving bikes between two stations
+type Transfer struct {
+	From     *model.Station
+	To       *model.Station
+	Count    int
+	Distance float64
+}
+
+// Plan contains the status of every station and the
+// transfers suggested to balance them
+type Plan struct {
+	Stations  []*Status
+	Transfers []*Transfer
+}
+
+// Planner defines the available methods for
+// planning the rebalancing of stations
+type Planner interface {
+	Plan(ctx context.Context, truckCapacity int) (*Plan, error)
+}
+
+// planner balances the stations provided by pedlar
+type planner struct {
+	pedlar     pedal.Pedlar
+	forecaster pedal.Forecaster
+}
+
+// New creates a planner that reads the stations from pedlar, if a
+// forecaster is provided the stations are balanced according to
+// their forecasted availability
+func New(pedlar pedal.Pedlar, forecaster pedal.Forecaster) Planner {
+	return &planner{
+		pedlar:     pedlar,
+		forecaster: forecaster,
+	}
+}
+
+// Plan classifies the open stations and suggests transfers from the
+// stations with too many bikes to the ones with too few. The closest
+// pairs are served first and no transfer moves more bikes than the
+// truck can carry, a pair is given more transfers when one is not
+// enough.
+func (p *planner) Plan(ctx context.Context, truckCapacity int) (*Plan, error) {
+	snapshot, err := p.pedlar.Snapshot(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	plan := &Plan{}
+	surplus, deficit := map[int]int{}, map[int]int{}
+	var donors, receivers []*model.Station
+	for _, station := range snapshot.Stations {
+		if station.Closed || !station.InService || station.NumberOfLocks <= 0 {
+			continue
+		}
+
+		status, err := p.status(station, snapshot.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		plan.Stations = append(plan.Stations, status)
+
+		projected := classify(status.Projected, station)
+		switch {
+		case projected == Full || projected == NearlyFull:
+			// We can't remove more bikes than are there now
+			n := int(math.Round(status.Projected)) - status.Target
+			if n > station.Availability.Bikes {
+				n = station.Availability.Bikes
+			}
+			if n > 0 {
+				surplus[station.ID] = n
+				donors = append(donors, station)
+			}
+		case projected == Empty || projected == NearlyEmpty:
+			// We can't add more bikes than there are free locks now
+			n := status.Target - int(math.Round(status.Projected))
+			if n > station.Availability.Locks {
+				n = station.Availability.Locks
+			}
+			if n > 0 {
+				deficit[station.ID] = n
+				receivers = append(receivers, station)
+			}
+		}
+	}
+
+	sort.Slice(plan.Stations, func(i, j int) bool {
+		return plan.Stations[i].Station.ID < plan.Stations[j].Station.ID
+	})
+
+	if truckCapacity <= 0 {
+		return plan, nil
+	}
+
+	// Pair the donors and receivers, closest first
+	var pairs []*Transfer
+	for _, from := range donors {
+		for _, to := range receivers {
+			pairs = append(pairs, &Transfer{
+				From:     from,
+				To:       to,
+				Distance: from.Center.Distance(to.Center),
+			})
+		}
+	}
+	sort.Slice(pairs, func(i, j int) bool {
+		if pairs[i].Distance == pairs[j].Distance {
+			if pairs[i].From.ID == pairs[j].From.ID {
+				return pairs[i].To.ID < pairs[j].To.ID
+			}
+			return pairs[i].From.ID < pairs[j].From.ID
+		}
+		return pairs[i].Distance < pairs[j].Distance
+	})
+
+	// A pair gets as many trips as it takes to exhaust either
+	// the surplus or the deficit, before moving on to the next
+	for _, pair := range pairs {
+		for {
+			count := surplus[pair.From.ID]
+			if deficit[pair.To.ID] < count {
+				count = deficit[pair.To.ID]
+			}
+			if truckCapacity < count {
+				count = truckCapacity
+			}
+			if count <= 0 {
+				break
+			}
+
+			trip := *pair
+			trip.Count = count
+			surplus[pair.From.ID] -= count
+			deficit[pair.To.ID] -= count
+			plan.Transfers = append(plan.Transfers, &trip)
+		}
+	}
+
+	return plan, nil
+}
+
+// status determines the current level and the projected
+// number of bikes at the station
+func (p *planner) status(station *model.Station, now time.Time) (*Status, error) {
+	status := &Status{
+		Station:   station,
+		Level:     Classify(station),
+		Projected: float64(station.Availability.Bikes),
+		Target:    int(math.Round(TargetRatio * float64(station.NumberOfLocks))),
+	}
+
+	if p.forecaster != nil {
+		forecast, err := p.forecaster.Forecast(station, now, Horizon)
+		if err != nil {
+			return nil, err
+		}
+		status.Projected = forecast.Bikes
+	}
+
+	return status, nil
+}
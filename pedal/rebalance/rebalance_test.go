@@ -0,0 +1,162 @@
+package rebalance_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/rebalance"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStation(id int, lat float64, bikes, locks int) *model.Station {
+	return &model.Station{
+		ID:            id,
+		InService:     true,
+		Title:         fmt.Sprintf("Station %d", id),
+		NumberOfLocks: bikes + locks,
+		Center:        model.Coord{Latitude: lat, Longitude: 10.75},
+		Availability:  model.Availability{Bikes: bikes, Locks: locks},
+	}
+}
+
+// forecaster predicts the provided number of bikes for every station
+type forecaster map[int]float64
+
+func (f forecaster) Forecast(station *model.Station, now time.Time, horizon time.Duration) (*model.Forecast, error) {
+	bikes, hasKey := f[station.ID]
+	if !hasKey {
+		bikes = float64(station.Availability.Bikes)
+	}
+	return &model.Forecast{StationID: station.ID, At: now.Add(horizon), Horizon: horizon, Bikes: bikes}, nil
+}
+
+func TestClassify(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Bikes  int
+		Locks  int
+		Expect rebalance.Level
+	}{
+		{Name: "Empty", Bikes: 0, Locks: 10, Expect: rebalance.Empty},
+		{Name: "Nearly empty", Bikes: 2, Locks: 8, Expect: rebalance.NearlyEmpty},
+		{Name: "Balanced", Bikes: 5, Locks: 5, Expect: rebalance.Balanced},
+		{Name: "Nearly full", Bikes: 8, Locks: 2, Expect: rebalance.NearlyFull},
+		{Name: "Full", Bikes: 10, Locks: 0, Expect: rebalance.Full},
+	}
+
+	for _, tc := range testCases {
+		got := rebalance.Classify(newStation(1, 59.91, tc.Bikes, tc.Locks))
+		assert.Equal(t, tc.Expect, got, tc.Name)
+	}
+}
+
+func TestPlanner_Plan(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			newStation(1, 59.9100, 10, 0),
+			newStation(2, 59.9110, 0, 10),
+			newStation(3, 59.9300, 9, 1),
+			newStation(4, 59.9285, 1, 9),
+			newStation(5, 59.9200, 5, 5),
+			newStation(6, 59.9105, 0, 10),
+		},
+	}
+
+	testCases := []struct {
+		Name         string
+		Capacity     int
+		Forecaster   pedal.Forecaster
+		Err          error
+		ExpectErr    bool
+		Expect       interface{}
+		ExpectLevels []string
+	}{
+		{
+			Name:     "Pairs the closest stations",
+			Capacity: 20,
+			Expect: [][3]int{
+				{1, 2, 5},
+				{3, 4, 4},
+			},
+			ExpectLevels: []string{"full", "empty", "nearly_full", "nearly_empty", "balanced"},
+		},
+		{
+			Name:     "Limited by the truck",
+			Capacity: 3,
+			Expect: [][3]int{
+				{1, 2, 3},
+				{1, 2, 2},
+				{3, 4, 3},
+				{3, 4, 1},
+			},
+		},
+		{
+			Name:       "Uses the forecast",
+			Capacity:   20,
+			Forecaster: forecaster{5: 0, 2: 5},
+			Expect: [][3]int{
+				{3, 4, 4},
+				{1, 5, 5},
+			},
+		},
+		{
+			Name:     "Zero capacity",
+			Capacity: 0,
+			Expect:   [][3]int(nil),
+		},
+		{
+			Name:      "With error fails",
+			Capacity:  20,
+			Err:       fmt.Errorf("nope"),
+			ExpectErr: true,
+			Expect:    "nope",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(stations, &model.StationAvailability{}, &model.Status{StationsClosed: []int{6}}, tc.Err)
+		got, err := rebalance.New(pedal.New(client), tc.Forecaster).Plan(context.Background(), tc.Capacity)
+		if tc.ExpectErr {
+			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
+			continue
+		}
+
+		assert.Nil(t, err, tc.Name)
+		var transfers [][3]int
+		for _, transfer := range got.Transfers {
+			transfers = append(transfers, [3]int{transfer.From.ID, transfer.To.ID, transfer.Count})
+		}
+		assert.Equal(t, tc.Expect, transfers, tc.Name)
+		assert.Len(t, got.Stations, 5, tc.Name)
+		if len(tc.ExpectLevels) > 0 {
+			var levels []string
+			for _, status := range got.Stations {
+				levels = append(levels, status.Level.String())
+			}
+			assert.Equal(t, tc.ExpectLevels, levels, tc.Name)
+		}
+	}
+}
+
+func TestPlanner_Plan_SurplusExceedsCapacity(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			newStation(1, 59.9100, 24, 0),
+			newStation(2, 59.9110, 0, 24),
+		},
+	}
+	client := mock.NewClient(stations, &model.StationAvailability{}, &model.Status{}, nil)
+	got, err := rebalance.New(pedal.New(client), nil).Plan(context.Background(), 10)
+	assert.Nil(t, err)
+
+	var transfers [][3]int
+	for _, transfer := range got.Transfers {
+		transfers = append(transfers, [3]int{transfer.From.ID, transfer.To.ID, transfer.Count})
+	}
+	assert.Equal(t, [][3]int{{1, 2, 10}, {1, 2, 2}}, transfers, "The surplus of 12 takes two trips")
+}
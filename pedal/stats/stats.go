@@ -0,0 +1,107 @@
+package stats
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// Percentiles are the utilisation percentiles that
+// are computed for every snapshot
+var Percentiles = []float64{10, 25, 50, 75, 90}
+
+// Percentile is the value at or below which the provided
+// share of the stations fall
+type Percentile struct {
+	Percentile float64
+	Value      float64
+}
+
+// Stats contains aggregate statistics for all stations
+type Stats struct {
+	UpdatedAt time.Time
+	Stations  int
+	Open      int
+	Closed    int
+	Empty     int
+	Full      int
+	Bikes     int
+	Locks     int
+	Capacity  int
+	// BikesInUseEstimate is the capacity less the bikes and the
+	// free locks, a snapshot only describes the docked bikes, so
+	// this assumes that every lock without a bike, that is not
+	// free, is waiting for a bike that is out, while it may as
+	// well be out of order. It is an upper bound, at best.
+	BikesInUseEstimate int
+	// Utilisation is the share of the locks at a station that
+	// have a bike in them, with the percentiles over all open
+	// stations
+	Utilisation []Percentile
+}
+
+// Compute aggregates the availability of the stations in the
+// snapshot, closed stations are counted, but otherwise left out
+func Compute(snapshot model.Snapshot) *Stats {
+	res := &Stats{
+		UpdatedAt: snapshot.UpdatedAt,
+		Stations:  len(snapshot.Stations),
+	}
+
+	var utilisation []float64
+	for _, station := range snapshot.Stations {
+		if station.Closed {
+			res.Closed++
+			continue
+		}
+		res.Open++
+
+		bikes, locks := station.Availability.Bikes, station.Availability.Locks
+		capacity := station.NumberOfLocks
+		if capacity < bikes+locks {
+			capacity = bikes + locks
+		}
+
+		res.Bikes += bikes
+		res.Locks += locks
+		res.Capacity += capacity
+		if inUse := capacity - bikes - locks; inUse > 0 {
+			res.BikesInUseEstimate += inUse
+		}
+
+		if bikes == 0 {
+			res.Empty++
+		}
+		if locks == 0 {
+			res.Full++
+		}
+		if capacity > 0 {
+			utilisation = append(utilisation, float64(bikes)/float64(capacity))
+		}
+	}
+
+	sort.Float64s(utilisation)
+	for _, p := range Percentiles {
+		res.Utilisation = append(res.Utilisation, Percentile{
+			Percentile: p,
+			Value:      percentile(utilisation, p),
+		})
+	}
+
+	return res
+}
+
+// percentile returns the nearest rank percentile of the
+// sorted values, or zero if there are none
+func percentile(sorted []float64, p float64) float64 {
+	if len(sorted) == 0 {
+		return 0
+	}
+	rank := int(math.Ceil(p / 100 * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
@@ -0,0 +1,98 @@
+package stats_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/stats"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompute(t *testing.T) {
+	now := time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		Name     string
+		Snapshot model.Snapshot
+		Expect   *stats.Stats
+	}{
+		{
+			Name: "Aggregates the open stations",
+			Snapshot: model.Snapshot{
+				UpdatedAt: now,
+				Stations: map[int]*model.Station{
+					1: {ID: 1, NumberOfLocks: 10, Availability: model.Availability{Bikes: 0, Locks: 8}},
+					2: {ID: 2, NumberOfLocks: 10, Availability: model.Availability{Bikes: 10, Locks: 0}},
+					3: {ID: 3, NumberOfLocks: 10, Availability: model.Availability{Bikes: 5, Locks: 4}},
+					4: {ID: 4, NumberOfLocks: 20, Availability: model.Availability{Bikes: 5, Locks: 15}},
+					5: {ID: 5, NumberOfLocks: 10, Availability: model.Availability{Bikes: 5, Locks: 5}, Closed: true},
+				},
+			},
+			Expect: &stats.Stats{
+				UpdatedAt: now,
+				Stations:  5,
+				Open:      4,
+				Closed:    1,
+				Empty:     1,
+				Full:      1,
+				Bikes:     20,
+				Locks:     27,
+				Capacity:  50,
+				// Station 1 and 3 have two and one locks without a bike
+				BikesInUseEstimate: 3,
+				Utilisation: []stats.Percentile{
+					{Percentile: 10, Value: 0},
+					{Percentile: 25, Value: 0},
+					{Percentile: 50, Value: 0.25},
+					{Percentile: 75, Value: 0.5},
+					{Percentile: 90, Value: 1},
+				},
+			},
+		},
+		{
+			Name: "More available than the locks",
+			Snapshot: model.Snapshot{
+				UpdatedAt: now,
+				Stations: map[int]*model.Station{
+					1: {ID: 1, NumberOfLocks: 10, Availability: model.Availability{Bikes: 6, Locks: 6}},
+					2: {ID: 2, NumberOfLocks: 10, Availability: model.Availability{Bikes: 2, Locks: 7}},
+				},
+			},
+			Expect: &stats.Stats{
+				UpdatedAt:          now,
+				Stations:           2,
+				Open:               2,
+				Bikes:              8,
+				Locks:              13,
+				Capacity:           22,
+				BikesInUseEstimate: 1,
+				Utilisation: []stats.Percentile{
+					{Percentile: 10, Value: 0.2},
+					{Percentile: 25, Value: 0.2},
+					{Percentile: 50, Value: 0.2},
+					{Percentile: 75, Value: 0.5},
+					{Percentile: 90, Value: 0.5},
+				},
+			},
+		},
+		{
+			Name:     "No stations",
+			Snapshot: model.Snapshot{UpdatedAt: now},
+			Expect: &stats.Stats{
+				UpdatedAt: now,
+				Utilisation: []stats.Percentile{
+					{Percentile: 10, Value: 0},
+					{Percentile: 25, Value: 0},
+					{Percentile: 50, Value: 0},
+					{Percentile: 75, Value: 0},
+					{Percentile: 90, Value: 0},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		assert.Equal(t, tc.Expect, stats.Compute(tc.Snapshot), tc.Name)
+	}
+}
@@ -0,0 +1,233 @@
+package stream
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// BacklogSize is the number of changes that are kept, so
+// subscribers that reconnect can resume where they left off
+var BacklogSize = 1000
+
+// BufferSize is the number of events that can be waiting for a
+// subscriber, a subscriber that falls further behind is dropped
+var BufferSize = 100
+
+// Types of events
+const (
+	// Snapshot contains all stations
+	Snapshot = "snapshot"
+	// Availability means the bikes or locks at a station changed
+	Availability = "availability"
+	// Closed means a station was closed
+	Closed = "closed"
+	// Reopened means a station was opened again
+	Reopened = "reopened"
+)
+
+// Event describes a change to a station, or the state of all
+// stations for a snapshot
+type Event struct {
+	ID       string
+	Type     string
+	At       time.Time
+	Stations []*model.Station
+}
+
+// Subscription receives the events published by the broker
+type Subscription struct {
+	broker *Broker
+	events chan Event
+}
+
+// Events returns the published events, the channel is closed
+// when the subscription is closed or the subscriber fell too
+// far behind
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Close stops the delivery of events
+func (s *Subscription) Close() {
+	s.broker.mutex.Lock()
+	defer s.broker.mutex.Unlock()
+	s.broker.remove(s)
+}
+
+// Broker compares the snapshots it observes and publishes
+// the changes to its subscribers
+type Broker struct {
+	mutex       sync.Mutex
+	epoch       string
+	seq         uint64
+	last        model.Snapshot
+	backlog     []Event
+	subscribers map[*Subscription]bool
+	closed      bool
+}
+
+// New creates a broker, the event IDs it hands out are
+// only valid for the lifetime of the broker
+func New() *Broker {
+	return &Broker{
+		epoch:       strconv.FormatInt(time.Now().UnixNano(), 36),
+		subscribers: map[*Subscription]bool{},
+	}
+}
+
+// Observe compares the snapshot with the previous one and publishes
+// the changes, it is meant to be registered with pedal.OnRefresh.
+// Snapshots that are not newer than the previous one are ignored.
+func (b *Broker) Observe(snapshot model.Snapshot) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if snapshot.Stations == nil || !snapshot.UpdatedAt.After(b.last.UpdatedAt) {
+		return
+	}
+	previous := b.last
+	b.last = snapshot
+
+	// There is nothing to compare the first snapshot with
+	if previous.Stations == nil {
+		return
+	}
+
+	for _, station := range sorted(snapshot.Stations) {
+		before, hasKey := previous.Stations[station.ID]
+		var kind string
+		switch {
+		case !hasKey:
+			kind = Availability
+		case station.Closed && !before.Closed:
+			kind = Closed
+		case !station.Closed && before.Closed:
+			kind = Reopened
+		case station.Availability != before.Availability:
+			kind = Availability
+		default:
+			continue
+		}
+
+		b.seq++
+		event := Event{
+			ID:       b.id(b.seq),
+			Type:     kind,
+			At:       snapshot.UpdatedAt,
+			Stations: []*model.Station{station},
+		}
+		b.backlog = append(b.backlog, event)
+		if len(b.backlog) > BacklogSize {
+			b.backlog = b.backlog[len(b.backlog)-BacklogSize:]
+		}
+		b.publish(event)
+	}
+}
+
+// Subscribe registers a subscriber and returns the events it must
+// handle before those that are delivered on the subscription. If
+// the last event ID is known and the changes since are still kept,
+// these are the missed changes, otherwise it is a snapshot.
+func (b *Broker) Subscribe(lastEventID string) (*Subscription, []Event, error) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	if b.closed {
+		return nil, nil, fmt.Errorf("broker is closed")
+	}
+	if b.last.Stations == nil {
+		return nil, nil, fmt.Errorf("no snapshot has been observed")
+	}
+
+	initial, ok := b.since(lastEventID)
+	if !ok {
+		initial = []Event{{
+			ID:       b.id(b.seq),
+			Type:     Snapshot,
+			At:       b.last.UpdatedAt,
+			Stations: sorted(b.last.Stations),
+		}}
+	}
+
+	s := &Subscription{
+		broker: b,
+		events: make(chan Event, BufferSize),
+	}
+	b.subscribers[s] = true
+	return s, initial, nil
+}
+
+// Close ends every subscription and rejects new subscribers, so
+// the streams end and their clients reconnect, e.g., to another
+// instance, when the server shuts down
+func (b *Broker) Close() {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	b.closed = true
+	for s := range b.subscribers {
+		b.remove(s)
+	}
+}
+
+// since returns the changes after the provided event ID,
+// if they are still in the backlog
+func (b *Broker) since(lastEventID string) ([]Event, bool) {
+	parts := strings.Split(lastEventID, "-")
+	if len(parts) != 2 || parts[0] != b.epoch {
+		return nil, false
+	}
+	seq, err := strconv.ParseUint(parts[1], 10, 64)
+	if err != nil || seq > b.seq {
+		return nil, false
+	}
+
+	oldest := b.seq - uint64(len(b.backlog)) + 1
+	if seq+1 < oldest {
+		return nil, false
+	}
+	return append([]Event{}, b.backlog[seq+1-oldest:]...), true
+}
+
+// publish delivers the event to every subscriber, those
+// that are not keeping up are dropped
+func (b *Broker) publish(event Event) {
+	for s := range b.subscribers {
+		select {
+		case s.events <- event:
+		default:
+			b.remove(s)
+		}
+	}
+}
+
+// remove unregisters the subscriber and closes its channel
+func (b *Broker) remove(s *Subscription) {
+	if !b.subscribers[s] {
+		return
+	}
+	delete(b.subscribers, s)
+	close(s.events)
+}
+
+func (b *Broker) id(seq uint64) string {
+	return fmt.Sprintf("%s-%d", b.epoch, seq)
+}
+
+// sorted returns the stations ordered by their ID
+func sorted(stations map[int]*model.Station) []*model.Station {
+	res := make([]*model.Station, 0, len(stations))
+	for _, station := range stations {
+		res = append(res, station)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
+	return res
+}
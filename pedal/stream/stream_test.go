@@ -0,0 +1,163 @@
+package stream_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/stream"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2018, 10, 1, 12, 0, 0, 0, time.UTC)
+
+// newSnapshot creates a snapshot, n minutes after the epoch, with
+// two stations that have the provided bikes and closed states
+func newSnapshot(n int, bikes [2]int, closed [2]bool) model.Snapshot {
+	snapshot := model.Snapshot{
+		Stations:  map[int]*model.Station{},
+		UpdatedAt: epoch.Add(time.Duration(n) * time.Minute),
+	}
+	for i := range bikes {
+		snapshot.Stations[i+1] = &model.Station{
+			ID:            i + 1,
+			NumberOfLocks: 10,
+			Availability:  model.Availability{Bikes: bikes[i], Locks: 10 - bikes[i]},
+			Closed:        closed[i],
+		}
+	}
+	return snapshot
+}
+
+// summary is the part of an event that is compared
+type summary struct {
+	Type     string
+	Stations []int
+}
+
+func summarise(events []stream.Event) []summary {
+	var res []summary
+	for _, event := range events {
+		s := summary{Type: event.Type}
+		for _, station := range event.Stations {
+			s.Stations = append(s.Stations, station.ID)
+		}
+		res = append(res, s)
+	}
+	return res
+}
+
+// drain returns the events that are waiting on the subscription
+func drain(s *stream.Subscription) []stream.Event {
+	var res []stream.Event
+	for {
+		select {
+		case event, ok := <-s.Events():
+			if !ok {
+				return res
+			}
+			res = append(res, event)
+		default:
+			return res
+		}
+	}
+}
+
+func TestBroker_Subscribe(t *testing.T) {
+	broker := stream.New()
+
+	_, _, err := broker.Subscribe("")
+	assert.NotNil(t, err, "Nothing observed fails")
+
+	broker.Observe(newSnapshot(0, [2]int{5, 5}, [2]bool{false, false}))
+	s, initial, err := broker.Subscribe("")
+	assert.Nil(t, err)
+	assert.Equal(t, []summary{{Type: stream.Snapshot, Stations: []int{1, 2}}}, summarise(initial), "Starts with a snapshot")
+
+	broker.Observe(newSnapshot(1, [2]int{4, 5}, [2]bool{false, false}))
+	broker.Observe(newSnapshot(2, [2]int{4, 5}, [2]bool{false, true}))
+	broker.Observe(newSnapshot(3, [2]int{3, 5}, [2]bool{false, true}))
+	// An older snapshot is ignored
+	broker.Observe(newSnapshot(1, [2]int{4, 5}, [2]bool{false, false}))
+	broker.Observe(newSnapshot(4, [2]int{3, 5}, [2]bool{false, false}))
+
+	events := drain(s)
+	assert.Equal(t, []summary{
+		{Type: stream.Availability, Stations: []int{1}},
+		{Type: stream.Closed, Stations: []int{2}},
+		{Type: stream.Availability, Stations: []int{1}},
+		{Type: stream.Reopened, Stations: []int{2}},
+	}, summarise(events), "Publishes the changes")
+	s.Close()
+	s.Close()
+
+	// Resuming replays the changes after the last event
+	_, initial, err = broker.Subscribe(events[1].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, summarise(events[2:]), summarise(initial), "Resumes")
+
+	_, initial, err = broker.Subscribe(events[3].ID)
+	assert.Nil(t, err)
+	assert.Empty(t, initial, "Resumes when up to date")
+
+	for _, lastEventID := range []string{"nope", "abc-1", events[3].ID + "0"} {
+		_, initial, err = broker.Subscribe(lastEventID)
+		assert.Nil(t, err)
+		assert.Equal(t, []summary{{Type: stream.Snapshot, Stations: []int{1, 2}}}, summarise(initial), lastEventID)
+		assert.Equal(t, events[3].ID, initial[0].ID, lastEventID)
+	}
+}
+
+func TestBroker_Backlog(t *testing.T) {
+	stream.BacklogSize = 2
+	defer func() { stream.BacklogSize = 1000 }()
+
+	broker := stream.New()
+	broker.Observe(newSnapshot(0, [2]int{5, 5}, [2]bool{false, false}))
+	s, snapshot, err := broker.Subscribe("")
+	assert.Nil(t, err)
+	for i := 1; i <= 3; i++ {
+		broker.Observe(newSnapshot(i, [2]int{5 - i, 5}, [2]bool{false, false}))
+	}
+	events := drain(s)
+	assert.Len(t, events, 3)
+
+	_, initial, err := broker.Subscribe(events[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, summarise(events[1:]), summarise(initial), "Still in the backlog")
+
+	_, initial, err = broker.Subscribe(snapshot[0].ID)
+	assert.Nil(t, err)
+	assert.Equal(t, stream.Snapshot, initial[0].Type, "No longer in the backlog")
+}
+
+func TestBroker_SlowSubscriber(t *testing.T) {
+	stream.BufferSize = 1
+	defer func() { stream.BufferSize = 100 }()
+
+	broker := stream.New()
+	broker.Observe(newSnapshot(0, [2]int{5, 5}, [2]bool{false, false}))
+	s, _, err := broker.Subscribe("")
+	assert.Nil(t, err)
+	broker.Observe(newSnapshot(1, [2]int{4, 4}, [2]bool{false, false}))
+
+	events := drain(s)
+	assert.Len(t, events, 1)
+	_, ok := <-s.Events()
+	assert.False(t, ok, "Dropped")
+}
+
+func TestBroker_Close(t *testing.T) {
+	broker := stream.New()
+	broker.Observe(newSnapshot(0, [2]int{5, 5}, [2]bool{false, false}))
+	s, _, err := broker.Subscribe("")
+	assert.Nil(t, err)
+
+	broker.Close()
+	_, ok := <-s.Events()
+	assert.False(t, ok, "Ended")
+	s.Close()
+
+	_, _, err = broker.Subscribe("")
+	assert.EqualError(t, err, "broker is closed")
+}
@@ -0,0 +1,159 @@
+package trip
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/model"
+)
+
+// WalkingSpeed is the assumed walking speed in metres per second
+var WalkingSpeed = 1.4
+
+// RidingSpeed is the assumed riding speed in metres per second
+var RidingSpeed = 4.5
+
+// Candidates is the number of pickup and drop-off stations
+// closest to the origin and destination that are considered
+var Candidates = 5
+
+// FewAvailable is the number of bikes or locks at, or below,
+// which a station is considered to be running out
+var FewAvailable = 2
+
+// Suggestion describes a way of travelling between two
+// coordinates by bike
+type Suggestion struct {
+	Pickup          *model.Station
+	Dropoff         *model.Station
+	WalkToPickup    float64
+	Ride            float64
+	WalkFromDropoff float64
+	Duration        time.Duration
+	Reasons         []string
+}
+
+// Planner defines the available methods for
+// planning a trip by bike
+type Planner interface {
+	Suggest(ctx context.Context, from, to model.Coord, limit int) ([]*Suggestion, error)
+}
+
+// planner suggests trips using the stations
+// provided by pedlar
+type planner struct {
+	pedlar pedal.Pedlar
+}
+
+// New creates a planner that reads the stations from pedlar
+func New(pedlar pedal.Pedlar) Planner {
+	return &planner{
+		pedlar: pedlar,
+	}
+}
+
+// Suggest returns up to limit pairs of pickup and drop-off
+// stations, ordered by the estimated duration of walking to
+// the pickup, riding in a straight line to the drop-off and
+// walking from there to the destination
+func (p *planner) Suggest(ctx context.Context, from, to model.Coord, limit int) ([]*Suggestion, error) {
+	stations, err := p.pedlar.Stations(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pickups := nearest(from, stations, func(s *model.Station) bool {
+		return s.Availability.Bikes > 0
+	})
+	dropoffs := nearest(to, stations, func(s *model.Station) bool {
+		return s.Availability.Locks > 0
+	})
+
+	var suggestions []*Suggestion
+	for i, pickup := range pickups {
+		for j, dropoff := range dropoffs {
+			if pickup.ID == dropoff.ID {
+				continue
+			}
+			suggestions = append(suggestions, suggest(from, to, pickup, dropoff, i == 0, j == 0))
+		}
+	}
+
+	sort.SliceStable(suggestions, func(i, j int) bool {
+		return suggestions[i].Duration < suggestions[j].Duration
+	})
+
+	if limit >= 0 && len(suggestions) > limit {
+		suggestions = suggestions[:limit]
+	}
+
+	return suggestions, nil
+}
+
+// suggest creates a suggestion for the provided stations
+// and explains why it is a good fit
+func suggest(from, to model.Coord, pickup, dropoff *model.Station, closestPickup, closestDropoff bool) *Suggestion {
+	s := &Suggestion{
+		Pickup:          pickup,
+		Dropoff:         dropoff,
+		WalkToPickup:    from.Distance(pickup.Center),
+		Ride:            pickup.Center.Distance(dropoff.Center),
+		WalkFromDropoff: dropoff.Center.Distance(to),
+	}
+
+	seconds := (s.WalkToPickup+s.WalkFromDropoff)/WalkingSpeed + s.Ride/RidingSpeed
+	s.Duration = time.Duration(seconds) * time.Second
+
+	if closestPickup {
+		s.Reasons = append(s.Reasons, "closest pickup with bikes available")
+	}
+	if closestDropoff {
+		s.Reasons = append(s.Reasons, "closest drop-off with free locks")
+	}
+	s.Reasons = append(s.Reasons,
+		fmt.Sprintf("%d bikes available at %s", pickup.Availability.Bikes, pickup.Title),
+		fmt.Sprintf("%d free locks at %s", dropoff.Availability.Locks, dropoff.Title),
+	)
+	if pickup.Availability.Bikes <= FewAvailable {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("few bikes left at %s", pickup.Title))
+	}
+	if dropoff.Availability.Locks <= FewAvailable {
+		s.Reasons = append(s.Reasons, fmt.Sprintf("few free locks left at %s", dropoff.Title))
+	}
+	s.Reasons = append(s.Reasons, fmt.Sprintf("walk %.0f m, ride %.0f m, walk %.0f m",
+		s.WalkToPickup,
+		s.Ride,
+		s.WalkFromDropoff,
+	))
+
+	return s
+}
+
+// nearest returns the candidate stations closest to the
+// coordinate that are open, in service and accepted
+func nearest(coord model.Coord, stations map[int]*model.Station, accept func(*model.Station) bool) []*model.Station {
+	var candidates []*model.Station
+	for _, station := range stations {
+		if station.Closed || !station.InService || !accept(station) {
+			continue
+		}
+		candidates = append(candidates, station)
+	}
+
+	sort.Slice(candidates, func(i, j int) bool {
+		di, dj := coord.Distance(candidates[i].Center), coord.Distance(candidates[j].Center)
+		if di == dj {
+			return candidates[i].ID < candidates[j].ID
+		}
+		return di < dj
+	})
+
+	if len(candidates) > Candidates {
+		candidates = candidates[:Candidates]
+	}
+
+	return candidates
+}
@@ -0,0 +1,97 @@
+package trip_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/trip"
+	"github.com/stretchr/testify/assert"
+)
+
+func newStation(id int, lat, lon float64, bikes, locks int) *model.Station {
+	return &model.Station{
+		ID:            id,
+		InService:     true,
+		Title:         fmt.Sprintf("Station %d", id),
+		NumberOfLocks: bikes + locks,
+		Center:        model.Coord{Latitude: lat, Longitude: lon},
+		Availability:  model.Availability{Bikes: bikes, Locks: locks},
+	}
+}
+
+func TestPlanner_Suggest(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			newStation(1, 59.9101, 10.7500, 5, 5),
+			newStation(2, 59.9100, 10.7501, 5, 5),
+			newStation(3, 59.9100, 10.7500, 0, 10),
+			newStation(4, 59.9300, 10.7500, 8, 1),
+			newStation(5, 59.9300, 10.7501, 10, 0),
+			newStation(6, 59.9200, 10.7500, 1, 9),
+		},
+	}
+
+	from := model.Coord{Latitude: 59.9100, Longitude: 10.7500}
+	to := model.Coord{Latitude: 59.9300, Longitude: 10.7500}
+
+	testCases := []struct {
+		Name          string
+		Limit         int
+		Err           error
+		ExpectErr     bool
+		Expect        interface{}
+		ExpectReasons []string
+	}{
+		{
+			Name:  "Suggests the best pairs",
+			Limit: 3,
+			Expect: [][2]int{
+				{1, 4},
+				{6, 4},
+				{1, 6},
+			},
+			ExpectReasons: []string{
+				"closest pickup with bikes available",
+				"closest drop-off with free locks",
+				"5 bikes available at Station 1",
+				"1 free locks at Station 4",
+				"few free locks left at Station 4",
+				"walk 11 m, ride 2213 m, walk 0 m",
+			},
+		},
+		{
+			Name:   "Zero limit",
+			Limit:  0,
+			Expect: [][2]int(nil),
+		},
+		{
+			Name:      "With error fails",
+			Err:       fmt.Errorf("nope"),
+			ExpectErr: true,
+			Expect:    "nope",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(stations, &model.StationAvailability{}, &model.Status{StationsClosed: []int{2}}, tc.Err)
+		got, err := trip.New(pedal.New(client)).Suggest(context.Background(), from, to, tc.Limit)
+		if tc.ExpectErr {
+			assert.Equal(t, tc.Expect, err.Error(), tc.Name)
+			continue
+		}
+
+		assert.Nil(t, err, tc.Name)
+		var pairs [][2]int
+		for _, s := range got {
+			pairs = append(pairs, [2]int{s.Pickup.ID, s.Dropoff.ID})
+		}
+		assert.Equal(t, tc.Expect, pairs, tc.Name)
+		if len(tc.ExpectReasons) > 0 {
+			assert.Equal(t, tc.ExpectReasons, got[0].Reasons, tc.Name)
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// HistorySample represents the state of a
+// station at a point in time
+type HistorySample struct {
+	Time         time.Time    `json:"time"`
+	Availability Availability `json:"availability"`
+	Closed       bool         `json:"closed"`
+	InService    bool         `json:"in_service"`
+}
+
+// Aggregate describes the spread of a value
+// within a period of time
+type Aggregate struct {
+	Min int     `json:"min"`
+	Max int     `json:"max"`
+	Avg float64 `json:"avg"`
+}
+
+// HistoryBucket represents the availability of
+// bikes and locks at a station within a period
+type HistoryBucket struct {
+	From    time.Time `json:"from"`
+	To      time.Time `json:"to"`
+	Samples int       `json:"samples"`
+	Bikes   Aggregate `json:"bikes"`
+	Locks   Aggregate `json:"locks"`
+}
+
+// HistoryService defines what methods a history
+// service implementation must implement
+type HistoryService interface {
+	Range(ctx context.Context, id int, from, to time.Time, step time.Duration) ([]HistoryBucket, error)
+	At(ctx context.Context, at time.Time, query StationQuery) (StationList, error)
+}
+
+// HistoryStore defines what methods a history
+// storage implementation must implement
+type HistoryStore interface {
+	Samples(ctx context.Context, id int, from, to time.Time) ([]HistorySample, error)
+	At(ctx context.Context, at time.Time) ([]Station, error)
+}
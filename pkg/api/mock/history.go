@@ -0,0 +1,82 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// NewHistorySamples creates mocked samples for every ten
+// minutes of the first hour of the first of october 2018
+func NewHistorySamples() []api.HistorySample {
+	start := time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+	var samples []api.HistorySample
+	for i := 0; i < 6; i++ {
+		samples = append(samples, api.HistorySample{
+			Time: start.Add(time.Duration(i) * 10 * time.Minute),
+			Availability: api.Availability{
+				Bikes: i,
+				Locks: 10 - i,
+			},
+			InService: true,
+		})
+	}
+	return samples
+}
+
+type historyStore struct {
+	SamplesFn func(ctx context.Context, id int, from, to time.Time) ([]api.HistorySample, error)
+	AtFn      func(ctx context.Context, at time.Time) ([]api.Station, error)
+}
+
+// Samples returns the values of the mocked function
+func (s *historyStore) Samples(ctx context.Context, id int, from, to time.Time) ([]api.HistorySample, error) {
+	return s.SamplesFn(ctx, id, from, to)
+}
+
+// At returns the values of the mocked function
+func (s *historyStore) At(ctx context.Context, at time.Time) ([]api.Station, error) {
+	return s.AtFn(ctx, at)
+}
+
+// NewHistoryStore creates a mocked history store using the
+// provided input values
+func NewHistoryStore(samples []api.HistorySample, station api.Station, err error) api.HistoryStore {
+	return &historyStore{
+		SamplesFn: func(context.Context, int, time.Time, time.Time) ([]api.HistorySample, error) {
+			return samples, err
+		},
+		AtFn: func(context.Context, time.Time) ([]api.Station, error) {
+			return []api.Station{station}, err
+		},
+	}
+}
+
+type historyService struct {
+	RangeFn func(ctx context.Context, id int, from, to time.Time, step time.Duration) ([]api.HistoryBucket, error)
+	AtFn    func(ctx context.Context, at time.Time, query api.StationQuery) (api.StationList, error)
+}
+
+// Range returns the value of the mocked function
+func (s *historyService) Range(ctx context.Context, id int, from, to time.Time, step time.Duration) ([]api.HistoryBucket, error) {
+	return s.RangeFn(ctx, id, from, to, step)
+}
+
+// At returns the value of the mocked function
+func (s *historyService) At(ctx context.Context, at time.Time, query api.StationQuery) (api.StationList, error) {
+	return s.AtFn(ctx, at, query)
+}
+
+// NewHistoryService creates a mocked history service using the
+// provided input values
+func NewHistoryService(buckets []api.HistoryBucket, station api.Station, err error) api.HistoryService {
+	return &historyService{
+		RangeFn: func(context.Context, int, time.Time, time.Time, time.Duration) ([]api.HistoryBucket, error) {
+			return buckets, err
+		},
+		AtFn: func(context.Context, time.Time, api.StationQuery) (api.StationList, error) {
+			return api.StationList{Stations: []api.Station{station}, Total: 1}, err
+		},
+	}
+}
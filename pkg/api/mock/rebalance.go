@@ -0,0 +1,81 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// NewRebalance creates a mocked rebalancing plan
+func NewRebalance() *api.Rebalance {
+	to := NewStation()
+	to.ID = 2
+	to.Title = "Arctic"
+	to.Subtitle = "Close to the polar bears"
+	return &api.Rebalance{
+		Stations: []api.StationBalance{
+			{
+				Station:   NewStation(),
+				Level:     "full",
+				Bikes:     10,
+				Locks:     0,
+				Projected: 10,
+				Target:    5,
+			},
+			{
+				Station:   to,
+				Level:     "empty",
+				Bikes:     0,
+				Locks:     10,
+				Projected: 0,
+				Target:    5,
+			},
+		},
+		Transfers: []api.Transfer{
+			{
+				From:     NewStation(),
+				To:       to,
+				Count:    5,
+				Distance: 1000,
+			},
+		},
+	}
+}
+
+type rebalanceStore struct {
+	PlanFn func(ctx context.Context, capacity int) (*api.Rebalance, error)
+}
+
+// Plan returns the values of the mocked function
+func (s *rebalanceStore) Plan(ctx context.Context, capacity int) (*api.Rebalance, error) {
+	return s.PlanFn(ctx, capacity)
+}
+
+// NewRebalanceStore creates a mocked rebalance store using
+// the provided input values
+func NewRebalanceStore(rebalance *api.Rebalance, err error) api.RebalanceStore {
+	return &rebalanceStore{
+		PlanFn: func(context.Context, int) (*api.Rebalance, error) {
+			return rebalance, err
+		},
+	}
+}
+
+type rebalanceService struct {
+	PlanFn func(ctx context.Context, capacity int) (*api.Rebalance, error)
+}
+
+// Plan returns the value of the mocked function
+func (s *rebalanceService) Plan(ctx context.Context, capacity int) (*api.Rebalance, error) {
+	return s.PlanFn(ctx, capacity)
+}
+
+// NewRebalanceService creates a mocked rebalance service using
+// the provided inputs values
+func NewRebalanceService(rebalance *api.Rebalance, err error) api.RebalanceService {
+	return &rebalanceService{
+		PlanFn: func(context.Context, int) (*api.Rebalance, error) {
+			return rebalance, err
+		},
+	}
+}
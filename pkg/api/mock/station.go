@@ -2,10 +2,33 @@ package mock
 
 import (
 	"context"
+	"time"
 
 	"github.com/paulbes/go-pedal/pkg/api"
 )
 
+// NewForecast creates a mocked forecast
+func NewForecast() api.Forecast {
+	return api.Forecast{
+		StationID:       1,
+		At:              time.Date(2018, 10, 1, 8, 20, 0, 0, time.UTC),
+		Horizon:         1200,
+		Bikes:           2.5,
+		Locks:           7.5,
+		BikeProbability: 0.9,
+		LockProbability: 1,
+	}
+}
+
+// NewStationVersion creates a mocked station version
+func NewStationVersion() api.StationVersion {
+	return api.StationVersion{
+		Tag:         "1b2d3f4a5c6e7d8f",
+		UpdatedAt:   time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC),
+		RefreshRate: 10 * time.Second,
+	}
+}
+
 // NewStation creates a mocked station
 func NewStation() api.Station {
 	return api.Station{
@@ -33,36 +56,67 @@ func NewStation() api.Station {
 }
 
 type stationStore struct {
-	GetFn  func(id int) (api.Station, error)
-	ListFn func() ([]api.Station, error)
+	GetFn      func(ctx context.Context, id int) (api.Station, error)
+	ListFn     func(ctx context.Context) ([]api.Station, error)
+	LocateFn   func(ctx context.Context, coord api.Coord) (api.Station, error)
+	ForecastFn func(ctx context.Context, id int, horizon time.Duration) (api.Forecast, error)
+	VersionFn  func(ctx context.Context) (api.StationVersion, error)
 }
 
 // Get returns the values of the mocked function
-func (s *stationStore) Get(id int) (api.Station, error) {
-	return s.GetFn(id)
+func (s *stationStore) Get(ctx context.Context, id int) (api.Station, error) {
+	return s.GetFn(ctx, id)
 }
 
 // List returns the values of the mocked function
-func (s *stationStore) List() ([]api.Station, error) {
-	return s.ListFn()
+func (s *stationStore) List(ctx context.Context) ([]api.Station, error) {
+	return s.ListFn(ctx)
+}
+
+// Locate returns the values of the mocked function
+func (s *stationStore) Locate(ctx context.Context, coord api.Coord) (api.Station, error) {
+	return s.LocateFn(ctx, coord)
+}
+
+// Forecast returns the values of the mocked function
+func (s *stationStore) Forecast(ctx context.Context, id int, horizon time.Duration) (api.Forecast, error) {
+	return s.ForecastFn(ctx, id, horizon)
+}
+
+// Version returns the values of the mocked function
+func (s *stationStore) Version(ctx context.Context) (api.StationVersion, error) {
+	return s.VersionFn(ctx)
 }
 
 // NewStationStore creates a mocked station store using the provided
 // input values
 func NewStationStore(station api.Station, err error) api.StationStore {
 	return &stationStore{
-		GetFn: func(int) (api.Station, error) {
+		GetFn: func(context.Context, int) (api.Station, error) {
 			return station, err
 		},
-		ListFn: func() ([]api.Station, error) {
+		ListFn: func(context.Context) ([]api.Station, error) {
 			return []api.Station{station}, err
 		},
+		LocateFn: func(context.Context, api.Coord) (api.Station, error) {
+			return station, err
+		},
+		ForecastFn: func(context.Context, int, time.Duration) (api.Forecast, error) {
+			return NewForecast(), err
+		},
+		VersionFn: func(context.Context) (api.StationVersion, error) {
+			return NewStationVersion(), err
+		},
 	}
 }
 
 type stationService struct {
-	GetFn  func(ctx context.Context, id int) (api.Station, error)
-	ListFn func(ctx context.Context) ([]api.Station, error)
+	GetFn      func(ctx context.Context, id int) (api.Station, error)
+	ListFn     func(ctx context.Context, query api.StationQuery) (api.StationList, error)
+	LocateFn   func(ctx context.Context, coord api.Coord) (api.Station, error)
+	NearbyFn   func(ctx context.Context, coord api.Coord, radius float64, limit int) ([]api.NearbyStation, error)
+	ForecastFn func(ctx context.Context, id int, horizon time.Duration) (api.Forecast, error)
+	VersionFn  func(ctx context.Context) (api.StationVersion, error)
 }
 
 // Get returns the value of the mocked function
@@ -71,8 +125,28 @@ func (s *stationService) Get(ctx context.Context, id int) (api.Station, error) {
 }
 
 // List returns the value of the mocked function
-func (s *stationService) List(ctx context.Context) ([]api.Station, error) {
-	return s.ListFn(ctx)
+func (s *stationService) List(ctx context.Context, query api.StationQuery) (api.StationList, error) {
+	return s.ListFn(ctx, query)
+}
+
+// Locate returns the value of the mocked function
+func (s *stationService) Locate(ctx context.Context, coord api.Coord) (api.Station, error) {
+	return s.LocateFn(ctx, coord)
+}
+
+// Nearby returns the value of the mocked function
+func (s *stationService) Nearby(ctx context.Context, coord api.Coord, radius float64, limit int) ([]api.NearbyStation, error) {
+	return s.NearbyFn(ctx, coord, radius, limit)
+}
+
+// Forecast returns the value of the mocked function
+func (s *stationService) Forecast(ctx context.Context, id int, horizon time.Duration) (api.Forecast, error) {
+	return s.ForecastFn(ctx, id, horizon)
+}
+
+// Version returns the value of the mocked function
+func (s *stationService) Version(ctx context.Context) (api.StationVersion, error) {
+	return s.VersionFn(ctx)
 }
 
 // NewStationService creates a mocked station service using the provided
@@ -82,8 +156,20 @@ func NewStationService(station api.Station, err error) api.StationService {
 		GetFn: func(context.Context, int) (api.Station, error) {
 			return station, err
 		},
-		ListFn: func(context.Context) ([]api.Station, error) {
-			return []api.Station{station}, err
+		ListFn: func(context.Context, api.StationQuery) (api.StationList, error) {
+			return api.StationList{Stations: []api.Station{station}, Total: 1}, err
+		},
+		LocateFn: func(context.Context, api.Coord) (api.Station, error) {
+			return station, err
+		},
+		NearbyFn: func(context.Context, api.Coord, float64, int) ([]api.NearbyStation, error) {
+			return []api.NearbyStation{{Station: station, Distance: 42}}, err
+		},
+		ForecastFn: func(context.Context, int, time.Duration) (api.Forecast, error) {
+			return NewForecast(), err
+		},
+		VersionFn: func(context.Context) (api.StationVersion, error) {
+			return NewStationVersion(), err
 		},
 	}
 }
@@ -0,0 +1,66 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// NewStats creates mocked statistics
+func NewStats() *api.Stats {
+	return &api.Stats{
+		UpdatedAt:          time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC),
+		Stations:           3,
+		Open:               2,
+		Closed:             1,
+		Empty:              1,
+		Full:               0,
+		Bikes:              5,
+		Locks:              14,
+		Capacity:           20,
+		BikesInUseEstimate: 1,
+		Utilisation: []api.Percentile{
+			{Percentile: 50, Value: 0},
+			{Percentile: 90, Value: 0.5},
+		},
+	}
+}
+
+type statsStore struct {
+	GetFn func(ctx context.Context) (*api.Stats, error)
+}
+
+// Get returns the values of the mocked function
+func (s *statsStore) Get(ctx context.Context) (*api.Stats, error) {
+	return s.GetFn(ctx)
+}
+
+// NewStatsStore creates a mocked stats store using
+// the provided input values
+func NewStatsStore(stats *api.Stats, err error) api.StatsStore {
+	return &statsStore{
+		GetFn: func(context.Context) (*api.Stats, error) {
+			return stats, err
+		},
+	}
+}
+
+type statsService struct {
+	GetFn func(ctx context.Context) (*api.Stats, error)
+}
+
+// Get returns the value of the mocked function
+func (s *statsService) Get(ctx context.Context) (*api.Stats, error) {
+	return s.GetFn(ctx)
+}
+
+// NewStatsService creates a mocked stats service using
+// the provided inputs values
+func NewStatsService(stats *api.Stats, err error) api.StatsService {
+	return &statsService{
+		GetFn: func(context.Context) (*api.Stats, error) {
+			return stats, err
+		},
+	}
+}
@@ -0,0 +1,76 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// NewStatus creates a mocked status, of stations that
+// were loaded a minute ago
+func NewStatus() api.Status {
+	updatedAt := time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC)
+	lastSuccess := time.Date(2018, 10, 1, 8, 0, 50, 0, time.UTC)
+	lastErrorAt := time.Date(2018, 10, 1, 7, 55, 0, 0, time.UTC)
+	age, feedAge := 60.0, 10.0
+	return api.Status{
+		Loaded:    true,
+		UpdatedAt: &updatedAt,
+		Age:       &age,
+		Feeds: map[string]api.FeedStatus{
+			"stations": {
+				LastSuccess: &lastSuccess,
+				LastError:   "could not connect to API",
+				LastErrorAt: &lastErrorAt,
+				Age:         &feedAge,
+			},
+			"availability": {
+				LastSuccess: &lastSuccess,
+				Age:         &feedAge,
+			},
+			"status": {
+				LastSuccess: &lastSuccess,
+				Age:         &feedAge,
+			},
+		},
+	}
+}
+
+type statusStore struct {
+	GetFn func(ctx context.Context) (api.Status, error)
+}
+
+// Get returns the values of the mocked function
+func (s *statusStore) Get(ctx context.Context) (api.Status, error) {
+	return s.GetFn(ctx)
+}
+
+// NewStatusStore creates a mocked status store using
+// the provided input values
+func NewStatusStore(status api.Status, err error) api.StatusStore {
+	return &statusStore{
+		GetFn: func(context.Context) (api.Status, error) {
+			return status, err
+		},
+	}
+}
+
+type statusService struct {
+	GetFn func(ctx context.Context) (api.Status, error)
+}
+
+// Get returns the value of the mocked function
+func (s *statusService) Get(ctx context.Context) (api.Status, error) {
+	return s.GetFn(ctx)
+}
+
+// NewStatusService creates a mocked status service using
+// the provided inputs values
+func NewStatusService(status api.Status, err error) api.StatusService {
+	return &statusService{
+		GetFn: func(context.Context) (api.Status, error) {
+			return status, err
+		},
+	}
+}
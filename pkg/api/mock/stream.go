@@ -0,0 +1,94 @@
+package mock
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// NewStationEvents creates mocked station events, a
+// snapshot followed by a change
+func NewStationEvents() []api.StationEvent {
+	station := NewStation()
+	at := time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC)
+	station.UpdatedAt = at
+	changed := station
+	changed.Availability = api.Availability{Bikes: 4, Locks: 6}
+	changed.UpdatedAt = at.Add(time.Minute)
+	return []api.StationEvent{
+		{ID: "e-0", Type: api.EventSnapshot, At: at, Stations: []api.Station{station}},
+		{ID: "e-1", Type: api.EventAvailability, At: changed.UpdatedAt, Stations: []api.Station{changed}},
+	}
+}
+
+// subscription delivers the events and then ends,
+// as if the subscriber fell behind
+type subscription struct {
+	events chan api.StationEvent
+}
+
+// Events returns the mocked events
+func (s *subscription) Events() <-chan api.StationEvent {
+	return s.events
+}
+
+// Close does nothing, the events are already delivered
+func (s *subscription) Close() {}
+
+// NewSubscription creates a mocked subscription that
+// delivers the provided events and then ends
+func NewSubscription(events []api.StationEvent) api.StationSubscription {
+	s := &subscription{
+		events: make(chan api.StationEvent, len(events)),
+	}
+	for _, event := range events {
+		s.events <- event
+	}
+	close(s.events)
+	return s
+}
+
+type streamStore struct {
+	SubscribeFn func(ctx context.Context, lastEventID string) (api.StationSubscription, error)
+}
+
+// Subscribe returns the values of the mocked function
+func (s *streamStore) Subscribe(ctx context.Context, lastEventID string) (api.StationSubscription, error) {
+	return s.SubscribeFn(ctx, lastEventID)
+}
+
+// NewStreamStore creates a mocked stream store using the provided
+// input values, every subscription delivers the events
+func NewStreamStore(events []api.StationEvent, err error) api.StreamStore {
+	return &streamStore{
+		SubscribeFn: func(context.Context, string) (api.StationSubscription, error) {
+			if err != nil {
+				return nil, err
+			}
+			return NewSubscription(events), nil
+		},
+	}
+}
+
+type streamService struct {
+	SubscribeFn func(ctx context.Context, lastEventID string) (api.StationSubscription, error)
+}
+
+// Subscribe returns the values of the mocked function
+func (s *streamService) Subscribe(ctx context.Context, lastEventID string) (api.StationSubscription, error) {
+	return s.SubscribeFn(ctx, lastEventID)
+}
+
+// NewStreamService creates a mocked stream service using the
+// provided input values, every subscription delivers the events
+func NewStreamService(events []api.StationEvent, err error) api.StreamService {
+	return &streamService{
+		SubscribeFn: func(context.Context, string) (api.StationSubscription, error) {
+			if err != nil {
+				return nil, err
+			}
+			return NewSubscription(events), nil
+		},
+	}
+}
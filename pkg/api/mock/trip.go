@@ -0,0 +1,64 @@
+package mock
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// NewTrip creates a mocked trip
+func NewTrip() api.Trip {
+	dropoff := NewStation()
+	dropoff.ID = 2
+	dropoff.Title = "Arctic"
+	dropoff.Subtitle = "Close to the polar bears"
+	return api.Trip{
+		Pickup:          NewStation(),
+		Dropoff:         dropoff,
+		WalkToPickup:    100,
+		Ride:            1000,
+		WalkFromDropoff: 50,
+		Duration:        330,
+		Reasons: []string{
+			"closest pickup with bikes available",
+		},
+	}
+}
+
+type tripStore struct {
+	SuggestFn func(ctx context.Context, from, to api.Coord, limit int) ([]api.Trip, error)
+}
+
+// Suggest returns the values of the mocked function
+func (s *tripStore) Suggest(ctx context.Context, from, to api.Coord, limit int) ([]api.Trip, error) {
+	return s.SuggestFn(ctx, from, to, limit)
+}
+
+// NewTripStore creates a mocked trip store using the provided
+// input values
+func NewTripStore(trip api.Trip, err error) api.TripStore {
+	return &tripStore{
+		SuggestFn: func(context.Context, api.Coord, api.Coord, int) ([]api.Trip, error) {
+			return []api.Trip{trip}, err
+		},
+	}
+}
+
+type tripService struct {
+	SuggestFn func(ctx context.Context, from, to api.Coord, limit int) ([]api.Trip, error)
+}
+
+// Suggest returns the value of the mocked function
+func (s *tripService) Suggest(ctx context.Context, from, to api.Coord, limit int) ([]api.Trip, error) {
+	return s.SuggestFn(ctx, from, to, limit)
+}
+
+// NewTripService creates a mocked trip service using the provided
+// inputs values
+func NewTripService(trip api.Trip, err error) api.TripService {
+	return &tripService{
+		SuggestFn: func(context.Context, api.Coord, api.Coord, int) ([]api.Trip, error) {
+			return []api.Trip{trip}, err
+		},
+	}
+}
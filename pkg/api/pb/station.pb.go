@@ -0,0 +1,767 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// source: pkg/api/pb/station.proto
+
+package pb
+
+/*
+Note: this is the gRPC contract of the station service, it mirrors
+the REST API. The Go code is generated with protoc-gen-go, from
+github.com/golang/protobuf v1.2.0, as vendored:
+
+  protoc --go_out=plugins=grpc:. pkg/api/pb/station.proto
+*/
+
+import proto "github.com/golang/protobuf/proto"
+import fmt "fmt"
+import math "math"
+import timestamp "github.com/golang/protobuf/ptypes/timestamp"
+import wrappers "github.com/golang/protobuf/ptypes/wrappers"
+
+import (
+	context "golang.org/x/net/context"
+	grpc "google.golang.org/grpc"
+)
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ = proto.Marshal
+var _ = fmt.Errorf
+var _ = math.Inf
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the proto package it is being compiled against.
+// A compilation error at this line likely means your copy of the
+// proto package needs to be updated.
+const _ = proto.ProtoPackageIsVersion2 // please upgrade the proto package
+
+type Coord struct {
+	Latitude             float64  `protobuf:"fixed64,1,opt,name=latitude,proto3" json:"latitude,omitempty"`
+	Longitude            float64  `protobuf:"fixed64,2,opt,name=longitude,proto3" json:"longitude,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Coord) Reset()         { *m = Coord{} }
+func (m *Coord) String() string { return proto.CompactTextString(m) }
+func (*Coord) ProtoMessage()    {}
+func (*Coord) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{0}
+}
+func (m *Coord) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Coord.Unmarshal(m, b)
+}
+func (m *Coord) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Coord.Marshal(b, m, deterministic)
+}
+func (dst *Coord) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Coord.Merge(dst, src)
+}
+func (m *Coord) XXX_Size() int {
+	return xxx_messageInfo_Coord.Size(m)
+}
+func (m *Coord) XXX_DiscardUnknown() {
+	xxx_messageInfo_Coord.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Coord proto.InternalMessageInfo
+
+func (m *Coord) GetLatitude() float64 {
+	if m != nil {
+		return m.Latitude
+	}
+	return 0
+}
+
+func (m *Coord) GetLongitude() float64 {
+	if m != nil {
+		return m.Longitude
+	}
+	return 0
+}
+
+type Availability struct {
+	Bikes                int32    `protobuf:"varint,1,opt,name=bikes,proto3" json:"bikes,omitempty"`
+	Locks                int32    `protobuf:"varint,2,opt,name=locks,proto3" json:"locks,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *Availability) Reset()         { *m = Availability{} }
+func (m *Availability) String() string { return proto.CompactTextString(m) }
+func (*Availability) ProtoMessage()    {}
+func (*Availability) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{1}
+}
+func (m *Availability) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Availability.Unmarshal(m, b)
+}
+func (m *Availability) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Availability.Marshal(b, m, deterministic)
+}
+func (dst *Availability) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Availability.Merge(dst, src)
+}
+func (m *Availability) XXX_Size() int {
+	return xxx_messageInfo_Availability.Size(m)
+}
+func (m *Availability) XXX_DiscardUnknown() {
+	xxx_messageInfo_Availability.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Availability proto.InternalMessageInfo
+
+func (m *Availability) GetBikes() int32 {
+	if m != nil {
+		return m.Bikes
+	}
+	return 0
+}
+
+func (m *Availability) GetLocks() int32 {
+	if m != nil {
+		return m.Locks
+	}
+	return 0
+}
+
+type Station struct {
+	Id                   int32                `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	InService            bool                 `protobuf:"varint,2,opt,name=in_service,json=inService,proto3" json:"in_service,omitempty"`
+	Title                string               `protobuf:"bytes,3,opt,name=title,proto3" json:"title,omitempty"`
+	Subtitle             string               `protobuf:"bytes,4,opt,name=subtitle,proto3" json:"subtitle,omitempty"`
+	NumberOfLocks        int32                `protobuf:"varint,5,opt,name=number_of_locks,json=numberOfLocks,proto3" json:"number_of_locks,omitempty"`
+	Center               *Coord               `protobuf:"bytes,6,opt,name=center,proto3" json:"center,omitempty"`
+	Bounds               []*Coord             `protobuf:"bytes,7,rep,name=bounds,proto3" json:"bounds,omitempty"`
+	Availability         *Availability        `protobuf:"bytes,8,opt,name=availability,proto3" json:"availability,omitempty"`
+	Closed               bool                 `protobuf:"varint,9,opt,name=closed,proto3" json:"closed,omitempty"`
+	UpdatedAt            *timestamp.Timestamp `protobuf:"bytes,10,opt,name=updated_at,json=updatedAt,proto3" json:"updated_at,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *Station) Reset()         { *m = Station{} }
+func (m *Station) String() string { return proto.CompactTextString(m) }
+func (*Station) ProtoMessage()    {}
+func (*Station) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{2}
+}
+func (m *Station) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_Station.Unmarshal(m, b)
+}
+func (m *Station) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_Station.Marshal(b, m, deterministic)
+}
+func (dst *Station) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_Station.Merge(dst, src)
+}
+func (m *Station) XXX_Size() int {
+	return xxx_messageInfo_Station.Size(m)
+}
+func (m *Station) XXX_DiscardUnknown() {
+	xxx_messageInfo_Station.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_Station proto.InternalMessageInfo
+
+func (m *Station) GetId() int32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+func (m *Station) GetInService() bool {
+	if m != nil {
+		return m.InService
+	}
+	return false
+}
+
+func (m *Station) GetTitle() string {
+	if m != nil {
+		return m.Title
+	}
+	return ""
+}
+
+func (m *Station) GetSubtitle() string {
+	if m != nil {
+		return m.Subtitle
+	}
+	return ""
+}
+
+func (m *Station) GetNumberOfLocks() int32 {
+	if m != nil {
+		return m.NumberOfLocks
+	}
+	return 0
+}
+
+func (m *Station) GetCenter() *Coord {
+	if m != nil {
+		return m.Center
+	}
+	return nil
+}
+
+func (m *Station) GetBounds() []*Coord {
+	if m != nil {
+		return m.Bounds
+	}
+	return nil
+}
+
+func (m *Station) GetAvailability() *Availability {
+	if m != nil {
+		return m.Availability
+	}
+	return nil
+}
+
+func (m *Station) GetClosed() bool {
+	if m != nil {
+		return m.Closed
+	}
+	return false
+}
+
+func (m *Station) GetUpdatedAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.UpdatedAt
+	}
+	return nil
+}
+
+type GetStationRequest struct {
+	Id                   int32    `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *GetStationRequest) Reset()         { *m = GetStationRequest{} }
+func (m *GetStationRequest) String() string { return proto.CompactTextString(m) }
+func (*GetStationRequest) ProtoMessage()    {}
+func (*GetStationRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{3}
+}
+func (m *GetStationRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_GetStationRequest.Unmarshal(m, b)
+}
+func (m *GetStationRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_GetStationRequest.Marshal(b, m, deterministic)
+}
+func (dst *GetStationRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_GetStationRequest.Merge(dst, src)
+}
+func (m *GetStationRequest) XXX_Size() int {
+	return xxx_messageInfo_GetStationRequest.Size(m)
+}
+func (m *GetStationRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_GetStationRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_GetStationRequest proto.InternalMessageInfo
+
+func (m *GetStationRequest) GetId() int32 {
+	if m != nil {
+		return m.Id
+	}
+	return 0
+}
+
+type ListStationsRequest struct {
+	Q string `protobuf:"bytes,1,opt,name=q,proto3" json:"q,omitempty"`
+	// The filters are wrapped, so they can be left out
+	Closed    *wrappers.BoolValue `protobuf:"bytes,2,opt,name=closed,proto3" json:"closed,omitempty"`
+	InService *wrappers.BoolValue `protobuf:"bytes,3,opt,name=in_service,json=inService,proto3" json:"in_service,omitempty"`
+	MinBikes  int32               `protobuf:"varint,4,opt,name=min_bikes,json=minBikes,proto3" json:"min_bikes,omitempty"`
+	MinLocks  int32               `protobuf:"varint,5,opt,name=min_locks,json=minLocks,proto3" json:"min_locks,omitempty"`
+	// One of id, title, bikes, locks or distance, a
+	// leading dash sorts in descending order
+	Sort string `protobuf:"bytes,6,opt,name=sort,proto3" json:"sort,omitempty"`
+	// Required when sorting by distance
+	Origin               *Coord   `protobuf:"bytes,7,opt,name=origin,proto3" json:"origin,omitempty"`
+	Offset               int32    `protobuf:"varint,8,opt,name=offset,proto3" json:"offset,omitempty"`
+	Limit                int32    `protobuf:"varint,9,opt,name=limit,proto3" json:"limit,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *ListStationsRequest) Reset()         { *m = ListStationsRequest{} }
+func (m *ListStationsRequest) String() string { return proto.CompactTextString(m) }
+func (*ListStationsRequest) ProtoMessage()    {}
+func (*ListStationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{4}
+}
+func (m *ListStationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStationsRequest.Unmarshal(m, b)
+}
+func (m *ListStationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStationsRequest.Marshal(b, m, deterministic)
+}
+func (dst *ListStationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStationsRequest.Merge(dst, src)
+}
+func (m *ListStationsRequest) XXX_Size() int {
+	return xxx_messageInfo_ListStationsRequest.Size(m)
+}
+func (m *ListStationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStationsRequest proto.InternalMessageInfo
+
+func (m *ListStationsRequest) GetQ() string {
+	if m != nil {
+		return m.Q
+	}
+	return ""
+}
+
+func (m *ListStationsRequest) GetClosed() *wrappers.BoolValue {
+	if m != nil {
+		return m.Closed
+	}
+	return nil
+}
+
+func (m *ListStationsRequest) GetInService() *wrappers.BoolValue {
+	if m != nil {
+		return m.InService
+	}
+	return nil
+}
+
+func (m *ListStationsRequest) GetMinBikes() int32 {
+	if m != nil {
+		return m.MinBikes
+	}
+	return 0
+}
+
+func (m *ListStationsRequest) GetMinLocks() int32 {
+	if m != nil {
+		return m.MinLocks
+	}
+	return 0
+}
+
+func (m *ListStationsRequest) GetSort() string {
+	if m != nil {
+		return m.Sort
+	}
+	return ""
+}
+
+func (m *ListStationsRequest) GetOrigin() *Coord {
+	if m != nil {
+		return m.Origin
+	}
+	return nil
+}
+
+func (m *ListStationsRequest) GetOffset() int32 {
+	if m != nil {
+		return m.Offset
+	}
+	return 0
+}
+
+func (m *ListStationsRequest) GetLimit() int32 {
+	if m != nil {
+		return m.Limit
+	}
+	return 0
+}
+
+type ListStationsResponse struct {
+	Stations             []*Station `protobuf:"bytes,1,rep,name=stations,proto3" json:"stations,omitempty"`
+	Total                int32      `protobuf:"varint,2,opt,name=total,proto3" json:"total,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}   `json:"-"`
+	XXX_unrecognized     []byte     `json:"-"`
+	XXX_sizecache        int32      `json:"-"`
+}
+
+func (m *ListStationsResponse) Reset()         { *m = ListStationsResponse{} }
+func (m *ListStationsResponse) String() string { return proto.CompactTextString(m) }
+func (*ListStationsResponse) ProtoMessage()    {}
+func (*ListStationsResponse) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{5}
+}
+func (m *ListStationsResponse) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_ListStationsResponse.Unmarshal(m, b)
+}
+func (m *ListStationsResponse) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_ListStationsResponse.Marshal(b, m, deterministic)
+}
+func (dst *ListStationsResponse) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_ListStationsResponse.Merge(dst, src)
+}
+func (m *ListStationsResponse) XXX_Size() int {
+	return xxx_messageInfo_ListStationsResponse.Size(m)
+}
+func (m *ListStationsResponse) XXX_DiscardUnknown() {
+	xxx_messageInfo_ListStationsResponse.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_ListStationsResponse proto.InternalMessageInfo
+
+func (m *ListStationsResponse) GetStations() []*Station {
+	if m != nil {
+		return m.Stations
+	}
+	return nil
+}
+
+func (m *ListStationsResponse) GetTotal() int32 {
+	if m != nil {
+		return m.Total
+	}
+	return 0
+}
+
+type WatchStationsRequest struct {
+	// Resumes the stream after the event, if it is still kept
+	LastEventId string `protobuf:"bytes,1,opt,name=last_event_id,json=lastEventId,proto3" json:"last_event_id,omitempty"`
+	// Only the changes to these stations are sent, when provided
+	Ids                  []int32  `protobuf:"varint,2,rep,packed,name=ids,proto3" json:"ids,omitempty"`
+	XXX_NoUnkeyedLiteral struct{} `json:"-"`
+	XXX_unrecognized     []byte   `json:"-"`
+	XXX_sizecache        int32    `json:"-"`
+}
+
+func (m *WatchStationsRequest) Reset()         { *m = WatchStationsRequest{} }
+func (m *WatchStationsRequest) String() string { return proto.CompactTextString(m) }
+func (*WatchStationsRequest) ProtoMessage()    {}
+func (*WatchStationsRequest) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{6}
+}
+func (m *WatchStationsRequest) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_WatchStationsRequest.Unmarshal(m, b)
+}
+func (m *WatchStationsRequest) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_WatchStationsRequest.Marshal(b, m, deterministic)
+}
+func (dst *WatchStationsRequest) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_WatchStationsRequest.Merge(dst, src)
+}
+func (m *WatchStationsRequest) XXX_Size() int {
+	return xxx_messageInfo_WatchStationsRequest.Size(m)
+}
+func (m *WatchStationsRequest) XXX_DiscardUnknown() {
+	xxx_messageInfo_WatchStationsRequest.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_WatchStationsRequest proto.InternalMessageInfo
+
+func (m *WatchStationsRequest) GetLastEventId() string {
+	if m != nil {
+		return m.LastEventId
+	}
+	return ""
+}
+
+func (m *WatchStationsRequest) GetIds() []int32 {
+	if m != nil {
+		return m.Ids
+	}
+	return nil
+}
+
+type StationEvent struct {
+	Id string `protobuf:"bytes,1,opt,name=id,proto3" json:"id,omitempty"`
+	// One of snapshot, availability, closed or reopened
+	Type                 string               `protobuf:"bytes,2,opt,name=type,proto3" json:"type,omitempty"`
+	At                   *timestamp.Timestamp `protobuf:"bytes,3,opt,name=at,proto3" json:"at,omitempty"`
+	Stations             []*Station           `protobuf:"bytes,4,rep,name=stations,proto3" json:"stations,omitempty"`
+	XXX_NoUnkeyedLiteral struct{}             `json:"-"`
+	XXX_unrecognized     []byte               `json:"-"`
+	XXX_sizecache        int32                `json:"-"`
+}
+
+func (m *StationEvent) Reset()         { *m = StationEvent{} }
+func (m *StationEvent) String() string { return proto.CompactTextString(m) }
+func (*StationEvent) ProtoMessage()    {}
+func (*StationEvent) Descriptor() ([]byte, []int) {
+	return fileDescriptor_station_60db0f933d730ffb, []int{7}
+}
+func (m *StationEvent) XXX_Unmarshal(b []byte) error {
+	return xxx_messageInfo_StationEvent.Unmarshal(m, b)
+}
+func (m *StationEvent) XXX_Marshal(b []byte, deterministic bool) ([]byte, error) {
+	return xxx_messageInfo_StationEvent.Marshal(b, m, deterministic)
+}
+func (dst *StationEvent) XXX_Merge(src proto.Message) {
+	xxx_messageInfo_StationEvent.Merge(dst, src)
+}
+func (m *StationEvent) XXX_Size() int {
+	return xxx_messageInfo_StationEvent.Size(m)
+}
+func (m *StationEvent) XXX_DiscardUnknown() {
+	xxx_messageInfo_StationEvent.DiscardUnknown(m)
+}
+
+var xxx_messageInfo_StationEvent proto.InternalMessageInfo
+
+func (m *StationEvent) GetId() string {
+	if m != nil {
+		return m.Id
+	}
+	return ""
+}
+
+func (m *StationEvent) GetType() string {
+	if m != nil {
+		return m.Type
+	}
+	return ""
+}
+
+func (m *StationEvent) GetAt() *timestamp.Timestamp {
+	if m != nil {
+		return m.At
+	}
+	return nil
+}
+
+func (m *StationEvent) GetStations() []*Station {
+	if m != nil {
+		return m.Stations
+	}
+	return nil
+}
+
+func init() {
+	proto.RegisterType((*Coord)(nil), "pedal.v1.Coord")
+	proto.RegisterType((*Availability)(nil), "pedal.v1.Availability")
+	proto.RegisterType((*Station)(nil), "pedal.v1.Station")
+	proto.RegisterType((*GetStationRequest)(nil), "pedal.v1.GetStationRequest")
+	proto.RegisterType((*ListStationsRequest)(nil), "pedal.v1.ListStationsRequest")
+	proto.RegisterType((*ListStationsResponse)(nil), "pedal.v1.ListStationsResponse")
+	proto.RegisterType((*WatchStationsRequest)(nil), "pedal.v1.WatchStationsRequest")
+	proto.RegisterType((*StationEvent)(nil), "pedal.v1.StationEvent")
+}
+
+// Reference imports to suppress errors if they are not otherwise used.
+var _ context.Context
+var _ grpc.ClientConn
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+const _ = grpc.SupportPackageIsVersion4
+
+// StationServiceClient is the client API for StationService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://godoc.org/google.golang.org/grpc#ClientConn.NewStream.
+type StationServiceClient interface {
+	GetStation(ctx context.Context, in *GetStationRequest, opts ...grpc.CallOption) (*Station, error)
+	ListStations(ctx context.Context, in *ListStationsRequest, opts ...grpc.CallOption) (*ListStationsResponse, error)
+	// WatchStations starts with a snapshot of the stations,
+	// followed by their changes as they happen
+	WatchStations(ctx context.Context, in *WatchStationsRequest, opts ...grpc.CallOption) (StationService_WatchStationsClient, error)
+}
+
+type stationServiceClient struct {
+	cc *grpc.ClientConn
+}
+
+func NewStationServiceClient(cc *grpc.ClientConn) StationServiceClient {
+	return &stationServiceClient{cc}
+}
+
+func (c *stationServiceClient) GetStation(ctx context.Context, in *GetStationRequest, opts ...grpc.CallOption) (*Station, error) {
+	out := new(Station)
+	err := c.cc.Invoke(ctx, "/pedal.v1.StationService/GetStation", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stationServiceClient) ListStations(ctx context.Context, in *ListStationsRequest, opts ...grpc.CallOption) (*ListStationsResponse, error) {
+	out := new(ListStationsResponse)
+	err := c.cc.Invoke(ctx, "/pedal.v1.StationService/ListStations", in, out, opts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *stationServiceClient) WatchStations(ctx context.Context, in *WatchStationsRequest, opts ...grpc.CallOption) (StationService_WatchStationsClient, error) {
+	stream, err := c.cc.NewStream(ctx, &_StationService_serviceDesc.Streams[0], "/pedal.v1.StationService/WatchStations", opts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &stationServiceWatchStationsClient{stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+type StationService_WatchStationsClient interface {
+	Recv() (*StationEvent, error)
+	grpc.ClientStream
+}
+
+type stationServiceWatchStationsClient struct {
+	grpc.ClientStream
+}
+
+func (x *stationServiceWatchStationsClient) Recv() (*StationEvent, error) {
+	m := new(StationEvent)
+	if err := x.ClientStream.RecvMsg(m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// StationServiceServer is the server API for StationService service.
+type StationServiceServer interface {
+	GetStation(context.Context, *GetStationRequest) (*Station, error)
+	ListStations(context.Context, *ListStationsRequest) (*ListStationsResponse, error)
+	// WatchStations starts with a snapshot of the stations,
+	// followed by their changes as they happen
+	WatchStations(*WatchStationsRequest, StationService_WatchStationsServer) error
+}
+
+func RegisterStationServiceServer(s *grpc.Server, srv StationServiceServer) {
+	s.RegisterService(&_StationService_serviceDesc, srv)
+}
+
+func _StationService_GetStation_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetStationRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StationServiceServer).GetStation(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pedal.v1.StationService/GetStation",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StationServiceServer).GetStation(ctx, req.(*GetStationRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StationService_ListStations_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(ListStationsRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(StationServiceServer).ListStations(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: "/pedal.v1.StationService/ListStations",
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(StationServiceServer).ListStations(ctx, req.(*ListStationsRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _StationService_WatchStations_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchStationsRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(StationServiceServer).WatchStations(m, &stationServiceWatchStationsServer{stream})
+}
+
+type StationService_WatchStationsServer interface {
+	Send(*StationEvent) error
+	grpc.ServerStream
+}
+
+type stationServiceWatchStationsServer struct {
+	grpc.ServerStream
+}
+
+func (x *stationServiceWatchStationsServer) Send(m *StationEvent) error {
+	return x.ServerStream.SendMsg(m)
+}
+
+var _StationService_serviceDesc = grpc.ServiceDesc{
+	ServiceName: "pedal.v1.StationService",
+	HandlerType: (*StationServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetStation",
+			Handler:    _StationService_GetStation_Handler,
+		},
+		{
+			MethodName: "ListStations",
+			Handler:    _StationService_ListStations_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchStations",
+			Handler:       _StationService_WatchStations_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "pkg/api/pb/station.proto",
+}
+
+func init() { proto.RegisterFile("pkg/api/pb/station.proto", fileDescriptor_station_60db0f933d730ffb) }
+
+var fileDescriptor_station_60db0f933d730ffb = []byte{
+	// 687 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x53, 0x5f, 0x6b, 0xdb, 0x3a,
+	0x14, 0xc7, 0x4e, 0x9c, 0xc6, 0xa7, 0x69, 0x7b, 0xab, 0x5b, 0x8a, 0x71, 0x6f, 0x7b, 0x83, 0x2f,
+	0xdc, 0x85, 0xc1, 0x9c, 0xad, 0x7b, 0x5a, 0xd9, 0x4b, 0x3b, 0xc6, 0x28, 0x74, 0x0c, 0xd4, 0xb1,
+	0xc1, 0xf6, 0x60, 0xe4, 0x58, 0xc9, 0x44, 0x15, 0xcb, 0xb5, 0xe4, 0x8c, 0x7e, 0x88, 0x7d, 0x9e,
+	0x7d, 0xa5, 0xbd, 0xef, 0x0b, 0x0c, 0x49, 0x76, 0xe2, 0xf4, 0x0f, 0x7d, 0xd3, 0x39, 0xe7, 0xa7,
+	0xa3, 0xa3, 0xdf, 0xef, 0x77, 0x20, 0x28, 0xae, 0x66, 0x63, 0x52, 0xb0, 0x71, 0x91, 0x8e, 0xa5,
+	0x22, 0x8a, 0x89, 0x3c, 0x2e, 0x4a, 0xa1, 0x04, 0xea, 0x17, 0x34, 0x23, 0x3c, 0x5e, 0xbc, 0x08,
+	0xff, 0x9d, 0x09, 0x31, 0xe3, 0x74, 0x6c, 0xf2, 0x69, 0x35, 0x1d, 0x2b, 0x36, 0xa7, 0x52, 0x91,
+	0x79, 0x61, 0xa1, 0xe1, 0xd1, 0x6d, 0xc0, 0xf7, 0x92, 0x14, 0x05, 0x2d, 0xa5, 0xad, 0x47, 0xa7,
+	0xe0, 0xbd, 0x11, 0xa2, 0xcc, 0x50, 0x08, 0x7d, 0x4e, 0x14, 0x53, 0x55, 0x46, 0x03, 0x67, 0xe8,
+	0x8c, 0x1c, 0xbc, 0x8c, 0xd1, 0x3f, 0xe0, 0x73, 0x91, 0xcf, 0x6c, 0xd1, 0x35, 0xc5, 0x55, 0x22,
+	0x3a, 0x81, 0xc1, 0xe9, 0x82, 0x30, 0x4e, 0x52, 0xc6, 0x99, 0xba, 0x41, 0x7b, 0xe0, 0xa5, 0xec,
+	0x8a, 0x4a, 0xd3, 0xc6, 0xc3, 0x36, 0xd0, 0x59, 0x2e, 0x26, 0x57, 0xd2, 0xdc, 0xf7, 0xb0, 0x0d,
+	0xa2, 0xdf, 0x2e, 0x6c, 0x5c, 0xda, 0xbf, 0xa1, 0x6d, 0x70, 0x59, 0x56, 0x5f, 0x72, 0x59, 0x86,
+	0x0e, 0x01, 0x58, 0x9e, 0x48, 0x5a, 0x2e, 0xd8, 0xc4, 0x3e, 0xdb, 0xc7, 0x3e, 0xcb, 0x2f, 0x6d,
+	0x42, 0x37, 0x54, 0x4c, 0x71, 0x1a, 0x74, 0x86, 0xce, 0xc8, 0xc7, 0x36, 0xd0, 0xdf, 0x90, 0x55,
+	0x6a, 0x0b, 0x5d, 0x53, 0x58, 0xc6, 0xe8, 0x7f, 0xd8, 0xc9, 0xab, 0x79, 0x4a, 0xcb, 0x44, 0x4c,
+	0x13, 0x3b, 0x8c, 0x67, 0x5e, 0xdb, 0xb2, 0xe9, 0x0f, 0xd3, 0x0b, 0x9d, 0x44, 0x4f, 0xa0, 0x37,
+	0xa1, 0xb9, 0xa2, 0x65, 0xd0, 0x1b, 0x3a, 0xa3, 0xcd, 0xe3, 0x9d, 0xb8, 0xe1, 0x3b, 0x36, 0x5c,
+	0xe1, 0xba, 0xac, 0x81, 0xa9, 0xa8, 0xf2, 0x4c, 0x06, 0x1b, 0xc3, 0xce, 0xbd, 0x40, 0x5b, 0x46,
+	0x27, 0x30, 0x20, 0x2d, 0x8a, 0x82, 0xbe, 0xe9, 0xbb, 0xbf, 0x82, 0xb7, 0x09, 0xc4, 0x6b, 0x58,
+	0xb4, 0x0f, 0xbd, 0x09, 0x17, 0x92, 0x66, 0x81, 0x6f, 0x28, 0xa8, 0x23, 0xf4, 0x0a, 0xa0, 0x2a,
+	0x32, 0xa2, 0x68, 0x96, 0x10, 0x15, 0x80, 0xe9, 0x18, 0xc6, 0x56, 0xee, 0xb8, 0x91, 0x3b, 0xfe,
+	0xd8, 0xf8, 0x01, 0xfb, 0x35, 0xfa, 0x54, 0x45, 0xff, 0xc1, 0xee, 0x3b, 0xaa, 0x6a, 0xde, 0x31,
+	0xbd, 0xae, 0xa8, 0x54, 0xb7, 0xe9, 0x8f, 0x7e, 0xba, 0xf0, 0xf7, 0x05, 0x93, 0x0d, 0x4c, 0x36,
+	0xb8, 0x01, 0x38, 0xd7, 0x06, 0xe6, 0x63, 0xe7, 0x1a, 0x1d, 0x2f, 0xa7, 0x73, 0x1f, 0x98, 0xe0,
+	0x4c, 0x08, 0xfe, 0x89, 0xf0, 0x8a, 0xb6, 0x27, 0x6f, 0x09, 0xdb, 0x79, 0xf4, 0x5e, 0x4b, 0xf4,
+	0x03, 0xf0, 0xe7, 0x2c, 0x4f, 0xac, 0xbf, 0xba, 0x66, 0xd6, 0xfe, 0x9c, 0xe5, 0x67, 0x3a, 0x6e,
+	0x8a, 0x6d, 0x65, 0x75, 0xd1, 0x8a, 0x8a, 0xa0, 0x2b, 0x45, 0xa9, 0x8c, 0xa4, 0x3e, 0x36, 0x67,
+	0xad, 0x9f, 0x28, 0xd9, 0x8c, 0xe5, 0xc1, 0xc6, 0x03, 0x42, 0xdb, 0xb2, 0xd6, 0x40, 0x4c, 0xa7,
+	0x92, 0x2a, 0xa3, 0x9c, 0x87, 0xeb, 0xc8, 0x98, 0x9a, 0xcd, 0x99, 0x0a, 0xfc, 0xda, 0xd4, 0x3a,
+	0x88, 0xbe, 0xc2, 0xde, 0x3a, 0x71, 0xb2, 0x10, 0xb9, 0xa4, 0xe8, 0x19, 0xf4, 0xeb, 0x3d, 0xd6,
+	0xbb, 0xa1, 0x0d, 0xb3, 0xbb, 0x7a, 0xb0, 0x51, 0x63, 0x09, 0x31, 0x06, 0x17, 0x8a, 0xf0, 0x66,
+	0x63, 0x4c, 0x10, 0x5d, 0xc0, 0xde, 0x67, 0xa2, 0x26, 0xdf, 0x6e, 0xcb, 0x12, 0xc1, 0x16, 0x27,
+	0x52, 0x25, 0x74, 0x41, 0x73, 0x95, 0xd4, 0x4a, 0xfa, 0x78, 0x53, 0x27, 0xdf, 0xea, 0xdc, 0x79,
+	0x86, 0xfe, 0x82, 0x0e, 0xcb, 0xf4, 0x06, 0x76, 0x46, 0x1e, 0xd6, 0xc7, 0xe8, 0x87, 0x03, 0x83,
+	0xba, 0x93, 0x01, 0xb5, 0x5c, 0xe0, 0x9b, 0x25, 0x44, 0xd0, 0x55, 0x37, 0x85, 0x5d, 0x3f, 0x1f,
+	0x9b, 0x33, 0x7a, 0x0a, 0x2e, 0x51, 0x41, 0xe7, 0x51, 0xc7, 0xb9, 0x44, 0xad, 0xfd, 0xb9, 0xfb,
+	0xe8, 0x9f, 0x8f, 0x7f, 0x39, 0xb0, 0x5d, 0x67, 0x1b, 0xc9, 0x5f, 0x03, 0xac, 0xcc, 0x8a, 0x0e,
+	0x56, 0xb7, 0xef, 0x58, 0x38, 0xbc, 0xdb, 0x1a, 0xbd, 0x87, 0x41, 0x5b, 0x0b, 0x74, 0xb8, 0x82,
+	0xdc, 0x63, 0xee, 0xf0, 0xe8, 0xa1, 0x72, 0x2d, 0xe1, 0x39, 0x6c, 0xad, 0xb1, 0x8f, 0x5a, 0x17,
+	0xee, 0x93, 0x25, 0xdc, 0xbf, 0x33, 0x92, 0xe1, 0xf9, 0xb9, 0x73, 0xd6, 0xfd, 0xe2, 0x16, 0x69,
+	0xda, 0x33, 0xbc, 0xbd, 0xfc, 0x33, 0x00, 0x4e, 0xaa, 0xb0, 0x65, 0xed, 0x05, 0x00, 0x00,
+}
@@ -0,0 +1,42 @@
+package api
+
+import "context"
+
+// StationBalance describes how full a station is and how
+// many bikes it should have once rebalanced
+type StationBalance struct {
+	Station   Station `json:"station"`
+	Level     string  `json:"level"`
+	Bikes     int     `json:"bikes"`
+	Locks     int     `json:"locks"`
+	Projected float64 `json:"projected"`
+	Target    int     `json:"target"`
+}
+
+// Transfer represents moving bikes from one
+// station to another
+type Transfer struct {
+	From     Station `json:"from"`
+	To       Station `json:"to"`
+	Count    int     `json:"count"`
+	Distance float64 `json:"distance"`
+}
+
+// Rebalance contains the balance of the stations
+// and the transfers that would even them out
+type Rebalance struct {
+	Stations  []StationBalance `json:"stations"`
+	Transfers []Transfer       `json:"transfers"`
+}
+
+// RebalanceService defines what methods a rebalance
+// service implementation must implement
+type RebalanceService interface {
+	Plan(ctx context.Context, capacity int) (*Rebalance, error)
+}
+
+// RebalanceStore defines what methods a rebalance
+// storage implementation must implement
+type RebalanceStore interface {
+	Plan(ctx context.Context, capacity int) (*Rebalance, error)
+}
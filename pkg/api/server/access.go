@@ -0,0 +1,235 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// Note: the API keys are optional, a request with a key is limited by
+// the limit of the key, while the anonymous requests are limited by
+// the IP they are made from. An unknown key is rejected, rather than
+// treated as anonymous, so a mistyped key is noticed.
+
+// APIKeyHeader is the header an API key is provided in
+const APIKeyHeader = "X-API-Key"
+
+// APIKeyParam is the query param an API key can be provided
+// in, when the client cannot set the header
+const APIKeyParam = "api_key"
+
+// APIKey identifies a client of the API
+type APIKey struct {
+	Name  string          `json:"name"`
+	Key   string          `json:"key"`
+	Limit ratelimit.Limit `json:"limit"`
+}
+
+// AccessConfig contains the API keys and the limits of the requests
+type AccessConfig struct {
+	Keys []APIKey `json:"keys"`
+	// Anonymous limits the requests without a key, by IP
+	Anonymous ratelimit.Limit `json:"anonymous"`
+	// RequireKey rejects the requests without a key
+	RequireKey bool `json:"require_key"`
+	// TrustProxy takes the IP of the client from the last entry
+	// of the X-Forwarded-For header, set by the proxy in front
+	TrustProxy bool `json:"trust_proxy"`
+}
+
+// LoadAccessConfig reads a JSON config from the provided path
+func LoadAccessConfig(path string) (*AccessConfig, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	config := &AccessConfig{}
+	err = json.Unmarshal(data, config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse access config %s: %s", path, err)
+	}
+	err = config.Validate()
+	if err != nil {
+		return nil, fmt.Errorf("invalid access config %s: %s", path, err)
+	}
+	return config, nil
+}
+
+// Validate ensures that every key is unique and named,
+// and that none of the limits are negative
+func (c *AccessConfig) Validate() error {
+	if c.Anonymous.PerMinute < 0 || c.Anonymous.Burst < 0 {
+		return fmt.Errorf("anonymous: limit must not be negative")
+	}
+	keys := map[string]bool{}
+	for i, key := range c.Keys {
+		if len(key.Name) == 0 {
+			return fmt.Errorf("keys[%d]: name is required", i)
+		}
+		if len(key.Key) == 0 {
+			return fmt.Errorf("%s: key is required", key.Name)
+		}
+		if keys[key.Key] {
+			return fmt.Errorf("%s: key is already used", key.Name)
+		}
+		if key.Limit.PerMinute < 0 || key.Limit.Burst < 0 {
+			return fmt.Errorf("%s: limit must not be negative", key.Name)
+		}
+		keys[key.Key] = true
+	}
+	return nil
+}
+
+// accessControl authenticates the API keys and
+// limits the requests of every caller
+type accessControl struct {
+	config  *AccessConfig
+	keys    map[string]APIKey
+	limiter *ratelimit.Limiter
+}
+
+func newAccessControl(config *AccessConfig) *accessControl {
+	keys := map[string]APIKey{}
+	for _, key := range config.Keys {
+		keys[key.Key] = key
+	}
+	return &accessControl{
+		config:  config,
+		keys:    keys,
+		limiter: ratelimit.New(),
+	}
+}
+
+// restricted reports if the responses differ by key, or are only
+// for those with a key, so shared caches must not store them
+func (a *accessControl) restricted() bool {
+	return len(a.config.Keys) > 0 || a.config.RequireKey
+}
+
+// allow authenticates the provided key, if any, and counts the
+// request against its limit, or the limit of the IP without a key
+func (a *accessControl) allow(provided, ip string) error {
+	var caller string
+	var limit ratelimit.Limit
+	if len(provided) > 0 {
+		key, hasKey := a.keys[provided]
+		if !hasKey {
+			return errors.New(fmt.Errorf("unknown API key"), "failed to authenticate", errors.Unauthorized)
+		}
+		caller, limit = "key:"+key.Key, key.Limit
+	} else {
+		if a.config.RequireKey {
+			return errors.New(fmt.Errorf("missing API key"), "failed to authenticate", errors.Unauthorized)
+		}
+		caller, limit = "ip:"+ip, a.config.Anonymous
+	}
+
+	allowed, wait := a.limiter.Allow(caller, limit)
+	if !allowed {
+		err := errors.New(fmt.Errorf("limit: %d per minute", limit.PerMinute), "too many requests", errors.TooManyRequests)
+		return errors.WithRetryAfter(err, wait)
+	}
+	return nil
+}
+
+// RateLimit creates a middleware for the router, that authenticates
+// the API keys and rejects the requests that exceed their limit
+func RateLimit(config *AccessConfig) func(http.Handler) http.Handler {
+	access := newAccessControl(config)
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if access.restricted() {
+				r = r.WithContext(context.WithValue(r.Context(), contextKeyRestricted, true))
+			}
+			err := access.allow(apiKey(r), clientIP(r, config.TrustProxy))
+			if err != nil {
+				encodeError(r.Context(), err, w)
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// RateLimitGRPC creates the interceptors of the gRPC server, that
+// authenticate the API keys and reject the calls that exceed their
+// limit, the key is provided in the metadata of the call and the
+// anonymous calls are limited by the IP of the peer
+func RateLimitGRPC(config *AccessConfig) []grpc.ServerOption {
+	access := newAccessControl(config)
+	allow := func(ctx context.Context) error {
+		var key, ip string
+		md, _ := metadata.FromIncomingContext(ctx)
+		if keys := md.Get(APIKeyHeader); len(keys) > 0 {
+			key = keys[0]
+		}
+		if p, ok := peer.FromContext(ctx); ok {
+			ip = p.Addr.String()
+			if host, _, err := net.SplitHostPort(ip); err == nil {
+				ip = host
+			}
+		}
+		err := access.allow(key, ip)
+		if err != nil {
+			return encodeGRPCError(err)
+		}
+		return nil
+	}
+
+	return []grpc.ServerOption{
+		grpc.UnaryInterceptor(func(ctx context.Context, req interface{}, _ *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+			err := allow(ctx)
+			if err != nil {
+				return nil, err
+			}
+			return handler(ctx, req)
+		}),
+		grpc.StreamInterceptor(func(srv interface{}, stream grpc.ServerStream, _ *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+			err := allow(stream.Context())
+			if err != nil {
+				return err
+			}
+			return handler(srv, stream)
+		}),
+	}
+}
+
+// isRestricted reports if the request passed the
+// access control of the API keys
+func isRestricted(ctx context.Context) bool {
+	restricted, _ := ctx.Value(contextKeyRestricted).(bool)
+	return restricted
+}
+
+// apiKey returns the API key of the request, if any, the
+// header takes precedence over the query param
+func apiKey(r *http.Request) string {
+	if key := r.Header.Get(APIKeyHeader); len(key) > 0 {
+		return key
+	}
+	return r.URL.Query().Get(APIKeyParam)
+}
+
+// clientIP returns the IP the request was made from
+func clientIP(r *http.Request, trustProxy bool) string {
+	if forwarded := r.Header["X-Forwarded-For"]; trustProxy && len(forwarded) > 0 {
+		entries := strings.Split(forwarded[len(forwarded)-1], ",")
+		return strings.TrimSpace(entries[len(entries)-1])
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
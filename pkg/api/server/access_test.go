@@ -0,0 +1,202 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pkg/api/mock"
+	"github.com/paulbes/go-pedal/pkg/ratelimit"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRateLimit(t *testing.T) {
+	config := &AccessConfig{
+		Keys: []APIKey{
+			{Name: "acme", Key: "s3cret", Limit: ratelimit.Limit{PerMinute: 2}},
+			{Name: "internal", Key: "unlimited"},
+		},
+		Anonymous: ratelimit.Limit{PerMinute: 1},
+	}
+
+	type request struct {
+		Path         string
+		Header       http.Header
+		RemoteAddr   string
+		ExpectCode   int
+		ExpectRetry  string
+		ExpectErrors string
+	}
+
+	testCases := []struct {
+		Name     string
+		Config   *AccessConfig
+		Requests []request
+	}{
+		{
+			Name:   "Anonymous requests are limited by IP",
+			Config: config,
+			Requests: []request{
+				{Path: "/v1/stations/1", RemoteAddr: "10.0.0.1:1234", ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1", RemoteAddr: "10.0.0.1:4321", ExpectCode: http.StatusTooManyRequests, ExpectRetry: "60", ExpectErrors: "toomanyrequests: too many requests: limit: 1 per minute"},
+				{Path: "/v1/stations/1", RemoteAddr: "10.0.0.2:1234", ExpectCode: http.StatusOK},
+			},
+		},
+		{
+			Name:   "Keys have their own limit",
+			Config: config,
+			Requests: []request{
+				{Path: "/v1/stations/1", Header: http.Header{APIKeyHeader: []string{"s3cret"}}, ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1?api_key=s3cret", ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1", Header: http.Header{APIKeyHeader: []string{"s3cret"}}, ExpectCode: http.StatusTooManyRequests, ExpectRetry: "30", ExpectErrors: "toomanyrequests: too many requests: limit: 2 per minute"},
+				{Path: "/v1/stations/1", ExpectCode: http.StatusOK},
+			},
+		},
+		{
+			Name:   "Keys without a limit",
+			Config: config,
+			Requests: []request{
+				{Path: "/v1/stations/1", Header: http.Header{APIKeyHeader: []string{"unlimited"}}, ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1", Header: http.Header{APIKeyHeader: []string{"unlimited"}}, ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1", Header: http.Header{APIKeyHeader: []string{"unlimited"}}, ExpectCode: http.StatusOK},
+			},
+		},
+		{
+			Name:   "Unknown key",
+			Config: config,
+			Requests: []request{
+				{Path: "/v1/stations/1?api_key=guess", ExpectCode: http.StatusUnauthorized, ExpectErrors: "unauthorized: failed to authenticate: unknown API key"},
+			},
+		},
+		{
+			Name:   "Key is required",
+			Config: &AccessConfig{Keys: config.Keys, RequireKey: true},
+			Requests: []request{
+				{Path: "/v1/stations/1", ExpectCode: http.StatusUnauthorized, ExpectErrors: "unauthorized: failed to authenticate: missing API key"},
+				{Path: "/v1/stations/1", Header: http.Header{APIKeyHeader: []string{"s3cret"}}, ExpectCode: http.StatusOK},
+			},
+		},
+		{
+			Name:   "Client behind a trusted proxy",
+			Config: &AccessConfig{Anonymous: config.Anonymous, TrustProxy: true},
+			Requests: []request{
+				{Path: "/v1/stations/1", Header: http.Header{"X-Forwarded-For": []string{"1.1.1.1, 10.0.0.1"}}, ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1", Header: http.Header{"X-Forwarded-For": []string{"2.2.2.2, 10.0.0.1"}}, ExpectCode: http.StatusTooManyRequests, ExpectRetry: "60", ExpectErrors: "toomanyrequests: too many requests: limit: 1 per minute"},
+				{Path: "/v1/stations/1", Header: http.Header{"X-Forwarded-For": []string{"10.0.0.2"}}, ExpectCode: http.StatusOK},
+			},
+		},
+		{
+			Name:   "Proxy is not trusted",
+			Config: &AccessConfig{Anonymous: config.Anonymous},
+			Requests: []request{
+				{Path: "/v1/stations/1", Header: http.Header{"X-Forwarded-For": []string{"1.1.1.1"}}, ExpectCode: http.StatusOK},
+				{Path: "/v1/stations/1", Header: http.Header{"X-Forwarded-For": []string{"2.2.2.2"}}, ExpectCode: http.StatusTooManyRequests, ExpectRetry: "60", ExpectErrors: "toomanyrequests: too many requests: limit: 1 per minute"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		endpoints := MakeEndpoints(Services{
+			Station: NewStationService(mock.NewStationStore(mock.NewStation(), nil)),
+		})
+		router := AttachRoutes(MakeHandlers(endpoints), log.NewNopLogger(), RateLimit(tc.Config))
+
+		for i, r := range tc.Requests {
+			name := fmt.Sprintf("%s: request %d", tc.Name, i)
+			recorder := httptest.NewRecorder()
+			req := httptest.NewRequest(http.MethodGet, r.Path, nil)
+			if len(r.RemoteAddr) > 0 {
+				req.RemoteAddr = r.RemoteAddr
+			}
+			for header, values := range r.Header {
+				for _, value := range values {
+					req.Header.Add(header, value)
+				}
+			}
+			router.ServeHTTP(recorder, req)
+
+			assert.Equal(t, r.ExpectCode, recorder.Code, name)
+			assert.Equal(t, r.ExpectRetry, recorder.Header().Get("Retry-After"), name)
+			if len(r.ExpectErrors) > 0 {
+				body := map[string]interface{}{}
+				assert.Nil(t, json.Unmarshal(recorder.Body.Bytes(), &body), name)
+				assert.Equal(t, r.ExpectErrors, body["message"], name)
+				assert.Equal(t, recorder.Header().Get("X-Request-ID"), body["request_id"], name)
+			}
+			assertMatchesSpec(t, router.(chi.Routes), req, recorder)
+		}
+	}
+}
+
+func TestLoadAccessConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "access")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	testCases := []struct {
+		Name      string
+		Content   string
+		Expect    interface{}
+		ExpectErr bool
+	}{
+		{
+			Name:    "Valid config",
+			Content: `{"keys": [{"name": "acme", "key": "s3cret", "limit": {"requests_per_minute": 600, "burst": 60}}], "anonymous": {"requests_per_minute": 60}, "trust_proxy": true}`,
+			Expect: &AccessConfig{
+				Keys:       []APIKey{{Name: "acme", Key: "s3cret", Limit: ratelimit.Limit{PerMinute: 600, Burst: 60}}},
+				Anonymous:  ratelimit.Limit{PerMinute: 60},
+				TrustProxy: true,
+			},
+		},
+		{
+			Name:      "Malformed",
+			Content:   `{"keys": `,
+			Expect:    "failed to parse access config %s: unexpected end of JSON input",
+			ExpectErr: true,
+		},
+		{
+			Name:      "Missing name",
+			Content:   `{"keys": [{"key": "s3cret"}]}`,
+			Expect:    "invalid access config %s: keys[0]: name is required",
+			ExpectErr: true,
+		},
+		{
+			Name:      "Missing key",
+			Content:   `{"keys": [{"name": "acme"}]}`,
+			Expect:    "invalid access config %s: acme: key is required",
+			ExpectErr: true,
+		},
+		{
+			Name:      "Duplicate key",
+			Content:   `{"keys": [{"name": "acme", "key": "s3cret"}, {"name": "other", "key": "s3cret"}]}`,
+			Expect:    "invalid access config %s: other: key is already used",
+			ExpectErr: true,
+		},
+		{
+			Name:      "Negative limit",
+			Content:   `{"anonymous": {"requests_per_minute": -1}}`,
+			Expect:    "invalid access config %s: anonymous: limit must not be negative",
+			ExpectErr: true,
+		},
+	}
+
+	for i, tc := range testCases {
+		path := filepath.Join(dir, fmt.Sprintf("access.%d.json", i))
+		assert.Nil(t, ioutil.WriteFile(path, []byte(tc.Content), 0600))
+
+		got, err := LoadAccessConfig(path)
+		if tc.ExpectErr {
+			assert.Equal(t, fmt.Sprintf(tc.Expect.(string), path), err.Error(), tc.Name)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.Expect, got, tc.Name)
+		}
+	}
+}
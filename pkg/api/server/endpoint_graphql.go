@@ -0,0 +1,336 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/graphql"
+)
+
+// Note: the GraphQL schema resolves the queries by calling the other
+// endpoints, with the same requests their decoders would have created,
+// so the services and the validation of the requests are shared.
+
+func makeGraphQLEndpoint(schema *graphql.Schema) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(graphql.Request)
+		return schema.Execute(ctx, req), nil
+	}
+}
+
+// timeScalar is an RFC3339 timestamp, the zero time is null
+var timeScalar = &graphql.Scalar{
+	Name: "Time",
+	ParseValue: func(value interface{}) (interface{}, error) {
+		s, ok := value.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected an RFC3339 Time, got %v", value)
+		}
+		return time.Parse(time.RFC3339, s)
+	},
+	Serialize: func(value interface{}) (interface{}, error) {
+		t, ok := value.(time.Time)
+		if !ok {
+			return nil, fmt.Errorf("expected a Time, got %T", value)
+		}
+		if t.IsZero() {
+			return nil, nil
+		}
+		return t.Format(time.RFC3339), nil
+	},
+}
+
+func nonNull(t graphql.Type) graphql.Type {
+	return &graphql.NonNull{OfType: t}
+}
+
+func listOf(t graphql.Type) graphql.Type {
+	return &graphql.NonNull{OfType: &graphql.List{OfType: &graphql.NonNull{OfType: t}}}
+}
+
+// valueField is a field that reads its value from the source
+func valueField(t graphql.Type, value func(source interface{}) interface{}) *graphql.Field {
+	return &graphql.Field{
+		Type: t,
+		Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+			return value(source), nil
+		},
+	}
+}
+
+// isNotFound reports if the error of an endpoint means that
+// what was requested does not exist
+func isNotFound(err error) bool {
+	coder, ok := err.(interface{ StatusCode() int })
+	return ok && coder.StatusCode() == http.StatusNotFound
+}
+
+// newGraphQLSchema creates the schema for stations and their stats
+func newGraphQLSchema(e Endpoints) *graphql.Schema {
+	coordType := &graphql.Object{
+		Name: "Coord",
+		Fields: graphql.Fields{
+			"latitude": valueField(nonNull(graphql.Float), func(source interface{}) interface{} {
+				return source.(api.Coord).Latitude
+			}),
+			"longitude": valueField(nonNull(graphql.Float), func(source interface{}) interface{} {
+				return source.(api.Coord).Longitude
+			}),
+		},
+	}
+
+	availabilityType := &graphql.Object{
+		Name: "Availability",
+		Fields: graphql.Fields{
+			"bikes": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(api.Availability).Bikes
+			}),
+			"locks": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(api.Availability).Locks
+			}),
+		},
+	}
+
+	stationType := &graphql.Object{
+		Name: "Station",
+		Fields: graphql.Fields{
+			"id": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(api.Station).ID
+			}),
+			"title": valueField(nonNull(graphql.String), func(source interface{}) interface{} {
+				return source.(api.Station).Title
+			}),
+			"subtitle": valueField(nonNull(graphql.String), func(source interface{}) interface{} {
+				return source.(api.Station).Subtitle
+			}),
+			"inService": valueField(nonNull(graphql.Boolean), func(source interface{}) interface{} {
+				return source.(api.Station).InService
+			}),
+			"numberOfLocks": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(api.Station).NumberOfLocks
+			}),
+			"center": valueField(nonNull(coordType), func(source interface{}) interface{} {
+				return source.(api.Station).Center
+			}),
+			"bounds": valueField(listOf(coordType), func(source interface{}) interface{} {
+				return source.(api.Station).Bounds
+			}),
+			"availability": valueField(nonNull(availabilityType), func(source interface{}) interface{} {
+				return source.(api.Station).Availability
+			}),
+			"closed": valueField(nonNull(graphql.Boolean), func(source interface{}) interface{} {
+				return source.(api.Station).Closed
+			}),
+			"updatedAt": valueField(timeScalar, func(source interface{}) interface{} {
+				return source.(api.Station).UpdatedAt
+			}),
+		},
+	}
+
+	stationListType := &graphql.Object{
+		Name: "StationList",
+		Fields: graphql.Fields{
+			"total": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(listStationResponse).Total
+			}),
+			"stations": valueField(listOf(stationType), func(source interface{}) interface{} {
+				return source.(listStationResponse).Stations
+			}),
+		},
+	}
+
+	nearbyStationType := &graphql.Object{
+		Name: "NearbyStation",
+		Fields: graphql.Fields{
+			"distance": valueField(nonNull(graphql.Float), func(source interface{}) interface{} {
+				return source.(api.NearbyStation).Distance
+			}),
+			"station": valueField(nonNull(stationType), func(source interface{}) interface{} {
+				return source.(api.NearbyStation).Station
+			}),
+		},
+	}
+
+	percentileType := &graphql.Object{
+		Name: "Percentile",
+		Fields: graphql.Fields{
+			"percentile": valueField(nonNull(graphql.Float), func(source interface{}) interface{} {
+				return source.(api.Percentile).Percentile
+			}),
+			"value": valueField(nonNull(graphql.Float), func(source interface{}) interface{} {
+				return source.(api.Percentile).Value
+			}),
+		},
+	}
+
+	statsType := &graphql.Object{
+		Name: "Stats",
+		Fields: graphql.Fields{
+			"updatedAt": valueField(timeScalar, func(source interface{}) interface{} {
+				return source.(*api.Stats).UpdatedAt
+			}),
+			"stations": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Stations
+			}),
+			"open": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Open
+			}),
+			"closed": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Closed
+			}),
+			"empty": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Empty
+			}),
+			"full": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Full
+			}),
+			"bikes": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Bikes
+			}),
+			"locks": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Locks
+			}),
+			"capacity": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).Capacity
+			}),
+			"bikesInUseEstimate": valueField(nonNull(graphql.Int), func(source interface{}) interface{} {
+				return source.(*api.Stats).BikesInUseEstimate
+			}),
+			"utilisation": valueField(listOf(percentileType), func(source interface{}) interface{} {
+				return source.(*api.Stats).Utilisation
+			}),
+		},
+	}
+
+	queryType := &graphql.Object{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"station": {
+				Type: stationType,
+				Args: graphql.Args{
+					"id": {Type: nonNull(graphql.Int)},
+				},
+				Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					res, err := e.GetStation(ctx, args["id"].(int))
+					if isNotFound(err) {
+						return nil, nil
+					}
+					return res, err
+				},
+			},
+			"stations": {
+				Type: nonNull(stationListType),
+				Args: graphql.Args{
+					"at":        {Type: timeScalar},
+					"q":         {Type: nonNull(graphql.String), Default: ""},
+					"closed":    {Type: graphql.Boolean},
+					"inService": {Type: graphql.Boolean},
+					"minBikes":  {Type: nonNull(graphql.Int), Default: 0},
+					"minLocks":  {Type: nonNull(graphql.Int), Default: 0},
+					"sort":      {Type: nonNull(graphql.String), Default: ""},
+					"latitude":  {Type: graphql.Float},
+					"longitude": {Type: graphql.Float},
+					"offset":    {Type: nonNull(graphql.Int), Default: 0},
+					"limit":     {Type: nonNull(graphql.Int), Default: 0},
+				},
+				Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					req, err := graphQLListStationRequest(args)
+					if err != nil {
+						return nil, err
+					}
+					return e.ListStation(ctx, req)
+				},
+			},
+			"nearby": {
+				Type: listOf(nearbyStationType),
+				Args: graphql.Args{
+					"latitude":  {Type: nonNull(graphql.Float)},
+					"longitude": {Type: nonNull(graphql.Float)},
+					"radius":    {Type: nonNull(graphql.Float), Default: 500.0},
+					"limit":     {Type: nonNull(graphql.Int), Default: 10},
+				},
+				Resolve: func(ctx context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+					req := nearbyStationRequest{
+						Coord: api.Coord{
+							Latitude:  args["latitude"].(float64),
+							Longitude: args["longitude"].(float64),
+						},
+						Radius: args["radius"].(float64),
+						Limit:  args["limit"].(int),
+					}
+					err := validateCoord(req.Coord, "latitude", "longitude")
+					if err != nil {
+						return nil, err
+					}
+					err = req.validate()
+					if err != nil {
+						return nil, err
+					}
+					return e.NearbyStation(ctx, req)
+				},
+			},
+			"stats": {
+				Type: nonNull(statsType),
+				Resolve: func(ctx context.Context, _ interface{}, _ map[string]interface{}) (interface{}, error) {
+					return e.GetStats(ctx, nil)
+				},
+			},
+		},
+	}
+
+	return &graphql.Schema{
+		Query: queryType,
+	}
+}
+
+// graphQLListStationRequest creates the request for the list
+// endpoint from the arguments of the stations query
+func graphQLListStationRequest(args map[string]interface{}) (listStationRequest, error) {
+	query := api.StationQuery{
+		Title:    args["q"].(string),
+		MinBikes: args["minBikes"].(int),
+		MinLocks: args["minLocks"].(int),
+		Offset:   args["offset"].(int),
+		Limit:    args["limit"].(int),
+	}
+	if closed, ok := args["closed"].(bool); ok {
+		query.Closed = &closed
+	}
+	if inService, ok := args["inService"].(bool); ok {
+		query.InService = &inService
+	}
+
+	err := validatePage(query.Offset, query.Limit)
+	if err != nil {
+		return listStationRequest{}, err
+	}
+
+	query.Sort, query.Descending, err = decodeSort(args["sort"].(string))
+	if err != nil {
+		return listStationRequest{}, err
+	}
+	if query.Sort == api.SortByDistance {
+		lat, hasLat := args["latitude"].(float64)
+		lon, hasLon := args["longitude"].(float64)
+		if !hasLat || !hasLon {
+			return listStationRequest{}, errors.New(fmt.Errorf("missing argument: latitude or longitude"), "sorting by distance requires an origin", errors.Unmarshal)
+		}
+		origin := api.Coord{Latitude: lat, Longitude: lon}
+		err = validateCoord(origin, "latitude", "longitude")
+		if err != nil {
+			return listStationRequest{}, err
+		}
+		query.Origin = &origin
+	}
+
+	at, _ := args["at"].(time.Time)
+	return listStationRequest{
+		At:    at,
+		Query: query,
+	}, nil
+}
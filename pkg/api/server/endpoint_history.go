@@ -0,0 +1,15 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+func makeStationHistoryEndpoint(s api.HistoryService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(stationHistoryRequest)
+		return s.Range(ctx, req.ID, req.From, req.To, req.Step)
+	}
+}
@@ -0,0 +1,15 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+func makePlanRebalanceEndpoint(s api.RebalanceService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(planRebalanceRequest)
+		return s.Plan(ctx, req.Capacity)
+	}
+}
@@ -18,8 +18,46 @@ func makeGetStationEndpoint(s api.StationService) endpoint.Endpoint {
 	}
 }
 
-func makeListStationEndpoint(s api.StationService) endpoint.Endpoint {
+func makeLocateStationEndpoint(s api.StationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(api.Coord)
+		return s.Locate(ctx, req)
+	}
+}
+
+func makeNearbyStationEndpoint(s api.StationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(nearbyStationRequest)
+		return s.Nearby(ctx, req.Coord, req.Radius, req.Limit)
+	}
+}
+
+func makeForecastStationEndpoint(s api.StationService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(forecastStationRequest)
+		return s.Forecast(ctx, req.ID, req.Horizon)
+	}
+}
+
+func makeListStationEndpoint(s api.StationService, h api.HistoryService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(listStationRequest)
+		var list api.StationList
+		var err error
+		if !req.At.IsZero() {
+			list, err = h.At(ctx, req.At, req.Query)
+		} else {
+			list, err = s.List(ctx, req.Query)
+		}
+		if err != nil {
+			return nil, err
+		}
+		return listStationResponse{list}, nil
+	}
+}
+
+func makeStationVersionEndpoint(s api.StationService) endpoint.Endpoint {
 	return func(ctx context.Context, _ interface{}) (interface{}, error) {
-		return s.List(ctx)
+		return s.Version(ctx)
 	}
 }
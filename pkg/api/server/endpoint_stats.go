@@ -0,0 +1,14 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+func makeGetStatsEndpoint(s api.StatsService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return s.Get(ctx)
+	}
+}
@@ -0,0 +1,35 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+func makeGetStatusEndpoint(s api.StatusService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		return s.Get(ctx)
+	}
+}
+
+func makeReadinessEndpoint(s api.StatusService) endpoint.Endpoint {
+	return func(ctx context.Context, _ interface{}) (interface{}, error) {
+		status, err := s.Get(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return readinessResponse{
+			Ready:  status.Ready,
+			Reason: status.Reason,
+		}, nil
+	}
+}
+
+// makeLivenessEndpoint is alive as long as it can answer,
+// it does not depend on any service
+func makeLivenessEndpoint() endpoint.Endpoint {
+	return func(_ context.Context, _ interface{}) (interface{}, error) {
+		return livenessResponse{Status: "ok"}, nil
+	}
+}
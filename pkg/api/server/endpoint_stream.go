@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// Note: the response of the stream endpoint is a subscription, it is
+// up to the transport to deliver the events for as long as it likes
+
+func makeStreamStationEndpoint(s api.StreamService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(streamStationRequest)
+		sub, err := s.Subscribe(ctx, req.LastEventID)
+		if err != nil {
+			return nil, err
+		}
+		return streamStationResponse{StationSubscription: sub, IDs: req.IDs}, nil
+	}
+}
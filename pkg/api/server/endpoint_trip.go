@@ -0,0 +1,15 @@
+package server
+
+import (
+	"context"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+func makeSuggestTripEndpoint(s api.TripService) endpoint.Endpoint {
+	return func(ctx context.Context, request interface{}) (interface{}, error) {
+		req := request.(suggestTripRequest)
+		return s.Suggest(ctx, req.From, req.To, req.Limit)
+	}
+}
@@ -4,8 +4,8 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi"
-	"github.com/go-chi/chi/middleware"
 	"github.com/go-kit/kit/endpoint"
+	"github.com/go-kit/kit/log"
 	kithttp "github.com/go-kit/kit/transport/http"
 	"github.com/paulbes/go-pedal/pkg/api"
 )
@@ -15,59 +15,198 @@ import (
 // can be reused by other implementations, such as
 // GraphQL, etc.
 type Endpoints struct {
-	GetStation  endpoint.Endpoint
-	ListStation endpoint.Endpoint
+	GetStation      endpoint.Endpoint
+	ListStation     endpoint.Endpoint
+	LocateStation   endpoint.Endpoint
+	NearbyStation   endpoint.Endpoint
+	SuggestTrip     endpoint.Endpoint
+	StationHistory  endpoint.Endpoint
+	ForecastStation endpoint.Endpoint
+	PlanRebalance   endpoint.Endpoint
+	GetStats        endpoint.Endpoint
+	StreamStation   endpoint.Endpoint
+	GraphQL         endpoint.Endpoint
+	StationVersion  endpoint.Endpoint
+	GetStatus       endpoint.Endpoint
+	Readiness       endpoint.Endpoint
+	Liveness        endpoint.Endpoint
 }
 
 // MakeEndpoints initialises the endpoints
 func MakeEndpoints(s Services) Endpoints {
-	return Endpoints{
-		GetStation:  makeGetStationEndpoint(s.Station),
-		ListStation: makeListStationEndpoint(s.Station),
+	e := Endpoints{
+		GetStation:      makeGetStationEndpoint(s.Station),
+		ListStation:     makeListStationEndpoint(s.Station, s.History),
+		LocateStation:   makeLocateStationEndpoint(s.Station),
+		NearbyStation:   makeNearbyStationEndpoint(s.Station),
+		SuggestTrip:     makeSuggestTripEndpoint(s.Trip),
+		StationHistory:  makeStationHistoryEndpoint(s.History),
+		ForecastStation: makeForecastStationEndpoint(s.Station),
+		PlanRebalance:   makePlanRebalanceEndpoint(s.Rebalance),
+		GetStats:        makeGetStatsEndpoint(s.Stats),
+		StreamStation:   makeStreamStationEndpoint(s.Stream),
+		StationVersion:  makeStationVersionEndpoint(s.Station),
+		GetStatus:       makeGetStatusEndpoint(s.Status),
+		Readiness:       makeReadinessEndpoint(s.Status),
+		Liveness:        makeLivenessEndpoint(),
 	}
+	// The GraphQL schema resolves its queries with the other endpoints
+	e.GraphQL = makeGraphQLEndpoint(newGraphQLSchema(e))
+	return e
 }
 
 // Handlers contains all available handlers for this API.
 // What handler is invoked and when is setup by the router.
 type Handlers struct {
-	GetStation  http.Handler
-	ListStation http.Handler
+	GetStation      http.Handler
+	ListStation     http.Handler
+	LocateStation   http.Handler
+	NearbyStation   http.Handler
+	SuggestTrip     http.Handler
+	StationHistory  http.Handler
+	ForecastStation http.Handler
+	PlanRebalance   http.Handler
+	GetStats        http.Handler
+	GetStationV2    http.Handler
+	ListStationV2   http.Handler
+	ListGeoJSON     http.Handler
+	StreamStation   http.Handler
+	WebSocket       http.Handler
+	GraphQL         http.Handler
+	OpenAPI         http.Handler
+	GetStatus       http.Handler
+	Readiness       http.Handler
+	Liveness        http.Handler
+	// Sessions are the websocket sessions of the handlers,
+	// pass them to Run, so they are waited for on shutdown
+	Sessions *Sessions
 }
 
 // MakeHandlers initialises the handlers with decoders, encoders, etc.
 func MakeHandlers(e Endpoints, serverOptions ...kithttp.ServerOption) *Handlers {
-	newServer := func(e endpoint.Endpoint, decodeRequestFn kithttp.DecodeRequestFunc) http.Handler {
+	// The request headers are made available to the encoders,
+	// so they can negotiate the representation, and the errors
+	// refer to the request that failed
+	serverOptions = append([]kithttp.ServerOption{
+		kithttp.ServerBefore(kithttp.PopulateRequestContext),
+		kithttp.ServerErrorEncoder(encodeError),
+	}, serverOptions...)
+
+	newServerWithEncoder := func(e endpoint.Endpoint, decodeRequestFn kithttp.DecodeRequestFunc, encodeResponseFn kithttp.EncodeResponseFunc) http.Handler {
 		return kithttp.NewServer(
 			e,
 			decodeRequestFn,
-			kithttp.EncodeJSONResponse,
+			encodeResponseFn,
 			serverOptions...,
 		)
 	}
+	newServer := func(e endpoint.Endpoint, decodeRequestFn kithttp.DecodeRequestFunc) http.Handler {
+		return newServerWithEncoder(e, decodeRequestFn, kithttp.EncodeJSONResponse)
+	}
+	newV2Server := func(e endpoint.Endpoint, decodeRequestFn kithttp.DecodeRequestFunc) http.Handler {
+		return newServerWithEncoder(e, decodeRequestFn, encodeStationV2Response)
+	}
+	newStationServer := func(e endpoint.Endpoint, decodeRequestFn kithttp.DecodeRequestFunc) http.Handler {
+		return newServerWithEncoder(e, decodeRequestFn, negotiateGeoJSON(kithttp.EncodeJSONResponse))
+	}
+	// The station responses can be cached until the snapshot changes
+	cached := func(representation string, h http.Handler) http.Handler {
+		return cacheable(e.StationVersion, fixedRepresentation(representation), h)
+	}
+	cachedNegotiated := func(h http.Handler) http.Handler {
+		return cacheable(e.StationVersion, negotiatedRepresentation, h)
+	}
+	// The websocket encoder takes over the connection of the request
+	sessions := newSessions()
+	newWebSocketServer := func(e endpoint.Endpoint) http.Handler {
+		options := append([]kithttp.ServerOption{kithttp.ServerBefore(populateRequest)}, serverOptions...)
+		return kithttp.NewServer(e, decodeWebSocketStationRequest, makeWebSocketEncoder(sessions), options...)
+	}
 
 	return &Handlers{
-		GetStation:  newServer(e.GetStation, decodeGetStationRequest),
-		ListStation: newServer(e.ListStation, kithttp.NopRequestDecoder),
+		GetStation:      cachedNegotiated(newStationServer(e.GetStation, decodeGetStationRequest)),
+		ListStation:     cachedNegotiated(newStationServer(e.ListStation, decodeListStationRequest)),
+		LocateStation:   cached(representationJSON, newServer(e.LocateStation, decodeLocateStationRequest)),
+		NearbyStation:   cachedNegotiated(newStationServer(e.NearbyStation, decodeNearbyStationRequest)),
+		SuggestTrip:     newServer(e.SuggestTrip, decodeSuggestTripRequest),
+		StationHistory:  newServer(e.StationHistory, decodeStationHistoryRequest),
+		ForecastStation: newServer(e.ForecastStation, decodeForecastStationRequest),
+		PlanRebalance:   newServer(e.PlanRebalance, decodePlanRebalanceRequest),
+		GetStats:        newServer(e.GetStats, kithttp.NopRequestDecoder),
+		GetStationV2:    cached(representationV2, newV2Server(e.GetStation, decodeGetStationRequest)),
+		ListStationV2:   cached(representationV2, newV2Server(e.ListStation, decodeListStationRequest)),
+		ListGeoJSON:     cached(representationGeoJSON, newServerWithEncoder(e.ListStation, decodeListStationRequest, encodeGeoJSONResponse)),
+		StreamStation:   newServerWithEncoder(e.StreamStation, decodeStreamStationRequest, encodeStreamStationResponse),
+		WebSocket:       newWebSocketServer(e.StreamStation),
+		GraphQL:         newServer(e.GraphQL, decodeGraphQLRequest),
+		OpenAPI:         newServer(makeOpenAPIEndpoint(), kithttp.NopRequestDecoder),
+		GetStatus:       newServer(e.GetStatus, kithttp.NopRequestDecoder),
+		Readiness:       newServer(e.Readiness, kithttp.NopRequestDecoder),
+		Liveness:        newServer(e.Liveness, kithttp.NopRequestDecoder),
+		Sessions:        sessions,
 	}
 }
 
 // AttachRoutes creates a router and adds the handlers with the
-// path, method, etc., that resolves to them.
-func AttachRoutes(handlers *Handlers) http.Handler {
+// path, method, etc., that resolves to them. Every request is
+// given an ID and logged to the logger, the middlewares are
+// applied to every route after that.
+func AttachRoutes(handlers *Handlers, logger log.Logger, middlewares ...func(http.Handler) http.Handler) http.Handler {
 	r := chi.NewRouter()
-	r.Use(middleware.Logger)
+	r.Use(requestID, accessLog(logger))
+	r.Use(middlewares...)
 
 	r.Route("/v1", func(r chi.Router) {
+		r.Method(http.MethodGet, "/stations.geojson", handlers.ListGeoJSON)
 		r.Route("/stations", func(r chi.Router) {
+			r.Method(http.MethodGet, "/locate", handlers.LocateStation)
+			r.Method(http.MethodGet, "/nearby", handlers.NearbyStation)
+			r.Method(http.MethodGet, "/stream", handlers.StreamStation)
+			r.Method(http.MethodGet, "/ws", handlers.WebSocket)
 			r.Method(http.MethodGet, "/{identifier}", handlers.GetStation)
+			r.Method(http.MethodGet, "/{identifier}/history", handlers.StationHistory)
+			r.Method(http.MethodGet, "/{identifier}/forecast", handlers.ForecastStation)
 			r.Method(http.MethodGet, "/", handlers.ListStation)
 		})
+		r.Route("/trips", func(r chi.Router) {
+			r.Method(http.MethodGet, "/suggest", handlers.SuggestTrip)
+		})
+		r.Method(http.MethodGet, "/rebalance", handlers.PlanRebalance)
+		r.Method(http.MethodGet, "/stats", handlers.GetStats)
+		r.Method(http.MethodGet, "/openapi.json", handlers.OpenAPI)
+		r.Method(http.MethodGet, "/status", handlers.GetStatus)
 	})
 
+	r.Method(http.MethodGet, "/graphql", handlers.GraphQL)
+	r.Method(http.MethodPost, "/graphql", handlers.GraphQL)
+
+	r.Route("/v2", func(r chi.Router) {
+		r.Route("/stations", func(r chi.Router) {
+			r.Method(http.MethodGet, "/{identifier}", handlers.GetStationV2)
+			r.Method(http.MethodGet, "/", handlers.ListStationV2)
+		})
+	})
+
+	return r
+}
+
+// AttachProbes creates a router for the liveness and readiness
+// probes, they are requested often by the orchestrator, so the
+// requests are not logged
+func AttachProbes(handlers *Handlers) http.Handler {
+	r := chi.NewRouter()
+	r.Method(http.MethodGet, "/healthz", handlers.Liveness)
+	r.Method(http.MethodGet, "/readyz", handlers.Readiness)
 	return r
 }
 
 // Services contains all available services for this API
 type Services struct {
-	Station api.StationService
+	Station   api.StationService
+	Trip      api.TripService
+	History   api.HistoryService
+	Rebalance api.RebalanceService
+	Stats     api.StatsService
+	Stream    api.StreamService
+	Status    api.StatusService
 }
@@ -1,14 +1,25 @@
 package server
 
 import (
+	"bytes"
+	"context"
 	"fmt"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
 	"github.com/magiconair/properties/assert"
+	"github.com/paulbes/go-pedal/pkg/api"
 	"github.com/paulbes/go-pedal/pkg/api/mock"
 	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/logging"
+	"github.com/paulbes/go-pedal/pkg/md"
 	"github.com/sebdah/goldie"
 )
 
@@ -17,6 +28,9 @@ func TestRoutes(t *testing.T) {
 		Name         string
 		Method       string
 		Path         string
+		Accept       string
+		ContentType  string
+		Body         string
 		Err          error
 		ExpectCode   int
 		ExpectGolden string
@@ -72,25 +86,728 @@ func TestRoutes(t *testing.T) {
 			ExpectCode:   http.StatusInternalServerError,
 			ExpectGolden: "list.500",
 		},
+		{
+			Name:         "Locate station ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/locate?lat=59.1&lon=59.09",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "locate.200",
+		},
+		{
+			Name:         "Locate station bad request",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/locate?lat=north&lon=59.09",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "locate.400",
+		},
+		{
+			Name:         "Locate station out of range",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/locate?lat=91&lon=59.09",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "locate.400.range",
+		},
+		{
+			Name:         "Locate station not a number",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/locate?lat=NaN&lon=59.09",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "locate.400.nan",
+		},
+		{
+			Name:         "Locate station infinite",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/locate?lat=59.1&lon=-Inf",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "locate.400.inf",
+		},
+		{
+			Name:         "Locate station not found",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/locate?lat=10&lon=10",
+			Err:          errors.New(fmt.Errorf("no station at: 10, 10"), "could not locate station", errors.NotFound),
+			ExpectCode:   http.StatusNotFound,
+			ExpectGolden: "locate.404",
+		},
+		{
+			Name:         "Nearby station ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=59.00001&lon=59.00002&radius=100&limit=5",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "nearby.200",
+		},
+		{
+			Name:         "Nearby station nothing close",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=10&lon=10",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "nearby.200.empty",
+		},
+		{
+			Name:         "Nearby station bad coordinate",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=north&lon=10",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "nearby.400",
+		},
+		{
+			Name:         "Nearby station coordinate out of range",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=91&lon=10",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "nearby.400.range",
+		},
+		{
+			Name:         "Nearby station bad radius",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=59.91&lon=10.75&radius=-1",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "nearby.400.radius",
+		},
+		{
+			Name:         "Nearby station radius not a number",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=59.91&lon=10.75&radius=nan",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "nearby.400.radius.nan",
+		},
+		{
+			Name:         "Nearby station bad limit",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=59.91&lon=10.75&limit=0",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "nearby.400.limit",
+		},
+		{
+			Name:         "Nearby station internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=59.91&lon=10.75",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to read stations", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "nearby.500",
+		},
+		{
+			Name:         "Suggest trip ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/trips/suggest?from_lat=59.91&from_lon=10.75&to_lat=59.93&to_lon=10.72",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "trip.200",
+		},
+		{
+			Name:         "Suggest trip missing destination",
+			Method:       http.MethodGet,
+			Path:         "/v1/trips/suggest?from_lat=59.91&from_lon=10.75",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "trip.400",
+		},
+		{
+			Name:         "Suggest trip origin not a number",
+			Method:       http.MethodGet,
+			Path:         "/v1/trips/suggest?from_lat=NaN&from_lon=10.75&to_lat=59.93&to_lon=10.72",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "trip.400.nan",
+		},
+		{
+			Name:         "Suggest trip bad limit",
+			Method:       http.MethodGet,
+			Path:         "/v1/trips/suggest?from_lat=59.91&from_lon=10.75&to_lat=59.93&to_lon=10.72&limit=100",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "trip.400.limit",
+		},
+		{
+			Name:         "Suggest trip internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/trips/suggest?from_lat=59.91&from_lon=10.75&to_lat=59.93&to_lon=10.72",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to suggest trips", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "trip.500",
+		},
+		{
+			Name:         "Station history ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/history?from=2018-10-01T00:00:00Z&to=2018-10-01T01:00:00Z&step=20m",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "history.200",
+		},
+		{
+			Name:         "Station history bad time",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/history?from=yesterday",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "history.400",
+		},
+		{
+			Name:         "Station history too many buckets",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/history?from=2018-10-01T00:00:00Z&to=2018-10-02T00:00:00Z&step=1s",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "history.400.step",
+		},
+		{
+			Name:         "Station history not recorded",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/history",
+			Err:          errors.New(fmt.Errorf("no history store configured"), "history is not recorded", errors.Unavailable),
+			ExpectCode:   http.StatusServiceUnavailable,
+			ExpectGolden: "history.503",
+		},
+		{
+			Name:         "Forecast station ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/forecast?horizon=20m",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "forecast.200",
+		},
+		{
+			Name:         "Forecast station bad horizon",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/forecast?horizon=48h",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "forecast.400",
+		},
+		{
+			Name:         "Forecast station not enabled",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1/forecast",
+			Err:          errors.New(fmt.Errorf("forecasting is not enabled"), "failed to forecast station", errors.Unavailable),
+			ExpectCode:   http.StatusServiceUnavailable,
+			ExpectGolden: "forecast.503",
+		},
+		{
+			Name:         "List station at ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?at=2018-10-01T00:30:00Z",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "list.at.200",
+		},
+		{
+			Name:         "List station at bad time",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?at=now",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "list.at.400",
+		},
+		{
+			Name:         "Plan rebalance ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/rebalance?capacity=10",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "rebalance.200",
+		},
+		{
+			Name:         "Plan rebalance bad capacity",
+			Method:       http.MethodGet,
+			Path:         "/v1/rebalance?capacity=0",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "rebalance.400",
+		},
+		{
+			Name:         "Plan rebalance internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/rebalance",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to plan rebalancing", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "rebalance.500",
+		},
+		{
+			Name:         "Get stats ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stats",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "stats.200",
+		},
+		{
+			Name:         "Get stats internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/stats",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to read stations", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "stats.500",
+		},
+		{
+			Name:         "Get status ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/status",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "status.200",
+		},
+		{
+			Name:         "Get status internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/status",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to read status", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "status.500",
+		},
+		{
+			Name:         "List station query ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?closed=false&in_service=true&min_bikes=1&min_locks=1&q=antarc&sort=-distance&lat=59.91&lon=10.75&offset=0&limit=10",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "list.query.200",
+		},
+		{
+			Name:         "List station query filtered",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?closed=true",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "list.query.200.empty",
+		},
+		{
+			Name:         "List station query bad bool",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?closed=maybe",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "list.query.400.bool",
+		},
+		{
+			Name:         "List station query bad sort",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?sort=popularity",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "list.query.400.sort",
+		},
+		{
+			Name:         "List station query distance without origin",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?sort=distance",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "list.query.400.origin",
+		},
+		{
+			Name:         "List station query distance from infinity",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?sort=distance&lat=Inf&lon=10.75",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "list.query.400.origin.inf",
+		},
+		{
+			Name:         "List station query bad limit",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?limit=5000",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "list.query.400.limit",
+		},
+		{
+			Name:         "Get station v2 ok",
+			Method:       http.MethodGet,
+			Path:         "/v2/stations/1",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "v2.get.200",
+		},
+		{
+			Name:         "Get station v2 not found",
+			Method:       http.MethodGet,
+			Path:         "/v2/stations/1",
+			Err:          errors.New(fmt.Errorf("no such id: 1"), "could not find station", errors.NotFound),
+			ExpectCode:   http.StatusNotFound,
+			ExpectGolden: "v2.get.404",
+		},
+		{
+			Name:         "List station v2 ok",
+			Method:       http.MethodGet,
+			Path:         "/v2/stations/",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "v2.list.200",
+		},
+		{
+			Name:         "List station v2 at ok",
+			Method:       http.MethodGet,
+			Path:         "/v2/stations/?at=2018-10-01T00:30:00Z",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "v2.list.at.200",
+		},
+		{
+			Name:         "List station v2 internal error",
+			Method:       http.MethodGet,
+			Path:         "/v2/stations/",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to read stations", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "v2.list.500",
+		},
+		{
+			Name:         "List station geojson ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations.geojson",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "geojson.list.200",
+		},
+		{
+			Name:         "List station geojson internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations.geojson",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to read stations", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "geojson.list.500",
+		},
+		{
+			Name:         "Get station geojson accepted",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/1",
+			Accept:       "text/html, application/geo+json;q=0.9",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "geojson.get.200",
+		},
+		{
+			Name:         "List station geojson accepted",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/?limit=1",
+			Accept:       "application/geo+json",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "geojson.list.query.200",
+		},
+		{
+			Name:         "Nearby station geojson accepted",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/nearby?lat=59.00001&lon=59.00002&radius=100&limit=5",
+			Accept:       "application/geo+json",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "geojson.nearby.200",
+		},
+		{
+			Name:         "Stream station ok",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/stream",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "stream.200",
+		},
+		{
+			Name:         "Stream station filtered",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/stream?ids=2,3",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "stream.200.filtered",
+		},
+		{
+			Name:         "Stream station bad ids",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/stream?ids=1,two",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "stream.400",
+		},
+		{
+			Name:         "Stream station internal error",
+			Method:       http.MethodGet,
+			Path:         "/v1/stations/stream",
+			Err:          errors.New(fmt.Errorf("uh oh"), "failed to read stations", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectGolden: "stream.500",
+		},
+		{
+			Name:         "GraphQL query ok",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			ContentType:  "application/json",
+			Body:         `{"query": "{ station(id: 1) { id title availability { bikes locks } updatedAt } stations(limit: 10, sort: \"-bikes\") { total stations { id center { latitude longitude } } } nearby(latitude: 59.00001, longitude: 59.00002) { distance station { id } } stats { stations capacity bikesInUseEstimate utilisation { percentile value } } }"}`,
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200",
+		},
+		{
+			Name:         "GraphQL query with variables",
+			Method:       http.MethodGet,
+			Path:         "/graphql?query=query+Station($id:+Int!)+{+station(id:+$id)+{+...parts+}+}+fragment+parts+on+Station+{+id+bounds+{+latitude+}+}&variables={\"id\":1}",
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200.variables",
+		},
+		{
+			Name:         "GraphQL query as application/graphql",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			ContentType:  "application/graphql",
+			Body:         `{ stats { bikes locks } }`,
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200.plain",
+		},
+		{
+			Name:         "GraphQL station not found",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			Body:         `{"query": "{ station(id: 1000) { id } }"}`,
+			Err:          errors.New(fmt.Errorf("no such id: 1000"), "could not find station", errors.NotFound),
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200.notfound",
+		},
+		{
+			Name:         "GraphQL invalid arguments",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			Body:         `{"query": "{ stations(limit: 2000) { total } }"}`,
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200.arguments",
+		},
+		{
+			Name:         "GraphQL invalid query",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			Body:         `{"query": "{ station(id: 1) { name } }"}`,
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200.invalid",
+		},
+		{
+			Name:         "GraphQL internal error",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			Body:         `{"query": "{ stats { bikes } }"}`,
+			Err:          errors.New(fmt.Errorf("uh oh"), "io error", errors.IO),
+			ExpectCode:   http.StatusOK,
+			ExpectGolden: "graphql.200.error",
+		},
+		{
+			Name:         "GraphQL bad request",
+			Method:       http.MethodPost,
+			Path:         "/graphql",
+			Body:         `{"query": `,
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "graphql.400",
+		},
+		{
+			Name:         "GraphQL missing query",
+			Method:       http.MethodGet,
+			Path:         "/graphql",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectGolden: "graphql.400.query",
+		},
 	}
 
 	for _, tc := range testCases {
 		// Here we could have created a mocked service instead,
 		// but now we get to test more with fewer tests :p
 		station := mock.NewStation()
+		station.UpdatedAt = time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC)
 		store := mock.NewStationStore(station, tc.Err)
 		service := NewStationService(store)
+		tripService := NewTripService(mock.NewTripStore(mock.NewTrip(), tc.Err))
+		historyService := NewHistoryService(mock.NewHistoryStore(mock.NewHistorySamples(), station, tc.Err))
+		rebalanceService := NewRebalanceService(mock.NewRebalanceStore(mock.NewRebalance(), tc.Err))
 		endpoints := MakeEndpoints(Services{
-			Station: service,
+			Station:   service,
+			Trip:      tripService,
+			History:   historyService,
+			Rebalance: rebalanceService,
+			Stats:     NewStatsService(mock.NewStatsStore(mock.NewStats(), tc.Err)),
+			Stream:    NewStreamService(mock.NewStreamStore(mock.NewStationEvents(), tc.Err)),
+			Status:    NewStatusService(mock.NewStatusStore(mock.NewStatus(), tc.Err)),
 		})
 		handlers := MakeHandlers(endpoints)
-		router := AttachRoutes(handlers)
+		router := AttachRoutes(handlers, log.NewNopLogger())
 
 		recorder := httptest.NewRecorder()
-		req := httptest.NewRequest(tc.Method, tc.Path, nil)
+		req := httptest.NewRequest(tc.Method, tc.Path, strings.NewReader(tc.Body))
+		req.Header.Set(logging.RequestIDHeader, "req-42")
+		if len(tc.Accept) > 0 {
+			req.Header.Set("Accept", tc.Accept)
+		}
+		if len(tc.ContentType) > 0 {
+			req.Header.Set("Content-Type", tc.ContentType)
+		}
 		router.ServeHTTP(recorder, req)
 
 		assert.Equal(t, recorder.Code, tc.ExpectCode, tc.Name)
+		assert.Equal(t, recorder.Header().Get(logging.RequestIDHeader), "req-42", tc.Name)
 		goldie.Assert(t, tc.ExpectGolden, recorder.Body.Bytes())
+		assertMatchesSpec(t, router.(chi.Routes), req, recorder)
+	}
+}
+
+func TestRoutes_RequestID(t *testing.T) {
+	err := errors.New(fmt.Errorf("uh oh"), "io error", errors.IO)
+	endpoints := MakeEndpoints(Services{
+		Station: NewStationService(mock.NewStationStore(mock.NewStation(), err)),
+	})
+	var logs bytes.Buffer
+	router := AttachRoutes(MakeHandlers(endpoints), log.NewLogfmtLogger(&logs))
+
+	testCases := []struct {
+		Name      string
+		RequestID string
+		Generated bool
+	}{
+		{Name: "Provided", RequestID: "abc-123"},
+		{Name: "Missing", Generated: true},
+		{Name: "Contains spaces", RequestID: "abc 123", Generated: true},
+		{Name: "Too long", RequestID: strings.Repeat("a", logging.MaxRequestIDLength+1), Generated: true},
+	}
+
+	for _, tc := range testCases {
+		logs.Reset()
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, "/v1/stations/1", nil)
+		if len(tc.RequestID) > 0 {
+			req.Header.Set(logging.RequestIDHeader, tc.RequestID)
+		}
+		router.ServeHTTP(recorder, req)
+
+		id := recorder.Header().Get(logging.RequestIDHeader)
+		if tc.Generated {
+			assert.Equal(t, len(id), 24, tc.Name)
+		} else {
+			assert.Equal(t, id, tc.RequestID, tc.Name)
+		}
+		assert.Equal(t, strings.Contains(recorder.Body.String(), fmt.Sprintf(`"request_id":"%s"`, id)), true, tc.Name)
+		assert.Equal(t, strings.Contains(logs.String(), "request_id="+id), true, tc.Name)
+		assert.Equal(t, strings.Contains(logs.String(), "route=/v1/stations/{identifier}"), true, tc.Name)
+		assert.Equal(t, strings.Contains(logs.String(), "status=500"), true, tc.Name)
+	}
+}
+
+func TestRoutes_ListStationTotalCount(t *testing.T) {
+	station := mock.NewStation()
+	endpoints := MakeEndpoints(Services{
+		Station: NewStationService(mock.NewStationStore(station, nil)),
+	})
+	router := AttachRoutes(MakeHandlers(endpoints), log.NewNopLogger())
+
+	testCases := []struct {
+		Path   string
+		Expect string
+	}{
+		{Path: "/v1/stations/?limit=1", Expect: "1"},
+		{Path: "/v2/stations/?limit=1", Expect: "1"},
+		{Path: "/v1/stations/?closed=true", Expect: "0"},
+	}
+
+	for _, tc := range testCases {
+		recorder := httptest.NewRecorder()
+		router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, tc.Path, nil))
+		assert.Equal(t, recorder.Header().Get("X-Total-Count"), tc.Expect, tc.Path)
+	}
+}
+
+func TestProbes(t *testing.T) {
+	notLoaded := api.Status{}
+	tooOld := mock.NewStatus()
+	age := MaxReadyAge.Seconds() + 1
+	tooOld.Age = &age
+
+	testCases := []struct {
+		Name       string
+		Path       string
+		Status     api.Status
+		Err        error
+		ExpectCode int
+		ExpectBody string
+	}{
+		{
+			Name:       "Alive",
+			Path:       "/healthz",
+			Err:        errors.New(fmt.Errorf("uh oh"), "failed to read status", errors.IO),
+			ExpectCode: http.StatusOK,
+			ExpectBody: `{"status":"ok"}`,
+		},
+		{
+			Name:       "Ready",
+			Path:       "/readyz",
+			Status:     mock.NewStatus(),
+			ExpectCode: http.StatusOK,
+			ExpectBody: `{"ready":true}`,
+		},
+		{
+			Name:       "Not ready, not loaded",
+			Path:       "/readyz",
+			Status:     notLoaded,
+			ExpectCode: http.StatusServiceUnavailable,
+			ExpectBody: `{"ready":false,"reason":"the stations have not been loaded"}`,
+		},
+		{
+			Name:       "Not ready, too old",
+			Path:       "/readyz",
+			Status:     tooOld,
+			ExpectCode: http.StatusServiceUnavailable,
+			ExpectBody: `{"ready":false,"reason":"the stations were updated more than 5m0s ago"}`,
+		},
+		{
+			Name:       "Not ready, internal error",
+			Path:       "/readyz",
+			Err:        errors.New(fmt.Errorf("uh oh"), "failed to read status", errors.IO),
+			ExpectCode: http.StatusInternalServerError,
+			ExpectBody: `{"message":"io: failed to read status: uh oh","code":500,"type":"io"}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		endpoints := MakeEndpoints(Services{
+			Status: NewStatusService(mock.NewStatusStore(tc.Status, tc.Err)),
+		})
+		router := AttachProbes(MakeHandlers(endpoints))
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+		router.ServeHTTP(recorder, req)
+
+		assert.Equal(t, recorder.Code, tc.ExpectCode, tc.Name)
+		assert.Equal(t, strings.TrimSpace(recorder.Body.String()), tc.ExpectBody, tc.Name)
+		assertMatchesSpec(t, router.(chi.Routes), req, recorder)
+	}
+}
+
+// openSubscription delivers the events and stays open
+// until it is closed
+type openSubscription struct {
+	events chan api.StationEvent
+	closed chan struct{}
+	once   sync.Once
+}
+
+func (s *openSubscription) Events() <-chan api.StationEvent {
+	return s.events
+}
+
+func (s *openSubscription) Close() {
+	s.once.Do(func() {
+		close(s.closed)
+	})
+}
+
+// recordingStreamService records the last event ID it was subscribed with
+type recordingStreamService struct {
+	sub         *openSubscription
+	lastEventID string
+}
+
+func (s *recordingStreamService) Subscribe(_ context.Context, lastEventID string) (api.StationSubscription, error) {
+	s.lastEventID = lastEventID
+	return s.sub, nil
+}
+
+func TestRoutes_StreamStation(t *testing.T) {
+	HeartbeatInterval = 10 * time.Millisecond
+	MaxStreamDuration = 100 * time.Millisecond
+	defer func() {
+		HeartbeatInterval = 15 * time.Second
+		MaxStreamDuration = 4 * time.Minute
+	}()
+
+	events := mock.NewStationEvents()
+	service := &recordingStreamService{
+		sub: &openSubscription{
+			events: make(chan api.StationEvent, 1),
+			closed: make(chan struct{}),
+		},
+	}
+	service.sub.events <- events[1]
+
+	router := AttachRoutes(MakeHandlers(MakeEndpoints(Services{Stream: service})), log.NewNopLogger())
+	server := httptest.NewServer(md.Cors(router))
+	defer server.Close()
+
+	req, _ := http.NewRequest(http.MethodGet, server.URL+"/v1/stations/stream", nil)
+	req.Header.Set("Last-Event-ID", "e-0")
+	resp, err := http.DefaultClient.Do(req)
+	assert.Equal(t, err, nil)
+	defer resp.Body.Close()
+
+	// The stream is ended by the server before the write timeout
+	body, err := ioutil.ReadAll(resp.Body)
+	assert.Equal(t, err, nil)
+	assert.Equal(t, resp.Header.Get("Content-Type"), "text/event-stream")
+	assert.Equal(t, resp.Header.Get("Access-Control-Allow-Origin"), "*")
+	assert.Equal(t, service.lastEventID, "e-0")
+	assert.Equal(t, strings.Contains(string(body), "id: e-1\nevent: availability\n"), true, "Delivers the events")
+	assert.Equal(t, strings.Contains(string(body), ": heartbeat\n\n"), true, "Sends heartbeats")
+
+	select {
+	case <-service.sub.closed:
+	case <-time.After(time.Second):
+		t.Error("expected the subscription to be closed")
 	}
 }
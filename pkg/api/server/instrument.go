@@ -0,0 +1,89 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/metrics"
+)
+
+// Note: the routes are instrumented by the transport, so the status
+// code of every response is known, while the endpoints are instrumented
+// by a middleware, which separates the failures from the successes.
+
+// Metrics contains the metrics of the requests to the API
+type Metrics struct {
+	// Requests is labelled with the route, method and code
+	Requests metrics.Counter
+	// Duration is labelled with the route and method
+	Duration metrics.Histogram
+	// EndpointDuration is labelled with the endpoint and success
+	EndpointDuration metrics.Histogram
+}
+
+// NewMetrics registers the metrics of the API
+func NewMetrics(r *metrics.Registry) Metrics {
+	return Metrics{
+		Requests:         r.NewCounter("pedal_http_requests_total", "The number of HTTP requests, by route, method and status code.", "route", "method", "code"),
+		Duration:         r.NewHistogram("pedal_http_request_duration_seconds", "The duration of HTTP requests in seconds, by route and method.", nil, "route", "method"),
+		EndpointDuration: r.NewHistogram("pedal_endpoint_duration_seconds", "The duration of the endpoints in seconds, by endpoint and success.", nil, "endpoint", "success"),
+	}
+}
+
+// InstrumentRoutes creates a middleware for the router, that counts
+// and times the requests by the pattern of the route that served them
+func InstrumentRoutes(m Metrics) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			route := servedRoute(r)
+			code := ww.Status()
+			if code == 0 {
+				code = http.StatusOK
+			}
+			m.Requests.With("route", route, "method", r.Method, "code", strconv.Itoa(code)).Add(1)
+			m.Duration.With("route", route, "method", r.Method).Observe(time.Since(begin).Seconds())
+		})
+	}
+}
+
+// instrumentEndpoint creates a middleware that times the endpoint
+func instrumentEndpoint(duration metrics.Histogram) endpoint.Middleware {
+	return func(next endpoint.Endpoint) endpoint.Endpoint {
+		return func(ctx context.Context, request interface{}) (response interface{}, err error) {
+			defer func(begin time.Time) {
+				success := strconv.FormatBool(err == nil)
+				duration.With("success", success).Observe(time.Since(begin).Seconds())
+			}(time.Now())
+			return next(ctx, request)
+		}
+	}
+}
+
+// InstrumentEndpoints times every endpoint, the probes are left out
+func InstrumentEndpoints(e Endpoints, m Metrics) Endpoints {
+	instrument := func(name string, next endpoint.Endpoint) endpoint.Endpoint {
+		return instrumentEndpoint(m.EndpointDuration.With("endpoint", name))(next)
+	}
+	e.GetStation = instrument("get_station", e.GetStation)
+	e.ListStation = instrument("list_station", e.ListStation)
+	e.LocateStation = instrument("locate_station", e.LocateStation)
+	e.NearbyStation = instrument("nearby_station", e.NearbyStation)
+	e.SuggestTrip = instrument("suggest_trip", e.SuggestTrip)
+	e.StationHistory = instrument("station_history", e.StationHistory)
+	e.ForecastStation = instrument("forecast_station", e.ForecastStation)
+	e.PlanRebalance = instrument("plan_rebalance", e.PlanRebalance)
+	e.GetStats = instrument("get_stats", e.GetStats)
+	e.StreamStation = instrument("stream_station", e.StreamStation)
+	e.GraphQL = instrument("graphql", e.GraphQL)
+	e.StationVersion = instrument("station_version", e.StationVersion)
+	e.GetStatus = instrument("get_status", e.GetStatus)
+	return e
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pkg/api/mock"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/metrics"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestInstrument(t *testing.T) {
+	registry := metrics.NewRegistry()
+	m := NewMetrics(registry)
+
+	serve := func(path string, err error) {
+		endpoints := MakeEndpoints(Services{
+			Station: NewStationService(mock.NewStationStore(mock.NewStation(), err)),
+			Stats:   NewStatsService(mock.NewStatsStore(mock.NewStats(), err)),
+		})
+		router := AttachRoutes(MakeHandlers(InstrumentEndpoints(endpoints, m)), log.NewNopLogger(), InstrumentRoutes(m))
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+	}
+	serve("/v1/stations/1", nil)
+	serve("/v1/stations/2", nil)
+	serve("/v1/stations/gimme", nil)
+	serve("/v1/stations/", nil)
+	serve("/v1/stats", errors.New(fmt.Errorf("uh oh"), "failed to read stations", errors.IO))
+	serve("/nowhere", nil)
+
+	var b bytes.Buffer
+	registry.WriteTo(&b)
+	lines := strings.Split(b.String(), "\n")
+	for _, line := range []string{
+		`pedal_http_requests_total{route="/v1/stations/{identifier}",method="GET",code="200"} 2`,
+		`pedal_http_requests_total{route="/v1/stations/{identifier}",method="GET",code="400"} 1`,
+		`pedal_http_requests_total{route="/v1/stations/",method="GET",code="200"} 1`,
+		`pedal_http_requests_total{route="/v1/stats",method="GET",code="500"} 1`,
+		`pedal_http_requests_total{route="unmatched",method="GET",code="404"} 1`,
+		`pedal_http_request_duration_seconds_count{route="/v1/stations/{identifier}",method="GET"} 3`,
+		`pedal_endpoint_duration_seconds_count{endpoint="get_station",success="true"} 2`,
+		`pedal_endpoint_duration_seconds_count{endpoint="get_stats",success="false"} 1`,
+	} {
+		assert.Contains(t, lines, line)
+	}
+}
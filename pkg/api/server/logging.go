@@ -0,0 +1,76 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+	"github.com/go-kit/kit/log"
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/logging"
+)
+
+// Note: the request ID is taken from the X-Request-ID header, or
+// generated, before anything else runs, so it is part of every log
+// entry, the response headers and the body of every error.
+
+// requestID adds the ID of the request to its context
+// and echoes it in the response headers
+func requestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := logging.RequestIDFromHeader(r.Header)
+		w.Header().Set(logging.RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logging.WithRequestID(r.Context(), id)))
+	})
+}
+
+// accessLog creates a middleware that logs every request
+// once it has been served
+func accessLog(logger log.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			begin := time.Now()
+			ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+			next.ServeHTTP(ww, r)
+
+			code := ww.Status()
+			if code == 0 {
+				code = http.StatusOK
+			}
+			logging.With(r.Context(), logger).Log(
+				"msg", "served request",
+				"method", r.Method,
+				"path", r.URL.Path,
+				"route", servedRoute(r),
+				"status", code,
+				"bytes", ww.BytesWritten(),
+				"took", time.Since(begin),
+			)
+		})
+	}
+}
+
+// servedRoute returns the pattern of the route that served
+// the request, it is known once the request has been routed
+func servedRoute(r *http.Request) string {
+	if rctx := chi.RouteContext(r.Context()); rctx != nil && len(rctx.RoutePattern()) > 0 {
+		// The patterns of the sub routers are joined as they are,
+		// so the index of a sub router ends up with a double slash
+		pattern := rctx.RoutePattern()
+		for strings.Contains(pattern, "//") {
+			pattern = strings.Replace(pattern, "//", "/", -1)
+		}
+		return pattern
+	}
+	return "unmatched"
+}
+
+// encodeError adds the request ID to the error before it is
+// encoded, so the caller can refer to it
+func encodeError(ctx context.Context, err error, w http.ResponseWriter) {
+	kithttp.DefaultErrorEncoder(ctx, errors.WithRequestID(err, logging.RequestID(ctx)), w)
+}
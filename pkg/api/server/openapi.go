@@ -0,0 +1,2463 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/go-kit/kit/endpoint"
+)
+
+// Note: the OpenAPI document describes every route of the API and is
+// served at /v1/openapi.json. The tests ensure that the routes and the
+// golden responses match it, so it must be updated along with them.
+
+func makeOpenAPIEndpoint() endpoint.Endpoint {
+	return func(_ context.Context, _ interface{}) (interface{}, error) {
+		return json.RawMessage(openAPISpec), nil
+	}
+}
+
+// openAPISpec is the OpenAPI 3 document of the API
+const openAPISpec = `{
+  "openapi": "3.0.2",
+  "info": {
+    "title": "go-pedal",
+    "description": "The availability of the city bike stations in Oslo. Every request is given an ID, taken from the X-Request-ID header or generated, which is echoed in the response headers and in the body of every error. An API key is optional, it is provided in the X-API-Key header or the api_key query param. The requests are limited per key, or per IP when no key is provided, and are answered with 429 Too Many Requests when the limit is exceeded.",
+    "version": "1.0.0",
+    "license": {
+      "name": "MIT"
+    }
+  },
+  "security": [
+    {},
+    {
+      "apiKeyHeader": []
+    },
+    {
+      "apiKeyQuery": []
+    }
+  ],
+  "paths": {
+    "/v1/openapi.json": {
+      "get": {
+        "summary": "This document",
+        "operationId": "getOpenAPI",
+        "tags": [
+          "meta"
+        ],
+        "responses": {
+          "200": {
+            "description": "The OpenAPI document of the API",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "object",
+                  "additionalProperties": true
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/v1/stations/": {
+      "get": {
+        "summary": "List stations",
+        "operationId": "listStations",
+        "tags": [
+          "stations"
+        ],
+        "description": "The stations are returned as GeoJSON when application/geo+json is accepted. The response is cached with the current version of the stations, unless at is provided.",
+        "parameters": [
+          {
+            "name": "q",
+            "in": "query",
+            "description": "Only stations with the text in their title, ignoring case",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "closed",
+            "in": "query",
+            "description": "Only stations that are, or are not, closed",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "in_service",
+            "in": "query",
+            "description": "Only stations that are, or are not, in service",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "min_bikes",
+            "in": "query",
+            "description": "Only stations with at least this many bikes available",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "min_locks",
+            "in": "query",
+            "description": "Only stations with at least this many locks available",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "The field to sort by, a leading dash sorts in descending order",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "id",
+                "-id",
+                "title",
+                "-title",
+                "bikes",
+                "-bikes",
+                "locks",
+                "-locks",
+                "distance",
+                "-distance"
+              ]
+            }
+          },
+          {
+            "name": "lat",
+            "in": "query",
+            "description": "The latitude of the origin, required when sorting by distance",
+            "schema": {
+              "type": "number",
+              "minimum": -90,
+              "maximum": 90
+            }
+          },
+          {
+            "name": "lon",
+            "in": "query",
+            "description": "The longitude of the origin, required when sorting by distance",
+            "schema": {
+              "type": "number",
+              "minimum": -180,
+              "maximum": 180
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "The number of stations to skip",
+            "schema": {
+              "type": "integer",
+              "minimum": 0,
+              "default": 0
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The number of stations to return, zero returns all of them",
+            "schema": {
+              "type": "integer",
+              "minimum": 0,
+              "maximum": 1000,
+              "default": 0
+            }
+          },
+          {
+            "name": "at",
+            "in": "query",
+            "description": "List the stations as they were recorded at this time, requires the history to be recorded",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The matching stations",
+            "headers": {
+              "X-Total-Count": {
+                "description": "The number of stations that matched the query, before paging",
+                "schema": {
+                  "type": "integer"
+                }
+              },
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            },
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Station"
+                  }
+                }
+              },
+              "application/geo+json": {
+                "schema": {
+                  "$ref": "#/components/schemas/FeatureCollection"
+                }
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "503": {
+            "$ref": "#/components/responses/Unavailable"
+          }
+        }
+      }
+    },
+    "/v1/stations.geojson": {
+      "get": {
+        "summary": "List stations as GeoJSON",
+        "operationId": "listStationsGeoJSON",
+        "tags": [
+          "stations"
+        ],
+        "parameters": [
+          {
+            "name": "q",
+            "in": "query",
+            "description": "Only stations with the text in their title, ignoring case",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "closed",
+            "in": "query",
+            "description": "Only stations that are, or are not, closed",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "in_service",
+            "in": "query",
+            "description": "Only stations that are, or are not, in service",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "min_bikes",
+            "in": "query",
+            "description": "Only stations with at least this many bikes available",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "min_locks",
+            "in": "query",
+            "description": "Only stations with at least this many locks available",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "The field to sort by, a leading dash sorts in descending order",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "id",
+                "-id",
+                "title",
+                "-title",
+                "bikes",
+                "-bikes",
+                "locks",
+                "-locks",
+                "distance",
+                "-distance"
+              ]
+            }
+          },
+          {
+            "name": "lat",
+            "in": "query",
+            "description": "The latitude of the origin, required when sorting by distance",
+            "schema": {
+              "type": "number",
+              "minimum": -90,
+              "maximum": 90
+            }
+          },
+          {
+            "name": "lon",
+            "in": "query",
+            "description": "The longitude of the origin, required when sorting by distance",
+            "schema": {
+              "type": "number",
+              "minimum": -180,
+              "maximum": 180
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "The number of stations to skip",
+            "schema": {
+              "type": "integer",
+              "minimum": 0,
+              "default": 0
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The number of stations to return, zero returns all of them",
+            "schema": {
+              "type": "integer",
+              "minimum": 0,
+              "maximum": 1000,
+              "default": 0
+            }
+          },
+          {
+            "name": "at",
+            "in": "query",
+            "description": "List the stations as they were recorded at this time, requires the history to be recorded",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The matching stations as a feature collection",
+            "content": {
+              "application/geo+json": {
+                "schema": {
+                  "$ref": "#/components/schemas/FeatureCollection"
+                }
+              }
+            },
+            "headers": {
+              "X-Total-Count": {
+                "description": "The number of stations that matched the query, before paging",
+                "schema": {
+                  "type": "integer"
+                }
+              },
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "503": {
+            "$ref": "#/components/responses/Unavailable"
+          }
+        },
+        "description": "The response is cached with the current version of the stations, unless at is provided."
+      }
+    },
+    "/v1/stations/{identifier}": {
+      "get": {
+        "summary": "Get a station",
+        "operationId": "getStation",
+        "tags": [
+          "stations"
+        ],
+        "description": "The station is returned as GeoJSON when application/geo+json is accepted.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/identifier"
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The station",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Station"
+                }
+              },
+              "application/geo+json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Feature"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/stations/locate": {
+      "get": {
+        "summary": "Locate the station at a position",
+        "operationId": "locateStation",
+        "tags": [
+          "stations"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/lat"
+          },
+          {
+            "$ref": "#/components/parameters/lon"
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The station whose bounds contain the position",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Station"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/stations/nearby": {
+      "get": {
+        "summary": "List stations near a position",
+        "operationId": "nearbyStations",
+        "tags": [
+          "stations"
+        ],
+        "description": "The stations are returned closest first, as GeoJSON when application/geo+json is accepted.",
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/lat"
+          },
+          {
+            "$ref": "#/components/parameters/lon"
+          },
+          {
+            "name": "radius",
+            "in": "query",
+            "description": "The radius in metres",
+            "schema": {
+              "type": "number",
+              "exclusiveMinimum": true,
+              "minimum": 0,
+              "maximum": 10000,
+              "default": 500
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The largest number of stations to return",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 100,
+              "default": 10
+            }
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The stations within the radius",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/NearbyStation"
+                  }
+                }
+              },
+              "application/geo+json": {
+                "schema": {
+                  "$ref": "#/components/schemas/FeatureCollection"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/stations/stream": {
+      "get": {
+        "summary": "Stream the changes to the stations",
+        "operationId": "streamStations",
+        "tags": [
+          "stations"
+        ],
+        "description": "A server-sent event stream, it starts with a snapshot event followed by availability, closed and reopened events. The data of every event is a StationEvent.",
+        "parameters": [
+          {
+            "name": "Last-Event-ID",
+            "in": "header",
+            "description": "Resumes the stream after this event",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "last_event_id",
+            "in": "query",
+            "description": "Resumes the stream after this event, when the header can not be set",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "ids",
+            "in": "query",
+            "description": "Only stream the changes to these stations",
+            "style": "form",
+            "explode": false,
+            "schema": {
+              "type": "array",
+              "items": {
+                "type": "integer"
+              }
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The stream of events",
+            "content": {
+              "text/event-stream": {
+                "schema": {
+                  "type": "string"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/stations/ws": {
+      "get": {
+        "summary": "Subscribe to the changes to stations over a websocket",
+        "operationId": "subscribeStations",
+        "tags": [
+          "stations"
+        ],
+        "description": "The client subscribes with {\"type\": \"subscribe\", \"ids\": [...]} or {\"type\": \"subscribe\", \"bounds\": {\"min\": Coord, \"max\": Coord}} and receives StationEvent messages.",
+        "responses": {
+          "101": {
+            "description": "Switched to the websocket protocol"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/stations/{identifier}/history": {
+      "get": {
+        "summary": "The availability of a station over time",
+        "operationId": "stationHistory",
+        "tags": [
+          "stations"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/identifier"
+          },
+          {
+            "name": "from",
+            "in": "query",
+            "description": "The start of the range, defaults to a day before to",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "to",
+            "in": "query",
+            "description": "The end of the range, defaults to now",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "name": "step",
+            "in": "query",
+            "description": "The duration of every bucket, e.g., 20m, defaults to 1h",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The buckets of the range",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/HistoryBucket"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "503": {
+            "$ref": "#/components/responses/Unavailable"
+          }
+        }
+      }
+    },
+    "/v1/stations/{identifier}/forecast": {
+      "get": {
+        "summary": "The expected availability of a station",
+        "operationId": "forecastStation",
+        "tags": [
+          "stations"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/identifier"
+          },
+          {
+            "name": "horizon",
+            "in": "query",
+            "description": "How far into the future, e.g., 20m, at most 24h, defaults to 20m",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The forecast",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Forecast"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "503": {
+            "$ref": "#/components/responses/Unavailable"
+          }
+        }
+      }
+    },
+    "/v1/trips/suggest": {
+      "get": {
+        "summary": "Suggest trips between two positions",
+        "operationId": "suggestTrips",
+        "tags": [
+          "trips"
+        ],
+        "parameters": [
+          {
+            "name": "from_lat",
+            "in": "query",
+            "description": "The latitude of the start",
+            "schema": {
+              "type": "number",
+              "minimum": -90,
+              "maximum": 90
+            },
+            "required": true
+          },
+          {
+            "name": "from_lon",
+            "in": "query",
+            "description": "The longitude of the start",
+            "schema": {
+              "type": "number",
+              "minimum": -180,
+              "maximum": 180
+            },
+            "required": true
+          },
+          {
+            "name": "to_lat",
+            "in": "query",
+            "description": "The latitude of the destination",
+            "schema": {
+              "type": "number",
+              "minimum": -90,
+              "maximum": 90
+            },
+            "required": true
+          },
+          {
+            "name": "to_lon",
+            "in": "query",
+            "description": "The longitude of the destination",
+            "schema": {
+              "type": "number",
+              "minimum": -180,
+              "maximum": 180
+            },
+            "required": true
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The largest number of trips to suggest",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 10,
+              "default": 3
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The suggested trips, fastest first",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/Trip"
+                  }
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/rebalance": {
+      "get": {
+        "summary": "Plan how to rebalance the stations",
+        "operationId": "planRebalance",
+        "tags": [
+          "rebalance"
+        ],
+        "parameters": [
+          {
+            "name": "capacity",
+            "in": "query",
+            "description": "The number of bikes the truck can carry",
+            "schema": {
+              "type": "integer",
+              "minimum": 1,
+              "maximum": 100,
+              "default": 20
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The plan",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Rebalance"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/stats": {
+      "get": {
+        "summary": "Aggregate statistics for all stations",
+        "operationId": "getStats",
+        "tags": [
+          "stats"
+        ],
+        "responses": {
+          "200": {
+            "description": "The statistics",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Stats"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v1/status": {
+      "get": {
+        "summary": "How up to date the stations are",
+        "operationId": "getStatus",
+        "tags": [
+          "status"
+        ],
+        "description": "Reports the calls to every feed of the upstream API, without calling it.",
+        "responses": {
+          "200": {
+            "description": "The status",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Status"
+                }
+              }
+            }
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/v2/stations/": {
+      "get": {
+        "summary": "List stations, including their availability",
+        "operationId": "listStationsV2",
+        "tags": [
+          "stations"
+        ],
+        "parameters": [
+          {
+            "name": "q",
+            "in": "query",
+            "description": "Only stations with the text in their title, ignoring case",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "closed",
+            "in": "query",
+            "description": "Only stations that are, or are not, closed",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "in_service",
+            "in": "query",
+            "description": "Only stations that are, or are not, in service",
+            "schema": {
+              "type": "boolean"
+            }
+          },
+          {
+            "name": "min_bikes",
+            "in": "query",
+            "description": "Only stations with at least this many bikes available",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "min_locks",
+            "in": "query",
+            "description": "Only stations with at least this many locks available",
+            "schema": {
+              "type": "integer",
+              "minimum": 0
+            }
+          },
+          {
+            "name": "sort",
+            "in": "query",
+            "description": "The field to sort by, a leading dash sorts in descending order",
+            "schema": {
+              "type": "string",
+              "enum": [
+                "id",
+                "-id",
+                "title",
+                "-title",
+                "bikes",
+                "-bikes",
+                "locks",
+                "-locks",
+                "distance",
+                "-distance"
+              ]
+            }
+          },
+          {
+            "name": "lat",
+            "in": "query",
+            "description": "The latitude of the origin, required when sorting by distance",
+            "schema": {
+              "type": "number",
+              "minimum": -90,
+              "maximum": 90
+            }
+          },
+          {
+            "name": "lon",
+            "in": "query",
+            "description": "The longitude of the origin, required when sorting by distance",
+            "schema": {
+              "type": "number",
+              "minimum": -180,
+              "maximum": 180
+            }
+          },
+          {
+            "name": "offset",
+            "in": "query",
+            "description": "The number of stations to skip",
+            "schema": {
+              "type": "integer",
+              "minimum": 0,
+              "default": 0
+            }
+          },
+          {
+            "name": "limit",
+            "in": "query",
+            "description": "The number of stations to return, zero returns all of them",
+            "schema": {
+              "type": "integer",
+              "minimum": 0,
+              "maximum": 1000,
+              "default": 0
+            }
+          },
+          {
+            "name": "at",
+            "in": "query",
+            "description": "List the stations as they were recorded at this time, requires the history to be recorded",
+            "schema": {
+              "type": "string",
+              "format": "date-time"
+            }
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The matching stations",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "type": "array",
+                  "items": {
+                    "$ref": "#/components/schemas/StationV2"
+                  }
+                }
+              }
+            },
+            "headers": {
+              "X-Total-Count": {
+                "description": "The number of stations that matched the query, before paging",
+                "schema": {
+                  "type": "integer"
+                }
+              },
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          },
+          "503": {
+            "$ref": "#/components/responses/Unavailable"
+          }
+        },
+        "description": "The response is cached with the current version of the stations, unless at is provided."
+      }
+    },
+    "/v2/stations/{identifier}": {
+      "get": {
+        "summary": "Get a station, including its availability",
+        "operationId": "getStationV2",
+        "tags": [
+          "stations"
+        ],
+        "parameters": [
+          {
+            "$ref": "#/components/parameters/identifier"
+          },
+          {
+            "$ref": "#/components/parameters/If-None-Match"
+          },
+          {
+            "$ref": "#/components/parameters/If-Modified-Since"
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The station",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/StationV2"
+                }
+              }
+            },
+            "headers": {
+              "ETag": {
+                "$ref": "#/components/headers/ETag"
+              },
+              "Last-Modified": {
+                "$ref": "#/components/headers/Last-Modified"
+              },
+              "Cache-Control": {
+                "$ref": "#/components/headers/Cache-Control"
+              }
+            }
+          },
+          "304": {
+            "$ref": "#/components/responses/NotModified"
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "404": {
+            "$ref": "#/components/responses/NotFound"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        }
+      }
+    },
+    "/graphql": {
+      "get": {
+        "summary": "Execute a GraphQL query",
+        "operationId": "queryGraphQL",
+        "tags": [
+          "graphql"
+        ],
+        "parameters": [
+          {
+            "name": "query",
+            "in": "query",
+            "description": "The GraphQL document",
+            "schema": {
+              "type": "string"
+            },
+            "required": true
+          },
+          {
+            "name": "operationName",
+            "in": "query",
+            "description": "The operation to execute, when the document has several",
+            "schema": {
+              "type": "string"
+            }
+          },
+          {
+            "name": "variables",
+            "in": "query",
+            "description": "The JSON encoded variables of the operation",
+            "schema": {
+              "type": "string"
+            }
+          }
+        ],
+        "responses": {
+          "200": {
+            "description": "The result, including any errors",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GraphQLResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      },
+      "post": {
+        "summary": "Execute a GraphQL query",
+        "operationId": "postGraphQL",
+        "tags": [
+          "graphql"
+        ],
+        "requestBody": {
+          "required": true,
+          "content": {
+            "application/json": {
+              "schema": {
+                "$ref": "#/components/schemas/GraphQLRequest"
+              }
+            },
+            "application/graphql": {
+              "schema": {
+                "type": "string"
+              }
+            }
+          }
+        },
+        "responses": {
+          "200": {
+            "description": "The result, including any errors",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/GraphQLResponse"
+                }
+              }
+            }
+          },
+          "400": {
+            "$ref": "#/components/responses/BadRequest"
+          },
+          "401": {
+            "$ref": "#/components/responses/Unauthorized"
+          },
+          "429": {
+            "$ref": "#/components/responses/TooManyRequests"
+          }
+        }
+      }
+    },
+    "/healthz": {
+      "get": {
+        "summary": "Liveness probe",
+        "operationId": "liveness",
+        "tags": [
+          "status"
+        ],
+        "description": "Not logged, and not a cross origin resource.",
+        "responses": {
+          "200": {
+            "description": "The API is alive",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Liveness"
+                }
+              }
+            }
+          }
+        },
+        "security": []
+      }
+    },
+    "/readyz": {
+      "get": {
+        "summary": "Readiness probe",
+        "operationId": "readiness",
+        "tags": [
+          "status"
+        ],
+        "description": "The API is ready once the stations are loaded, as long as their availability is not too old. Not logged, and not a cross origin resource.",
+        "responses": {
+          "200": {
+            "description": "The API is ready",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Readiness"
+                }
+              }
+            }
+          },
+          "503": {
+            "description": "The API is not ready, the reason is provided",
+            "content": {
+              "application/json": {
+                "schema": {
+                  "$ref": "#/components/schemas/Readiness"
+                }
+              }
+            }
+          },
+          "500": {
+            "$ref": "#/components/responses/InternalError"
+          }
+        },
+        "security": []
+      }
+    }
+  },
+  "components": {
+    "schemas": {
+      "Error": {
+        "type": "object",
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "code": {
+            "type": "integer"
+          },
+          "type": {
+            "type": "string",
+            "enum": [
+              "notfound",
+              "unmarshal",
+              "marshal",
+              "io",
+              "unavailable",
+              "unauthorized",
+              "toomanyrequests"
+            ]
+          },
+          "request_id": {
+            "type": "string",
+            "description": "The ID of the request that failed, as echoed in the X-Request-ID header"
+          }
+        },
+        "required": [
+          "message",
+          "code",
+          "type"
+        ],
+        "description": "Every error has this shape, the code is the HTTP status code"
+      },
+      "Coord": {
+        "type": "object",
+        "properties": {
+          "latitude": {
+            "type": "number",
+            "minimum": -90,
+            "maximum": 90
+          },
+          "longitude": {
+            "type": "number",
+            "minimum": -180,
+            "maximum": 180
+          }
+        },
+        "required": [
+          "latitude",
+          "longitude"
+        ]
+      },
+      "Availability": {
+        "type": "object",
+        "properties": {
+          "bikes": {
+            "type": "integer"
+          },
+          "locks": {
+            "type": "integer"
+          }
+        },
+        "required": [
+          "bikes",
+          "locks"
+        ]
+      },
+      "Station": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "in_service": {
+            "type": "boolean"
+          },
+          "title": {
+            "type": "string"
+          },
+          "subtitle": {
+            "type": "string"
+          },
+          "number_of_locks": {
+            "type": "integer"
+          },
+          "center": {
+            "$ref": "#/components/schemas/Coord"
+          },
+          "bounds": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Coord"
+            },
+            "nullable": true
+          }
+        },
+        "required": [
+          "id",
+          "in_service",
+          "title",
+          "subtitle",
+          "number_of_locks",
+          "center",
+          "bounds"
+        ]
+      },
+      "StationV2": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "in_service": {
+            "type": "boolean"
+          },
+          "title": {
+            "type": "string"
+          },
+          "subtitle": {
+            "type": "string"
+          },
+          "number_of_locks": {
+            "type": "integer"
+          },
+          "center": {
+            "$ref": "#/components/schemas/Coord"
+          },
+          "bounds": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Coord"
+            },
+            "nullable": true
+          },
+          "availability": {
+            "$ref": "#/components/schemas/Availability"
+          },
+          "closed": {
+            "type": "boolean"
+          },
+          "updated_at": {
+            "type": "string",
+            "format": "date-time"
+          }
+        },
+        "required": [
+          "id",
+          "in_service",
+          "title",
+          "subtitle",
+          "number_of_locks",
+          "center",
+          "bounds",
+          "availability",
+          "closed",
+          "updated_at"
+        ]
+      },
+      "NearbyStation": {
+        "type": "object",
+        "properties": {
+          "station": {
+            "$ref": "#/components/schemas/Station"
+          },
+          "distance": {
+            "type": "number",
+            "description": "The distance in metres"
+          }
+        },
+        "required": [
+          "station",
+          "distance"
+        ]
+      },
+      "StationEvent": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "string"
+          },
+          "type": {
+            "type": "string",
+            "enum": [
+              "snapshot",
+              "availability",
+              "closed",
+              "reopened"
+            ]
+          },
+          "at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "stations": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/StationV2"
+            }
+          }
+        },
+        "required": [
+          "id",
+          "type",
+          "at",
+          "stations"
+        ]
+      },
+      "Aggregate": {
+        "type": "object",
+        "properties": {
+          "min": {
+            "type": "integer"
+          },
+          "max": {
+            "type": "integer"
+          },
+          "avg": {
+            "type": "number"
+          }
+        },
+        "required": [
+          "min",
+          "max",
+          "avg"
+        ]
+      },
+      "HistoryBucket": {
+        "type": "object",
+        "properties": {
+          "from": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "to": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "samples": {
+            "type": "integer"
+          },
+          "bikes": {
+            "$ref": "#/components/schemas/Aggregate"
+          },
+          "locks": {
+            "$ref": "#/components/schemas/Aggregate"
+          }
+        },
+        "required": [
+          "from",
+          "to",
+          "samples",
+          "bikes",
+          "locks"
+        ]
+      },
+      "Forecast": {
+        "type": "object",
+        "properties": {
+          "station_id": {
+            "type": "integer"
+          },
+          "at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "horizon": {
+            "type": "number",
+            "description": "The horizon in seconds"
+          },
+          "bikes": {
+            "type": "number"
+          },
+          "locks": {
+            "type": "number"
+          },
+          "bike_probability": {
+            "type": "number"
+          },
+          "lock_probability": {
+            "type": "number"
+          }
+        },
+        "required": [
+          "station_id",
+          "at",
+          "horizon",
+          "bikes",
+          "locks",
+          "bike_probability",
+          "lock_probability"
+        ]
+      },
+      "Trip": {
+        "type": "object",
+        "properties": {
+          "pickup": {
+            "$ref": "#/components/schemas/Station"
+          },
+          "dropoff": {
+            "$ref": "#/components/schemas/Station"
+          },
+          "walk_to_pickup": {
+            "type": "number"
+          },
+          "ride": {
+            "type": "number"
+          },
+          "walk_from_dropoff": {
+            "type": "number"
+          },
+          "duration": {
+            "type": "number",
+            "description": "The duration in seconds"
+          },
+          "reasons": {
+            "type": "array",
+            "items": {
+              "type": "string"
+            },
+            "nullable": true
+          }
+        },
+        "required": [
+          "pickup",
+          "dropoff",
+          "walk_to_pickup",
+          "ride",
+          "walk_from_dropoff",
+          "duration",
+          "reasons"
+        ]
+      },
+      "StationBalance": {
+        "type": "object",
+        "properties": {
+          "station": {
+            "$ref": "#/components/schemas/Station"
+          },
+          "level": {
+            "type": "string",
+            "enum": [
+              "balanced",
+              "empty",
+              "nearly_empty",
+              "nearly_full",
+              "full"
+            ]
+          },
+          "bikes": {
+            "type": "integer"
+          },
+          "locks": {
+            "type": "integer"
+          },
+          "projected": {
+            "type": "number"
+          },
+          "target": {
+            "type": "integer"
+          }
+        },
+        "required": [
+          "station",
+          "level",
+          "bikes",
+          "locks",
+          "projected",
+          "target"
+        ]
+      },
+      "Transfer": {
+        "type": "object",
+        "properties": {
+          "from": {
+            "$ref": "#/components/schemas/Station"
+          },
+          "to": {
+            "$ref": "#/components/schemas/Station"
+          },
+          "count": {
+            "type": "integer"
+          },
+          "distance": {
+            "type": "number"
+          }
+        },
+        "required": [
+          "from",
+          "to",
+          "count",
+          "distance"
+        ]
+      },
+      "Rebalance": {
+        "type": "object",
+        "properties": {
+          "stations": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/StationBalance"
+            },
+            "nullable": true
+          },
+          "transfers": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Transfer"
+            },
+            "nullable": true
+          }
+        },
+        "required": [
+          "stations",
+          "transfers"
+        ]
+      },
+      "Percentile": {
+        "type": "object",
+        "properties": {
+          "percentile": {
+            "type": "number"
+          },
+          "value": {
+            "type": "number"
+          }
+        },
+        "required": [
+          "percentile",
+          "value"
+        ]
+      },
+      "Stats": {
+        "type": "object",
+        "properties": {
+          "updated_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "stations": {
+            "type": "integer"
+          },
+          "open": {
+            "type": "integer"
+          },
+          "closed": {
+            "type": "integer"
+          },
+          "empty": {
+            "type": "integer"
+          },
+          "full": {
+            "type": "integer"
+          },
+          "bikes": {
+            "type": "integer"
+          },
+          "locks": {
+            "type": "integer"
+          },
+          "capacity": {
+            "type": "integer"
+          },
+          "bikes_in_use_estimate": {
+            "type": "integer",
+            "description": "The capacity less the bikes and the free locks of the open stations, assuming that every lock that is neither free nor has a bike is waiting for a bike that is out, while it may be out of order"
+          },
+          "utilisation": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Percentile"
+            },
+            "nullable": true
+          }
+        },
+        "required": [
+          "updated_at",
+          "stations",
+          "open",
+          "closed",
+          "empty",
+          "full",
+          "bikes",
+          "locks",
+          "capacity",
+          "bikes_in_use_estimate",
+          "utilisation"
+        ]
+      },
+      "Geometry": {
+        "type": "object",
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "Point",
+              "Polygon"
+            ]
+          },
+          "coordinates": {
+            "oneOf": [
+              {
+                "type": "array",
+                "items": {
+                  "type": "number"
+                }
+              },
+              {
+                "type": "array",
+                "items": {
+                  "type": "array",
+                  "items": {
+                    "type": "array",
+                    "items": {
+                      "type": "number"
+                    }
+                  }
+                }
+              }
+            ]
+          }
+        },
+        "required": [
+          "type",
+          "coordinates"
+        ]
+      },
+      "FeatureProperties": {
+        "type": "object",
+        "properties": {
+          "id": {
+            "type": "integer"
+          },
+          "title": {
+            "type": "string"
+          },
+          "subtitle": {
+            "type": "string"
+          },
+          "in_service": {
+            "type": "boolean"
+          },
+          "number_of_locks": {
+            "type": "integer"
+          },
+          "bikes": {
+            "type": "integer"
+          },
+          "locks": {
+            "type": "integer"
+          },
+          "closed": {
+            "type": "boolean"
+          },
+          "updated_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "distance": {
+            "type": "number",
+            "description": "The distance in metres, for nearby stations"
+          },
+          "bounds": {
+            "$ref": "#/components/schemas/Geometry"
+          }
+        },
+        "required": [
+          "id",
+          "title",
+          "subtitle",
+          "in_service",
+          "number_of_locks",
+          "bikes",
+          "locks",
+          "closed"
+        ]
+      },
+      "Feature": {
+        "type": "object",
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "Feature"
+            ]
+          },
+          "id": {
+            "type": "integer"
+          },
+          "geometry": {
+            "$ref": "#/components/schemas/Geometry"
+          },
+          "properties": {
+            "$ref": "#/components/schemas/FeatureProperties"
+          }
+        },
+        "required": [
+          "type",
+          "id",
+          "geometry",
+          "properties"
+        ]
+      },
+      "FeatureCollection": {
+        "type": "object",
+        "properties": {
+          "type": {
+            "type": "string",
+            "enum": [
+              "FeatureCollection"
+            ]
+          },
+          "features": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/Feature"
+            }
+          }
+        },
+        "required": [
+          "type",
+          "features"
+        ]
+      },
+      "GraphQLRequest": {
+        "type": "object",
+        "properties": {
+          "query": {
+            "type": "string"
+          },
+          "operationName": {
+            "type": "string"
+          },
+          "variables": {
+            "type": "object",
+            "additionalProperties": true,
+            "nullable": true
+          }
+        },
+        "required": [
+          "query"
+        ]
+      },
+      "GraphQLError": {
+        "type": "object",
+        "properties": {
+          "message": {
+            "type": "string"
+          },
+          "path": {
+            "type": "array",
+            "items": {
+              "oneOf": [
+                {
+                  "type": "string"
+                },
+                {
+                  "type": "integer"
+                }
+              ]
+            }
+          }
+        },
+        "required": [
+          "message"
+        ]
+      },
+      "GraphQLResponse": {
+        "type": "object",
+        "properties": {
+          "data": {
+            "type": "object",
+            "additionalProperties": true,
+            "nullable": true
+          },
+          "errors": {
+            "type": "array",
+            "items": {
+              "$ref": "#/components/schemas/GraphQLError"
+            }
+          }
+        },
+        "required": [
+          "data"
+        ]
+      },
+      "FeedStatus": {
+        "type": "object",
+        "description": "The calls to one of the feeds of the upstream API, the age is the number of seconds since the last success",
+        "properties": {
+          "last_success": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          },
+          "last_error": {
+            "type": "string"
+          },
+          "last_error_at": {
+            "type": "string",
+            "format": "date-time"
+          },
+          "age": {
+            "type": "number",
+            "nullable": true
+          }
+        },
+        "required": [
+          "last_success",
+          "age"
+        ]
+      },
+      "Status": {
+        "type": "object",
+        "description": "How up to date the stations are, the age is the number of seconds since the availability was updated",
+        "properties": {
+          "ready": {
+            "type": "boolean"
+          },
+          "reason": {
+            "type": "string"
+          },
+          "loaded": {
+            "type": "boolean"
+          },
+          "stale": {
+            "type": "boolean",
+            "description": "The upstream API could not be reached, so the last stations are served"
+          },
+          "updated_at": {
+            "type": "string",
+            "format": "date-time",
+            "nullable": true
+          },
+          "age": {
+            "type": "number",
+            "nullable": true
+          },
+          "all_stations_closed": {
+            "type": "boolean"
+          },
+          "feeds": {
+            "type": "object",
+            "description": "The feeds by name, i.e., stations, availability and status",
+            "additionalProperties": {
+              "$ref": "#/components/schemas/FeedStatus"
+            }
+          }
+        },
+        "required": [
+          "ready",
+          "loaded",
+          "stale",
+          "updated_at",
+          "age",
+          "all_stations_closed",
+          "feeds"
+        ]
+      },
+      "Readiness": {
+        "type": "object",
+        "properties": {
+          "ready": {
+            "type": "boolean"
+          },
+          "reason": {
+            "type": "string"
+          }
+        },
+        "required": [
+          "ready"
+        ]
+      },
+      "Liveness": {
+        "type": "object",
+        "properties": {
+          "status": {
+            "type": "string",
+            "enum": [
+              "ok"
+            ]
+          }
+        },
+        "required": [
+          "status"
+        ]
+      }
+    },
+    "parameters": {
+      "identifier": {
+        "name": "identifier",
+        "in": "path",
+        "required": true,
+        "description": "The ID of the station",
+        "schema": {
+          "type": "integer"
+        }
+      },
+      "lat": {
+        "name": "lat",
+        "in": "query",
+        "description": "The latitude of the position",
+        "schema": {
+          "type": "number",
+          "minimum": -90,
+          "maximum": 90
+        },
+        "required": true
+      },
+      "lon": {
+        "name": "lon",
+        "in": "query",
+        "description": "The longitude of the position",
+        "schema": {
+          "type": "number",
+          "minimum": -180,
+          "maximum": 180
+        },
+        "required": true
+      },
+      "If-None-Match": {
+        "name": "If-None-Match",
+        "in": "header",
+        "description": "The entity tags of the representations the client has, the response is 304 Not Modified when one of them is current.",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "If-Modified-Since": {
+        "name": "If-Modified-Since",
+        "in": "header",
+        "description": "The response is 304 Not Modified when the stations have not been updated since, ignored when If-None-Match is provided.",
+        "schema": {
+          "type": "string"
+        }
+      }
+    },
+    "headers": {
+      "ETag": {
+        "description": "A weak entity tag derived from the version of the stations and the representation of the response",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "Last-Modified": {
+        "description": "When the availability of the stations was last updated",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "Cache-Control": {
+        "description": "How long the response can be cached, which is the refresh rate of the upstream, it is private when API keys are configured",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "X-Request-ID": {
+        "description": "The ID of the request, as provided by the client or generated",
+        "schema": {
+          "type": "string"
+        }
+      },
+      "Retry-After": {
+        "description": "The number of seconds to wait before the request is retried",
+        "schema": {
+          "type": "integer"
+        }
+      }
+    },
+    "responses": {
+      "BadRequest": {
+        "description": "The request could not be decoded or is out of range",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        },
+        "headers": {
+          "X-Request-ID": {
+            "$ref": "#/components/headers/X-Request-ID"
+          }
+        }
+      },
+      "NotFound": {
+        "description": "The station does not exist",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        },
+        "headers": {
+          "X-Request-ID": {
+            "$ref": "#/components/headers/X-Request-ID"
+          }
+        }
+      },
+      "InternalError": {
+        "description": "Reading from the upstream API failed",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        },
+        "headers": {
+          "X-Request-ID": {
+            "$ref": "#/components/headers/X-Request-ID"
+          }
+        }
+      },
+      "Unavailable": {
+        "description": "The feature is not enabled, e.g., history or forecasting",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        },
+        "headers": {
+          "X-Request-ID": {
+            "$ref": "#/components/headers/X-Request-ID"
+          }
+        }
+      },
+      "NotModified": {
+        "description": "The stations have not changed since the client last requested them",
+        "headers": {
+          "ETag": {
+            "$ref": "#/components/headers/ETag"
+          },
+          "Last-Modified": {
+            "$ref": "#/components/headers/Last-Modified"
+          },
+          "Cache-Control": {
+            "$ref": "#/components/headers/Cache-Control"
+          }
+        }
+      },
+      "Unauthorized": {
+        "description": "The API key is unknown, or missing while it is required",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        },
+        "headers": {
+          "X-Request-ID": {
+            "$ref": "#/components/headers/X-Request-ID"
+          }
+        }
+      },
+      "TooManyRequests": {
+        "description": "The limit of requests has been exceeded",
+        "content": {
+          "application/json": {
+            "schema": {
+              "$ref": "#/components/schemas/Error"
+            }
+          }
+        },
+        "headers": {
+          "Retry-After": {
+            "$ref": "#/components/headers/Retry-After"
+          },
+          "X-Request-ID": {
+            "$ref": "#/components/headers/X-Request-ID"
+          }
+        }
+      }
+    },
+    "securitySchemes": {
+      "apiKeyHeader": {
+        "type": "apiKey",
+        "in": "header",
+        "name": "X-API-Key"
+      },
+      "apiKeyQuery": {
+        "type": "apiKey",
+        "in": "query",
+        "name": "api_key"
+      }
+    }
+  }
+}
+`
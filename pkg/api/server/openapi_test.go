@@ -0,0 +1,340 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"mime"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+	"github.com/stretchr/testify/assert"
+)
+
+// spec is the parsed OpenAPI document, it is navigated by
+// the keys of the document, following any references
+type spec map[string]interface{}
+
+func loadSpec(t *testing.T) spec {
+	var res spec
+	err := json.Unmarshal([]byte(openAPISpec), &res)
+	if err != nil {
+		t.Fatalf("failed to parse the OpenAPI document: %s", err)
+	}
+	return res
+}
+
+// resolve follows the reference of the node, if it has one
+func (s spec) resolve(node interface{}) map[string]interface{} {
+	m, _ := node.(map[string]interface{})
+	ref, ok := m["$ref"].(string)
+	if !ok {
+		return m
+	}
+	var res interface{} = map[string]interface{}(s)
+	for _, key := range strings.Split(strings.TrimPrefix(ref, "#/"), "/") {
+		res = res.(map[string]interface{})[key]
+	}
+	return s.resolve(res)
+}
+
+// operation returns the description of the method on the route pattern
+func (s spec) operation(pattern, method string) map[string]interface{} {
+	paths := s["paths"].(map[string]interface{})
+	path, _ := paths[pattern].(map[string]interface{})
+	op, _ := path[strings.ToLower(method)].(map[string]interface{})
+	return op
+}
+
+// routePattern normalises the pattern of a route, chi
+// marks where a route is mounted on another with a *
+func routePattern(route string) string {
+	return strings.Replace(route, "/*/", "/", -1)
+}
+
+func TestOpenAPI_Routes(t *testing.T) {
+	s := loadSpec(t)
+	handlers := MakeHandlers(MakeEndpoints(Services{}))
+
+	var routes []string
+	for _, router := range []http.Handler{AttachRoutes(handlers, log.NewNopLogger()), AttachProbes(handlers)} {
+		err := chi.Walk(router.(chi.Routes), func(method, route string, _ http.Handler, _ ...func(http.Handler) http.Handler) error {
+			routes = append(routes, fmt.Sprintf("%s %s", method, routePattern(route)))
+			return nil
+		})
+		assert.Nil(t, err)
+	}
+
+	var documented []string
+	for pattern, path := range s["paths"].(map[string]interface{}) {
+		for method := range path.(map[string]interface{}) {
+			documented = append(documented, fmt.Sprintf("%s %s", strings.ToUpper(method), pattern))
+		}
+	}
+
+	sort.Strings(routes)
+	sort.Strings(documented)
+	assert.Equal(t, routes, documented, "Every route is documented, and nothing else")
+}
+
+func TestOpenAPI_Served(t *testing.T) {
+	router := AttachRoutes(MakeHandlers(MakeEndpoints(Services{})), log.NewNopLogger())
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.JSONEq(t, openAPISpec, recorder.Body.String())
+	assertMatchesSpec(t, router.(chi.Routes), httptest.NewRequest(http.MethodGet, "/v1/openapi.json", nil), recorder)
+}
+
+// assertMatchesSpec ensures that the request and the recorded
+// response are described by the OpenAPI document
+func assertMatchesSpec(t *testing.T, router chi.Routes, req *http.Request, recorder *httptest.ResponseRecorder) {
+	// The router rejects the methods that are not routed
+	if recorder.Code == http.StatusMethodNotAllowed {
+		return
+	}
+
+	s := loadSpec(t)
+	rctx := chi.NewRouteContext()
+	if !router.Match(rctx, req.Method, req.URL.Path) {
+		t.Errorf("%s %s: no route matches", req.Method, req.URL.Path)
+		return
+	}
+	pattern := routePattern(rctx.RoutePattern())
+	op := s.operation(pattern, req.Method)
+	if op == nil {
+		t.Errorf("%s %s: not documented", req.Method, pattern)
+		return
+	}
+
+	params := map[string]bool{}
+	for _, p := range s.parameters(pattern, op) {
+		param := s.resolve(p)
+		params[fmt.Sprintf("%s:%s", param["in"], param["name"])] = true
+	}
+	for _, scheme := range s.securitySchemes(op) {
+		params[fmt.Sprintf("%s:%s", scheme["in"], scheme["name"])] = true
+	}
+	for name := range req.URL.Query() {
+		if !params["query:"+name] {
+			t.Errorf("%s %s: query param %s is not documented", req.Method, pattern, name)
+		}
+	}
+
+	responses := op["responses"].(map[string]interface{})
+	response := s.resolve(responses[strconv.Itoa(recorder.Code)])
+	if response == nil {
+		t.Errorf("%s %s: response %d is not documented", req.Method, pattern, recorder.Code)
+		return
+	}
+
+	content, _ := response["content"].(map[string]interface{})
+	if content == nil {
+		return
+	}
+	mediaType, _, _ := mime.ParseMediaType(recorder.Header().Get("Content-Type"))
+	media, ok := content[mediaType].(map[string]interface{})
+	if !ok {
+		t.Errorf("%s %s: response %d as %s is not documented", req.Method, pattern, recorder.Code, mediaType)
+		return
+	}
+	if !strings.HasSuffix(mediaType, "json") {
+		return
+	}
+
+	var body interface{}
+	err := json.Unmarshal(recorder.Body.Bytes(), &body)
+	if err != nil {
+		t.Errorf("%s %s: response %d is not JSON: %s", req.Method, pattern, recorder.Code, err)
+		return
+	}
+	for _, problem := range s.validate(media["schema"], body, "body") {
+		t.Errorf("%s %s: response %d: %s", req.Method, pattern, recorder.Code, problem)
+	}
+}
+
+// parameters returns the parameters of the operation, including
+// those shared by every method of the path
+func (s spec) parameters(pattern string, op map[string]interface{}) []interface{} {
+	path, _ := s["paths"].(map[string]interface{})[pattern].(map[string]interface{})
+	shared, _ := path["parameters"].([]interface{})
+	params, _ := op["parameters"].([]interface{})
+	return append(append([]interface{}{}, shared...), params...)
+}
+
+// securitySchemes returns the schemes the operation can be
+// authenticated with, those of the document are the default
+func (s spec) securitySchemes(op map[string]interface{}) []map[string]interface{} {
+	requirements, hasKey := op["security"].([]interface{})
+	if !hasKey {
+		requirements, _ = s["security"].([]interface{})
+	}
+	components, _ := s["components"].(map[string]interface{})
+	schemes, _ := components["securitySchemes"].(map[string]interface{})
+
+	var res []map[string]interface{}
+	for _, requirement := range requirements {
+		for name := range requirement.(map[string]interface{}) {
+			res = append(res, s.resolve(schemes[name]))
+		}
+	}
+	return res
+}
+
+// validate returns the problems with the value, according to the
+// schema, objects must not have properties that are not described
+func (s spec) validate(node interface{}, value interface{}, at string) []string {
+	schema := s.resolve(node)
+	if schema == nil {
+		return nil
+	}
+
+	if oneOf, ok := schema["oneOf"].([]interface{}); ok {
+		matches := 0
+		for _, option := range oneOf {
+			if len(s.validate(option, value, at)) == 0 {
+				matches++
+			}
+		}
+		if matches != 1 {
+			return []string{fmt.Sprintf("%s: matches %d of the schemas, expected one", at, matches)}
+		}
+		return nil
+	}
+
+	if value == nil {
+		if nullable, _ := schema["nullable"].(bool); nullable {
+			return nil
+		}
+		return []string{fmt.Sprintf("%s: must not be null", at)}
+	}
+
+	if enum, ok := schema["enum"].([]interface{}); ok {
+		found := false
+		for _, option := range enum {
+			found = found || option == value
+		}
+		if !found {
+			return []string{fmt.Sprintf("%s: %v is not one of %v", at, value, enum)}
+		}
+	}
+
+	var problems []string
+	switch schema["type"] {
+	case "object":
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an object, got %T", at, value)}
+		}
+		props, _ := schema["properties"].(map[string]interface{})
+		required, _ := schema["required"].([]interface{})
+		for _, name := range required {
+			if _, hasKey := obj[name.(string)]; !hasKey {
+				problems = append(problems, fmt.Sprintf("%s: missing required property %s", at, name))
+			}
+		}
+		// Additional properties are either allowed, or described by a schema
+		additional, _ := schema["additionalProperties"].(bool)
+		additionalSchema, _ := schema["additionalProperties"].(map[string]interface{})
+		for name, v := range obj {
+			prop, hasKey := props[name]
+			if !hasKey && additionalSchema != nil {
+				prop, hasKey = additionalSchema, true
+			}
+			if !hasKey {
+				if !additional {
+					problems = append(problems, fmt.Sprintf("%s: property %s is not documented", at, name))
+				}
+				continue
+			}
+			problems = append(problems, s.validate(prop, v, at+"."+name)...)
+		}
+	case "array":
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected an array, got %T", at, value)}
+		}
+		for i, item := range items {
+			problems = append(problems, s.validate(schema["items"], item, fmt.Sprintf("%s[%d]", at, i))...)
+		}
+	case "string":
+		if _, ok := value.(string); !ok {
+			return []string{fmt.Sprintf("%s: expected a string, got %T", at, value)}
+		}
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return []string{fmt.Sprintf("%s: expected a boolean, got %T", at, value)}
+		}
+	case "number", "integer":
+		f, ok := value.(float64)
+		if !ok {
+			return []string{fmt.Sprintf("%s: expected a number, got %T", at, value)}
+		}
+		if schema["type"] == "integer" && f != math.Trunc(f) {
+			return []string{fmt.Sprintf("%s: expected an integer, got %g", at, f)}
+		}
+	}
+	return problems
+}
+
+func TestSpec_Validate(t *testing.T) {
+	s := loadSpec(t)
+	schemaRef := func(name string) map[string]interface{} {
+		return map[string]interface{}{"$ref": "#/components/schemas/" + name}
+	}
+
+	testCases := []struct {
+		name   string
+		schema interface{}
+		value  string
+		expect []string
+	}{
+		{
+			name:   "Valid",
+			schema: schemaRef("Error"),
+			value:  `{"message": "uh oh", "code": 500, "type": "io"}`,
+		},
+		{
+			name:   "Missing and undocumented properties",
+			schema: schemaRef("Error"),
+			value:  `{"message": "uh oh", "type": "io", "status": 500}`,
+			expect: []string{"body: missing required property code", "body: property status is not documented"},
+		},
+		{
+			name:   "Wrong types and enums",
+			schema: schemaRef("Error"),
+			value:  `{"message": 1, "code": 1.5, "type": "oops"}`,
+			expect: []string{"body.code: expected an integer, got 1.5", "body.message: expected a string, got float64", "body.type: oops is not one of [notfound unmarshal marshal io unavailable unauthorized toomanyrequests]"},
+		},
+		{
+			name:   "Nullable",
+			schema: schemaRef("Station"),
+			value:  `{"id": 1, "in_service": true, "title": "", "subtitle": "", "number_of_locks": 1, "center": null, "bounds": null}`,
+			expect: []string{"body.center: must not be null"},
+		},
+		{
+			name:   "One of",
+			schema: schemaRef("Geometry"),
+			value:  `{"type": "Point", "coordinates": [[1, 2]]}`,
+			expect: []string{"body.coordinates: matches 0 of the schemas, expected one"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			var value interface{}
+			err := json.Unmarshal([]byte(tc.value), &value)
+			assert.Nil(t, err)
+			got := s.validate(tc.schema, value, "body")
+			sort.Strings(got)
+			assert.Equal(t, tc.expect, got)
+		})
+	}
+}
@@ -0,0 +1,204 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"google.golang.org/grpc"
+)
+
+// Note: on shutdown the server stops accepting connections and waits
+// for the requests in flight to complete. The streams would never
+// complete by themselves, so they must be ended by the functions
+// registered with http.Server.RegisterOnShutdown, e.g., by closing
+// the stream broker, which is also where background work is stopped.
+// The websocket sessions have taken over their connections, so the
+// server no longer knows about them, the handlers track them instead
+// and they are waited for here, or closed when they take too long.
+// A gRPC server is shut down at the same time, and given the same
+// grace period, its streams are ended by the same functions.
+
+// Sessions tracks the websocket sessions of a server, they have
+// taken over their connections, so Run waits for them to end and
+// closes their connections when they outlive the grace period
+type Sessions struct {
+	mutex   sync.Mutex
+	count   int
+	conns   map[io.Closer]bool
+	changed chan struct{}
+}
+
+func newSessions() *Sessions {
+	return &Sessions{
+		conns:   map[io.Closer]bool{},
+		changed: make(chan struct{}),
+	}
+}
+
+// add adds the delta to the count of sessions
+func (g *Sessions) add(delta int) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.count += delta
+	close(g.changed)
+	g.changed = make(chan struct{})
+}
+
+// hold tracks the connection of a session, until it is released
+func (g *Sessions) hold(conn io.Closer) (release func()) {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	g.conns[conn] = true
+	return func() {
+		g.mutex.Lock()
+		defer g.mutex.Unlock()
+		delete(g.conns, conn)
+	}
+}
+
+// close closes the connections of the sessions that are still running
+func (g *Sessions) close() {
+	g.mutex.Lock()
+	defer g.mutex.Unlock()
+
+	for conn := range g.conns {
+		conn.Close()
+	}
+}
+
+// wait blocks until every session has ended, or the context is done
+func (g *Sessions) wait(ctx context.Context) error {
+	for {
+		g.mutex.Lock()
+		count, changed := g.count, g.changed
+		g.mutex.Unlock()
+		if count == 0 {
+			return nil
+		}
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// RunOption configures what else is served alongside the HTTP server
+type RunOption func(*runner)
+
+type runner struct {
+	sessions     *Sessions
+	grpcServer   *grpc.Server
+	grpcListener net.Listener
+}
+
+// WithSessions waits for the websocket sessions of the server as well,
+// they are those of the handlers the server serves, see MakeHandlers
+func WithSessions(sessions *Sessions) RunOption {
+	return func(r *runner) {
+		r.sessions = sessions
+	}
+}
+
+// WithGRPC serves the gRPC server on the listener as well
+func WithGRPC(server *grpc.Server, listener net.Listener) RunOption {
+	return func(r *runner) {
+		r.grpcServer = server
+		r.grpcListener = listener
+	}
+}
+
+// Run serves the HTTP server on the listener until the context is
+// cancelled or the process receives SIGINT or SIGTERM, it then shuts
+// the server down, giving the requests in flight and the websocket
+// sessions the grace period to complete. An error is returned if the
+// server fails to serve, or if the requests did not complete in time,
+// in which case the remaining connections, including those of the
+// websocket sessions, are closed.
+func Run(ctx context.Context, server *http.Server, listener net.Listener, grace time.Duration, logger log.Logger, options ...RunOption) error {
+	r := &runner{sessions: newSessions()}
+	for _, option := range options {
+		option(r)
+	}
+
+	signals := make(chan os.Signal, 1)
+	signal.Notify(signals, syscall.SIGINT, syscall.SIGTERM)
+	defer signal.Stop(signals)
+
+	servers := 1
+	errs := make(chan error, 2)
+	go func() {
+		errs <- server.Serve(listener)
+	}()
+	logger.Log("msg", "server is listening", "addr", listener.Addr())
+	if r.grpcServer != nil {
+		servers++
+		go func() {
+			errs <- r.grpcServer.Serve(r.grpcListener)
+		}()
+		logger.Log("msg", "grpc server is listening", "addr", r.grpcListener.Addr())
+	}
+
+	select {
+	case err := <-errs:
+		server.Close()
+		r.sessions.close()
+		if r.grpcServer != nil {
+			r.grpcServer.Stop()
+		}
+		return fmt.Errorf("failed to serve: %s", err)
+	case <-ctx.Done():
+		logger.Log("msg", "shutting down", "reason", ctx.Err(), "grace", grace)
+	case sig := <-signals:
+		logger.Log("msg", "shutting down", "reason", sig, "grace", grace)
+	}
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), grace)
+	defer cancel()
+	grpcStopped := make(chan struct{})
+	if r.grpcServer != nil {
+		go func() {
+			r.grpcServer.GracefulStop()
+			close(grpcStopped)
+		}()
+	} else {
+		close(grpcStopped)
+	}
+	err := server.Shutdown(shutdownCtx)
+	if err == nil {
+		err = r.sessions.wait(shutdownCtx)
+	}
+	if err == nil {
+		select {
+		case <-grpcStopped:
+		case <-shutdownCtx.Done():
+			err = shutdownCtx.Err()
+		}
+	}
+	if err != nil {
+		server.Close()
+		r.sessions.close()
+		if r.grpcServer != nil {
+			r.grpcServer.Stop()
+		}
+		return fmt.Errorf("failed to complete requests within %s: %s", grace, err)
+	}
+	// Serve returns as soon as the shutdown starts
+	for i := 0; i < servers; i++ {
+		<-errs
+	}
+	logger.Log("msg", "server is shut down")
+	return nil
+}
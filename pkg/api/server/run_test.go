@@ -0,0 +1,309 @@
+package server
+
+import (
+	"context"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/api/pb"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestRun(t *testing.T) {
+	// Connections that were dialed but never used keep the
+	// server from shutting down, so they are not kept around
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	testCases := []struct {
+		Name string
+		// Stop ends the server after a request is in flight
+		Stop      func(cancel context.CancelFunc)
+		Grace     time.Duration
+		Blocking  bool
+		ExpectErr string
+	}{
+		{
+			Name:  "Context is cancelled",
+			Stop:  func(cancel context.CancelFunc) { cancel() },
+			Grace: time.Second,
+		},
+		{
+			Name:  "Process is terminated",
+			Stop:  func(_ context.CancelFunc) { syscall.Kill(syscall.Getpid(), syscall.SIGTERM) },
+			Grace: time.Second,
+		},
+		{
+			Name:      "Request outlives the grace period",
+			Stop:      func(cancel context.CancelFunc) { cancel() },
+			Grace:     50 * time.Millisecond,
+			Blocking:  true,
+			ExpectErr: "failed to complete requests within 50ms: context deadline exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err, tc.Name)
+		url := "http://" + listener.Addr().String()
+
+		// The in flight request completes once the shutdown has
+		// started, like a stream, unless it is blocking
+		started, shutdown, release := make(chan struct{}, 1), make(chan struct{}), make(chan struct{})
+		server := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == "/slow" {
+					started <- struct{}{}
+					if tc.Blocking {
+						<-release
+					} else {
+						<-shutdown
+					}
+				}
+				w.Write([]byte("done"))
+			}),
+		}
+		server.RegisterOnShutdown(func() { close(shutdown) })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- Run(ctx, server, listener, tc.Grace, log.NewNopLogger())
+		}()
+
+		// The signals are handled once the server is serving
+		res, err := client.Get(url + "/fast")
+		assert.Nil(t, err, tc.Name)
+		res.Body.Close()
+
+		type result struct {
+			Body string
+			Err  error
+		}
+		inFlight := make(chan result, 1)
+		go func() {
+			res, err := client.Get(url + "/slow")
+			if err != nil {
+				inFlight <- result{Err: err}
+				return
+			}
+			defer res.Body.Close()
+			body, err := ioutil.ReadAll(res.Body)
+			inFlight <- result{Body: string(body), Err: err}
+		}()
+		<-started
+		tc.Stop(cancel)
+
+		select {
+		case err = <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: server did not stop", tc.Name)
+		}
+		got := <-inFlight
+		if len(tc.ExpectErr) > 0 {
+			assert.EqualError(t, err, tc.ExpectErr, tc.Name)
+			assert.Error(t, got.Err, tc.Name)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Nil(t, got.Err, tc.Name)
+			assert.Equal(t, "done", got.Body, tc.Name)
+		}
+
+		_, err = client.Get(url + "/fast")
+		assert.Error(t, err, "%s: no longer accepting connections", tc.Name)
+		close(release)
+		cancel()
+	}
+}
+
+func TestRun_Sessions(t *testing.T) {
+	client := &http.Client{Transport: &http.Transport{DisableKeepAlives: true}}
+
+	testCases := []struct {
+		Name      string
+		Grace     time.Duration
+		Blocking  bool
+		ExpectErr string
+	}{
+		{
+			Name:  "Session ends within the grace period",
+			Grace: time.Second,
+		},
+		{
+			Name:      "Session outlives the grace period",
+			Grace:     50 * time.Millisecond,
+			Blocking:  true,
+			ExpectErr: "failed to complete requests within 50ms: context deadline exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err, tc.Name)
+		url := "http://" + listener.Addr().String()
+
+		// The session takes over the connection, like a websocket,
+		// and ends a while after the shutdown has started
+		started, shutdown, release, ended := make(chan struct{}, 1), make(chan struct{}), make(chan struct{}), make(chan struct{})
+		sessions := newSessions()
+		server := &http.Server{
+			Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path != "/session" {
+					w.Write([]byte("done"))
+					return
+				}
+				sessions.add(1)
+				defer sessions.add(-1)
+				conn, _, err := w.(http.Hijacker).Hijack()
+				assert.Nil(t, err, tc.Name)
+				defer conn.Close()
+				defer sessions.hold(conn)()
+				started <- struct{}{}
+				if tc.Blocking {
+					<-release
+				} else {
+					<-shutdown
+					time.Sleep(20 * time.Millisecond)
+				}
+				conn.Write([]byte("HTTP/1.1 200 OK\r\nContent-Length: 4\r\nConnection: close\r\n\r\ndone"))
+				close(ended)
+			}),
+		}
+		server.RegisterOnShutdown(func() { close(shutdown) })
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- Run(ctx, server, listener, tc.Grace, log.NewNopLogger(), WithSessions(sessions))
+		}()
+
+		res, err := client.Get(url + "/fast")
+		assert.Nil(t, err, tc.Name)
+		res.Body.Close()
+
+		inFlight := make(chan string, 1)
+		go func() {
+			res, err := client.Get(url + "/session")
+			if err != nil {
+				inFlight <- err.Error()
+				return
+			}
+			defer res.Body.Close()
+			body, _ := ioutil.ReadAll(res.Body)
+			inFlight <- string(body)
+		}()
+		<-started
+		cancel()
+
+		select {
+		case err = <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: server did not stop", tc.Name)
+		}
+		if len(tc.ExpectErr) > 0 {
+			assert.EqualError(t, err, tc.ExpectErr, tc.Name)
+			// The connection of the session is closed,
+			// before the session has a chance to respond
+			assert.NotEqual(t, "done", <-inFlight, tc.Name)
+			close(release)
+			continue
+		}
+		assert.Nil(t, err, tc.Name)
+		select {
+		case <-ended:
+		default:
+			t.Errorf("%s: server stopped before the session ended", tc.Name)
+		}
+		assert.Equal(t, "done", <-inFlight, tc.Name)
+		assert.Nil(t, sessions.wait(context.Background()), tc.Name)
+	}
+}
+
+func TestRun_GRPC(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Grace     time.Duration
+		Blocking  bool
+		ExpectErr string
+	}{
+		{
+			Name:  "Stream ends within the grace period",
+			Grace: time.Second,
+		},
+		{
+			Name:      "Stream outlives the grace period",
+			Grace:     50 * time.Millisecond,
+			Blocking:  true,
+			ExpectErr: "failed to complete requests within 50ms: context deadline exceeded",
+		},
+	}
+
+	for _, tc := range testCases {
+		listener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err, tc.Name)
+		grpcListener, err := net.Listen("tcp", "127.0.0.1:0")
+		assert.Nil(t, err, tc.Name)
+
+		sub := &openSubscription{
+			events: make(chan api.StationEvent, 1),
+			closed: make(chan struct{}),
+		}
+		sub.events <- api.StationEvent{ID: "e-0", Type: api.EventSnapshot}
+		grpcServer := grpc.NewServer()
+		pb.RegisterStationServiceServer(grpcServer, MakeGRPCServer(MakeEndpoints(Services{Stream: &recordingStreamService{sub: sub}})))
+
+		// Like the broker, the subscriptions end once
+		// the shutdown has started, unless blocking
+		server := &http.Server{Handler: http.NotFoundHandler()}
+		if !tc.Blocking {
+			server.RegisterOnShutdown(func() { close(sub.events) })
+		}
+
+		ctx, cancel := context.WithCancel(context.Background())
+		stopped := make(chan error, 1)
+		go func() {
+			stopped <- Run(ctx, server, listener, tc.Grace, log.NewNopLogger(), WithGRPC(grpcServer, grpcListener))
+		}()
+
+		conn, err := grpc.Dial(grpcListener.Addr().String(), grpc.WithInsecure())
+		assert.Nil(t, err, tc.Name)
+		stream, err := pb.NewStationServiceClient(conn).WatchStations(context.Background(), &pb.WatchStationsRequest{})
+		assert.Nil(t, err, tc.Name)
+		event, err := stream.Recv()
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, "e-0", event.Id, tc.Name)
+		cancel()
+
+		select {
+		case err = <-stopped:
+		case <-time.After(5 * time.Second):
+			t.Fatalf("%s: server did not stop", tc.Name)
+		}
+		if len(tc.ExpectErr) > 0 {
+			assert.EqualError(t, err, tc.ExpectErr, tc.Name)
+		} else {
+			assert.Nil(t, err, tc.Name)
+		}
+		_, err = stream.Recv()
+		assert.Equal(t, codes.Unavailable, status.Code(err), tc.Name)
+		conn.Close()
+	}
+}
+
+func TestRun_FailedToServe(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.Nil(t, err)
+	listener.Close()
+
+	err = Run(context.Background(), &http.Server{}, listener, time.Second, log.NewNopLogger())
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "failed to serve")
+}
@@ -0,0 +1,73 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+type historyService struct {
+	store api.HistoryStore
+}
+
+// Range downsamples the history of the station into buckets
+// of step duration, buckets without samples are left out
+func (s *historyService) Range(ctx context.Context, id int, from, to time.Time, step time.Duration) ([]api.HistoryBucket, error) {
+	samples, err := s.store.Samples(ctx, id, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	buckets := []api.HistoryBucket{}
+	var bikes, locks int
+	for _, sample := range samples {
+		start := from.Add(sample.Time.Sub(from) / step * step)
+		if len(buckets) == 0 || !buckets[len(buckets)-1].From.Equal(start) {
+			buckets = append(buckets, api.HistoryBucket{
+				From:  start,
+				To:    start.Add(step),
+				Bikes: api.Aggregate{Min: sample.Availability.Bikes, Max: sample.Availability.Bikes},
+				Locks: api.Aggregate{Min: sample.Availability.Locks, Max: sample.Availability.Locks},
+			})
+			bikes, locks = 0, 0
+		}
+
+		bucket := &buckets[len(buckets)-1]
+		bucket.Samples++
+		bikes += sample.Availability.Bikes
+		locks += sample.Availability.Locks
+		bucket.Bikes = aggregate(bucket.Bikes, sample.Availability.Bikes, bikes, bucket.Samples)
+		bucket.Locks = aggregate(bucket.Locks, sample.Availability.Locks, locks, bucket.Samples)
+	}
+
+	return buckets, nil
+}
+
+func (s *historyService) At(ctx context.Context, at time.Time, query api.StationQuery) (api.StationList, error) {
+	stations, err := s.store.At(ctx, at)
+	if err != nil {
+		return api.StationList{}, err
+	}
+	return queryStations(stations, query), nil
+}
+
+// aggregate adds the value to the aggregate, given the
+// running sum and count of the values seen so far
+func aggregate(a api.Aggregate, value, sum, count int) api.Aggregate {
+	if value < a.Min {
+		a.Min = value
+	}
+	if value > a.Max {
+		a.Max = value
+	}
+	a.Avg = float64(sum) / float64(count)
+	return a
+}
+
+// NewHistoryService returns an initialised history service
+func NewHistoryService(store api.HistoryStore) api.HistoryService {
+	return &historyService{
+		store: store,
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+type rebalanceService struct {
+	store api.RebalanceStore
+}
+
+func (s *rebalanceService) Plan(ctx context.Context, capacity int) (*api.Rebalance, error) {
+	return s.store.Plan(ctx, capacity)
+}
+
+// NewRebalanceService returns an initialised rebalance service
+func NewRebalanceService(store api.RebalanceStore) api.RebalanceService {
+	return &rebalanceService{
+		store: store,
+	}
+}
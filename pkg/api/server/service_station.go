@@ -2,6 +2,9 @@ package server
 
 import (
 	"context"
+	"sort"
+	"strings"
+	"time"
 
 	"github.com/paulbes/go-pedal/pkg/api"
 )
@@ -14,11 +17,115 @@ type stationService struct {
 }
 
 func (s *stationService) Get(ctx context.Context, id int) (api.Station, error) {
-	return s.store.Get(id)
+	return s.store.Get(ctx, id)
 }
 
-func (s *stationService) List(ctx context.Context) ([]api.Station, error) {
-	return s.store.List()
+func (s *stationService) List(ctx context.Context, query api.StationQuery) (api.StationList, error) {
+	stations, err := s.store.List(ctx)
+	if err != nil {
+		return api.StationList{}, err
+	}
+	return queryStations(stations, query), nil
+}
+
+func (s *stationService) Locate(ctx context.Context, coord api.Coord) (api.Station, error) {
+	return s.store.Locate(ctx, coord)
+}
+
+func (s *stationService) Nearby(ctx context.Context, coord api.Coord, radius float64, limit int) ([]api.NearbyStation, error) {
+	stations, err := s.store.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	res := []api.NearbyStation{}
+	for _, station := range stations {
+		if d := coord.Distance(station.Center); d <= radius {
+			res = append(res, api.NearbyStation{
+				Station:  station,
+				Distance: d,
+			})
+		}
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Distance == res[j].Distance {
+			return res[i].Station.ID < res[j].Station.ID
+		}
+		return res[i].Distance < res[j].Distance
+	})
+	if len(res) > limit {
+		res = res[:limit]
+	}
+	return res, nil
+}
+
+func (s *stationService) Forecast(ctx context.Context, id int, horizon time.Duration) (api.Forecast, error) {
+	return s.store.Forecast(ctx, id, horizon)
+}
+
+func (s *stationService) Version(ctx context.Context) (api.StationVersion, error) {
+	return s.store.Version(ctx)
+}
+
+// queryStations filters, sorts and paginates the stations, the
+// station id breaks ties so the order is stable between pages
+func queryStations(stations []api.Station, query api.StationQuery) api.StationList {
+	title := strings.ToLower(query.Title)
+	matched := []api.Station{}
+	for _, station := range stations {
+		switch {
+		case query.Closed != nil && station.Closed != *query.Closed:
+		case query.InService != nil && station.InService != *query.InService:
+		case station.Availability.Bikes < query.MinBikes:
+		case station.Availability.Locks < query.MinLocks:
+		case len(title) > 0 && !strings.Contains(strings.ToLower(station.Title), title):
+		default:
+			matched = append(matched, station)
+		}
+	}
+
+	less := func(a, b api.Station) int {
+		switch query.Sort {
+		case api.SortByTitle:
+			return strings.Compare(strings.ToLower(a.Title), strings.ToLower(b.Title))
+		case api.SortByBikes:
+			return a.Availability.Bikes - b.Availability.Bikes
+		case api.SortByLocks:
+			return a.Availability.Locks - b.Availability.Locks
+		case api.SortByDistance:
+			da, db := query.Origin.Distance(a.Center), query.Origin.Distance(b.Center)
+			if da < db {
+				return -1
+			} else if da > db {
+				return 1
+			}
+		}
+		return 0
+	}
+	sort.Slice(matched, func(i, j int) bool {
+		c := less(matched[i], matched[j])
+		if query.Descending {
+			c = -c
+		}
+		if c == 0 {
+			return matched[i].ID < matched[j].ID
+		}
+		return c < 0
+	})
+
+	res := api.StationList{
+		Stations: []api.Station{},
+		Total:    len(matched),
+	}
+	if query.Offset >= len(matched) {
+		return res
+	}
+	end := len(matched)
+	if query.Limit > 0 && query.Offset+query.Limit < end {
+		end = query.Offset + query.Limit
+	}
+	res.Stations = matched[query.Offset:end]
+	return res
 }
 
 // NewStationService returns an initialised station service
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestQueryStations(t *testing.T) {
+	newStation := func(id int, title string, bikes, locks int, closed bool, lat float64) api.Station {
+		return api.Station{
+			ID:           id,
+			InService:    true,
+			Title:        title,
+			Center:       api.Coord{Latitude: lat, Longitude: 10.75},
+			Availability: api.Availability{Bikes: bikes, Locks: locks},
+			Closed:       closed,
+		}
+	}
+	stations := []api.Station{
+		newStation(3, "Carl Berners plass", 2, 8, false, 59.93),
+		newStation(1, "Aker Brygge", 5, 5, false, 59.91),
+		newStation(4, "Bislett", 5, 0, true, 59.92),
+		newStation(2, "Alexander Kiellands plass", 0, 10, false, 59.928),
+	}
+	yes, no := true, false
+	origin := api.Coord{Latitude: 59.93, Longitude: 10.75}
+
+	testCases := []struct {
+		Name        string
+		Query       api.StationQuery
+		Expect      []int
+		ExpectTotal int
+	}{
+		{
+			Name:        "Sorted by id by default",
+			Expect:      []int{1, 2, 3, 4},
+			ExpectTotal: 4,
+		},
+		{
+			Name:        "Filter closed",
+			Query:       api.StationQuery{Closed: &no},
+			Expect:      []int{1, 2, 3},
+			ExpectTotal: 3,
+		},
+		{
+			Name:        "Filter in service",
+			Query:       api.StationQuery{InService: &yes, Closed: &yes},
+			Expect:      []int{4},
+			ExpectTotal: 1,
+		},
+		{
+			Name:        "Filter bikes and locks",
+			Query:       api.StationQuery{MinBikes: 2, MinLocks: 1},
+			Expect:      []int{1, 3},
+			ExpectTotal: 2,
+		},
+		{
+			Name:        "Search title",
+			Query:       api.StationQuery{Title: "PLASS"},
+			Expect:      []int{2, 3},
+			ExpectTotal: 2,
+		},
+		{
+			Name:        "Sort by title",
+			Query:       api.StationQuery{Sort: api.SortByTitle},
+			Expect:      []int{1, 2, 4, 3},
+			ExpectTotal: 4,
+		},
+		{
+			Name:        "Sort by bikes descending, ties by id",
+			Query:       api.StationQuery{Sort: api.SortByBikes, Descending: true},
+			Expect:      []int{1, 4, 3, 2},
+			ExpectTotal: 4,
+		},
+		{
+			Name:        "Sort by locks",
+			Query:       api.StationQuery{Sort: api.SortByLocks},
+			Expect:      []int{4, 1, 3, 2},
+			ExpectTotal: 4,
+		},
+		{
+			Name:        "Sort by distance",
+			Query:       api.StationQuery{Sort: api.SortByDistance, Origin: &origin},
+			Expect:      []int{3, 2, 4, 1},
+			ExpectTotal: 4,
+		},
+		{
+			Name:        "Paginate",
+			Query:       api.StationQuery{Offset: 1, Limit: 2},
+			Expect:      []int{2, 3},
+			ExpectTotal: 4,
+		},
+		{
+			Name:        "Paginate past the end",
+			Query:       api.StationQuery{Offset: 10, Limit: 2},
+			Expect:      []int{},
+			ExpectTotal: 4,
+		},
+	}
+
+	for _, tc := range testCases {
+		got := queryStations(stations, tc.Query)
+		ids := []int{}
+		for _, station := range got.Stations {
+			ids = append(ids, station.ID)
+		}
+		assert.Equal(t, tc.Expect, ids, tc.Name)
+		assert.Equal(t, tc.ExpectTotal, got.Total, tc.Name)
+	}
+}
+
+// listStore is a station store that lists the provided stations
+type listStore struct {
+	api.StationStore
+	stations []api.Station
+}
+
+func (s listStore) List(context.Context) ([]api.Station, error) {
+	return s.stations, nil
+}
+
+func TestStationService_Nearby(t *testing.T) {
+	station := func(id int, lat float64) api.Station {
+		return api.Station{ID: id, Center: api.Coord{Latitude: lat, Longitude: 10.75}}
+	}
+	service := NewStationService(listStore{stations: []api.Station{
+		station(1, 59.9130),
+		station(2, 59.9110),
+		station(3, 59.9500),
+		station(4, 59.9090),
+	}})
+	origin := api.Coord{Latitude: 59.91, Longitude: 10.75}
+
+	testCases := []struct {
+		Name   string
+		Radius float64
+		Limit  int
+		Expect []int
+	}{
+		{Name: "Closest first within radius", Radius: 500, Limit: 10, Expect: []int{2, 4, 1}},
+		{Name: "Limited", Radius: 500, Limit: 2, Expect: []int{2, 4}},
+		{Name: "Nothing within radius", Radius: 50, Limit: 10, Expect: []int{}},
+	}
+
+	for _, tc := range testCases {
+		got, err := service.Nearby(context.Background(), origin, tc.Radius, tc.Limit)
+		assert.Nil(t, err, tc.Name)
+		ids := []int{}
+		for _, nearby := range got {
+			ids = append(ids, nearby.Station.ID)
+			assert.InDelta(t, origin.Distance(nearby.Station.Center), nearby.Distance, 0.001, tc.Name)
+		}
+		assert.Equal(t, tc.Expect, ids, tc.Name)
+	}
+}
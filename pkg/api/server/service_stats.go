@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+type statsService struct {
+	store api.StatsStore
+}
+
+func (s *statsService) Get(ctx context.Context) (*api.Stats, error) {
+	return s.store.Get(ctx)
+}
+
+// NewStatsService returns an initialised stats service
+func NewStatsService(store api.StatsStore) api.StatsService {
+	return &statsService{
+		store: store,
+	}
+}
@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// MaxReadyAge is the oldest the availability of the
+// stations may be, for the API to be considered ready
+var MaxReadyAge = 5 * time.Minute
+
+type statusService struct {
+	store api.StatusStore
+}
+
+// Get reports the status, the API is ready once the stations
+// have been loaded, as long as they are not too old
+func (s *statusService) Get(ctx context.Context) (api.Status, error) {
+	status, err := s.store.Get(ctx)
+	if err != nil {
+		return api.Status{}, err
+	}
+
+	switch {
+	case !status.Loaded:
+		status.Reason = "the stations have not been loaded"
+	case status.Age != nil && *status.Age > MaxReadyAge.Seconds():
+		status.Reason = fmt.Sprintf("the stations were updated more than %s ago", MaxReadyAge)
+	default:
+		status.Ready = true
+	}
+	return status, nil
+}
+
+// NewStatusService returns an initialised status service
+func NewStatusService(store api.StatusStore) api.StatusService {
+	return &statusService{
+		store: store,
+	}
+}
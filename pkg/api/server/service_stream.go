@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+type streamService struct {
+	store api.StreamStore
+}
+
+func (s *streamService) Subscribe(ctx context.Context, lastEventID string) (api.StationSubscription, error) {
+	return s.store.Subscribe(ctx, lastEventID)
+}
+
+// NewStreamService returns an initialised stream service
+func NewStreamService(store api.StreamStore) api.StreamService {
+	return &streamService{
+		store: store,
+	}
+}
@@ -0,0 +1,22 @@
+package server
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+type tripService struct {
+	store api.TripStore
+}
+
+func (s *tripService) Suggest(ctx context.Context, from, to api.Coord, limit int) ([]api.Trip, error) {
+	return s.store.Suggest(ctx, from, to, limit)
+}
+
+// NewTripService returns an initialised trip service
+func NewTripService(store api.TripStore) api.TripService {
+	return &tripService{
+		store: store,
+	}
+}
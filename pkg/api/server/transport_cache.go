@@ -0,0 +1,156 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// Note: the station responses are derived from the latest snapshot,
+// so they can be validated with its version and cached for as long as
+// the upstream takes to refresh. Historical responses, i.e., those
+// requested with the at param, are not affected.
+
+// cacheHeaders are only kept on successful responses
+var cacheHeaders = []string{"ETag", "Last-Modified", "Cache-Control"}
+
+// varyAccept is added to the Vary header, the other values
+// are left alone, e.g., the Origin of the CORS middleware
+const varyAccept = "Accept"
+
+// Representations of the stations, they are part of the
+// entity tag, as each has its own body
+const (
+	representationJSON    = "json"
+	representationV2      = "v2"
+	representationGeoJSON = "geojson"
+)
+
+// negotiatedRepresentation returns the representation chosen
+// by negotiateGeoJSON for the request
+func negotiatedRepresentation(r *http.Request) string {
+	if acceptsGeoJSON(r.Header.Get("Accept")) {
+		return representationGeoJSON
+	}
+	return representationJSON
+}
+
+// fixedRepresentation returns a function for the handlers
+// that always respond with the same representation
+func fixedRepresentation(representation string) func(r *http.Request) string {
+	return func(*http.Request) string {
+		return representation
+	}
+}
+
+// cacheable adds the caching headers of the last station version
+// to the response, and answers with 304 Not Modified when the client
+// already has that version. The version is that of the snapshot the
+// body is served from, refreshed when due, at worst a refresh lands
+// in between and the client fetches the body again on the next
+// request.
+func cacheable(version endpoint.Endpoint, representation func(r *http.Request) string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if len(r.URL.Query().Get("at")) > 0 {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		// Should the version be unavailable, so is the response
+		// and the handler takes care of the error
+		res, err := version(r.Context(), nil)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+		v := res.(api.StationVersion)
+
+		scope := "public"
+		if isRestricted(r.Context()) {
+			scope = "private"
+		}
+		etag := fmt.Sprintf(`W/"%s-%s"`, v.Tag, representation(r))
+		w.Header().Set("ETag", etag)
+		if !v.UpdatedAt.IsZero() {
+			w.Header().Set("Last-Modified", v.UpdatedAt.UTC().Format(http.TimeFormat))
+		}
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", scope, int(v.RefreshRate.Seconds())))
+		w.Header().Add("Vary", varyAccept)
+
+		if notModified(r, etag, v.UpdatedAt) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		next.ServeHTTP(&cacheResponseWriter{ResponseWriter: w}, r)
+	})
+}
+
+// notModified reports if the conditional headers of the request
+// match the current version, If-None-Match takes precedence over
+// If-Modified-Since as described by RFC 7232
+func notModified(r *http.Request, etag string, updatedAt time.Time) bool {
+	if match := r.Header.Get("If-None-Match"); len(match) > 0 {
+		for _, candidate := range strings.Split(match, ",") {
+			candidate = strings.TrimSpace(candidate)
+			if candidate == "*" || weakTag(candidate) == weakTag(etag) {
+				return true
+			}
+		}
+		return false
+	}
+
+	since, err := http.ParseTime(r.Header.Get("If-Modified-Since"))
+	if err != nil || updatedAt.IsZero() {
+		return false
+	}
+	return !updatedAt.Truncate(time.Second).After(since)
+}
+
+// weakTag strips the weak indicator, so entity tags
+// can be compared with the weak comparison function
+func weakTag(tag string) string {
+	return strings.TrimPrefix(tag, "W/")
+}
+
+// cacheResponseWriter drops the caching headers
+// when the response was not successful
+type cacheResponseWriter struct {
+	http.ResponseWriter
+	wroteHeader bool
+}
+
+func (w *cacheResponseWriter) WriteHeader(code int) {
+	if !w.wroteHeader && code != http.StatusOK {
+		for _, header := range cacheHeaders {
+			w.Header().Del(header)
+		}
+		removeVary(w.Header(), varyAccept)
+	}
+	w.wroteHeader = true
+	w.ResponseWriter.WriteHeader(code)
+}
+
+// removeVary removes a single value from the Vary header,
+// and the header itself once no values remain
+func removeVary(header http.Header, value string) {
+	var kept []string
+	for _, v := range header["Vary"] {
+		if v != value {
+			kept = append(kept, v)
+		}
+	}
+	if len(kept) == 0 {
+		header.Del("Vary")
+		return
+	}
+	header["Vary"] = kept
+}
+
+func (w *cacheResponseWriter) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	return w.ResponseWriter.Write(b)
+}
@@ -0,0 +1,194 @@
+package server
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pkg/api/mock"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/md"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCacheable(t *testing.T) {
+	etag := `W/"1b2d3f4a5c6e7d8f-json"`
+	cachedAs := func(etag, cacheControl string) http.Header {
+		return http.Header{
+			"Etag":          []string{etag},
+			"Last-Modified": []string{"Mon, 01 Oct 2018 08:00:00 GMT"},
+			"Cache-Control": []string{cacheControl},
+			"Vary":          []string{"Accept"},
+		}
+	}
+	cached := cachedAs(etag, "public, max-age=10")
+
+	testCases := []struct {
+		Name         string
+		Path         string
+		Header       http.Header
+		Err          error
+		Access       *AccessConfig
+		Origin       string
+		ExpectCode   int
+		ExpectHeader http.Header
+	}{
+		{
+			Name:         "No conditions",
+			Path:         "/v1/stations/1",
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: cached,
+		},
+		{
+			Name:         "Matching entity tag",
+			Path:         "/v1/stations/1",
+			Header:       http.Header{"If-None-Match": []string{etag}},
+			ExpectCode:   http.StatusNotModified,
+			ExpectHeader: cached,
+		},
+		{
+			Name:         "Matching entity tag, compared weakly",
+			Path:         "/v2/stations/",
+			Header:       http.Header{"If-None-Match": []string{`"abc", "1b2d3f4a5c6e7d8f-v2"`}},
+			ExpectCode:   http.StatusNotModified,
+			ExpectHeader: cachedAs(`W/"1b2d3f4a5c6e7d8f-v2"`, "public, max-age=10"),
+		},
+		{
+			Name:         "Any entity tag",
+			Path:         "/v1/stations.geojson",
+			Header:       http.Header{"If-None-Match": []string{"*"}},
+			ExpectCode:   http.StatusNotModified,
+			ExpectHeader: cachedAs(`W/"1b2d3f4a5c6e7d8f-geojson"`, "public, max-age=10"),
+		},
+		{
+			Name: "Entity tag of another representation",
+			Path: "/v1/stations/1",
+			Header: http.Header{
+				"Accept":        []string{GeoJSONContentType},
+				"If-None-Match": []string{etag},
+			},
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: cachedAs(`W/"1b2d3f4a5c6e7d8f-geojson"`, "public, max-age=10"),
+		},
+		{
+			Name:         "Outdated entity tag",
+			Path:         "/v1/stations/nearby?lat=59&lon=59",
+			Header:       http.Header{"If-None-Match": []string{`W/"abc"`}},
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: cached,
+		},
+		{
+			Name:         "Not modified since",
+			Path:         "/v1/stations/locate?lat=59&lon=59",
+			Header:       http.Header{"If-Modified-Since": []string{"Mon, 01 Oct 2018 08:00:00 GMT"}},
+			ExpectCode:   http.StatusNotModified,
+			ExpectHeader: cached,
+		},
+		{
+			Name:         "Modified since",
+			Path:         "/v1/stations/",
+			Header:       http.Header{"If-Modified-Since": []string{"Mon, 01 Oct 2018 07:59:59 GMT"}},
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: cached,
+		},
+		{
+			Name: "Outdated entity tag takes precedence",
+			Path: "/v1/stations/",
+			Header: http.Header{
+				"If-None-Match":     []string{`W/"abc"`},
+				"If-Modified-Since": []string{"Mon, 01 Oct 2018 08:00:00 GMT"},
+			},
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: cached,
+		},
+		{
+			Name:         "API keys are on",
+			Path:         "/v1/stations/1",
+			Access:       &AccessConfig{Keys: []APIKey{{Name: "test", Key: "abc"}}},
+			Header:       http.Header{APIKeyHeader: []string{"abc"}},
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: cachedAs(etag, "private, max-age=10"),
+		},
+		{
+			Name:       "Varies by origin",
+			Path:       "/v1/stations/1",
+			Origin:     "https://example.com",
+			Header:     http.Header{"Origin": []string{"https://example.com"}},
+			ExpectCode: http.StatusOK,
+			ExpectHeader: http.Header{
+				"Etag":          []string{etag},
+				"Last-Modified": []string{"Mon, 01 Oct 2018 08:00:00 GMT"},
+				"Cache-Control": []string{"public, max-age=10"},
+				"Vary":          []string{"Origin", "Accept"},
+			},
+		},
+		{
+			Name:         "Historical stations",
+			Path:         "/v1/stations/?at=2018-10-01T08:00:00Z",
+			Header:       http.Header{"If-None-Match": []string{"*"}},
+			ExpectCode:   http.StatusOK,
+			ExpectHeader: http.Header{},
+		},
+		{
+			Name:         "Bad request",
+			Path:         "/v1/stations/abc",
+			ExpectCode:   http.StatusBadRequest,
+			ExpectHeader: http.Header{},
+		},
+		{
+			Name:         "Bad request, still varies by origin",
+			Path:         "/v1/stations/abc",
+			Origin:       "https://example.com",
+			Header:       http.Header{"Origin": []string{"https://example.com"}},
+			ExpectCode:   http.StatusBadRequest,
+			ExpectHeader: http.Header{"Vary": []string{"Origin"}},
+		},
+		{
+			Name:         "Storage error",
+			Path:         "/v1/stations/1",
+			Header:       http.Header{"If-None-Match": []string{"*"}},
+			Err:          errors.New(fmt.Errorf("uh oh"), "io error", errors.IO),
+			ExpectCode:   http.StatusInternalServerError,
+			ExpectHeader: http.Header{},
+		},
+	}
+
+	defer func(origins []string) { md.AllowedOrigins = origins }(md.AllowedOrigins)
+	for _, tc := range testCases {
+		station := mock.NewStation()
+		endpoints := MakeEndpoints(Services{
+			Station: NewStationService(mock.NewStationStore(station, tc.Err)),
+			History: NewHistoryService(mock.NewHistoryStore(mock.NewHistorySamples(), station, tc.Err)),
+		})
+		var middlewares []func(http.Handler) http.Handler
+		if tc.Access != nil {
+			middlewares = append(middlewares, RateLimit(tc.Access))
+		}
+		router := AttachRoutes(MakeHandlers(endpoints), log.NewNopLogger(), middlewares...)
+		var handler http.Handler = router
+		if len(tc.Origin) > 0 {
+			md.AllowedOrigins = []string{tc.Origin}
+			handler = md.Cors(router)
+		}
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, tc.Path, nil)
+		for name, values := range tc.Header {
+			req.Header[name] = values
+		}
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.ExpectCode, recorder.Code, tc.Name)
+		for _, name := range cacheHeaders {
+			assert.Equal(t, tc.ExpectHeader.Get(name), recorder.Header().Get(name), fmt.Sprintf("%s: %s", tc.Name, name))
+		}
+		assert.Equal(t, tc.ExpectHeader["Vary"], recorder.Header()["Vary"], fmt.Sprintf("%s: Vary", tc.Name))
+		if tc.ExpectCode == http.StatusNotModified {
+			assert.Empty(t, recorder.Body.String(), tc.Name)
+		}
+		assertMatchesSpec(t, router.(chi.Routes), req, recorder)
+	}
+}
@@ -0,0 +1,158 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"mime"
+	"net/http"
+	"strings"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// Note: the GeoJSON representation (RFC 7946) is meant for map
+// frontends, every station becomes a feature with its center as
+// the geometry and its bounds, when available, as a property.
+
+// GeoJSONContentType is the media type of GeoJSON documents
+const GeoJSONContentType = "application/geo+json"
+
+type geometry struct {
+	Type        string      `json:"type"`
+	Coordinates interface{} `json:"coordinates"`
+}
+
+type featureProperties struct {
+	ID            int        `json:"id"`
+	Title         string     `json:"title"`
+	Subtitle      string     `json:"subtitle"`
+	InService     bool       `json:"in_service"`
+	NumberOfLocks int        `json:"number_of_locks"`
+	Bikes         int        `json:"bikes"`
+	Locks         int        `json:"locks"`
+	Closed        bool       `json:"closed"`
+	UpdatedAt     *time.Time `json:"updated_at,omitempty"`
+	Distance      *float64   `json:"distance,omitempty"`
+	Bounds        *geometry  `json:"bounds,omitempty"`
+}
+
+type feature struct {
+	Type       string            `json:"type"`
+	ID         int               `json:"id"`
+	Geometry   geometry          `json:"geometry"`
+	Properties featureProperties `json:"properties"`
+}
+
+type featureCollection struct {
+	Type     string    `json:"type"`
+	Features []feature `json:"features"`
+}
+
+// position converts a coordinate to a GeoJSON position,
+// which has the longitude first
+func position(coord api.Coord) []float64 {
+	return []float64{coord.Longitude, coord.Latitude}
+}
+
+// convertFeature maps a station to a GeoJSON feature
+func convertFeature(station api.Station) feature {
+	res := feature{
+		Type: "Feature",
+		ID:   station.ID,
+		Geometry: geometry{
+			Type:        "Point",
+			Coordinates: position(station.Center),
+		},
+		Properties: featureProperties{
+			ID:            station.ID,
+			Title:         station.Title,
+			Subtitle:      station.Subtitle,
+			InService:     station.InService,
+			NumberOfLocks: station.NumberOfLocks,
+			Bikes:         station.Availability.Bikes,
+			Locks:         station.Availability.Locks,
+			Closed:        station.Closed,
+		},
+	}
+	if !station.UpdatedAt.IsZero() {
+		updatedAt := station.UpdatedAt
+		res.Properties.UpdatedAt = &updatedAt
+	}
+
+	// A polygon requires a closed ring of at least four positions
+	if len(station.Bounds) >= 3 {
+		var ring [][]float64
+		for _, coord := range station.Bounds {
+			ring = append(ring, position(coord))
+		}
+		if station.Bounds[0] != station.Bounds[len(station.Bounds)-1] {
+			ring = append(ring, position(station.Bounds[0]))
+		}
+		res.Properties.Bounds = &geometry{
+			Type:        "Polygon",
+			Coordinates: [][][]float64{ring},
+		}
+	}
+	return res
+}
+
+// encodeGeoJSONResponse encodes stations as GeoJSON, a single station
+// becomes a feature and lists of stations a feature collection
+func encodeGeoJSONResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	var document interface{}
+	switch res := response.(type) {
+	case api.Station:
+		document = convertFeature(res)
+	case listStationResponse:
+		collection := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+		for _, station := range res.Stations {
+			collection.Features = append(collection.Features, convertFeature(station))
+		}
+		for key, values := range res.Headers() {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		document = collection
+	case []api.NearbyStation:
+		collection := featureCollection{Type: "FeatureCollection", Features: []feature{}}
+		for _, nearby := range res {
+			f := convertFeature(nearby.Station)
+			distance := nearby.Distance
+			f.Properties.Distance = &distance
+			collection.Features = append(collection.Features, f)
+		}
+		document = collection
+	default:
+		return kithttp.EncodeJSONResponse(ctx, w, response)
+	}
+
+	w.Header().Set("Content-Type", GeoJSONContentType+"; charset=utf-8")
+	return json.NewEncoder(w).Encode(document)
+}
+
+// negotiateGeoJSON encodes the response as GeoJSON when the client
+// accepts it, and with the provided encoder otherwise
+func negotiateGeoJSON(encodeResponseFn kithttp.EncodeResponseFunc) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		accept, _ := ctx.Value(kithttp.ContextKeyRequestAccept).(string)
+		if acceptsGeoJSON(accept) {
+			return encodeGeoJSONResponse(ctx, w, response)
+		}
+		return encodeResponseFn(ctx, w, response)
+	}
+}
+
+// acceptsGeoJSON reports if GeoJSON is among the media
+// types of the provided accept header
+func acceptsGeoJSON(accept string) bool {
+	for _, mediaRange := range strings.Split(accept, ",") {
+		mediaType, _, err := mime.ParseMediaType(mediaRange)
+		if err == nil && mediaType == GeoJSONContentType {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,52 @@
+package server
+
+import (
+	"testing"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConvertFeature_Bounds(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Bounds []api.Coord
+		Expect *geometry
+	}{
+		{
+			Name:   "Too few points",
+			Bounds: []api.Coord{{Latitude: 1, Longitude: 2}},
+			Expect: nil,
+		},
+		{
+			Name: "Closes the ring",
+			Bounds: []api.Coord{
+				{Latitude: 1, Longitude: 2},
+				{Latitude: 3, Longitude: 4},
+				{Latitude: 5, Longitude: 6},
+			},
+			Expect: &geometry{
+				Type:        "Polygon",
+				Coordinates: [][][]float64{{{2, 1}, {4, 3}, {6, 5}, {2, 1}}},
+			},
+		},
+		{
+			Name: "Already closed",
+			Bounds: []api.Coord{
+				{Latitude: 1, Longitude: 2},
+				{Latitude: 3, Longitude: 4},
+				{Latitude: 5, Longitude: 6},
+				{Latitude: 1, Longitude: 2},
+			},
+			Expect: &geometry{
+				Type:        "Polygon",
+				Coordinates: [][][]float64{{{2, 1}, {4, 3}, {6, 5}, {2, 1}}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		got := convertFeature(api.Station{ID: 1, Bounds: tc.Bounds})
+		assert.Equal(t, tc.Expect, got.Properties.Bounds, tc.Name)
+	}
+}
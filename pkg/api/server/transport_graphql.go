@@ -0,0 +1,60 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"mime"
+	"net/http"
+
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/graphql"
+)
+
+// MaxGraphQLRequestSize is the largest request body, in bytes,
+// that is accepted by the GraphQL endpoint
+var MaxGraphQLRequestSize int64 = 64 << 10
+
+// decodeGraphQLRequest reads the query from the parameters of a GET
+// request, or the body of a POST request. The body is either the JSON
+// encoded request, or just the query when sent as application/graphql.
+func decodeGraphQLRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	if r.Method == http.MethodGet {
+		req := graphql.Request{
+			Query:         r.URL.Query().Get("query"),
+			OperationName: r.URL.Query().Get("operationName"),
+		}
+		if param := r.URL.Query().Get("variables"); len(param) > 0 {
+			err := json.Unmarshal([]byte(param), &req.Variables)
+			if err != nil {
+				return nil, errors.New(err, "failed to decode variables param", errors.Unmarshal)
+			}
+		}
+		return validateGraphQLRequest(req)
+	}
+
+	body, err := ioutil.ReadAll(http.MaxBytesReader(nil, r.Body, MaxGraphQLRequestSize))
+	if err != nil {
+		return nil, errors.New(err, "failed to read request body", errors.Unmarshal)
+	}
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if mediaType == "application/graphql" {
+		return validateGraphQLRequest(graphql.Request{Query: string(body)})
+	}
+
+	var req graphql.Request
+	err = json.Unmarshal(body, &req)
+	if err != nil {
+		return nil, errors.New(err, "failed to decode request body", errors.Unmarshal)
+	}
+	return validateGraphQLRequest(req)
+}
+
+func validateGraphQLRequest(req graphql.Request) (interface{}, error) {
+	if len(req.Query) == 0 {
+		return nil, errors.New(fmt.Errorf("missing query"), "failed to read required query", errors.Unmarshal)
+	}
+	return req, nil
+}
@@ -0,0 +1,263 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/go-kit/kit/endpoint"
+	kitgrpc "github.com/go-kit/kit/transport/grpc"
+	"github.com/golang/protobuf/ptypes"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/api/pb"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/logging"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// Note: the gRPC transport serves the same endpoints as the HTTP
+// transport, the requests are validated the same way and the errors
+// are converted to the status codes that match their type. The
+// changes to the stations are sent until the client goes away, the
+// subscription ends or the stream has been open for too long, the
+// client can resume where it left off with the ID of the last event.
+
+// requestIDMetadata is the metadata key the request ID is received
+// and returned in, the keys of gRPC metadata are lower case
+var requestIDMetadata = strings.ToLower(logging.RequestIDHeader)
+
+type grpcServer struct {
+	getStation   kitgrpc.Handler
+	listStations kitgrpc.Handler
+	watch        endpoint.Endpoint
+}
+
+// MakeGRPCServer initialises the gRPC station service with decoders, encoders, etc.
+func MakeGRPCServer(e Endpoints, serverOptions ...kitgrpc.ServerOption) pb.StationServiceServer {
+	serverOptions = append([]kitgrpc.ServerOption{kitgrpc.ServerBefore(populateGRPCRequestID)}, serverOptions...)
+	return &grpcServer{
+		getStation:   kitgrpc.NewServer(e.GetStation, decodeGRPCGetStationRequest, encodeGRPCStation, serverOptions...),
+		listStations: kitgrpc.NewServer(e.ListStation, decodeGRPCListStationsRequest, encodeGRPCListStationsResponse, serverOptions...),
+		watch:        e.StreamStation,
+	}
+}
+
+func (s *grpcServer) GetStation(ctx context.Context, req *pb.GetStationRequest) (*pb.Station, error) {
+	_, res, err := s.getStation.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return res.(*pb.Station), nil
+}
+
+func (s *grpcServer) ListStations(ctx context.Context, req *pb.ListStationsRequest) (*pb.ListStationsResponse, error) {
+	_, res, err := s.listStations.ServeGRPC(ctx, req)
+	if err != nil {
+		return nil, encodeGRPCError(err)
+	}
+	return res.(*pb.ListStationsResponse), nil
+}
+
+// WatchStations sends the events of the subscription, go-kit only
+// provides unary servers, so the stream is served by hand
+func (s *grpcServer) WatchStations(req *pb.WatchStationsRequest, stream pb.StationService_WatchStationsServer) error {
+	ctx := stream.Context()
+	md, _ := metadata.FromIncomingContext(ctx)
+	ctx = populateGRPCRequestID(ctx, md)
+
+	request := streamStationRequest{LastEventID: req.LastEventId}
+	if len(req.Ids) > 0 {
+		request.IDs = map[int]bool{}
+		for _, id := range req.Ids {
+			request.IDs[int(id)] = true
+		}
+	}
+	response, err := s.watch(ctx, request)
+	if err != nil {
+		return encodeGRPCError(err)
+	}
+	res := response.(streamStationResponse)
+	defer res.Close()
+
+	deadline := time.NewTimer(MaxStreamDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			return nil
+		case event, ok := <-res.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "the subscription has ended, resume with the ID of the last event")
+			}
+			event, ok = res.filter(event)
+			if !ok {
+				continue
+			}
+			err = stream.Send(convertGRPCStationEvent(event))
+			if err != nil {
+				return nil
+			}
+		}
+	}
+}
+
+// populateGRPCRequestID adds the ID of the request to its
+// context, and returns it in the headers of the response
+func populateGRPCRequestID(ctx context.Context, md metadata.MD) context.Context {
+	header := http.Header{}
+	for _, value := range md.Get(requestIDMetadata) {
+		header.Add(logging.RequestIDHeader, value)
+	}
+	id := logging.RequestIDFromHeader(header)
+	// Fails only outside of a call, e.g., in tests
+	_ = grpc.SetHeader(ctx, metadata.Pairs(requestIDMetadata, id))
+	return logging.WithRequestID(ctx, id)
+}
+
+func decodeGRPCGetStationRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.GetStationRequest)
+	return int(req.Id), nil
+}
+
+func decodeGRPCListStationsRequest(_ context.Context, request interface{}) (interface{}, error) {
+	req := request.(*pb.ListStationsRequest)
+	query := api.StationQuery{
+		Title:    req.Q,
+		MinBikes: int(req.MinBikes),
+		MinLocks: int(req.MinLocks),
+		Offset:   int(req.Offset),
+		Limit:    int(req.Limit),
+	}
+	if req.Closed != nil {
+		closed := req.Closed.Value
+		query.Closed = &closed
+	}
+	if req.InService != nil {
+		inService := req.InService.Value
+		query.InService = &inService
+	}
+	err := validatePage(query.Offset, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Sort, query.Descending, err = decodeSort(req.Sort)
+	if err != nil {
+		return nil, err
+	}
+	if query.Sort == api.SortByDistance {
+		if req.Origin == nil {
+			return nil, errors.New(fmt.Errorf("missing origin"), "origin is required when sorting by distance", errors.Unmarshal)
+		}
+		origin := api.Coord{
+			Latitude:  req.Origin.Latitude,
+			Longitude: req.Origin.Longitude,
+		}
+		err = validateCoord(origin, "latitude", "longitude")
+		if err != nil {
+			return nil, err
+		}
+		query.Origin = &origin
+	}
+
+	return listStationRequest{Query: query}, nil
+}
+
+func encodeGRPCStation(_ context.Context, response interface{}) (interface{}, error) {
+	return convertGRPCStation(response.(api.Station)), nil
+}
+
+func encodeGRPCListStationsResponse(_ context.Context, response interface{}) (interface{}, error) {
+	res := response.(listStationResponse)
+	list := &pb.ListStationsResponse{
+		Total: int32(res.Total),
+	}
+	for _, station := range res.Stations {
+		list.Stations = append(list.Stations, convertGRPCStation(station))
+	}
+	return list, nil
+}
+
+// encodeGRPCError converts the error to a status, with
+// the code that matches the type of the error
+func encodeGRPCError(err error) error {
+	code := codes.Internal
+	typ, ok := errors.TypeOf(err)
+	if ok {
+		switch typ {
+		case errors.NotFound:
+			code = codes.NotFound
+		case errors.Unmarshal:
+			code = codes.InvalidArgument
+		case errors.Unavailable:
+			code = codes.Unavailable
+		case errors.Unauthorized:
+			code = codes.Unauthenticated
+		case errors.TooManyRequests:
+			code = codes.ResourceExhausted
+		}
+	}
+	return status.Error(code, err.Error())
+}
+
+func convertGRPCCoord(coord api.Coord) *pb.Coord {
+	return &pb.Coord{
+		Latitude:  coord.Latitude,
+		Longitude: coord.Longitude,
+	}
+}
+
+func convertGRPCStation(station api.Station) *pb.Station {
+	res := &pb.Station{
+		Id:            int32(station.ID),
+		InService:     station.InService,
+		Title:         station.Title,
+		Subtitle:      station.Subtitle,
+		NumberOfLocks: int32(station.NumberOfLocks),
+		Center:        convertGRPCCoord(station.Center),
+		Availability: &pb.Availability{
+			Bikes: int32(station.Availability.Bikes),
+			Locks: int32(station.Availability.Locks),
+		},
+		Closed:    station.Closed,
+		UpdatedAt: convertGRPCTime(station.UpdatedAt),
+	}
+	for _, coord := range station.Bounds {
+		res.Bounds = append(res.Bounds, convertGRPCCoord(coord))
+	}
+	return res
+}
+
+func convertGRPCStationEvent(event api.StationEvent) *pb.StationEvent {
+	res := &pb.StationEvent{
+		Id:   event.ID,
+		Type: event.Type,
+		At:   convertGRPCTime(event.At),
+	}
+	for _, station := range event.Stations {
+		res.Stations = append(res.Stations, convertGRPCStation(station))
+	}
+	return res
+}
+
+// convertGRPCTime leaves out the unknown times,
+// and those a timestamp cannot represent
+func convertGRPCTime(t time.Time) *timestamp.Timestamp {
+	if t.IsZero() {
+		return nil
+	}
+	ts, err := ptypes.TimestampProto(t)
+	if err != nil {
+		return nil
+	}
+	return ts
+}
@@ -0,0 +1,255 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/paulbes/go-pedal/pkg/api/mock"
+	"github.com/paulbes/go-pedal/pkg/api/pb"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/stretchr/testify/assert"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// grpcClient serves the endpoints over an in-memory connection
+// and returns a client of it, the server is stopped when done
+func grpcClient(t *testing.T, err error, options ...grpc.ServerOption) (pb.StationServiceClient, func()) {
+	endpoints := MakeEndpoints(Services{
+		Station: NewStationService(mock.NewStationStore(mock.NewStation(), err)),
+		Stream:  NewStreamService(mock.NewStreamStore(mock.NewStationEvents(), err)),
+	})
+	server := grpc.NewServer(options...)
+	pb.RegisterStationServiceServer(server, MakeGRPCServer(endpoints))
+	listener := bufconn.Listen(1024 * 1024)
+	go server.Serve(listener)
+
+	conn, dialErr := grpc.Dial("bufconn",
+		grpc.WithInsecure(),
+		grpc.WithDialer(func(string, time.Duration) (net.Conn, error) {
+			return listener.Dial()
+		}),
+	)
+	if dialErr != nil {
+		t.Fatalf("failed to dial: %s", dialErr)
+	}
+	return pb.NewStationServiceClient(conn), func() {
+		conn.Close()
+		server.Stop()
+	}
+}
+
+func TestGRPC_GetStation(t *testing.T) {
+	testCases := []struct {
+		Name       string
+		Err        error
+		ExpectCode codes.Code
+	}{
+		{
+			Name:       "Found",
+			ExpectCode: codes.OK,
+		},
+		{
+			Name:       "Not found",
+			Err:        errors.New(fmt.Errorf("no station"), "not found", errors.NotFound),
+			ExpectCode: codes.NotFound,
+		},
+		{
+			Name:       "Unavailable",
+			Err:        errors.New(fmt.Errorf("no snapshot"), "unavailable", errors.Unavailable),
+			ExpectCode: codes.Unavailable,
+		},
+		{
+			Name:       "Unknown error",
+			Err:        fmt.Errorf("uh oh"),
+			ExpectCode: codes.Internal,
+		},
+	}
+
+	for _, tc := range testCases {
+		client, stop := grpcClient(t, tc.Err)
+		var header metadata.MD
+		ctx := metadata.AppendToOutgoingContext(context.Background(), "x-request-id", "req-42")
+		station, err := client.GetStation(ctx, &pb.GetStationRequest{Id: 1}, grpc.Header(&header))
+		stop()
+
+		assert.Equal(t, tc.ExpectCode, status.Code(err), tc.Name)
+		assert.Equal(t, []string{"req-42"}, header.Get("x-request-id"), tc.Name)
+		if tc.ExpectCode == codes.OK {
+			assert.Equal(t, int32(1), station.Id, tc.Name)
+			assert.Equal(t, "Antarctica", station.Title, tc.Name)
+			assert.Equal(t, &pb.Availability{Bikes: 5, Locks: 5}, station.Availability, tc.Name)
+			assert.Equal(t, []*pb.Coord{{Latitude: 59.10, Longitude: 59.09}}, station.Bounds, tc.Name)
+		}
+	}
+}
+
+func TestGRPC_ListStations(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		Request     *pb.ListStationsRequest
+		ExpectCode  codes.Code
+		ExpectTotal int32
+	}{
+		{
+			Name:        "All stations",
+			Request:     &pb.ListStationsRequest{},
+			ExpectCode:  codes.OK,
+			ExpectTotal: 1,
+		},
+		{
+			Name:        "Filtered away",
+			Request:     &pb.ListStationsRequest{Closed: &wrappers.BoolValue{Value: true}},
+			ExpectCode:  codes.OK,
+			ExpectTotal: 0,
+		},
+		{
+			Name:        "Sorted by distance",
+			Request:     &pb.ListStationsRequest{Sort: "distance", Origin: &pb.Coord{Latitude: 59, Longitude: 59}},
+			ExpectCode:  codes.OK,
+			ExpectTotal: 1,
+		},
+		{
+			Name:       "Sorted by distance without origin",
+			Request:    &pb.ListStationsRequest{Sort: "distance"},
+			ExpectCode: codes.InvalidArgument,
+		},
+		{
+			Name:       "Origin out of range",
+			Request:    &pb.ListStationsRequest{Sort: "distance", Origin: &pb.Coord{Latitude: 91, Longitude: 59}},
+			ExpectCode: codes.InvalidArgument,
+		},
+		{
+			Name:       "Origin is not a number",
+			Request:    &pb.ListStationsRequest{Sort: "distance", Origin: &pb.Coord{Latitude: math.NaN(), Longitude: 59}},
+			ExpectCode: codes.InvalidArgument,
+		},
+		{
+			Name:       "Unknown sort",
+			Request:    &pb.ListStationsRequest{Sort: "colour"},
+			ExpectCode: codes.InvalidArgument,
+		},
+		{
+			Name:       "Limit out of range",
+			Request:    &pb.ListStationsRequest{Limit: 100000},
+			ExpectCode: codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		client, stop := grpcClient(t, nil)
+		list, err := client.ListStations(context.Background(), tc.Request)
+		stop()
+
+		assert.Equal(t, tc.ExpectCode, status.Code(err), tc.Name)
+		if tc.ExpectCode == codes.OK {
+			assert.Equal(t, tc.ExpectTotal, list.Total, tc.Name)
+			assert.Len(t, list.Stations, int(tc.ExpectTotal), tc.Name)
+		}
+	}
+}
+
+func TestGRPC_WatchStations(t *testing.T) {
+	testCases := []struct {
+		Name           string
+		IDs            []int32
+		ExpectIDs      []string
+		ExpectStations []int
+	}{
+		{
+			Name:           "All stations",
+			ExpectIDs:      []string{"e-0", "e-1"},
+			ExpectStations: []int{1, 1},
+		},
+		{
+			Name:           "Requested stations",
+			IDs:            []int32{1},
+			ExpectIDs:      []string{"e-0", "e-1"},
+			ExpectStations: []int{1, 1},
+		},
+		{
+			Name:           "Other stations",
+			IDs:            []int32{2},
+			ExpectIDs:      []string{"e-0"},
+			ExpectStations: []int{0},
+		},
+	}
+
+	for _, tc := range testCases {
+		client, stop := grpcClient(t, nil)
+		stream, err := client.WatchStations(context.Background(), &pb.WatchStationsRequest{Ids: tc.IDs})
+		assert.Nil(t, err, tc.Name)
+
+		var ids []string
+		var stations []int
+		for {
+			event, err := stream.Recv()
+			if err != nil {
+				// The mocked subscription ends once the events are delivered
+				assert.NotEqual(t, io.EOF, err, tc.Name)
+				assert.Equal(t, codes.Unavailable, status.Code(err), tc.Name)
+				break
+			}
+			ids = append(ids, event.Id)
+			stations = append(stations, len(event.Stations))
+		}
+		stop()
+
+		assert.Equal(t, tc.ExpectIDs, ids, tc.Name)
+		assert.Equal(t, tc.ExpectStations, stations, tc.Name)
+	}
+}
+
+func TestGRPC_RateLimit(t *testing.T) {
+	config := &AccessConfig{
+		Keys:       []APIKey{{Name: "test", Key: "secret"}},
+		RequireKey: true,
+	}
+
+	testCases := []struct {
+		Name       string
+		Key        string
+		ExpectCode codes.Code
+	}{
+		{
+			Name:       "Missing key",
+			ExpectCode: codes.Unauthenticated,
+		},
+		{
+			Name:       "Unknown key",
+			Key:        "guess",
+			ExpectCode: codes.Unauthenticated,
+		},
+		{
+			Name:       "Known key",
+			Key:        "secret",
+			ExpectCode: codes.OK,
+		},
+	}
+
+	for _, tc := range testCases {
+		client, stop := grpcClient(t, nil, RateLimitGRPC(config)...)
+		ctx := context.Background()
+		if len(tc.Key) > 0 {
+			ctx = metadata.AppendToOutgoingContext(ctx, "x-api-key", tc.Key)
+		}
+		_, err := client.GetStation(ctx, &pb.GetStationRequest{Id: 1})
+		assert.Equal(t, tc.ExpectCode, status.Code(err), tc.Name+": get")
+
+		stream, err := client.WatchStations(ctx, &pb.WatchStationsRequest{})
+		if err == nil {
+			_, err = stream.Recv()
+		}
+		assert.Equal(t, tc.ExpectCode, status.Code(err), tc.Name+": watch")
+		stop()
+	}
+}
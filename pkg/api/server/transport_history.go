@@ -0,0 +1,72 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+// MaxHistoryBuckets is the largest number of buckets
+// the history can be downsampled into at once
+var MaxHistoryBuckets = 1000
+
+type stationHistoryRequest struct {
+	ID   int
+	From time.Time
+	To   time.Time
+	Step time.Duration
+}
+
+func decodeStationHistoryRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeGetStationRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := decodeTimeParam(r, "to", time.Now())
+	if err != nil {
+		return nil, err
+	}
+	from, err := decodeTimeParam(r, "from", to.Add(-24*time.Hour))
+	if err != nil {
+		return nil, err
+	}
+	if !from.Before(to) {
+		return nil, errors.New(fmt.Errorf("from: %s, to: %s", from.Format(time.RFC3339), to.Format(time.RFC3339)), "from must be before to", errors.Unmarshal)
+	}
+
+	step := time.Hour
+	if param := r.URL.Query().Get("step"); len(param) > 0 {
+		step, err = time.ParseDuration(param)
+		if err != nil {
+			return nil, errors.New(err, "failed to convert step param to duration", errors.Unmarshal)
+		}
+	}
+	if step <= 0 || to.Sub(from)/step >= time.Duration(MaxHistoryBuckets) {
+		return nil, errors.New(fmt.Errorf("step: %s", step), fmt.Sprintf("step must be positive and result in less than %d buckets", MaxHistoryBuckets), errors.Unmarshal)
+	}
+
+	return stationHistoryRequest{
+		ID:   id.(int),
+		From: from,
+		To:   to,
+		Step: step,
+	}, nil
+}
+
+// decodeTimeParam reads an optional RFC3339 timestamp from
+// the query parameters
+func decodeTimeParam(r *http.Request, name string, defaultValue time.Time) (time.Time, error) {
+	param := r.URL.Query().Get(name)
+	if len(param) == 0 {
+		return defaultValue, nil
+	}
+	value, err := time.Parse(time.RFC3339, param)
+	if err != nil {
+		return time.Time{}, errors.New(err, fmt.Sprintf("failed to convert %s param to time", name), errors.Unmarshal)
+	}
+	return value, nil
+}
@@ -0,0 +1,30 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+// MaxTruckCapacity is the largest number of bikes
+// a truck can be said to carry
+var MaxTruckCapacity = 100
+
+type planRebalanceRequest struct {
+	Capacity int
+}
+
+func decodePlanRebalanceRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	capacity, err := decodeIntParam(r, "capacity", 20)
+	if err != nil {
+		return nil, err
+	}
+	if capacity < 1 || capacity > MaxTruckCapacity {
+		return nil, errors.New(fmt.Errorf("capacity: %d", capacity), fmt.Sprintf("capacity must be between 1 and %d", MaxTruckCapacity), errors.Unmarshal)
+	}
+	return planRebalanceRequest{
+		Capacity: capacity,
+	}, nil
+}
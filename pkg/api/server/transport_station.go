@@ -2,10 +2,16 @@ package server
 
 import (
 	"context"
+	"encoding/json"
+	"fmt"
+	"math"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
+	"github.com/paulbes/go-pedal/pkg/api"
 	"github.com/paulbes/go-pedal/pkg/errors"
 )
 
@@ -19,3 +25,278 @@ func decodeGetStationRequest(_ context.Context, r *http.Request) (interface{}, e
 	}
 	return id, nil
 }
+
+// MaxStationLimit is the largest page of
+// stations that can be requested at once
+var MaxStationLimit = 1000
+
+type listStationRequest struct {
+	At    time.Time
+	Query api.StationQuery
+}
+
+func decodeListStationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	at, err := decodeTimeParam(r, "at", time.Time{})
+	if err != nil {
+		return nil, err
+	}
+
+	query := api.StationQuery{
+		Title: r.URL.Query().Get("q"),
+	}
+	query.Closed, err = decodeBoolParam(r, "closed")
+	if err != nil {
+		return nil, err
+	}
+	query.InService, err = decodeBoolParam(r, "in_service")
+	if err != nil {
+		return nil, err
+	}
+	query.MinBikes, err = decodeIntParam(r, "min_bikes", 0)
+	if err != nil {
+		return nil, err
+	}
+	query.MinLocks, err = decodeIntParam(r, "min_locks", 0)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Offset, err = decodeIntParam(r, "offset", 0)
+	if err != nil {
+		return nil, err
+	}
+	query.Limit, err = decodeIntParam(r, "limit", 0)
+	if err != nil {
+		return nil, err
+	}
+	err = validatePage(query.Offset, query.Limit)
+	if err != nil {
+		return nil, err
+	}
+
+	query.Sort, query.Descending, err = decodeSort(r.URL.Query().Get("sort"))
+	if err != nil {
+		return nil, err
+	}
+	if query.Sort == api.SortByDistance {
+		origin, err := decodeCoordParams(r, "lat", "lon")
+		if err != nil {
+			return nil, err
+		}
+		query.Origin = &origin
+	}
+
+	return listStationRequest{
+		At:    at,
+		Query: query,
+	}, nil
+}
+
+// validatePage ensures the requested page of stations is within bounds
+func validatePage(offset, limit int) error {
+	if offset < 0 {
+		return errors.New(fmt.Errorf("offset: %d", offset), "offset must not be negative", errors.Unmarshal)
+	}
+	if limit < 0 || limit > MaxStationLimit {
+		return errors.New(fmt.Errorf("limit: %d", limit), fmt.Sprintf("limit must be between 0 and %d", MaxStationLimit), errors.Unmarshal)
+	}
+	return nil
+}
+
+// decodeSort reads the field to sort by, a leading
+// dash sorts in descending order
+func decodeSort(sortBy string) (string, bool, error) {
+	descending := false
+	if strings.HasPrefix(sortBy, "-") {
+		sortBy, descending = sortBy[1:], true
+	}
+	switch sortBy {
+	case "", api.SortByID, api.SortByTitle, api.SortByBikes, api.SortByLocks, api.SortByDistance:
+		return sortBy, descending, nil
+	default:
+		return "", false, errors.New(fmt.Errorf("sort: %s", sortBy), "sort must be one of id, title, bikes, locks or distance", errors.Unmarshal)
+	}
+}
+
+// listStationResponse is encoded as a plain list of stations,
+// the total number of matching stations is provided as a header
+type listStationResponse struct {
+	api.StationList
+}
+
+func (r listStationResponse) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.Stations)
+}
+
+func (r listStationResponse) Headers() http.Header {
+	return http.Header{
+		"X-Total-Count": []string{strconv.Itoa(r.Total)},
+	}
+}
+
+// MaxForecastHorizon is the furthest into the
+// future a forecast can be requested for
+var MaxForecastHorizon = 24 * time.Hour
+
+type forecastStationRequest struct {
+	ID      int
+	Horizon time.Duration
+}
+
+func decodeForecastStationRequest(ctx context.Context, r *http.Request) (interface{}, error) {
+	id, err := decodeGetStationRequest(ctx, r)
+	if err != nil {
+		return nil, err
+	}
+
+	horizon := 20 * time.Minute
+	if param := r.URL.Query().Get("horizon"); len(param) > 0 {
+		horizon, err = time.ParseDuration(param)
+		if err != nil {
+			return nil, errors.New(err, "failed to convert horizon param to duration", errors.Unmarshal)
+		}
+	}
+	if horizon < 0 || horizon > MaxForecastHorizon {
+		return nil, errors.New(fmt.Errorf("horizon: %s", horizon), fmt.Sprintf("horizon must be between 0s and %s", MaxForecastHorizon), errors.Unmarshal)
+	}
+
+	return forecastStationRequest{
+		ID:      id.(int),
+		Horizon: horizon,
+	}, nil
+}
+
+func decodeLocateStationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	return decodeCoordParams(r, "lat", "lon")
+}
+
+// MaxNearbyRadius is the largest radius in metres
+// nearby stations can be requested within
+var MaxNearbyRadius = 10000.0
+
+// MaxNearbyLimit is the largest number of nearby
+// stations that can be requested at once
+var MaxNearbyLimit = 100
+
+type nearbyStationRequest struct {
+	Coord  api.Coord
+	Radius float64
+	Limit  int
+}
+
+func decodeNearbyStationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	coord, err := decodeCoordParams(r, "lat", "lon")
+	if err != nil {
+		return nil, err
+	}
+
+	radius := 500.0
+	if len(r.URL.Query().Get("radius")) > 0 {
+		radius, err = decodeFloatParam(r, "radius")
+		if err != nil {
+			return nil, err
+		}
+	}
+	limit, err := decodeIntParam(r, "limit", 10)
+	if err != nil {
+		return nil, err
+	}
+
+	req := nearbyStationRequest{
+		Coord:  coord,
+		Radius: radius,
+		Limit:  limit,
+	}
+	err = req.validate()
+	if err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// validate ensures the radius and limit are within bounds
+func (r nearbyStationRequest) validate() error {
+	if math.IsNaN(r.Radius) || r.Radius <= 0 || r.Radius > MaxNearbyRadius {
+		return errors.New(fmt.Errorf("radius: %g", r.Radius), fmt.Sprintf("radius must be above 0 and at most %g", MaxNearbyRadius), errors.Unmarshal)
+	}
+	if r.Limit < 1 || r.Limit > MaxNearbyLimit {
+		return errors.New(fmt.Errorf("limit: %d", r.Limit), fmt.Sprintf("limit must be between 1 and %d", MaxNearbyLimit), errors.Unmarshal)
+	}
+	return nil
+}
+
+// decodeCoordParams reads a required and valid coordinate
+// from the query parameters
+func decodeCoordParams(r *http.Request, latName, lonName string) (api.Coord, error) {
+	lat, err := decodeFloatParam(r, latName)
+	if err != nil {
+		return api.Coord{}, err
+	}
+	lon, err := decodeFloatParam(r, lonName)
+	if err != nil {
+		return api.Coord{}, err
+	}
+	coord := api.Coord{
+		Latitude:  lat,
+		Longitude: lon,
+	}
+	err = validateCoord(coord, latName, lonName)
+	if err != nil {
+		return api.Coord{}, err
+	}
+	return coord, nil
+}
+
+// validateCoord ensures the coordinate is within range, the
+// names of the params are used to describe the problem
+func validateCoord(coord api.Coord, latName, lonName string) error {
+	lat, lon := coord.Latitude, coord.Longitude
+	if math.IsNaN(lat) || math.IsNaN(lon) || lat < -90 || lat > 90 || lon < -180 || lon > 180 {
+		return errors.New(fmt.Errorf("%s: %g, %s: %g", latName, lat, lonName, lon), "coordinate is out of range", errors.Unmarshal)
+	}
+	return nil
+}
+
+// decodeIntParam reads an optional int from the query parameters
+func decodeIntParam(r *http.Request, name string, defaultValue int) (int, error) {
+	param := r.URL.Query().Get(name)
+	if len(param) == 0 {
+		return defaultValue, nil
+	}
+	value, err := strconv.Atoi(param)
+	if err != nil {
+		return 0, errors.New(err, fmt.Sprintf("failed to convert %s param to int", name), errors.Unmarshal)
+	}
+	return value, nil
+}
+
+// decodeBoolParam reads an optional bool from the query
+// parameters, nil is returned if it was not provided
+func decodeBoolParam(r *http.Request, name string) (*bool, error) {
+	param := r.URL.Query().Get(name)
+	if len(param) == 0 {
+		return nil, nil
+	}
+	value, err := strconv.ParseBool(param)
+	if err != nil {
+		return nil, errors.New(err, fmt.Sprintf("failed to convert %s param to bool", name), errors.Unmarshal)
+	}
+	return &value, nil
+}
+
+// decodeFloatParam reads a required and finite float from the
+// query parameters, NaN and Inf are parsed, but never meant
+func decodeFloatParam(r *http.Request, name string) (float64, error) {
+	param := r.URL.Query().Get(name)
+	if len(param) == 0 {
+		return 0, errors.New(fmt.Errorf("missing query param: %s", name), "failed to read required param", errors.Unmarshal)
+	}
+	value, err := strconv.ParseFloat(param, 64)
+	if err != nil {
+		return 0, errors.New(err, fmt.Sprintf("failed to convert %s param to float", name), errors.Unmarshal)
+	}
+	if math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, errors.New(fmt.Errorf("%s: %s", name, param), fmt.Sprintf("%s param must be a finite number", name), errors.Unmarshal)
+	}
+	return value, nil
+}
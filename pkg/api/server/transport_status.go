@@ -0,0 +1,27 @@
+package server
+
+import (
+	"net/http"
+)
+
+// Note: the liveness and readiness probes are meant for the
+// orchestrator, they never call the upstream API, so they
+// can be requested as often as required.
+
+type livenessResponse struct {
+	Status string `json:"status"`
+}
+
+// readinessResponse is encoded with 503 Service
+// Unavailable, while the API is not ready
+type readinessResponse struct {
+	Ready  bool   `json:"ready"`
+	Reason string `json:"reason,omitempty"`
+}
+
+func (r readinessResponse) StatusCode() int {
+	if !r.Ready {
+		return http.StatusServiceUnavailable
+	}
+	return http.StatusOK
+}
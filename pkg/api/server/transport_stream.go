@@ -0,0 +1,162 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+// Note: the stations are streamed as server-sent events, the client
+// starts out with a snapshot of all stations and then receives the
+// changes. The ID of every event can be provided as Last-Event-ID
+// when reconnecting, to resume without missing any changes.
+
+// HeartbeatInterval is how often a comment is written to an
+// idle stream, so proxies do not close the connection
+var HeartbeatInterval = 15 * time.Second
+
+// MaxStreamDuration is how long a stream is kept open, it must
+// be shorter than the WriteTimeout of the server, so the stream
+// is ended cleanly and the client reconnects and resumes
+var MaxStreamDuration = 4 * time.Minute
+
+// StreamRetry is how long the client should wait
+// before reconnecting to an ended stream
+var StreamRetry = 3 * time.Second
+
+type streamStationRequest struct {
+	LastEventID string
+	IDs         map[int]bool
+}
+
+func decodeStreamStationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	req := streamStationRequest{
+		LastEventID: r.Header.Get("Last-Event-ID"),
+	}
+	// Not every client is able to set the header
+	if len(req.LastEventID) == 0 {
+		req.LastEventID = r.URL.Query().Get("last_event_id")
+	}
+
+	param := r.URL.Query().Get("ids")
+	if len(param) > 0 {
+		req.IDs = map[int]bool{}
+		for _, s := range strings.Split(param, ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(s))
+			if err != nil {
+				return nil, errors.New(err, "failed to convert ids param to int", errors.Unmarshal)
+			}
+			req.IDs[id] = true
+		}
+	}
+
+	return req, nil
+}
+
+type streamStationResponse struct {
+	api.StationSubscription
+	// IDs limits the events to these stations, if not empty
+	IDs map[int]bool
+}
+
+// filter limits the event to the requested stations, false is
+// returned if none of the stations of the event remain
+func (r streamStationResponse) filter(event api.StationEvent) (api.StationEvent, bool) {
+	if len(r.IDs) == 0 {
+		return event, true
+	}
+	return event.Filter(func(station api.Station) bool {
+		return r.IDs[station.ID]
+	})
+}
+
+type stationEvent struct {
+	ID       string      `json:"id"`
+	Type     string      `json:"type"`
+	At       time.Time   `json:"at"`
+	Stations []stationV2 `json:"stations"`
+}
+
+func convertStationEvent(event api.StationEvent) stationEvent {
+	res := stationEvent{
+		ID:       event.ID,
+		Type:     event.Type,
+		At:       event.At,
+		Stations: []stationV2{},
+	}
+	for _, station := range event.Stations {
+		res.Stations = append(res.Stations, convertStationV2(station))
+	}
+	return res
+}
+
+// writeEvent writes the event in the server-sent events format
+func writeEvent(w http.ResponseWriter, event api.StationEvent) error {
+	data, err := json.Marshal(convertStationEvent(event))
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data)
+	return err
+}
+
+// encodeStreamStationResponse writes the events of the subscription
+// until the client goes away, the subscription ends or the stream
+// has been open for too long. Once the stream has started, errors
+// can no longer be reported to the client, so they end the stream.
+func encodeStreamStationResponse(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	res := response.(streamStationResponse)
+	defer res.Close()
+
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		return errors.New(fmt.Errorf("response writer cannot be flushed"), "failed to stream stations", errors.Unavailable)
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	// Ensure that nginx and friends do not buffer the stream
+	w.Header().Set("X-Accel-Buffering", "no")
+	w.WriteHeader(http.StatusOK)
+	_, err := fmt.Fprintf(w, "retry: %d\n\n", StreamRetry/time.Millisecond)
+	if err != nil {
+		return nil
+	}
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(HeartbeatInterval)
+	defer heartbeat.Stop()
+	deadline := time.NewTimer(MaxStreamDuration)
+	defer deadline.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-deadline.C:
+			return nil
+		case <-heartbeat.C:
+			_, err = fmt.Fprint(w, ": heartbeat\n\n")
+		case event, ok := <-res.Events():
+			if !ok {
+				return nil
+			}
+			event, ok = res.filter(event)
+			if !ok {
+				continue
+			}
+			err = writeEvent(w, event)
+		}
+		if err != nil {
+			return nil
+		}
+		flusher.Flush()
+	}
+}
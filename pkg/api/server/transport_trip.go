@@ -0,0 +1,43 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+// MaxTripSuggestions is the largest number of trips
+// that can be requested at once
+var MaxTripSuggestions = 10
+
+type suggestTripRequest struct {
+	From  api.Coord
+	To    api.Coord
+	Limit int
+}
+
+func decodeSuggestTripRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	from, err := decodeCoordParams(r, "from_lat", "from_lon")
+	if err != nil {
+		return nil, err
+	}
+	to, err := decodeCoordParams(r, "to_lat", "to_lon")
+	if err != nil {
+		return nil, err
+	}
+	limit, err := decodeIntParam(r, "limit", 3)
+	if err != nil {
+		return nil, err
+	}
+	if limit < 1 || limit > MaxTripSuggestions {
+		return nil, errors.New(fmt.Errorf("limit: %d", limit), fmt.Sprintf("limit must be between 1 and %d", MaxTripSuggestions), errors.Unmarshal)
+	}
+	return suggestTripRequest{
+		From:  from,
+		To:    to,
+		Limit: limit,
+	}, nil
+}
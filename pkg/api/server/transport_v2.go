@@ -0,0 +1,62 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+// Note: version 2 of the API reuses the endpoints of version 1, only
+// the representation of the stations differs, i.e., it includes the
+// availability, closed state and when the station was last updated.
+
+type stationV2 struct {
+	ID            int              `json:"id"`
+	InService     bool             `json:"in_service"`
+	Title         string           `json:"title"`
+	Subtitle      string           `json:"subtitle"`
+	NumberOfLocks int              `json:"number_of_locks"`
+	Center        api.Coord        `json:"center"`
+	Bounds        []api.Coord      `json:"bounds"`
+	Availability  api.Availability `json:"availability"`
+	Closed        bool             `json:"closed"`
+	UpdatedAt     time.Time        `json:"updated_at"`
+}
+
+func convertStationV2(station api.Station) stationV2 {
+	return stationV2{
+		ID:            station.ID,
+		InService:     station.InService,
+		Title:         station.Title,
+		Subtitle:      station.Subtitle,
+		NumberOfLocks: station.NumberOfLocks,
+		Center:        station.Center,
+		Bounds:        station.Bounds,
+		Availability:  station.Availability,
+		Closed:        station.Closed,
+		UpdatedAt:     station.UpdatedAt,
+	}
+}
+
+func encodeStationV2Response(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+	switch res := response.(type) {
+	case api.Station:
+		return kithttp.EncodeJSONResponse(ctx, w, convertStationV2(res))
+	case listStationResponse:
+		stations := []stationV2{}
+		for _, station := range res.Stations {
+			stations = append(stations, convertStationV2(station))
+		}
+		for key, values := range res.Headers() {
+			for _, value := range values {
+				w.Header().Add(key, value)
+			}
+		}
+		return kithttp.EncodeJSONResponse(ctx, w, stations)
+	default:
+		return kithttp.EncodeJSONResponse(ctx, w, response)
+	}
+}
@@ -0,0 +1,434 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"time"
+
+	kithttp "github.com/go-kit/kit/transport/http"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+	"github.com/paulbes/go-pedal/pkg/websocket"
+)
+
+// Note: the websocket API reuses the stream endpoint, but instead of
+// receiving every change, a client subscribes to the stations it cares
+// about, by their ID or by a bounding box. Every subscription is answered
+// with the current state of the subscribed stations, followed by their
+// changes as they happen.
+
+// PingInterval is how often the client is pinged
+var PingInterval = 30 * time.Second
+
+// PongWait is how long the client has to respond to a ping,
+// or send anything else, before it is disconnected
+var PongWait = 60 * time.Second
+
+// WriteWait is how long writing a single message may take
+var WriteWait = 10 * time.Second
+
+// SendBufferSize is the number of messages that can be waiting
+// for a client, a client that falls further behind is disconnected
+var SendBufferSize = 64
+
+// The types of messages sent by the client
+const (
+	messageSubscribe   = "subscribe"
+	messageUnsubscribe = "unsubscribe"
+)
+
+// The types of messages sent by the server, besides the station events
+const (
+	messageSubscribed = "subscribed"
+	messageError      = "error"
+)
+
+type contextKey int
+
+const (
+	// contextKeyRequest is used to hand the request to the encoder, it
+	// needs the request to take over the connection
+	contextKeyRequest contextKey = iota
+	// contextKeyRestricted marks the requests that passed the access
+	// control of the API keys, so their responses are kept private
+	contextKeyRestricted
+)
+
+func populateRequest(ctx context.Context, r *http.Request) context.Context {
+	return context.WithValue(ctx, contextKeyRequest, r)
+}
+
+func decodeWebSocketStationRequest(_ context.Context, r *http.Request) (interface{}, error) {
+	if !websocket.IsUpgrade(r) {
+		return nil, errors.New(fmt.Errorf("missing upgrade headers"), "expected a websocket upgrade", errors.Unmarshal)
+	}
+	return streamStationRequest{}, nil
+}
+
+type boundingBox struct {
+	Min api.Coord `json:"min"`
+	Max api.Coord `json:"max"`
+}
+
+func (b boundingBox) validate() error {
+	for _, coord := range []api.Coord{b.Min, b.Max} {
+		if coord.Latitude < -90 || coord.Latitude > 90 || coord.Longitude < -180 || coord.Longitude > 180 {
+			return fmt.Errorf("bounds out of range")
+		}
+	}
+	if b.Min.Latitude > b.Max.Latitude || b.Min.Longitude > b.Max.Longitude {
+		return fmt.Errorf("bounds min must be south west of max")
+	}
+	return nil
+}
+
+func (b boundingBox) contains(coord api.Coord) bool {
+	return coord.Latitude >= b.Min.Latitude && coord.Latitude <= b.Max.Latitude &&
+		coord.Longitude >= b.Min.Longitude && coord.Longitude <= b.Max.Longitude
+}
+
+type clientMessage struct {
+	Type   string       `json:"type"`
+	IDs    []int        `json:"ids"`
+	Bounds *boundingBox `json:"bounds"`
+}
+
+type subscribedMessage struct {
+	Type   string        `json:"type"`
+	IDs    []int         `json:"ids"`
+	Bounds []boundingBox `json:"bounds"`
+}
+
+type errorMessage struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
+// session is the state of a single websocket client
+type session struct {
+	conn *websocket.Conn
+	send chan []byte
+
+	// The subscription of the client
+	ids    map[int]bool
+	bounds []boundingBox
+
+	// The latest state of all stations
+	stations    map[int]api.Station
+	lastEventID string
+	at          time.Time
+
+	// How the connection is closed, once send is closed
+	closeCode   int
+	closeReason string
+}
+
+func newSession(conn *websocket.Conn) *session {
+	return &session{
+		conn:      conn,
+		send:      make(chan []byte, SendBufferSize),
+		ids:       map[int]bool{},
+		stations:  map[int]api.Station{},
+		closeCode: websocket.CloseNormalClosure,
+	}
+}
+
+// queue adds the message to those waiting for the client,
+// false is returned if the client is too far behind
+func (s *session) queue(message interface{}) bool {
+	data, err := json.Marshal(message)
+	if err != nil {
+		return false
+	}
+	select {
+	case s.send <- data:
+		return true
+	default:
+		return false
+	}
+}
+
+func (s *session) isSubscribed() bool {
+	return len(s.ids) > 0 || len(s.bounds) > 0
+}
+
+func (s *session) matches(station api.Station) bool {
+	if s.ids[station.ID] {
+		return true
+	}
+	for _, b := range s.bounds {
+		if b.contains(station.Center) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *session) subscribed() subscribedMessage {
+	res := subscribedMessage{
+		Type:   messageSubscribed,
+		IDs:    []int{},
+		Bounds: append([]boundingBox{}, s.bounds...),
+	}
+	for id := range s.ids {
+		res.IDs = append(res.IDs, id)
+	}
+	sort.Ints(res.IDs)
+	return res
+}
+
+// snapshot returns the latest state of the subscribed stations
+func (s *session) snapshot() api.StationEvent {
+	res := api.StationEvent{
+		ID:   s.lastEventID,
+		Type: api.EventSnapshot,
+		At:   s.at,
+	}
+	for _, station := range s.stations {
+		res.Stations = append(res.Stations, station)
+	}
+	sort.Slice(res.Stations, func(i, j int) bool {
+		return res.Stations[i].ID < res.Stations[j].ID
+	})
+	res, _ = res.Filter(s.matches)
+	return res
+}
+
+// handle applies a message from the client and returns the replies
+func (s *session) handle(data []byte) []interface{} {
+	var msg clientMessage
+	err := json.Unmarshal(data, &msg)
+	if err != nil {
+		return []interface{}{errorMessage{Type: messageError, Message: fmt.Sprintf("failed to parse message: %s", err)}}
+	}
+	if msg.Bounds != nil {
+		err = msg.Bounds.validate()
+		if err != nil {
+			return []interface{}{errorMessage{Type: messageError, Message: err.Error()}}
+		}
+	}
+
+	switch msg.Type {
+	case messageSubscribe:
+		if len(msg.IDs) == 0 && msg.Bounds == nil {
+			return []interface{}{errorMessage{Type: messageError, Message: "expected ids or bounds to subscribe to"}}
+		}
+		for _, id := range msg.IDs {
+			s.ids[id] = true
+		}
+		if msg.Bounds != nil && !s.hasBounds(*msg.Bounds) {
+			s.bounds = append(s.bounds, *msg.Bounds)
+		}
+		return []interface{}{s.subscribed(), convertStationEvent(s.snapshot())}
+	case messageUnsubscribe:
+		// Without anything to unsubscribe from, everything goes
+		if len(msg.IDs) == 0 && msg.Bounds == nil {
+			s.ids = map[int]bool{}
+			s.bounds = nil
+		}
+		for _, id := range msg.IDs {
+			delete(s.ids, id)
+		}
+		if msg.Bounds != nil {
+			var bounds []boundingBox
+			for _, b := range s.bounds {
+				if b != *msg.Bounds {
+					bounds = append(bounds, b)
+				}
+			}
+			s.bounds = bounds
+		}
+		return []interface{}{s.subscribed()}
+	default:
+		return []interface{}{errorMessage{Type: messageError, Message: fmt.Sprintf("unknown message type: %s", msg.Type)}}
+	}
+}
+
+func (s *session) hasBounds(bounds boundingBox) bool {
+	for _, b := range s.bounds {
+		if b == bounds {
+			return true
+		}
+	}
+	return false
+}
+
+// apply updates the state of the stations with the event and
+// returns the part of it the client is subscribed to, if any
+func (s *session) apply(event api.StationEvent) (api.StationEvent, bool) {
+	if event.Type == api.EventSnapshot {
+		s.stations = map[int]api.Station{}
+	}
+	for _, station := range event.Stations {
+		s.stations[station.ID] = station
+	}
+	s.lastEventID, s.at = event.ID, event.At
+
+	if !s.isSubscribed() {
+		return event, false
+	}
+	return event.Filter(s.matches)
+}
+
+// close ends the session, the client is told why
+func (s *session) close(code int, reason string) {
+	s.closeCode, s.closeReason = code, reason
+}
+
+// run handles the events and the messages from the client, until
+// either ends or the client falls too far behind
+func (s *session) run(messages <-chan []byte, events <-chan api.StationEvent) {
+	handleEvent := func(event api.StationEvent, ok bool) bool {
+		if !ok {
+			s.close(websocket.CloseTryAgainLater, "subscription ended")
+			return false
+		}
+		event, ok = s.apply(event)
+		if ok && !s.queue(convertStationEvent(event)) {
+			s.close(websocket.CloseTryAgainLater, "too slow")
+			return false
+		}
+		return true
+	}
+
+	for {
+		// Events are handled first, so the replies to the
+		// client reflect the latest state of the stations
+		select {
+		case event, ok := <-events:
+			if !handleEvent(event, ok) {
+				return
+			}
+			continue
+		default:
+		}
+
+		select {
+		case event, ok := <-events:
+			if !handleEvent(event, ok) {
+				return
+			}
+		case data, ok := <-messages:
+			if !ok {
+				return
+			}
+			for _, reply := range s.handle(data) {
+				if !s.queue(reply) {
+					s.close(websocket.CloseTryAgainLater, "too slow")
+					return
+				}
+			}
+		}
+	}
+}
+
+// read forwards the messages from the client until the connection
+// fails, the client is expected to respond to the pings in time
+func (s *session) read(messages chan<- []byte, quit <-chan struct{}) {
+	defer close(messages)
+
+	s.conn.SetReadDeadline(time.Now().Add(PongWait))
+	s.conn.PongHandler = func([]byte) error {
+		return s.conn.SetReadDeadline(time.Now().Add(PongWait))
+	}
+
+	for {
+		_, data, err := s.conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		s.conn.SetReadDeadline(time.Now().Add(PongWait))
+		select {
+		case messages <- data:
+		case <-quit:
+			return
+		}
+	}
+}
+
+// write delivers the queued messages and pings the client, once
+// the queue is closed the client is told the session is over
+func (s *session) write(done chan<- struct{}) {
+	defer close(done)
+
+	ticker := time.NewTicker(PingInterval)
+	defer ticker.Stop()
+
+	for {
+		var err error
+		select {
+		case data, ok := <-s.send:
+			if !ok {
+				s.conn.WriteClose(s.closeCode, s.closeReason, time.Now().Add(WriteWait))
+				return
+			}
+			err = s.conn.WriteMessage(websocket.TextMessage, data, time.Now().Add(WriteWait))
+		case <-ticker.C:
+			err = s.conn.WriteControl(websocket.PingMessage, nil, time.Now().Add(WriteWait))
+		}
+		if err != nil {
+			// Closing the connection ends the reader,
+			// which in turn ends the session
+			s.conn.Close()
+			return
+		}
+	}
+}
+
+// makeWebSocketEncoder returns an encoder that takes over the
+// connection and runs the session, tracked by the sessions, errors
+// can only be reported before the upgrade
+func makeWebSocketEncoder(sessions *Sessions) kithttp.EncodeResponseFunc {
+	return func(ctx context.Context, w http.ResponseWriter, response interface{}) error {
+		return encodeWebSocketStationResponse(ctx, w, response, sessions)
+	}
+}
+
+func encodeWebSocketStationResponse(ctx context.Context, w http.ResponseWriter, response interface{}, sessions *Sessions) error {
+	res := response.(streamStationResponse)
+	defer res.Close()
+
+	// The session is counted while the server still tracks the
+	// request, so a shutdown never misses it
+	sessions.add(1)
+	defer sessions.add(-1)
+
+	r, ok := ctx.Value(contextKeyRequest).(*http.Request)
+	if !ok {
+		return errors.New(fmt.Errorf("request is missing from context"), "failed to upgrade to websocket", errors.Unavailable)
+	}
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		return errors.New(err, "failed to upgrade to websocket", errors.Unmarshal)
+	}
+	defer conn.Close()
+	defer sessions.hold(conn)()
+
+	s := newSession(conn)
+	messages := make(chan []byte)
+	quit := make(chan struct{})
+	defer close(quit)
+	go s.read(messages, quit)
+	done := make(chan struct{})
+	go s.write(done)
+
+	s.run(messages, res.Events())
+	close(s.send)
+	<-done
+
+	// Give the client a chance to acknowledge the close
+	timeout := time.After(WriteWait)
+	for {
+		select {
+		case _, ok := <-messages:
+			if !ok {
+				return nil
+			}
+		case <-timeout:
+			return nil
+		}
+	}
+}
@@ -0,0 +1,250 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-kit/kit/log"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/md"
+	"github.com/paulbes/go-pedal/pkg/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+func newWebSocketStation(id int, lat, lon float64, bikes int) api.Station {
+	return api.Station{
+		ID:            id,
+		InService:     true,
+		NumberOfLocks: 10,
+		Center:        api.Coord{Latitude: lat, Longitude: lon},
+		Availability:  api.Availability{Bikes: bikes, Locks: 10 - bikes},
+	}
+}
+
+// webSocketServer serves the websocket API with a subscription
+// that delivers the events written to the returned channel
+func webSocketServer(t *testing.T) (*httptest.Server, *openSubscription) {
+	sub := &openSubscription{
+		events: make(chan api.StationEvent, 10),
+		closed: make(chan struct{}),
+	}
+	sub.events <- api.StationEvent{
+		ID:   "e-0",
+		Type: api.EventSnapshot,
+		Stations: []api.Station{
+			newWebSocketStation(1, 59.91, 10.75, 5),
+			newWebSocketStation(2, 59.92, 10.76, 5),
+			newWebSocketStation(3, 59.95, 10.70, 5),
+		},
+	}
+	router := AttachRoutes(MakeHandlers(MakeEndpoints(Services{Stream: &recordingStreamService{sub: sub}})), log.NewNopLogger())
+	return httptest.NewServer(md.Cors(router)), sub
+}
+
+func dialWebSocket(t *testing.T, server *httptest.Server) *websocket.Conn {
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http")+"/v1/stations/ws", time.Second)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	return conn
+}
+
+// message is the part of a server message that is compared
+type message struct {
+	Type     string `json:"type"`
+	ID       string `json:"id"`
+	IDs      []int  `json:"ids"`
+	Message  string `json:"message"`
+	Stations []struct {
+		ID           int              `json:"id"`
+		Availability api.Availability `json:"availability"`
+		Closed       bool             `json:"closed"`
+	} `json:"stations"`
+}
+
+func (m message) stationIDs() []int {
+	res := []int{}
+	for _, station := range m.Stations {
+		res = append(res, station.ID)
+	}
+	return res
+}
+
+func send(t *testing.T, conn *websocket.Conn, msg string) {
+	err := conn.WriteMessage(websocket.TextMessage, []byte(msg), time.Now().Add(time.Second))
+	assert.Nil(t, err)
+}
+
+func receive(t *testing.T, conn *websocket.Conn) message {
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, data, err := conn.ReadMessage()
+	if err != nil {
+		t.Fatalf("failed to read message: %s", err)
+	}
+	var res message
+	err = json.Unmarshal(data, &res)
+	assert.Nil(t, err)
+	return res
+}
+
+func TestWebSocket_Subscribe(t *testing.T) {
+	server, sub := webSocketServer(t)
+	defer server.Close()
+	conn := dialWebSocket(t, server)
+	defer conn.Close()
+
+	// Subscribing returns the current state of the stations
+	send(t, conn, `{"type": "subscribe", "ids": [1]}`)
+	got := receive(t, conn)
+	assert.Equal(t, messageSubscribed, got.Type)
+	assert.Equal(t, []int{1}, got.IDs)
+	got = receive(t, conn)
+	assert.Equal(t, api.EventSnapshot, got.Type)
+	assert.Equal(t, "e-0", got.ID)
+	assert.Equal(t, []int{1}, got.stationIDs())
+
+	// Only the subscribed stations are delivered
+	sub.events <- api.StationEvent{ID: "e-1", Type: api.EventAvailability, Stations: []api.Station{newWebSocketStation(2, 59.92, 10.76, 4)}}
+	sub.events <- api.StationEvent{ID: "e-2", Type: api.EventAvailability, Stations: []api.Station{newWebSocketStation(1, 59.91, 10.75, 4)}}
+	got = receive(t, conn)
+	assert.Equal(t, "e-2", got.ID)
+	assert.Equal(t, []int{1}, got.stationIDs())
+	assert.Equal(t, api.Availability{Bikes: 4, Locks: 6}, got.Stations[0].Availability)
+
+	// A bounding box adds the stations within it, with their latest state
+	send(t, conn, `{"type": "subscribe", "bounds": {"min": {"latitude": 59.915, "longitude": 10.755}, "max": {"latitude": 59.93, "longitude": 10.77}}}`)
+	got = receive(t, conn)
+	assert.Equal(t, messageSubscribed, got.Type)
+	got = receive(t, conn)
+	assert.Equal(t, []int{1, 2}, got.stationIDs())
+	assert.Equal(t, 4, got.Stations[1].Availability.Bikes)
+
+	// Unsubscribing removes the stations
+	send(t, conn, `{"type": "unsubscribe", "ids": [1]}`)
+	got = receive(t, conn)
+	assert.Equal(t, messageSubscribed, got.Type)
+	assert.Equal(t, []int{}, got.IDs)
+	sub.events <- api.StationEvent{ID: "e-3", Type: api.EventClosed, Stations: []api.Station{newWebSocketStation(1, 59.91, 10.75, 4)}}
+	closed := newWebSocketStation(2, 59.92, 10.76, 4)
+	closed.Closed = true
+	sub.events <- api.StationEvent{ID: "e-4", Type: api.EventClosed, Stations: []api.Station{closed}}
+	got = receive(t, conn)
+	assert.Equal(t, api.EventClosed, got.Type)
+	assert.Equal(t, []int{2}, got.stationIDs())
+	assert.True(t, got.Stations[0].Closed)
+
+	// Mistakes are reported, without ending the session
+	for _, msg := range []string{
+		`{"type": "subscribe"`,
+		`{"type": "subscribe"}`,
+		`{"type": "subscribe", "bounds": {"min": {"latitude": 60}, "max": {"latitude": 59}}}`,
+		`{"type": "publish"}`,
+	} {
+		send(t, conn, msg)
+		got = receive(t, conn)
+		assert.Equal(t, messageError, got.Type, msg)
+		assert.NotEmpty(t, got.Message, msg)
+	}
+
+	// Unsubscribing from everything
+	send(t, conn, `{"type": "unsubscribe"}`)
+	got = receive(t, conn)
+	assert.Equal(t, messageSubscribed, got.Type)
+	sub.events <- api.StationEvent{ID: "e-5", Type: api.EventReopened, Stations: []api.Station{newWebSocketStation(2, 59.92, 10.76, 4)}}
+	send(t, conn, `{"type": "subscribe", "ids": [3]}`)
+	got = receive(t, conn)
+	assert.Equal(t, messageSubscribed, got.Type, "Nothing was delivered")
+	assert.Equal(t, []int{3}, got.IDs)
+
+	// The client closes the session
+	err := conn.WriteClose(websocket.CloseNormalClosure, "", time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	select {
+	case <-sub.closed:
+	case <-time.After(time.Second):
+		t.Error("expected the subscription to be closed")
+	}
+}
+
+func TestWebSocket_Keepalive(t *testing.T) {
+	PingInterval = 10 * time.Millisecond
+	PongWait = 50 * time.Millisecond
+	defer func() {
+		PingInterval = 30 * time.Second
+		PongWait = 60 * time.Second
+	}()
+
+	server, _ := webSocketServer(t)
+	defer server.Close()
+
+	// A client that responds to the pings stays connected
+	conn := dialWebSocket(t, server)
+	defer conn.Close()
+	pings := 0
+	defaultHandler := conn.PingHandler
+	conn.PingHandler = func(payload []byte) error {
+		pings++
+		return defaultHandler(payload)
+	}
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		send(t, conn, `{"type": "subscribe", "ids": [1]}`)
+	}()
+	got := receive(t, conn)
+	assert.Equal(t, messageSubscribed, got.Type)
+	assert.True(t, pings > 5, "Pinged")
+
+	// A client that does not is disconnected
+	silent := dialWebSocket(t, server)
+	defer silent.Close()
+	silent.PingHandler = func([]byte) error { return nil }
+	silent.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := silent.ReadMessage()
+	assert.NotNil(t, err)
+}
+
+func TestWebSocket_SubscriptionEnded(t *testing.T) {
+	server, sub := webSocketServer(t)
+	defer server.Close()
+	conn := dialWebSocket(t, server)
+	defer conn.Close()
+
+	// The stream drops subscribers that fall behind
+	close(sub.events)
+	conn.SetReadDeadline(time.Now().Add(time.Second))
+	_, _, err := conn.ReadMessage()
+	assert.Equal(t, &websocket.CloseError{Code: websocket.CloseTryAgainLater, Reason: "subscription ended"}, err)
+}
+
+func TestSession_Backpressure(t *testing.T) {
+	SendBufferSize = 1
+	defer func() { SendBufferSize = 64 }()
+
+	s := newSession(nil)
+	events := make(chan api.StationEvent, 3)
+	events <- api.StationEvent{ID: "e-0", Type: api.EventSnapshot, Stations: []api.Station{newWebSocketStation(1, 59.91, 10.75, 5)}}
+	s.ids[1] = true
+	for i := 0; i < 2; i++ {
+		events <- api.StationEvent{ID: "e-1", Type: api.EventAvailability, Stations: []api.Station{newWebSocketStation(1, 59.91, 10.75, i)}}
+	}
+
+	// Nobody is writing, so the second message does not fit
+	s.run(make(chan []byte), events)
+	assert.Len(t, s.send, 1)
+	assert.Equal(t, websocket.CloseTryAgainLater, s.closeCode)
+	assert.Equal(t, "too slow", s.closeReason)
+}
+
+func TestWebSocket_NotUpgrade(t *testing.T) {
+	server, _ := webSocketServer(t)
+	defer server.Close()
+
+	resp, err := http.Get(server.URL + "/v1/stations/ws")
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
@@ -1,6 +1,11 @@
 package api
 
-import "context"
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+)
 
 // Note: this represents our API domain model
 // I have decoupled this from the pedlar model
@@ -17,6 +22,7 @@ type Station struct {
 	Bounds        []Coord      `json:"bounds"`
 	Availability  Availability `json:"-"`
 	Closed        bool         `json:"-"`
+	UpdatedAt     time.Time    `json:"-"`
 }
 
 // Availability describes how many locks
@@ -32,16 +38,92 @@ type Coord struct {
 	Longitude float64 `json:"longitude"`
 }
 
+// Distance returns the great circle distance in metres
+// between the two coordinates
+func (c Coord) Distance(to Coord) float64 {
+	return model.Coord(c).Distance(model.Coord(to))
+}
+
+// The fields stations can be sorted by
+const (
+	SortByID       = "id"
+	SortByTitle    = "title"
+	SortByBikes    = "bikes"
+	SortByLocks    = "locks"
+	SortByDistance = "distance"
+)
+
+// StationQuery describes which stations to list and in
+// what order, the optional filters are ignored when nil
+// or empty. A limit of zero returns all stations.
+type StationQuery struct {
+	Closed     *bool
+	InService  *bool
+	MinBikes   int
+	MinLocks   int
+	Title      string
+	Sort       string
+	Descending bool
+	// Origin is required when sorting by distance
+	Origin *Coord
+	Offset int
+	Limit  int
+}
+
+// StationList contains a page of stations and the total
+// number of stations that matched the query
+type StationList struct {
+	Stations []Station
+	Total    int
+}
+
+// NearbyStation represents a station and its
+// distance in metres from a coordinate
+type NearbyStation struct {
+	Station  Station `json:"station"`
+	Distance float64 `json:"distance"`
+}
+
+// Forecast describes the expected availability of
+// bikes and locks at a station in the future, the
+// horizon is provided in seconds
+type Forecast struct {
+	StationID       int       `json:"station_id"`
+	At              time.Time `json:"at"`
+	Horizon         float64   `json:"horizon"`
+	Bikes           float64   `json:"bikes"`
+	Locks           float64   `json:"locks"`
+	BikeProbability float64   `json:"bike_probability"`
+	LockProbability float64   `json:"lock_probability"`
+}
+
+// StationVersion identifies the data the stations are served
+// from, it changes whenever the stations, their availability or
+// their status change. The refresh rate is how often the data is
+// expected to change.
+type StationVersion struct {
+	Tag         string
+	UpdatedAt   time.Time
+	RefreshRate time.Duration
+}
+
 // StationService defines what methods a station
 // service implementation must implement
 type StationService interface {
 	Get(ctx context.Context, id int) (Station, error)
-	List(ctx context.Context) ([]Station, error)
+	List(ctx context.Context, query StationQuery) (StationList, error)
+	Locate(ctx context.Context, coord Coord) (Station, error)
+	Nearby(ctx context.Context, coord Coord, radius float64, limit int) ([]NearbyStation, error)
+	Forecast(ctx context.Context, id int, horizon time.Duration) (Forecast, error)
+	Version(ctx context.Context) (StationVersion, error)
 }
 
 // StationStore defines what methods a station
 // storage implementation must implement
 type StationStore interface {
-	Get(id int) (Station, error)
-	List() ([]Station, error)
+	Get(ctx context.Context, id int) (Station, error)
+	List(ctx context.Context) ([]Station, error)
+	Locate(ctx context.Context, coord Coord) (Station, error)
+	Forecast(ctx context.Context, id int, horizon time.Duration) (Forecast, error)
+	Version(ctx context.Context) (StationVersion, error)
 }
@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// Percentile represents the value at or below which
+// the provided share of the stations fall
+type Percentile struct {
+	Percentile float64 `json:"percentile"`
+	Value      float64 `json:"value"`
+}
+
+// Stats represents aggregate statistics for all stations
+type Stats struct {
+	UpdatedAt time.Time `json:"updated_at"`
+	Stations  int       `json:"stations"`
+	Open      int       `json:"open"`
+	Closed    int       `json:"closed"`
+	Empty     int       `json:"empty"`
+	Full      int       `json:"full"`
+	Bikes     int       `json:"bikes"`
+	Locks     int       `json:"locks"`
+	Capacity  int       `json:"capacity"`
+	// BikesInUseEstimate assumes that the locks that are neither
+	// free nor have a bike are waiting for a bike that is out
+	BikesInUseEstimate int          `json:"bikes_in_use_estimate"`
+	Utilisation        []Percentile `json:"utilisation"`
+}
+
+// StatsService defines what methods a stats
+// service implementation must implement
+type StatsService interface {
+	Get(ctx context.Context) (*Stats, error)
+}
+
+// StatsStore defines what methods a stats
+// storage implementation must implement
+type StatsStore interface {
+	Get(ctx context.Context) (*Stats, error)
+}
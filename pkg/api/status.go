@@ -0,0 +1,42 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// FeedStatus describes the calls to one of the feeds of the
+// upstream API, the age is the number of seconds since the
+// last successful call, the times are null until it happens
+type FeedStatus struct {
+	LastSuccess *time.Time `json:"last_success"`
+	LastError   string     `json:"last_error,omitempty"`
+	LastErrorAt *time.Time `json:"last_error_at,omitempty"`
+	Age         *float64   `json:"age"`
+}
+
+// Status describes how up to date the stations are, the age
+// is the number of seconds since the availability was updated
+// by the upstream API, it is null until the stations are loaded
+type Status struct {
+	Ready             bool                  `json:"ready"`
+	Reason            string                `json:"reason,omitempty"`
+	Loaded            bool                  `json:"loaded"`
+	Stale             bool                  `json:"stale"`
+	UpdatedAt         *time.Time            `json:"updated_at"`
+	Age               *float64              `json:"age"`
+	AllStationsClosed bool                  `json:"all_stations_closed"`
+	Feeds             map[string]FeedStatus `json:"feeds"`
+}
+
+// StatusService defines what methods a status
+// service implementation must implement
+type StatusService interface {
+	Get(ctx context.Context) (Status, error)
+}
+
+// StatusStore defines what methods a status
+// storage implementation must implement
+type StatusStore interface {
+	Get(ctx context.Context) (Status, error)
+}
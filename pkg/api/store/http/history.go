@@ -0,0 +1,101 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/history"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+type historyStore struct {
+	pedlar  pedal.Pedlar
+	history history.Store
+}
+
+// Samples reads the recorded history of the station
+// within the time range
+func (s *historyStore) Samples(ctx context.Context, id int, from, to time.Time) ([]api.HistorySample, error) {
+	if s.history == nil {
+		return nil, errHistoryDisabled()
+	}
+
+	stations, err := s.pedlar.Stations(ctx)
+	if err != nil {
+		return nil, errors.New(err, "failed to read stations", errors.IO)
+	}
+	if _, hasKey := stations[id]; !hasKey {
+		return nil, errors.New(fmt.Errorf("no such id: %d", id), "could not find station", errors.NotFound)
+	}
+
+	samples, err := s.history.Query(id, from, to)
+	if err != nil {
+		return nil, errors.New(err, "failed to read history", errors.IO)
+	}
+
+	res := []api.HistorySample{}
+	for _, sample := range samples {
+		res = append(res, api.HistorySample{
+			Time: sample.Time,
+			Availability: api.Availability{
+				Bikes: sample.Bikes,
+				Locks: sample.Locks,
+			},
+			Closed:    sample.Closed,
+			InService: sample.InService,
+		})
+	}
+	return res, nil
+}
+
+// At returns the stations as they were recorded at the provided
+// time, stations without a recording at the time are left out
+func (s *historyStore) At(ctx context.Context, at time.Time) ([]api.Station, error) {
+	if s.history == nil {
+		return nil, errHistoryDisabled()
+	}
+
+	stations, err := s.pedlar.Stations(ctx)
+	if err != nil {
+		return nil, errors.New(err, "failed to read stations", errors.IO)
+	}
+
+	samples, err := s.history.At(at)
+	if err != nil {
+		return nil, errors.New(err, "failed to read history", errors.IO)
+	}
+
+	res := []api.Station{}
+	for _, sample := range samples {
+		station, hasKey := stations[sample.StationID]
+		if !hasKey {
+			continue
+		}
+		converted := convertStation(station)
+		converted.InService = sample.InService
+		converted.Closed = sample.Closed
+		converted.UpdatedAt = sample.Time
+		converted.Availability = api.Availability{
+			Bikes: sample.Bikes,
+			Locks: sample.Locks,
+		}
+		res = append(res, converted)
+	}
+	return res, nil
+}
+
+func errHistoryDisabled() error {
+	return errors.New(fmt.Errorf("no history store configured"), "history is not recorded", errors.Unavailable)
+}
+
+// NewHistoryStore creates a new history store, if no history is
+// provided the store reports that the history is unavailable
+func NewHistoryStore(pedlar pedal.Pedlar, history history.Store) api.HistoryStore {
+	return &historyStore{
+		pedlar:  pedlar,
+		history: history,
+	}
+}
@@ -0,0 +1,151 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/history"
+	mock3 "github.com/paulbes/go-pedal/pedal/model/mock"
+	"github.com/paulbes/go-pedal/pkg/api"
+	mock2 "github.com/paulbes/go-pedal/pkg/api/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+var epoch = time.Date(2018, 10, 1, 0, 0, 0, 0, time.UTC)
+
+func openHistory(t *testing.T) (history.Store, func()) {
+	dir, err := ioutil.TempDir("", "history")
+	assert.Nil(t, err)
+
+	store, err := history.Open(dir, time.Hour, 0)
+	assert.Nil(t, err)
+	err = store.Append(
+		history.Sample{Time: epoch, StationID: 1, Bikes: 2, Locks: 8, InService: true},
+		history.Sample{Time: epoch.Add(time.Minute), StationID: 1, Bikes: 3, Locks: 7, Closed: true, InService: true},
+		history.Sample{Time: epoch.Add(time.Minute), StationID: 1000, Bikes: 3, Locks: 7},
+	)
+	assert.Nil(t, err)
+
+	return store, func() {
+		store.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+func TestHistoryStore_Samples(t *testing.T) {
+	store, cleanup := openHistory(t)
+	defer cleanup()
+
+	testCases := []struct {
+		Name      string
+		ID        int
+		History   history.Store
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:    "Get samples",
+			ID:      1,
+			History: store,
+			Expect: []api.HistorySample{
+				{Time: epoch, Availability: api.Availability{Bikes: 2, Locks: 8}, InService: true},
+				{Time: epoch.Add(time.Minute), Availability: api.Availability{Bikes: 3, Locks: 7}, Closed: true, InService: true},
+			},
+		},
+		{
+			Name:      "Get samples, bad id",
+			ID:        1000,
+			History:   store,
+			ExpectErr: true,
+			Expect:    "notfound: could not find station: no such id: 1000",
+		},
+		{
+			Name:      "Get samples, storage error",
+			ID:        1,
+			History:   store,
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to read stations: could not connect to API",
+		},
+		{
+			Name:      "Get samples, not recorded",
+			ID:        1,
+			ExpectErr: true,
+			Expect:    "unavailable: history is not recorded: no history store configured",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
+		store := NewHistoryStore(pedal.New(client), tc.History)
+		got, err := store.Samples(context.Background(), tc.ID, epoch, epoch.Add(time.Hour))
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.Expect, got)
+		}
+	}
+}
+
+func TestHistoryStore_At(t *testing.T) {
+	store, cleanup := openHistory(t)
+	defer cleanup()
+
+	station := mock2.NewStation()
+	station.Availability = api.Availability{Bikes: 2, Locks: 8}
+	station.UpdatedAt = epoch
+
+	testCases := []struct {
+		Name      string
+		At        time.Time
+		History   history.Store
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:    "Get stations at",
+			At:      epoch.Add(30 * time.Second),
+			History: store,
+			Expect:  []api.Station{station},
+		},
+		{
+			Name:    "Get stations before anything",
+			At:      epoch.Add(-time.Second),
+			History: store,
+			Expect:  []api.Station{},
+		},
+		{
+			Name:      "Get stations at, storage error",
+			History:   store,
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to read stations: could not connect to API",
+		},
+		{
+			Name:      "Get stations at, not recorded",
+			ExpectErr: true,
+			Expect:    "unavailable: history is not recorded: no history store configured",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
+		store := NewHistoryStore(pedal.New(client), tc.History)
+		got, err := store.At(context.Background(), tc.At)
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.Expect, got)
+		}
+	}
+}
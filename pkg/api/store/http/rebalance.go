@@ -0,0 +1,53 @@
+package http
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pedal/rebalance"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+type rebalanceStore struct {
+	planner rebalance.Planner
+}
+
+// Plan asks the planner for the balance of the stations and
+// the transfers that would even them out
+func (s *rebalanceStore) Plan(ctx context.Context, capacity int) (*api.Rebalance, error) {
+	plan, err := s.planner.Plan(ctx, capacity)
+	if err != nil {
+		return nil, errors.New(err, "failed to plan rebalancing", errors.IO)
+	}
+
+	res := &api.Rebalance{
+		Stations:  []api.StationBalance{},
+		Transfers: []api.Transfer{},
+	}
+	for _, status := range plan.Stations {
+		res.Stations = append(res.Stations, api.StationBalance{
+			Station:   convertStation(status.Station),
+			Level:     status.Level.String(),
+			Bikes:     status.Station.Availability.Bikes,
+			Locks:     status.Station.Availability.Locks,
+			Projected: status.Projected,
+			Target:    status.Target,
+		})
+	}
+	for _, transfer := range plan.Transfers {
+		res.Transfers = append(res.Transfers, api.Transfer{
+			From:     convertStation(transfer.From),
+			To:       convertStation(transfer.To),
+			Count:    transfer.Count,
+			Distance: transfer.Distance,
+		})
+	}
+	return res, nil
+}
+
+// NewRebalanceStore creates a new rebalance store
+func NewRebalanceStore(planner rebalance.Planner) api.RebalanceStore {
+	return &rebalanceStore{
+		planner: planner,
+	}
+}
@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/rebalance"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRebalanceStore_Plan(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			{ID: 1, InService: true, Title: "A", NumberOfLocks: 10, Center: model.Coord{Latitude: 59.91, Longitude: 10.75}, Availability: model.Availability{Bikes: 10, Locks: 0}},
+			{ID: 2, InService: true, Title: "B", NumberOfLocks: 10, Center: model.Coord{Latitude: 59.92, Longitude: 10.75}, Availability: model.Availability{Bikes: 0, Locks: 10}},
+		},
+	}
+
+	testCases := []struct {
+		Name      string
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:   "Plan rebalancing",
+			Expect: [][3]int{{1, 2, 5}},
+		},
+		{
+			Name:      "Plan rebalancing, storage error",
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to plan rebalancing: could not connect to API",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(stations, &model.StationAvailability{}, &model.Status{}, tc.Err)
+		store := NewRebalanceStore(rebalance.New(pedal.New(client), nil))
+		got, err := store.Plan(context.Background(), 20)
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			var transfers [][3]int
+			for _, transfer := range got.Transfers {
+				transfers = append(transfers, [3]int{transfer.From.ID, transfer.To.ID, transfer.Count})
+			}
+			assert.Equal(t, tc.Expect, transfers)
+			assert.Equal(t, "full", got.Stations[0].Level)
+			assert.Equal(t, "empty", got.Stations[1].Level)
+		}
+	}
+}
@@ -1,7 +1,9 @@
 package http
 
 import (
+	"context"
 	"fmt"
+	"time"
 
 	"github.com/paulbes/go-pedal/pedal/model"
 
@@ -19,34 +21,95 @@ type stationStore struct {
 
 // Get reads the stations from the pedlar client and returns
 // the station that was requested
-func (s *stationStore) Get(id int) (api.Station, error) {
-	stations, err := s.pedlar.Stations()
+func (s *stationStore) Get(ctx context.Context, id int) (api.Station, error) {
+	snapshot, err := s.pedlar.Snapshot(ctx)
 	if err != nil {
 		return api.Station{}, errors.New(err, "failed to read station", errors.IO)
 	}
 
-	station, hasKey := stations[id]
+	station, hasKey := snapshot.Stations[id]
 	if !hasKey {
 		return api.Station{}, errors.New(fmt.Errorf("no such id: %d", id), "could not find station", errors.NotFound)
 	}
 
-	return convertStation(station), nil
+	res := convertStation(station)
+	res.UpdatedAt = snapshot.UpdatedAt
+	return res, nil
 }
 
 // List reads the stations from the pedlar client and returns
 // all the stations
-func (s *stationStore) List() ([]api.Station, error) {
-	stations, err := s.pedlar.Stations()
+func (s *stationStore) List(ctx context.Context) ([]api.Station, error) {
+	snapshot, err := s.pedlar.Snapshot(ctx)
 	if err != nil {
 		return nil, errors.New(err, "failed to read stations", errors.IO)
 	}
 	var res []api.Station
-	for _, station := range stations {
-		res = append(res, convertStation(station))
+	for _, station := range snapshot.Stations {
+		converted := convertStation(station)
+		converted.UpdatedAt = snapshot.UpdatedAt
+		res = append(res, converted)
 	}
 	return res, nil
 }
 
+// Locate reads the stations from the pedlar client and returns
+// the station found at the provided coordinate
+func (s *stationStore) Locate(ctx context.Context, coord api.Coord) (api.Station, error) {
+	station, found, err := s.pedlar.StationAt(ctx, convertCoord(coord))
+	if err != nil {
+		return api.Station{}, errors.New(err, "failed to read stations", errors.IO)
+	}
+
+	if !found {
+		return api.Station{}, errors.New(fmt.Errorf("no station at: %g, %g", coord.Latitude, coord.Longitude), "could not locate station", errors.NotFound)
+	}
+
+	return convertStation(station), nil
+}
+
+// Forecast asks pedlar for the expected availability
+// at the station the horizon into the future
+func (s *stationStore) Forecast(ctx context.Context, id int, horizon time.Duration) (api.Forecast, error) {
+	forecast, found, err := s.pedlar.Forecast(ctx, id, horizon)
+	if err == pedal.ErrForecastDisabled {
+		return api.Forecast{}, errors.New(err, "failed to forecast station", errors.Unavailable)
+	}
+	if err != nil {
+		return api.Forecast{}, errors.New(err, "failed to forecast station", errors.IO)
+	}
+
+	if !found {
+		return api.Forecast{}, errors.New(fmt.Errorf("no such id: %d", id), "could not find station", errors.NotFound)
+	}
+
+	return api.Forecast{
+		StationID:       forecast.StationID,
+		At:              forecast.At,
+		Horizon:         forecast.Horizon.Seconds(),
+		Bikes:           forecast.Bikes,
+		Locks:           forecast.Locks,
+		BikeProbability: forecast.BikeProbability,
+		LockProbability: forecast.LockProbability,
+	}, nil
+}
+
+// Version reads the version of the latest snapshot from the pedlar
+// client, it is refreshed when due, like the stations themselves,
+// so the version never lags behind what is served
+func (s *stationStore) Version(ctx context.Context) (api.StationVersion, error) {
+	snapshot, err := s.pedlar.Snapshot(ctx)
+	if err != nil {
+		return api.StationVersion{}, errors.New(err, "failed to read station version", errors.IO)
+	}
+
+	return api.StationVersion{
+		Tag:         snapshot.Version,
+		UpdatedAt:   snapshot.UpdatedAt,
+		RefreshRate: time.Duration(float64(snapshot.RefreshRate) * float64(time.Second)),
+	}, nil
+}
+
 // convertStation maps stations between the two domain
 // models
 func convertStation(station *model.Station) api.Station {
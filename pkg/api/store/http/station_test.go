@@ -1,8 +1,12 @@
 package http
 
 import (
+	"context"
 	"fmt"
 	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/model"
 
 	mock3 "github.com/paulbes/go-pedal/pedal/model/mock"
 
@@ -14,6 +18,9 @@ import (
 )
 
 func TestStationStore_Get(t *testing.T) {
+	station := mock2.NewStation()
+	station.UpdatedAt = mock3.NewStationAvailability().UpdatedAt
+
 	testCases := []struct {
 		Name      string
 		ID        int
@@ -25,7 +32,7 @@ func TestStationStore_Get(t *testing.T) {
 			Name:      "Get station",
 			ID:        1,
 			ExpectErr: false,
-			Expect:    mock2.NewStation(),
+			Expect:    station,
 		},
 		{
 			Name:      "Get station, bad id",
@@ -45,7 +52,7 @@ func TestStationStore_Get(t *testing.T) {
 	for _, tc := range testCases {
 		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
 		store := NewStationStore(pedal.New(client))
-		got, err := store.Get(tc.ID)
+		got, err := store.Get(context.Background(), tc.ID)
 		if tc.ExpectErr {
 			assert.Equal(t, err.Error(), tc.Expect)
 		} else {
@@ -56,6 +63,9 @@ func TestStationStore_Get(t *testing.T) {
 }
 
 func TestStationStore_List(t *testing.T) {
+	station := mock2.NewStation()
+	station.UpdatedAt = mock3.NewStationAvailability().UpdatedAt
+
 	testCases := []struct {
 		Name      string
 		Err       error
@@ -65,7 +75,7 @@ func TestStationStore_List(t *testing.T) {
 		{
 			Name:      "List stations",
 			ExpectErr: false,
-			Expect:    []api.Station{mock2.NewStation()},
+			Expect:    []api.Station{station},
 		},
 		{
 			Name:      "List stations, storage error",
@@ -78,7 +88,49 @@ func TestStationStore_List(t *testing.T) {
 	for _, tc := range testCases {
 		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
 		store := NewStationStore(pedal.New(client))
-		got, err := store.List()
+		got, err := store.List(context.Background())
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.Expect, got)
+		}
+	}
+}
+
+func TestStationStore_Locate(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Coord     api.Coord
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:      "Locate station",
+			Coord:     api.Coord{Latitude: 59.10, Longitude: 59.09},
+			ExpectErr: false,
+			Expect:    mock2.NewStation(),
+		},
+		{
+			Name:      "Locate station, nothing there",
+			Coord:     api.Coord{Latitude: 10, Longitude: 10},
+			ExpectErr: true,
+			Expect:    "notfound: could not locate station: no station at: 10, 10",
+		},
+		{
+			Name:      "Locate station, storage error",
+			Coord:     api.Coord{Latitude: 59.10, Longitude: 59.09},
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to read stations: could not connect to API",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
+		store := NewStationStore(pedal.New(client))
+		got, err := store.Locate(context.Background(), tc.Coord)
 		if tc.ExpectErr {
 			assert.Equal(t, err.Error(), tc.Expect)
 		} else {
@@ -87,3 +139,121 @@ func TestStationStore_List(t *testing.T) {
 		}
 	}
 }
+
+type forecaster struct{}
+
+func (forecaster) Forecast(station *model.Station, now time.Time, horizon time.Duration) (*model.Forecast, error) {
+	return &model.Forecast{
+		StationID:       station.ID,
+		At:              now.Add(horizon),
+		Horizon:         horizon,
+		Bikes:           4.5,
+		Locks:           5.5,
+		BikeProbability: 0.75,
+		LockProbability: 0.95,
+	}, nil
+}
+
+func TestStationStore_Forecast(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		ID        int
+		Options   []pedal.Option
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:    "Forecast station",
+			ID:      1,
+			Options: []pedal.Option{pedal.WithForecaster(forecaster{})},
+			Expect: api.Forecast{
+				StationID:       1,
+				At:              mock3.NewStationAvailability().UpdatedAt.Add(20 * time.Minute),
+				Horizon:         1200,
+				Bikes:           4.5,
+				Locks:           5.5,
+				BikeProbability: 0.75,
+				LockProbability: 0.95,
+			},
+		},
+		{
+			Name:      "Forecast station, bad id",
+			ID:        1000,
+			Options:   []pedal.Option{pedal.WithForecaster(forecaster{})},
+			ExpectErr: true,
+			Expect:    "notfound: could not find station: no such id: 1000",
+		},
+		{
+			Name:      "Forecast station, not enabled",
+			ID:        1,
+			ExpectErr: true,
+			Expect:    "unavailable: failed to forecast station: forecasting is not enabled",
+		},
+		{
+			Name:      "Forecast station, storage error",
+			ID:        1,
+			Options:   []pedal.Option{pedal.WithForecaster(forecaster{})},
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to forecast station: could not connect to API",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
+		store := NewStationStore(pedal.New(client, tc.Options...))
+		got, err := store.Forecast(context.Background(), tc.ID, 20*time.Minute)
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.Expect, got)
+		}
+	}
+}
+
+func TestStationStore_Version(t *testing.T) {
+	testCases := []struct {
+		Name      string
+		Loaded    bool
+		Err       error
+		ExpectErr string
+	}{
+		{
+			Name:   "Version",
+			Loaded: true,
+		},
+		{
+			Name: "Version, refreshed when nothing is loaded",
+		},
+		{
+			Name:      "Version, nothing to load",
+			Err:       fmt.Errorf("something went wrong"),
+			ExpectErr: "io: failed to read station version: something went wrong",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), mock3.NewStatus(), tc.Err)
+		pedlar := pedal.New(client)
+		store := NewStationStore(pedlar)
+		if tc.Loaded {
+			_, err := pedlar.Snapshot(context.Background())
+			assert.Nil(t, err, tc.Name)
+		}
+		got, err := store.Version(context.Background())
+		if len(tc.ExpectErr) > 0 {
+			assert.EqualError(t, err, tc.ExpectErr, tc.Name)
+			continue
+		}
+		assert.Nil(t, err, tc.Name)
+		snapshot, ok := pedlar.Last()
+		assert.True(t, ok, tc.Name)
+		assert.Equal(t, api.StationVersion{
+			Tag:         snapshot.Version,
+			UpdatedAt:   mock3.NewStationAvailability().UpdatedAt,
+			RefreshRate: 10 * time.Second,
+		}, got, tc.Name)
+	}
+}
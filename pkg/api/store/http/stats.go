@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/stats"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+type statsStore struct {
+	pedlar pedal.Pedlar
+}
+
+// Get computes the statistics from the latest snapshot
+// of the stations
+func (s *statsStore) Get(ctx context.Context) (*api.Stats, error) {
+	snapshot, err := s.pedlar.Snapshot(ctx)
+	if err != nil {
+		return nil, errors.New(err, "failed to read stations", errors.IO)
+	}
+
+	computed := stats.Compute(snapshot)
+	res := &api.Stats{
+		UpdatedAt:          computed.UpdatedAt,
+		Stations:           computed.Stations,
+		Open:               computed.Open,
+		Closed:             computed.Closed,
+		Empty:              computed.Empty,
+		Full:               computed.Full,
+		Bikes:              computed.Bikes,
+		Locks:              computed.Locks,
+		Capacity:           computed.Capacity,
+		BikesInUseEstimate: computed.BikesInUseEstimate,
+		Utilisation:        []api.Percentile{},
+	}
+	for _, p := range computed.Utilisation {
+		res.Utilisation = append(res.Utilisation, api.Percentile{
+			Percentile: p.Percentile,
+			Value:      p.Value,
+		})
+	}
+	return res, nil
+}
+
+// NewStatsStore creates a new stats store
+func NewStatsStore(pedlar pedal.Pedlar) api.StatsStore {
+	return &statsStore{
+		pedlar: pedlar,
+	}
+}
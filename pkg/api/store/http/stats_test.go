@@ -0,0 +1,52 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatsStore_Get(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			{ID: 1, InService: true, Title: "A", NumberOfLocks: 10, Availability: model.Availability{Bikes: 0, Locks: 9}},
+			{ID: 2, InService: true, Title: "B", NumberOfLocks: 10, Availability: model.Availability{Bikes: 6, Locks: 4}},
+		},
+	}
+
+	testCases := []struct {
+		Name      string
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:   "Get stats",
+			Expect: [4]int{6, 13, 1, 1},
+		},
+		{
+			Name:      "Get stats, storage error",
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to read stations: could not connect to API",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(stations, &model.StationAvailability{}, &model.Status{}, tc.Err)
+		store := NewStatsStore(pedal.New(client))
+		got, err := store.Get(context.Background())
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			assert.Equal(t, tc.Expect, [4]int{got.Bikes, got.Locks, got.BikesInUseEstimate, got.Empty})
+			assert.Len(t, got.Utilisation, 5)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package http
+
+import (
+	"context"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pkg/api"
+)
+
+type statusStore struct {
+	pedlar pedal.Pedlar
+}
+
+// Get reports the health of the pedlar client, this does
+// not refresh the stations, so it never calls the API
+func (s *statusStore) Get(ctx context.Context) (api.Status, error) {
+	health := s.pedlar.Health()
+	now := time.Now()
+
+	res := api.Status{
+		Loaded:            health.Loaded,
+		Stale:             health.Stale,
+		AllStationsClosed: health.AllStationsClosed,
+		Feeds:             map[string]api.FeedStatus{},
+	}
+	if health.Loaded {
+		res.UpdatedAt, res.Age = since(health.UpdatedAt, now)
+	}
+	for name, feed := range health.Feeds {
+		status := api.FeedStatus{
+			LastError: feed.LastError,
+		}
+		status.LastSuccess, status.Age = since(feed.LastSuccess, now)
+		if !feed.LastErrorAt.IsZero() {
+			at := feed.LastErrorAt
+			status.LastErrorAt = &at
+		}
+		res.Feeds[name] = status
+	}
+	return res, nil
+}
+
+// since returns the time and the number of seconds that have
+// passed since then, both are nil if it has not happened
+func since(t time.Time, now time.Time) (*time.Time, *float64) {
+	if t.IsZero() {
+		return nil, nil
+	}
+	age := now.Sub(t).Seconds()
+	return &t, &age
+}
+
+// NewStatusStore creates a new status store
+func NewStatusStore(pedlar pedal.Pedlar) api.StatusStore {
+	return &statusStore{
+		pedlar: pedlar,
+	}
+}
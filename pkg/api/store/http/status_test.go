@@ -0,0 +1,58 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	mock3 "github.com/paulbes/go-pedal/pedal/model/mock"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStatusStore_Get(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		Err         error
+		ExpectError string
+	}{
+		{
+			Name: "Get status",
+		},
+		{
+			Name:        "Get status, storage error",
+			Err:         fmt.Errorf("could not connect to API"),
+			ExpectError: "could not connect to API",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(mock3.NewStations(), mock3.NewStationAvailability(), &model.Status{AllStationsClosed: true}, tc.Err)
+		pedlar := pedal.New(client)
+		store := NewStatusStore(pedlar)
+
+		// Nothing is loaded until the stations are requested
+		got, err := store.Get(context.Background())
+		assert.Nil(t, err, tc.Name)
+		assert.False(t, got.Loaded, tc.Name)
+		assert.Nil(t, got.Age, tc.Name)
+		assert.Empty(t, got.Feeds, tc.Name)
+
+		pedlar.Stations(context.Background())
+		got, err = store.Get(context.Background())
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, tc.Err == nil, got.Loaded, tc.Name)
+		assert.Equal(t, tc.Err == nil, got.AllStationsClosed, tc.Name)
+
+		feed := got.Feeds[model.FeedStations]
+		assert.Equal(t, tc.ExpectError, feed.LastError, tc.Name)
+		assert.Equal(t, tc.Err == nil, feed.Age != nil, tc.Name)
+		assert.Equal(t, tc.Err != nil, feed.LastErrorAt != nil, tc.Name)
+		if tc.Err == nil {
+			assert.True(t, mock3.NewStationAvailability().UpdatedAt.Equal(*got.UpdatedAt), tc.Name)
+			assert.True(t, *got.Age > 0, tc.Name)
+		}
+	}
+}
@@ -0,0 +1,110 @@
+package http
+
+import (
+	"context"
+	"sync"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/stream"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+type streamStore struct {
+	pedlar pedal.Pedlar
+	broker *stream.Broker
+}
+
+// Subscribe refreshes the stations, so there is something to start
+// out with, and subscribes to the changes published by the broker
+func (s *streamStore) Subscribe(ctx context.Context, lastEventID string) (api.StationSubscription, error) {
+	snapshot, err := s.pedlar.Snapshot(ctx)
+	if err != nil {
+		return nil, errors.New(err, "failed to read stations", errors.IO)
+	}
+	// The snapshot might have been served from the cache, or
+	// loaded from disk, so the broker has not necessarily seen it
+	s.broker.Observe(snapshot)
+
+	sub, initial, err := s.broker.Subscribe(lastEventID)
+	if err != nil {
+		return nil, errors.New(err, "failed to subscribe to stations", errors.Unavailable)
+	}
+
+	res := &subscription{
+		sub:    sub,
+		events: make(chan api.StationEvent),
+		done:   make(chan struct{}),
+	}
+	go res.run(initial)
+	return res, nil
+}
+
+// subscription converts the events from the broker
+type subscription struct {
+	sub    *stream.Subscription
+	events chan api.StationEvent
+	done   chan struct{}
+	once   sync.Once
+}
+
+func (s *subscription) Events() <-chan api.StationEvent {
+	return s.events
+}
+
+func (s *subscription) Close() {
+	s.once.Do(func() {
+		close(s.done)
+		s.sub.Close()
+	})
+}
+
+// run delivers the initial events followed by those from the
+// broker, until either side ends the subscription
+func (s *subscription) run(initial []stream.Event) {
+	defer close(s.events)
+
+	send := func(event stream.Event) bool {
+		select {
+		case s.events <- convertEvent(event):
+			return true
+		case <-s.done:
+			return false
+		}
+	}
+
+	for _, event := range initial {
+		if !send(event) {
+			return
+		}
+	}
+	for event := range s.sub.Events() {
+		if !send(event) {
+			return
+		}
+	}
+}
+
+func convertEvent(event stream.Event) api.StationEvent {
+	res := api.StationEvent{
+		ID:       event.ID,
+		Type:     event.Type,
+		At:       event.At,
+		Stations: []api.Station{},
+	}
+	for _, station := range event.Stations {
+		s := convertStation(station)
+		s.UpdatedAt = event.At
+		res.Stations = append(res.Stations, s)
+	}
+	return res
+}
+
+// NewStreamStore creates a new stream store, the broker
+// must be registered with the pedlar using pedal.OnRefresh
+func NewStreamStore(pedlar pedal.Pedlar, broker *stream.Broker) api.StreamStore {
+	return &streamStore{
+		pedlar: pedlar,
+		broker: broker,
+	}
+}
@@ -0,0 +1,77 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/stream"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+// next waits for the next event on the subscription
+func next(t *testing.T, sub api.StationSubscription) api.StationEvent {
+	select {
+	case event := <-sub.Events():
+		return event
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+	}
+	return api.StationEvent{}
+}
+
+func TestStreamStore_Subscribe(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			{ID: 1, InService: true, Title: "A", NumberOfLocks: 10},
+			{ID: 2, InService: true, Title: "B", NumberOfLocks: 10},
+		},
+	}
+	availability := &model.StationAvailability{UpdatedAt: time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC)}
+
+	t.Run("Storage error", func(t *testing.T) {
+		client := mock.NewClient(stations, availability, &model.Status{}, fmt.Errorf("could not connect to API"))
+		_, err := NewStreamStore(pedal.New(client), stream.New()).Subscribe(context.Background(), "")
+		assert.Equal(t, "io: failed to read stations: could not connect to API", err.Error())
+	})
+
+	client := mock.NewClient(stations, availability, &model.Status{}, nil)
+	broker := stream.New()
+	store := NewStreamStore(pedal.New(client, pedal.OnRefresh(broker.Observe)), broker)
+
+	sub, err := store.Subscribe(context.Background(), "")
+	assert.Nil(t, err)
+	snapshot := next(t, sub)
+	assert.Equal(t, api.EventSnapshot, snapshot.Type)
+	assert.Len(t, snapshot.Stations, 2)
+	assert.Equal(t, availability.UpdatedAt, snapshot.Stations[0].UpdatedAt)
+
+	// A station closes in the next refresh
+	broker.Observe(model.Snapshot{
+		UpdatedAt: availability.UpdatedAt.Add(time.Minute),
+		Stations: map[int]*model.Station{
+			1: {ID: 1, InService: true, Title: "A", NumberOfLocks: 10},
+			2: {ID: 2, InService: true, Title: "B", NumberOfLocks: 10, Closed: true},
+		},
+	})
+	closed := next(t, sub)
+	assert.Equal(t, api.EventClosed, closed.Type)
+	assert.Equal(t, "B", closed.Stations[0].Title)
+	assert.True(t, closed.Stations[0].Closed)
+
+	sub.Close()
+	sub.Close()
+	_, ok := <-sub.Events()
+	assert.False(t, ok, "Closed")
+
+	// Resuming after the snapshot replays the change
+	sub, err = store.Subscribe(context.Background(), snapshot.ID)
+	assert.Nil(t, err)
+	assert.Equal(t, closed, next(t, sub))
+	sub.Close()
+}
@@ -0,0 +1,54 @@
+package http
+
+import (
+	"context"
+
+	"github.com/paulbes/go-pedal/pedal/model"
+
+	"github.com/paulbes/go-pedal/pedal/trip"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/paulbes/go-pedal/pkg/errors"
+)
+
+type tripStore struct {
+	planner trip.Planner
+}
+
+// Suggest asks the planner for the best trips between the
+// provided coordinates
+func (s *tripStore) Suggest(ctx context.Context, from, to api.Coord, limit int) ([]api.Trip, error) {
+	suggestions, err := s.planner.Suggest(ctx, convertCoord(from), convertCoord(to), limit)
+	if err != nil {
+		return nil, errors.New(err, "failed to suggest trips", errors.IO)
+	}
+
+	res := []api.Trip{}
+	for _, suggestion := range suggestions {
+		res = append(res, api.Trip{
+			Pickup:          convertStation(suggestion.Pickup),
+			Dropoff:         convertStation(suggestion.Dropoff),
+			WalkToPickup:    suggestion.WalkToPickup,
+			Ride:            suggestion.Ride,
+			WalkFromDropoff: suggestion.WalkFromDropoff,
+			Duration:        suggestion.Duration.Seconds(),
+			Reasons:         suggestion.Reasons,
+		})
+	}
+	return res, nil
+}
+
+// convertCoord maps coordinates between the two domain
+// models
+func convertCoord(coord api.Coord) model.Coord {
+	return model.Coord{
+		Latitude:  coord.Latitude,
+		Longitude: coord.Longitude,
+	}
+}
+
+// NewTripStore creates a new trip store
+func NewTripStore(planner trip.Planner) api.TripStore {
+	return &tripStore{
+		planner: planner,
+	}
+}
@@ -0,0 +1,57 @@
+package http
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/paulbes/go-pedal/pedal"
+	"github.com/paulbes/go-pedal/pedal/client/mock"
+	"github.com/paulbes/go-pedal/pedal/model"
+	"github.com/paulbes/go-pedal/pedal/trip"
+	"github.com/paulbes/go-pedal/pkg/api"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTripStore_Suggest(t *testing.T) {
+	stations := &model.Stations{
+		Stations: []*model.Station{
+			{ID: 1, InService: true, Title: "A", Center: model.Coord{Latitude: 59.91, Longitude: 10.75}, Availability: model.Availability{Bikes: 3, Locks: 3}},
+			{ID: 2, InService: true, Title: "B", Center: model.Coord{Latitude: 59.92, Longitude: 10.75}, Availability: model.Availability{Bikes: 3, Locks: 3}},
+		},
+	}
+
+	testCases := []struct {
+		Name      string
+		Err       error
+		ExpectErr bool
+		Expect    interface{}
+	}{
+		{
+			Name:   "Suggest trips",
+			Expect: [][2]int{{1, 2}, {2, 1}},
+		},
+		{
+			Name:      "Suggest trips, storage error",
+			Err:       fmt.Errorf("could not connect to API"),
+			ExpectErr: true,
+			Expect:    "io: failed to suggest trips: could not connect to API",
+		},
+	}
+
+	for _, tc := range testCases {
+		client := mock.NewClient(stations, &model.StationAvailability{}, &model.Status{}, tc.Err)
+		store := NewTripStore(trip.New(pedal.New(client)))
+		got, err := store.Suggest(context.Background(), api.Coord{Latitude: 59.91, Longitude: 10.75}, api.Coord{Latitude: 59.92, Longitude: 10.75}, 5)
+		if tc.ExpectErr {
+			assert.Equal(t, err.Error(), tc.Expect)
+		} else {
+			assert.Nil(t, err, tc.Name)
+			var pairs [][2]int
+			for _, suggestion := range got {
+				pairs = append(pairs, [2]int{suggestion.Pickup.ID, suggestion.Dropoff.ID})
+			}
+			assert.Equal(t, tc.Expect, pairs)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"context"
+	"time"
+)
+
+// The types of station events
+const (
+	EventSnapshot     = "snapshot"
+	EventAvailability = "availability"
+	EventClosed       = "closed"
+	EventReopened     = "reopened"
+)
+
+// StationEvent represents a change to a station, or the
+// state of all stations when a subscriber starts out
+type StationEvent struct {
+	ID       string
+	Type     string
+	At       time.Time
+	Stations []Station
+}
+
+// Filter returns the event with only the stations that are
+// kept, and false if a change no longer concerns any station.
+// A snapshot is always kept, even if it ends up empty.
+func (e StationEvent) Filter(keep func(station Station) bool) (StationEvent, bool) {
+	stations := []Station{}
+	for _, station := range e.Stations {
+		if keep(station) {
+			stations = append(stations, station)
+		}
+	}
+	e.Stations = stations
+	return e, len(stations) > 0 || e.Type == EventSnapshot
+}
+
+// StationSubscription delivers station events as they happen,
+// the channel is closed when the subscription ends, e.g., when
+// the subscriber fell too far behind
+type StationSubscription interface {
+	Events() <-chan StationEvent
+	Close()
+}
+
+// StreamService defines what methods a stream
+// service implementation must implement
+type StreamService interface {
+	Subscribe(ctx context.Context, lastEventID string) (StationSubscription, error)
+}
+
+// StreamStore defines what methods a stream
+// storage implementation must implement
+type StreamStore interface {
+	Subscribe(ctx context.Context, lastEventID string) (StationSubscription, error)
+}
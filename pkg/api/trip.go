@@ -0,0 +1,27 @@
+package api
+
+import "context"
+
+// Trip represents a suggested way of travelling
+// between two coordinates by bike
+type Trip struct {
+	Pickup          Station  `json:"pickup"`
+	Dropoff         Station  `json:"dropoff"`
+	WalkToPickup    float64  `json:"walk_to_pickup"`
+	Ride            float64  `json:"ride"`
+	WalkFromDropoff float64  `json:"walk_from_dropoff"`
+	Duration        float64  `json:"duration"`
+	Reasons         []string `json:"reasons"`
+}
+
+// TripService defines what methods a trip
+// service implementation must implement
+type TripService interface {
+	Suggest(ctx context.Context, from, to Coord, limit int) ([]Trip, error)
+}
+
+// TripStore defines what methods a trip
+// storage implementation must implement
+type TripStore interface {
+	Suggest(ctx context.Context, from, to Coord, limit int) ([]Trip, error)
+}
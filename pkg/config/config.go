@@ -0,0 +1,351 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/url"
+	"strings"
+	"time"
+
+	"github.com/paulbes/go-pedal/pedal/client"
+	"github.com/paulbes/go-pedal/pedal/history"
+	"github.com/paulbes/go-pedal/pkg/logging"
+)
+
+// Note: a setting is read from, in order of precedence, its flag, its
+// environment variable, the config file and finally its default. The
+// environment variable of a flag is its name in upper case, prefixed
+// by PEDAL_, e.g., -client-identifier is PEDAL_CLIENT_IDENTIFIER.
+
+// EnvPrefix is prepended to the names of the environment variables
+const EnvPrefix = "PEDAL_"
+
+// Duration is read from, and written to, JSON as
+// a string, e.g., 5s or 1h30m
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON implements the json.Marshaler interface
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements the json.Unmarshaler interface
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	err := json.Unmarshal(data, &s)
+	if err != nil {
+		return fmt.Errorf("duration must be a string, e.g., 5s: %s", data)
+	}
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+// Server contains the settings of the HTTP and gRPC servers
+type Server struct {
+	Port          int      `json:"port"`
+	GRPCPort      int      `json:"grpc_port"`
+	ReadTimeout   Duration `json:"read_timeout"`
+	WriteTimeout  Duration `json:"write_timeout"`
+	ShutdownGrace Duration `json:"shutdown_grace"`
+	LogFormat     string   `json:"log_format"`
+}
+
+// Upstream contains the settings of the Oslo City Bike API
+type Upstream struct {
+	ClientIdentifier string   `json:"client_identifier"`
+	URL              string   `json:"url"`
+	Timeout          Duration `json:"timeout"`
+	PollInterval     Duration `json:"poll_interval"`
+}
+
+// Cache contains the settings of the persisted stations
+type Cache struct {
+	SnapshotFile string `json:"snapshot_file"`
+}
+
+// CORS contains the settings of the cross origin requests
+type CORS struct {
+	AllowedOrigins []string `json:"allowed_origins"`
+}
+
+// History contains the settings of the recorded availability
+type History struct {
+	Dir       string   `json:"dir"`
+	Retention Duration `json:"retention"`
+}
+
+// Config contains every setting of the commands
+type Config struct {
+	Server       Server   `json:"server"`
+	Upstream     Upstream `json:"upstream"`
+	Cache        Cache    `json:"cache"`
+	CORS         CORS     `json:"cors"`
+	History      History  `json:"history"`
+	AlertConfig  string   `json:"alert_config"`
+	AccessConfig string   `json:"access_config"`
+
+	// File is the config file the settings were read from
+	File string `json:"-"`
+	// PrintConfig asks for the settings to be printed
+	// instead of running the command
+	PrintConfig bool `json:"-"`
+
+	flags *flag.FlagSet
+	api   bool
+}
+
+// Default returns the default settings
+func Default() Config {
+	return Config{
+		Server: Server{
+			Port:          8080,
+			ReadTimeout:   Duration{5 * time.Second},
+			WriteTimeout:  Duration{300 * time.Second},
+			ShutdownGrace: Duration{15 * time.Second},
+			LogFormat:     logging.FormatLogfmt,
+		},
+		Upstream: Upstream{
+			URL:          client.BaseURL,
+			Timeout:      Duration{5 * time.Second},
+			PollInterval: Duration{10 * time.Second},
+		},
+		CORS: CORS{
+			AllowedOrigins: []string{"*"},
+		},
+		History: History{
+			Retention: Duration{history.DefaultRetention},
+		},
+	}
+}
+
+// NewAPI creates the config of the API, with the default
+// settings, and registers every flag with the flag set
+func NewAPI(flags *flag.FlagSet) *Config {
+	c := &Config{}
+	*c = Default()
+	c.flags, c.api = flags, true
+	c.registerUpstream()
+	c.registerAPI()
+	c.registerFile()
+	return c
+}
+
+// NewCLI creates the config of the CLI, with the default settings,
+// only the flags of the upstream API are registered with the flag set
+func NewCLI(flags *flag.FlagSet) *Config {
+	c := &Config{}
+	*c = Default()
+	c.flags = flags
+	c.registerUpstream()
+	c.registerFile()
+	return c
+}
+
+func (c *Config) registerUpstream() {
+	c.flags.StringVar(&c.Upstream.ClientIdentifier, "client-identifier", c.Upstream.ClientIdentifier, "Oslo City Bike Client Identifier")
+	c.flags.StringVar(&c.Upstream.URL, "upstream-url", c.Upstream.URL, "Base URL of the Oslo City Bike API")
+	c.flags.DurationVar(&c.Upstream.Timeout.Duration, "upstream-timeout", c.Upstream.Timeout.Duration, "Timeout of the calls to the Oslo City Bike API")
+}
+
+func (c *Config) registerAPI() {
+	c.flags.IntVar(&c.Server.Port, "port", c.Server.Port, "Port to serve the API on")
+	c.flags.IntVar(&c.Server.GRPCPort, "grpc-port", c.Server.GRPCPort, "Port to serve the gRPC API on, disabled if 0")
+	c.flags.DurationVar(&c.Server.ReadTimeout.Duration, "read-timeout", c.Server.ReadTimeout.Duration, "How long to wait for a request to be read")
+	c.flags.DurationVar(&c.Server.WriteTimeout.Duration, "write-timeout", c.Server.WriteTimeout.Duration, "How long a response may be written for, streams are ended before it")
+	c.flags.DurationVar(&c.Server.ShutdownGrace.Duration, "shutdown-grace", c.Server.ShutdownGrace.Duration, "How long the requests in flight are given to complete on SIGINT or SIGTERM")
+	c.flags.StringVar(&c.Server.LogFormat, "log-format", c.Server.LogFormat, "Format of the logs, logfmt or json")
+	c.flags.DurationVar(&c.Upstream.PollInterval.Duration, "poll-interval", c.Upstream.PollInterval.Duration, "How often to refresh the stations, for recording history, alerting and streaming")
+	c.flags.StringVar(&c.Cache.SnapshotFile, "snapshot-file", c.Cache.SnapshotFile, "File to persist the latest stations to, served while the upstream API is down, disabled if empty")
+	c.flags.Var((*stringList)(&c.CORS.AllowedOrigins), "cors-origins", "Comma separated origins allowed to make cross origin requests, * allows any")
+	c.flags.StringVar(&c.History.Dir, "history-dir", c.History.Dir, "Directory to record the availability history in, disabled if empty")
+	c.flags.DurationVar(&c.History.Retention.Duration, "history-retention", c.History.Retention.Duration, "How long to keep the availability history")
+	c.flags.StringVar(&c.AlertConfig, "alert-config", c.AlertConfig, "JSON file with the alert rules and webhook, disabled if empty")
+	c.flags.StringVar(&c.AccessConfig, "access-config", c.AccessConfig, "JSON file with the API keys and rate limits, disabled if empty")
+}
+
+func (c *Config) registerFile() {
+	c.flags.StringVar(&c.File, "config", c.File, "JSON file to read the settings from, the flags and environment take precedence")
+	c.flags.BoolVar(&c.PrintConfig, "print-config", c.PrintConfig, "Print the settings as JSON, for use with -config, and exit")
+}
+
+// Load parses the arguments and reads the settings from the flags,
+// the environment and the config file, which is provided by the
+// -config flag or its environment variable. The settings are not
+// validated, so they can be printed as they are, see Validate.
+func (c *Config) Load(args []string, getenv func(string) string) error {
+	err := c.flags.Parse(args)
+	if err != nil {
+		return err
+	}
+
+	// Remember the flags that were provided, so they can be
+	// applied again on top of the file and the environment
+	provided := map[string]string{}
+	c.flags.Visit(func(f *flag.Flag) {
+		provided[f.Name] = f.Value.String()
+	})
+	file, printConfig := c.File, c.PrintConfig
+	if len(file) == 0 {
+		file = getenv(envName("config"))
+	}
+
+	// The flags are bound to the fields, so the config is
+	// reset in place rather than replaced
+	flags, api := c.flags, c.api
+	*c = Default()
+	c.flags, c.api = flags, api
+	if len(file) > 0 {
+		err = c.readFile(file)
+		if err != nil {
+			return err
+		}
+	}
+	c.File, c.PrintConfig = file, printConfig
+
+	var errs []string
+	c.flags.VisitAll(func(f *flag.Flag) {
+		value, hasKey := provided[f.Name]
+		name := "-" + f.Name
+		if !hasKey {
+			value = getenv(envName(f.Name))
+			name = envName(f.Name)
+		}
+		if len(value) == 0 && !hasKey {
+			return
+		}
+		if err := f.Value.Set(value); err != nil {
+			errs = append(errs, fmt.Sprintf("%s: %s", name, err))
+		}
+	})
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// readFile reads the settings in the config file, those
+// not in the file keep their current value
+func (c *Config) readFile(path string) error {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.DisallowUnknownFields()
+	err = decoder.Decode(c)
+	if err != nil {
+		return fmt.Errorf("failed to parse config %s: %s", path, err)
+	}
+	return nil
+}
+
+// Validate ensures that the settings can be used, only the
+// settings of the command are validated
+func (c *Config) Validate() error {
+	var errs []string
+	invalid := func(format string, args ...interface{}) {
+		errs = append(errs, fmt.Sprintf(format, args...))
+	}
+
+	if len(c.Upstream.ClientIdentifier) == 0 {
+		invalid("client identifier is required")
+	}
+	if u, err := url.Parse(c.Upstream.URL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || len(u.Host) == 0 {
+		invalid("upstream url must be an absolute http or https URL, got: %q", c.Upstream.URL)
+	}
+	if c.Upstream.Timeout.Duration < time.Second {
+		invalid("upstream timeout must be at least 1s, got: %s", c.Upstream.Timeout)
+	}
+
+	if c.api {
+		if c.Server.Port < 0 || c.Server.Port > 65535 {
+			invalid("port must be between 0 and 65535, got: %d", c.Server.Port)
+		}
+		if c.Server.GRPCPort < 0 || c.Server.GRPCPort > 65535 {
+			invalid("grpc port must be between 0 and 65535, got: %d", c.Server.GRPCPort)
+		} else if c.Server.GRPCPort > 0 && c.Server.GRPCPort == c.Server.Port {
+			invalid("grpc port must differ from the port, got: %d", c.Server.GRPCPort)
+		}
+		if c.Server.ReadTimeout.Duration <= 0 {
+			invalid("read timeout must be positive, got: %s", c.Server.ReadTimeout)
+		}
+		if c.Server.WriteTimeout.Duration <= StreamMargin {
+			invalid("write timeout must be longer than %s, so the streams can end before it, got: %s", StreamMargin, c.Server.WriteTimeout)
+		}
+		if c.Server.ShutdownGrace.Duration <= 0 {
+			invalid("shutdown grace must be positive, got: %s", c.Server.ShutdownGrace)
+		}
+		if c.Server.LogFormat != logging.FormatLogfmt && c.Server.LogFormat != logging.FormatJSON {
+			invalid("log format must be %s or %s, got: %q", logging.FormatLogfmt, logging.FormatJSON, c.Server.LogFormat)
+		}
+		if c.Upstream.PollInterval.Duration <= 0 {
+			invalid("poll interval must be positive, got: %s", c.Upstream.PollInterval)
+		}
+		if len(c.CORS.AllowedOrigins) == 0 {
+			invalid("at least one cors origin is required, * allows any")
+		}
+		for _, origin := range c.CORS.AllowedOrigins {
+			if u, err := url.Parse(origin); origin != "*" && (err != nil || len(u.Scheme) == 0 || len(u.Host) == 0 || len(u.Path) > 0) {
+				invalid("cors origin must be * or a scheme and host, e.g., https://example.com, got: %q", origin)
+			}
+		}
+		if c.History.Retention.Duration <= 0 {
+			invalid("history retention must be positive, got: %s", c.History.Retention)
+		}
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid settings: %s", strings.Join(errs, ", "))
+	}
+	return nil
+}
+
+// StreamMargin is how long before the write timeout the
+// streams are ended, so the clients can reconnect
+var StreamMargin = 30 * time.Second
+
+// Print writes the settings as JSON, in the format of the config
+// file, the secrets are left out, so they should be provided by
+// their environment variable
+func (c *Config) Print(w io.Writer) error {
+	redacted := *c
+	redacted.Upstream.ClientIdentifier = ""
+	data, err := json.MarshalIndent(redacted, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(w, "%s\n", data)
+	return err
+}
+
+// envName returns the environment variable of the flag
+func envName(flag string) string {
+	return EnvPrefix + strings.ToUpper(strings.Replace(flag, "-", "_", -1))
+}
+
+// stringList is a comma separated flag
+type stringList []string
+
+func (l *stringList) String() string {
+	if l == nil {
+		return ""
+	}
+	return strings.Join(*l, ",")
+}
+
+func (l *stringList) Set(value string) error {
+	res := []string{}
+	for _, s := range strings.Split(value, ",") {
+		if s = strings.TrimSpace(s); len(s) > 0 {
+			res = append(res, s)
+		}
+	}
+	*l = res
+	return nil
+}
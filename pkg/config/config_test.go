@@ -0,0 +1,190 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestConfig_Load(t *testing.T) {
+	dir, err := ioutil.TempDir("", "config")
+	assert.Nil(t, err)
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.json")
+	content := `{"server": {"port": 9090, "read_timeout": "10s"}, "upstream": {"client_identifier": "from-file"}, "cors": {"allowed_origins": ["https://example.com"]}}`
+	assert.Nil(t, ioutil.WriteFile(file, []byte(content), 0600))
+	malformed := filepath.Join(dir, "malformed.json")
+	assert.Nil(t, ioutil.WriteFile(malformed, []byte(`{"server": {"prot": 9090}}`), 0600))
+
+	defaults := Default()
+	defaults.Upstream.ClientIdentifier = "id"
+
+	testCases := []struct {
+		Name      string
+		CLI       bool
+		Args      []string
+		Env       map[string]string
+		Expect    func(c *Config)
+		ExpectErr string
+	}{
+		{
+			Name: "Defaults",
+			Args: []string{"-client-identifier", "id"},
+			Expect: func(c *Config) {
+				assert.Equal(t, defaults.Server, c.Server)
+				assert.Equal(t, defaults.Upstream, c.Upstream)
+				assert.Equal(t, []string{"*"}, c.CORS.AllowedOrigins)
+			},
+		},
+		{
+			Name: "Environment",
+			Env: map[string]string{
+				"PEDAL_CLIENT_IDENTIFIER": "from-env",
+				"PEDAL_WRITE_TIMEOUT":     "1m",
+				"PEDAL_CORS_ORIGINS":      "https://a.com, https://b.com",
+			},
+			Expect: func(c *Config) {
+				assert.Equal(t, "from-env", c.Upstream.ClientIdentifier)
+				assert.Equal(t, time.Minute, c.Server.WriteTimeout.Duration)
+				assert.Equal(t, []string{"https://a.com", "https://b.com"}, c.CORS.AllowedOrigins)
+			},
+		},
+		{
+			Name: "File, environment and flags by precedence",
+			Args: []string{"-config", file, "-port", "7070"},
+			Env: map[string]string{
+				"PEDAL_PORT":         "6060",
+				"PEDAL_READ_TIMEOUT": "20s",
+			},
+			Expect: func(c *Config) {
+				assert.Equal(t, file, c.File)
+				assert.Equal(t, 7070, c.Server.Port)
+				assert.Equal(t, 20*time.Second, c.Server.ReadTimeout.Duration)
+				assert.Equal(t, "from-file", c.Upstream.ClientIdentifier)
+				assert.Equal(t, []string{"https://example.com"}, c.CORS.AllowedOrigins)
+				assert.Equal(t, defaults.Server.WriteTimeout, c.Server.WriteTimeout)
+			},
+		},
+		{
+			Name: "File from the environment",
+			Env:  map[string]string{"PEDAL_CONFIG": file},
+			Expect: func(c *Config) {
+				assert.Equal(t, 9090, c.Server.Port)
+			},
+		},
+		{
+			Name: "Print config",
+			Args: []string{"-client-identifier", "id", "-print-config"},
+			Expect: func(c *Config) {
+				assert.True(t, c.PrintConfig)
+			},
+		},
+		{
+			Name: "Print config is not validated",
+			Args: []string{"-port", "70000", "-print-config"},
+			Expect: func(c *Config) {
+				assert.True(t, c.PrintConfig)
+				assert.Equal(t, 70000, c.Server.Port)
+			},
+		},
+		{
+			Name: "CLI only validates the upstream settings",
+			CLI:  true,
+			Args: []string{"-client-identifier", "id", "stations"},
+			Env:  map[string]string{"PEDAL_PORT": "-1"},
+			Expect: func(c *Config) {
+				assert.Equal(t, 8080, c.Server.Port)
+				assert.Equal(t, []string{"stations"}, c.flags.Args())
+			},
+		},
+		{
+			Name:      "Unknown setting in file",
+			Args:      []string{"-config", malformed},
+			ExpectErr: "failed to parse config " + malformed + `: json: unknown field "prot"`,
+		},
+		{
+			Name:      "Malformed environment",
+			Env:       map[string]string{"PEDAL_CLIENT_IDENTIFIER": "id", "PEDAL_POLL_INTERVAL": "often"},
+			ExpectErr: "invalid settings: PEDAL_POLL_INTERVAL: parse error",
+		},
+		{
+			Name:      "Missing client identifier",
+			CLI:       true,
+			ExpectErr: "invalid settings: client identifier is required",
+		},
+		{
+			Name:      "Same ports",
+			Args:      []string{"-client-identifier", "id", "-port", "9090", "-grpc-port", "9090"},
+			ExpectErr: "invalid settings: grpc port must differ from the port, got: 9090",
+		},
+		{
+			Name: "Invalid settings",
+			Args: []string{
+				"-client-identifier", "id",
+				"-port", "70000",
+				"-grpc-port", "-1",
+				"-write-timeout", "30s",
+				"-upstream-timeout", "100ms",
+				"-upstream-url", "localhost",
+				"-log-format", "xml",
+				"-cors-origins", "example.com",
+			},
+			ExpectErr: `invalid settings: upstream url must be an absolute http or https URL, got: "localhost", ` +
+				`upstream timeout must be at least 1s, got: 100ms, ` +
+				`port must be between 0 and 65535, got: 70000, ` +
+				`grpc port must be between 0 and 65535, got: -1, ` +
+				`write timeout must be longer than 30s, so the streams can end before it, got: 30s, ` +
+				`log format must be logfmt or json, got: "xml", ` +
+				`cors origin must be * or a scheme and host, e.g., https://example.com, got: "example.com"`,
+		},
+	}
+
+	for _, tc := range testCases {
+		flags := flag.NewFlagSet("test", flag.ContinueOnError)
+		flags.SetOutput(ioutil.Discard)
+		var c *Config
+		if tc.CLI {
+			c = NewCLI(flags)
+		} else {
+			c = NewAPI(flags)
+		}
+		err := c.Load(tc.Args, func(key string) string { return tc.Env[key] })
+		if err == nil && !c.PrintConfig {
+			err = c.Validate()
+		}
+		if len(tc.ExpectErr) > 0 {
+			assert.Error(t, err, tc.Name)
+			if err != nil {
+				assert.Contains(t, err.Error(), tc.ExpectErr, tc.Name)
+			}
+		} else {
+			assert.Nil(t, err, tc.Name)
+			tc.Expect(c)
+		}
+	}
+}
+
+func TestConfig_Print(t *testing.T) {
+	c := Default()
+	c.Upstream.ClientIdentifier = "secret"
+	buf := &bytes.Buffer{}
+	assert.Nil(t, c.Print(buf))
+	assert.Equal(t, "secret", c.Upstream.ClientIdentifier, "The settings are left as they are")
+
+	// The printed settings can be read back as a config
+	// file, without the secrets
+	got := Config{}
+	assert.Nil(t, json.Unmarshal(buf.Bytes(), &got))
+	expect := Default()
+	assert.Equal(t, expect, got)
+	assert.Contains(t, buf.String(), `"read_timeout": "5s"`)
+	assert.NotContains(t, buf.String(), "secret")
+}
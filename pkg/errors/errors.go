@@ -3,7 +3,10 @@ package errors
 import (
 	"encoding/json"
 	"fmt"
+	"math"
 	"net/http"
+	"strconv"
+	"time"
 )
 
 // nolint
@@ -12,12 +15,17 @@ const (
 	Unmarshal
 	Marshal
 	IO
+	Unavailable
+	Unauthorized
+	TooManyRequests
 )
 
 type errors struct {
-	err error
-	msg string
-	typ int
+	err        error
+	msg        string
+	typ        int
+	requestID  string
+	retryAfter time.Duration
 }
 
 // New creates a new error with the provided information
@@ -29,6 +37,42 @@ func New(err error, msg string, typ int) error {
 	}
 }
 
+// TypeOf returns the type of the error, false is returned
+// for errors not created by this package
+func TypeOf(err error) (int, bool) {
+	e, ok := err.(*errors)
+	if !ok {
+		return 0, false
+	}
+	return e.typ, true
+}
+
+// WithRequestID attaches the ID of the request that failed, so it is
+// returned to the caller and can be matched with the logs, errors
+// not created by this package are returned as is
+func WithRequestID(err error, requestID string) error {
+	e, ok := err.(*errors)
+	if !ok {
+		return err
+	}
+	res := *e
+	res.requestID = requestID
+	return &res
+}
+
+// WithRetryAfter tells the caller how long to wait before the
+// request is retried, errors not created by this package are
+// returned as is
+func WithRetryAfter(err error, retryAfter time.Duration) error {
+	e, ok := err.(*errors)
+	if !ok {
+		return err
+	}
+	res := *e
+	res.retryAfter = retryAfter
+	return &res
+}
+
 // codeString converts the int const
 // to a string representation
 func (e *errors) codeString() string {
@@ -42,6 +86,12 @@ func (e *errors) codeString() string {
 		typ = "unmarshal"
 	case IO:
 		typ = "io"
+	case Unavailable:
+		typ = "unavailable"
+	case Unauthorized:
+		typ = "unauthorized"
+	case TooManyRequests:
+		typ = "toomanyrequests"
 	default:
 		typ = "unknown"
 	}
@@ -63,6 +113,12 @@ func (e *errors) StatusCode() int {
 		code = http.StatusNotFound
 	case Unmarshal:
 		code = http.StatusBadRequest
+	case Unavailable:
+		code = http.StatusServiceUnavailable
+	case Unauthorized:
+		code = http.StatusUnauthorized
+	case TooManyRequests:
+		code = http.StatusTooManyRequests
 	case Marshal:
 		fallthrough
 	case IO:
@@ -73,17 +129,29 @@ func (e *errors) StatusCode() int {
 	return code
 }
 
+// Headers implements the go-kit Headerer interface, so
+// that the caller is told when to retry, if it is known
+func (e *errors) Headers() http.Header {
+	if e.retryAfter <= 0 {
+		return nil
+	}
+	seconds := int(math.Ceil(e.retryAfter.Seconds()))
+	return http.Header{"Retry-After": []string{strconv.Itoa(seconds)}}
+}
+
 // MarshalJSON implements the json.Marshaller interface
 // so that the error can be marshalled
 func (e *errors) MarshalJSON() ([]byte, error) {
 	content := struct {
-		Message string `json:"message"`
-		Code    int    `json:"code"`
-		Type    string `json:"type"`
+		Message   string `json:"message"`
+		Code      int    `json:"code"`
+		Type      string `json:"type"`
+		RequestID string `json:"request_id,omitempty"`
 	}{
-		Message: e.Error(),
-		Code:    e.StatusCode(),
-		Type:    e.codeString(),
+		Message:   e.Error(),
+		Code:      e.StatusCode(),
+		Type:      e.codeString(),
+		RequestID: e.requestID,
 	}
 	data, err := json.Marshal(&content)
 	if err != nil {
@@ -0,0 +1,556 @@
+package graphql
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+)
+
+// Request is a GraphQL request, as it is posted by clients
+type Request struct {
+	Query         string                 `json:"query"`
+	OperationName string                 `json:"operationName"`
+	Variables     map[string]interface{} `json:"variables"`
+}
+
+// Error describes why the request, or a field, failed,
+// the path is set for the errors of fields
+type Error struct {
+	Message string        `json:"message"`
+	Path    []interface{} `json:"path,omitempty"`
+}
+
+// Response is the result of a request, data is null if the
+// request could not be executed at all
+type Response struct {
+	Data   interface{} `json:"data"`
+	Errors []Error     `json:"errors,omitempty"`
+}
+
+// orderedMap is an object in the response, the
+// fields are written in the order they were queried
+type orderedMap struct {
+	keys   []string
+	values map[string]interface{}
+}
+
+func (m *orderedMap) set(key string, value interface{}) {
+	if _, hasKey := m.values[key]; !hasKey {
+		m.keys = append(m.keys, key)
+	}
+	m.values[key] = value
+}
+
+func (m *orderedMap) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, key := range m.keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		k, err := json.Marshal(key)
+		if err != nil {
+			return nil, err
+		}
+		v, err := json.Marshal(m.values[key])
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(k)
+		buf.WriteByte(':')
+		buf.Write(v)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// MaxDepth is how deeply the fields of a query can be nested
+var MaxDepth = 10
+
+// MaxFields is how many fields a query can select, once the
+// fragments are spread, a field within a list is counted once
+var MaxFields = 500
+
+// MaxAliases is how many aliased fields a query can select,
+// once the fragments are spread
+var MaxAliases = 50
+
+// cost is what the selections ask of the executor, the fragments
+// are counted every time they are spread, as they are executed
+type cost struct {
+	depth   int
+	fields  int
+	aliases int
+}
+
+// add adds the cost of selections next to these
+func (c *cost) add(other cost) {
+	if other.depth > c.depth {
+		c.depth = other.depth
+	}
+	c.fields += other.fields
+	c.aliases += other.aliases
+}
+
+// check ensures the cost is within the limits, the counts only
+// grow, so they are checked as they are added up, which keeps
+// them from overflowing
+func (c cost) check() error {
+	if c.depth > MaxDepth {
+		return fmt.Errorf("query is nested deeper than %d levels", MaxDepth)
+	}
+	if c.fields > MaxFields {
+		return fmt.Errorf("query selects more than %d fields", MaxFields)
+	}
+	if c.aliases > MaxAliases {
+		return fmt.Errorf("query uses more than %d aliases", MaxAliases)
+	}
+	return nil
+}
+
+type executor struct {
+	schema    *Schema
+	fragments map[string]*fragment
+	// costs are the costs of the fragments that are validated,
+	// so every fragment is validated once, however often it is
+	// spread
+	costs     map[string]cost
+	variables map[string]interface{}
+	errors    []Error
+}
+
+// Execute parses, validates and executes the query of the request
+func (s *Schema) Execute(ctx context.Context, req Request) Response {
+	doc, err := parse(req.Query)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	op, err := selectOperation(doc, req.OperationName)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	e := &executor{
+		schema:    s,
+		fragments: doc.fragments,
+		costs:     map[string]cost{},
+	}
+	e.variables, err = coerceVariables(op, req.Variables)
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	_, err = e.validate(s.Query, op.selections, map[string]bool{})
+	if err != nil {
+		return Response{Errors: []Error{{Message: err.Error()}}}
+	}
+
+	data, _ := e.executeSelections(ctx, s.Query, nil, op.selections, nil)
+	return Response{Data: data, Errors: e.errors}
+}
+
+func selectOperation(doc *document, name string) (*operation, error) {
+	var res *operation
+	switch {
+	case len(name) > 0:
+		for _, op := range doc.operations {
+			if op.name == name {
+				res = op
+			}
+		}
+		if res == nil {
+			return nil, fmt.Errorf("unknown operation named %q", name)
+		}
+	case len(doc.operations) == 1:
+		res = doc.operations[0]
+	default:
+		return nil, fmt.Errorf("must provide operation name if query contains multiple operations")
+	}
+
+	if res.kind != "query" {
+		return nil, fmt.Errorf("only queries are supported, not %s", res.kind)
+	}
+	return res, nil
+}
+
+// resolveType converts a reference to an input type
+func resolveType(ref *typeRef) (Type, error) {
+	var t Type
+	if ref.ofType != nil {
+		ofType, err := resolveType(ref.ofType)
+		if err != nil {
+			return nil, err
+		}
+		t = &List{OfType: ofType}
+	} else {
+		scalar, hasKey := scalars[ref.name]
+		if !hasKey {
+			return nil, fmt.Errorf("unknown type %q", ref.name)
+		}
+		t = scalar
+	}
+	if ref.nonNull {
+		t = &NonNull{OfType: t}
+	}
+	return t, nil
+}
+
+// coerceVariables converts the provided variables to the types
+// defined by the operation, applying the defaults
+func coerceVariables(op *operation, provided map[string]interface{}) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	for _, def := range op.variables {
+		t, err := resolveType(def.typ)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %s", def.name, err)
+		}
+
+		value, hasKey := provided[def.name]
+		if !hasKey {
+			if !def.hasDefault {
+				if isNonNull(t) {
+					return nil, fmt.Errorf("variable $%s of type %s was not provided", def.name, def.typ)
+				}
+				continue
+			}
+			value = def.defaultValue
+		}
+
+		res[def.name], err = coerceInput(t, value)
+		if err != nil {
+			return nil, fmt.Errorf("variable $%s: %s", def.name, err)
+		}
+	}
+	return res, nil
+}
+
+// replaceVariables replaces the variables in the value with
+// their values, false is returned if a variable is not set
+func (e *executor) replaceVariables(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case variable:
+		res, hasKey := e.variables[string(v)]
+		return res, hasKey
+	case []interface{}:
+		res := make([]interface{}, 0, len(v))
+		for _, item := range v {
+			// A list item without a value is null
+			replaced, _ := e.replaceVariables(item)
+			res = append(res, replaced)
+		}
+		return res, true
+	case map[string]interface{}:
+		res := map[string]interface{}{}
+		for key, item := range v {
+			if replaced, ok := e.replaceVariables(item); ok {
+				res[key] = replaced
+			}
+		}
+		return res, true
+	default:
+		return value, true
+	}
+}
+
+// coerceArguments converts the arguments of the field
+// to the types of the definition
+func (e *executor) coerceArguments(defs Args, args map[string]interface{}) (map[string]interface{}, error) {
+	res := map[string]interface{}{}
+	for name, def := range defs {
+		value, hasKey := args[name]
+		if hasKey {
+			value, hasKey = e.replaceVariables(value)
+		}
+		if !hasKey {
+			if def.Default != nil {
+				res[name] = def.Default
+			} else if isNonNull(def.Type) {
+				return nil, fmt.Errorf("argument %q of type %s is required", name, def.Type)
+			}
+			continue
+		}
+
+		coerced, err := coerceInput(def.Type, value)
+		if err != nil {
+			return nil, fmt.Errorf("argument %q: %s", name, err)
+		}
+		res[name] = coerced
+	}
+	return res, nil
+}
+
+// validate ensures that the selections exist on the object, that
+// their arguments are known and that leaves are selected, the cost
+// of the selections is returned, once it is known to be acceptable
+func (e *executor) validate(obj *Object, selections []selection, visited map[string]bool) (cost, error) {
+	var res cost
+	for _, s := range selections {
+		switch sel := s.(type) {
+		case *field:
+			res.add(cost{depth: 1, fields: 1})
+			if len(sel.alias) > 0 {
+				res.aliases++
+			}
+			if sel.name == "__typename" {
+				if len(sel.selections) > 0 {
+					return cost{}, fmt.Errorf("field \"__typename\" must not have a selection")
+				}
+				break
+			}
+			def, hasKey := obj.Fields[sel.name]
+			if !hasKey {
+				return cost{}, fmt.Errorf("cannot query field %q on type %q", sel.name, obj.Name)
+			}
+			for name := range sel.arguments {
+				if _, hasKey := def.Args[name]; !hasKey {
+					return cost{}, fmt.Errorf("unknown argument %q on field %q of type %q", name, sel.name, obj.Name)
+				}
+			}
+			for name, arg := range def.Args {
+				if _, hasKey := sel.arguments[name]; !hasKey && isNonNull(arg.Type) && arg.Default == nil {
+					return cost{}, fmt.Errorf("field %q argument %q of type %s is required", sel.name, name, arg.Type)
+				}
+			}
+
+			switch t := namedType(def.Type).(type) {
+			case *Object:
+				if len(sel.selections) == 0 {
+					return cost{}, fmt.Errorf("field %q of type %s must have a selection of subfields", sel.name, def.Type)
+				}
+				sub, err := e.validate(t, sel.selections, visited)
+				if err != nil {
+					return cost{}, err
+				}
+				sub.depth++
+				res.add(sub)
+			default:
+				if len(sel.selections) > 0 {
+					return cost{}, fmt.Errorf("field %q must not have a selection since type %s has no subfields", sel.name, def.Type)
+				}
+			}
+		case *fragmentSpread:
+			f, hasKey := e.fragments[sel.name]
+			if !hasKey {
+				return cost{}, fmt.Errorf("unknown fragment %q", sel.name)
+			}
+			if visited[sel.name] {
+				return cost{}, fmt.Errorf("cannot spread fragment %q within itself", sel.name)
+			}
+			if f.typeCondition != obj.Name {
+				return cost{}, fmt.Errorf("fragment %q on %q cannot be spread on type %q", sel.name, f.typeCondition, obj.Name)
+			}
+			sub, validated := e.costs[sel.name]
+			if !validated {
+				visited[sel.name] = true
+				var err error
+				sub, err = e.validate(obj, f.selections, visited)
+				delete(visited, sel.name)
+				if err != nil {
+					return cost{}, err
+				}
+				e.costs[sel.name] = sub
+			}
+			res.add(sub)
+		case *inlineFragment:
+			if len(sel.typeCondition) > 0 && sel.typeCondition != obj.Name {
+				return cost{}, fmt.Errorf("inline fragment on %q cannot be used on type %q", sel.typeCondition, obj.Name)
+			}
+			sub, err := e.validate(obj, sel.selections, visited)
+			if err != nil {
+				return cost{}, err
+			}
+			res.add(sub)
+		}
+		err := res.check()
+		if err != nil {
+			return cost{}, err
+		}
+	}
+	return res, nil
+}
+
+// include evaluates the @skip and @include directives
+func (e *executor) include(directives []*directive) bool {
+	for _, d := range directives {
+		value, _ := e.replaceVariables(d.arguments["if"])
+		condition, _ := value.(bool)
+		switch d.name {
+		case "skip":
+			if condition {
+				return false
+			}
+		case "include":
+			if !condition {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// collectFields flattens the fragments of the selections and groups
+// the fields by their response key, in the order they were queried
+func (e *executor) collectFields(selections []selection, keys *[]string, fields map[string][]*field) {
+	for _, s := range selections {
+		switch sel := s.(type) {
+		case *field:
+			if !e.include(sel.directives) {
+				continue
+			}
+			key := sel.responseKey()
+			if _, hasKey := fields[key]; !hasKey {
+				*keys = append(*keys, key)
+			}
+			fields[key] = append(fields[key], sel)
+		case *fragmentSpread:
+			f := e.fragments[sel.name]
+			if !e.include(sel.directives) || !e.include(f.directives) {
+				continue
+			}
+			e.collectFields(f.selections, keys, fields)
+		case *inlineFragment:
+			if !e.include(sel.directives) {
+				continue
+			}
+			e.collectFields(sel.selections, keys, fields)
+		}
+	}
+}
+
+func appendPath(path []interface{}, key interface{}) []interface{} {
+	res := make([]interface{}, len(path), len(path)+1)
+	copy(res, path)
+	return append(res, key)
+}
+
+func (e *executor) fieldError(path []interface{}, err error) {
+	e.errors = append(e.errors, Error{Message: err.Error(), Path: path})
+}
+
+// executeSelections resolves the fields of the object, false is
+// returned if a non-null field is null, so the object is null
+func (e *executor) executeSelections(ctx context.Context, obj *Object, source interface{}, selections []selection, path []interface{}) (interface{}, bool) {
+	var keys []string
+	fields := map[string][]*field{}
+	e.collectFields(selections, &keys, fields)
+
+	res := &orderedMap{values: map[string]interface{}{}}
+	for _, key := range keys {
+		f := fields[key][0]
+		fieldPath := appendPath(path, key)
+		if f.name == "__typename" {
+			res.set(key, obj.Name)
+			continue
+		}
+
+		def := obj.Fields[f.name]
+		value, ok := e.executeField(ctx, def, source, fields[key], fieldPath)
+		if !ok {
+			if isNonNull(def.Type) {
+				return nil, false
+			}
+			value = nil
+		}
+		res.set(key, value)
+	}
+	return res, true
+}
+
+func (e *executor) executeField(ctx context.Context, def *Field, source interface{}, fields []*field, path []interface{}) (interface{}, bool) {
+	args, err := e.coerceArguments(def.Args, fields[0].arguments)
+	if err != nil {
+		e.fieldError(path, err)
+		return nil, false
+	}
+
+	var value interface{}
+	if def.Resolve != nil {
+		value, err = def.Resolve(ctx, source, args)
+	} else if m, ok := source.(map[string]interface{}); ok {
+		value = m[fields[0].name]
+	}
+	if err != nil {
+		e.fieldError(path, err)
+		return nil, false
+	}
+
+	// The selections of fields with the same response key are merged
+	var selections []selection
+	for _, f := range fields {
+		selections = append(selections, f.selections...)
+	}
+	return e.completeValue(ctx, def.Type, value, selections, path)
+}
+
+// isNil reports if the value is nil, including typed nils, a nil
+// slice is not, it is an empty list
+func isNil(value interface{}) bool {
+	if value == nil {
+		return true
+	}
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+// completeValue converts the resolved value to the type, false is
+// returned if it must be null, but the type does not allow it
+func (e *executor) completeValue(ctx context.Context, t Type, value interface{}, selections []selection, path []interface{}) (interface{}, bool) {
+	if n, ok := t.(*NonNull); ok {
+		res, ok := e.completeValue(ctx, n.OfType, value, selections, path)
+		if !ok {
+			return nil, false
+		}
+		if res == nil {
+			e.fieldError(path, fmt.Errorf("cannot return null for non-nullable field"))
+			return nil, false
+		}
+		return res, true
+	}
+	if isNil(value) {
+		return nil, true
+	}
+
+	switch typ := t.(type) {
+	case *List:
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+			e.fieldError(path, fmt.Errorf("expected a list, got %T", value))
+			return nil, false
+		}
+		res := make([]interface{}, 0, v.Len())
+		for i := 0; i < v.Len(); i++ {
+			item, ok := e.completeValue(ctx, typ.OfType, v.Index(i).Interface(), selections, appendPath(path, i))
+			if !ok {
+				// A null item that is not allowed nulls the list
+				if isNonNull(typ.OfType) {
+					return nil, false
+				}
+				item = nil
+			}
+			res = append(res, item)
+		}
+		return res, true
+	case *Scalar:
+		// Optional values are often pointers
+		if v := reflect.ValueOf(value); v.Kind() == reflect.Ptr {
+			value = v.Elem().Interface()
+		}
+		res, err := typ.Serialize(value)
+		if err != nil {
+			e.fieldError(path, err)
+			return nil, false
+		}
+		return res, true
+	case *Object:
+		return e.executeSelections(ctx, typ, value, selections, path)
+	default:
+		e.fieldError(path, fmt.Errorf("unsupported type %s", t))
+		return nil, false
+	}
+}
@@ -0,0 +1,343 @@
+package graphql_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/graphql"
+	"github.com/stretchr/testify/assert"
+)
+
+type book struct {
+	ID     int
+	Title  string
+	Rating *float64
+	Tags   []string
+}
+
+var rating = 4.5
+
+var books = []book{
+	{ID: 1, Title: "Dune", Rating: &rating, Tags: []string{"sci-fi"}},
+	{ID: 2, Title: "Emma"},
+}
+
+func newSchema() *graphql.Schema {
+	bookType := &graphql.Object{
+		Name: "Book",
+		Fields: graphql.Fields{
+			"id": {
+				Type: &graphql.NonNull{OfType: graphql.Int},
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return source.(book).ID, nil
+				},
+			},
+			"title": {
+				Type: &graphql.NonNull{OfType: graphql.String},
+				Args: graphql.Args{
+					"upper": {Type: graphql.Boolean, Default: false},
+				},
+				Resolve: func(_ context.Context, source interface{}, args map[string]interface{}) (interface{}, error) {
+					if args["upper"].(bool) {
+						return fmt.Sprintf("%s!", source.(book).Title), nil
+					}
+					return source.(book).Title, nil
+				},
+			},
+			"rating": {
+				Type: graphql.Float,
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return source.(book).Rating, nil
+				},
+			},
+			"tags": {
+				Type: &graphql.NonNull{OfType: &graphql.List{OfType: &graphql.NonNull{OfType: graphql.String}}},
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return source.(book).Tags, nil
+				},
+			},
+			"author": {
+				Type: &graphql.NonNull{OfType: graphql.String},
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					if source.(book).ID == 2 {
+						return nil, fmt.Errorf("author unknown")
+					}
+					return "Herbert", nil
+				},
+			},
+			"meta": {
+				Type: &graphql.Object{
+					Name: "Meta",
+					Fields: graphql.Fields{
+						"pages": {Type: graphql.Int},
+					},
+				},
+				Resolve: func(_ context.Context, source interface{}, _ map[string]interface{}) (interface{}, error) {
+					return map[string]interface{}{"pages": 100 * source.(book).ID}, nil
+				},
+			},
+		},
+	}
+
+	return &graphql.Schema{
+		Query: &graphql.Object{
+			Name: "Query",
+			Fields: graphql.Fields{
+				"book": {
+					Type: bookType,
+					Args: graphql.Args{
+						"id": {Type: &graphql.NonNull{OfType: graphql.Int}},
+					},
+					Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+						for _, b := range books {
+							if b.ID == args["id"].(int) {
+								return b, nil
+							}
+						}
+						return nil, nil
+					},
+				},
+				"books": {
+					Type: &graphql.NonNull{OfType: &graphql.List{OfType: &graphql.NonNull{OfType: bookType}}},
+					Args: graphql.Args{
+						"ids": {Type: &graphql.List{OfType: graphql.Int}},
+					},
+					Resolve: func(_ context.Context, _ interface{}, args map[string]interface{}) (interface{}, error) {
+						ids, hasKey := args["ids"]
+						if !hasKey {
+							return books, nil
+						}
+						var res []book
+						for _, id := range ids.([]interface{}) {
+							for _, b := range books {
+								if b.ID == id {
+									res = append(res, b)
+								}
+							}
+						}
+						return res, nil
+					},
+				},
+			},
+		},
+	}
+}
+
+func TestSchema_Execute(t *testing.T) {
+	testCases := []struct {
+		name   string
+		req    graphql.Request
+		expect string
+	}{
+		{
+			name:   "Fields are returned in the order they were queried",
+			req:    graphql.Request{Query: `{ book(id: 1) { title id rating tags } }`},
+			expect: `{"data":{"book":{"title":"Dune","id":1,"rating":4.5,"tags":["sci-fi"]}}}`,
+		},
+		{
+			name:   "Aliases, defaults and arguments",
+			req:    graphql.Request{Query: `{ a: book(id: 1) { title } b: book(id: 1) { title(upper: true) } }`},
+			expect: `{"data":{"a":{"title":"Dune"},"b":{"title":"Dune!"}}}`,
+		},
+		{
+			name:   "Nil pointers and slices",
+			req:    graphql.Request{Query: `{ book(id: 2) { rating tags __typename } }`},
+			expect: `{"data":{"book":{"rating":null,"tags":[],"__typename":"Book"}}}`,
+		},
+		{
+			name:   "Nested objects are read from maps",
+			req:    graphql.Request{Query: `{ books { meta { pages } } }`},
+			expect: `{"data":{"books":[{"meta":{"pages":100}},{"meta":{"pages":200}}]}}`,
+		},
+		{
+			name: "Variables",
+			req: graphql.Request{
+				Query:     `query Books($ids: [Int], $upper: Boolean = true) { books(ids: $ids) { title(upper: $upper) } }`,
+				Variables: map[string]interface{}{"ids": []interface{}{float64(2)}},
+			},
+			expect: `{"data":{"books":[{"title":"Emma!"}]}}`,
+		},
+		{
+			name:   "A single value is a list of one",
+			req:    graphql.Request{Query: `{ books(ids: 1) { id } }`},
+			expect: `{"data":{"books":[{"id":1}]}}`,
+		},
+		{
+			name: "Fragments and directives",
+			req: graphql.Request{
+				Query: `query ($skip: Boolean!) {
+					book(id: 1) { ...parts ... on Book { rating @skip(if: $skip) } ... @include(if: false) { tags } }
+				}
+				fragment parts on Book { id, title }`,
+				Variables: map[string]interface{}{"skip": true},
+			},
+			expect: `{"data":{"book":{"id":1,"title":"Dune"}}}`,
+		},
+		{
+			name: "Operation by name",
+			req: graphql.Request{
+				Query:         `query A { book(id: 1) { id } } query B { book(id: 2) { id } }`,
+				OperationName: "B",
+			},
+			expect: `{"data":{"book":{"id":2}}}`,
+		},
+		{
+			name:   "A missing object is null",
+			req:    graphql.Request{Query: `{ book(id: 3) { id } }`},
+			expect: `{"data":{"book":null}}`,
+		},
+		{
+			name:   "Errors propagate to the nearest nullable field",
+			req:    graphql.Request{Query: `{ book(id: 2) { id author } }`},
+			expect: `{"data":{"book":null},"errors":[{"message":"author unknown","path":["book","author"]}]}`,
+		},
+		{
+			name:   "Errors in a non-null list null the data",
+			req:    graphql.Request{Query: `{ books { author } }`},
+			expect: `{"data":null,"errors":[{"message":"author unknown","path":["books",1,"author"]}]}`,
+		},
+		{
+			name:   "Syntax error",
+			req:    graphql.Request{Query: `{ book(id: 1) { id }`},
+			expect: `{"data":null,"errors":[{"message":"syntax error at 1:21: unexpected end of document"}]}`,
+		},
+		{
+			name:   "Unknown field",
+			req:    graphql.Request{Query: `{ book(id: 1) { isbn } }`},
+			expect: `{"data":null,"errors":[{"message":"cannot query field \"isbn\" on type \"Book\""}]}`,
+		},
+		{
+			name:   "Missing argument",
+			req:    graphql.Request{Query: `{ book { id } }`},
+			expect: `{"data":null,"errors":[{"message":"field \"book\" argument \"id\" of type Int! is required"}]}`,
+		},
+		{
+			name:   "Missing selection",
+			req:    graphql.Request{Query: `{ book(id: 1) }`},
+			expect: `{"data":null,"errors":[{"message":"field \"book\" of type Book must have a selection of subfields"}]}`,
+		},
+		{
+			name:   "Recursive fragment",
+			req:    graphql.Request{Query: `{ book(id: 1) { ...a } } fragment a on Book { id ...a }`},
+			expect: `{"data":null,"errors":[{"message":"cannot spread fragment \"a\" within itself"}]}`,
+		},
+		{
+			name:   "Wrong argument type",
+			req:    graphql.Request{Query: `{ book(id: "1") { id } }`},
+			expect: `{"data":{"book":null},"errors":[{"message":"argument \"id\": expected an Int, got 1","path":["book"]}]}`,
+		},
+		{
+			name:   "Missing variable",
+			req:    graphql.Request{Query: `query ($id: Int!) { book(id: $id) { id } }`},
+			expect: `{"data":null,"errors":[{"message":"variable $id of type Int! was not provided"}]}`,
+		},
+		{
+			name:   "Mutations are not supported",
+			req:    graphql.Request{Query: `mutation { book(id: 1) { id } }`},
+			expect: `{"data":null,"errors":[{"message":"only queries are supported, not mutation"}]}`,
+		},
+	}
+
+	schema := newSchema()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.Marshal(schema.Execute(context.Background(), tc.req))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, string(got))
+		})
+	}
+}
+
+func TestSchema_Execute_Limits(t *testing.T) {
+	maxDepth, maxFields, maxAliases := graphql.MaxDepth, graphql.MaxFields, graphql.MaxAliases
+	defer func() {
+		graphql.MaxDepth, graphql.MaxFields, graphql.MaxAliases = maxDepth, maxFields, maxAliases
+	}()
+	graphql.MaxDepth, graphql.MaxFields, graphql.MaxAliases = 2, 4, 1
+
+	testCases := []struct {
+		name   string
+		query  string
+		expect string
+	}{
+		{
+			name:   "Within the limits",
+			query:  `{ a: book(id: 1) { ...parts } } fragment parts on Book { id title }`,
+			expect: `{"data":{"a":{"id":1,"title":"Dune"}}}`,
+		},
+		{
+			name:   "Too deep",
+			query:  `{ book(id: 1) { meta { pages } } }`,
+			expect: `{"data":null,"errors":[{"message":"query is nested deeper than 2 levels"}]}`,
+		},
+		{
+			name:   "Too many fields",
+			query:  `{ book(id: 1) { id title rating tags } }`,
+			expect: `{"data":null,"errors":[{"message":"query selects more than 4 fields"}]}`,
+		},
+		{
+			name:   "Too many aliases",
+			query:  `{ a: book(id: 1) { id } b: book(id: 2) { id } }`,
+			expect: `{"data":null,"errors":[{"message":"query uses more than 1 aliases"}]}`,
+		},
+		{
+			name:   "Fragments are counted every time they are spread",
+			query:  `{ book(id: 1) { ...parts ...parts } } fragment parts on Book { id title }`,
+			expect: `{"data":null,"errors":[{"message":"query selects more than 4 fields"}]}`,
+		},
+		{
+			name:   "Fragments are nested where they are spread",
+			query:  `{ book(id: 1) { ...meta } } fragment meta on Book { meta { pages } }`,
+			expect: `{"data":null,"errors":[{"message":"query is nested deeper than 2 levels"}]}`,
+		},
+	}
+
+	schema := newSchema()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := json.Marshal(schema.Execute(context.Background(), graphql.Request{Query: tc.query}))
+			assert.Nil(t, err)
+			assert.Equal(t, tc.expect, string(got))
+		})
+	}
+}
+
+func TestSchema_Execute_ExponentialFragments(t *testing.T) {
+	// Every fragment spreads the next twice, so the last is
+	// spread 2^50 times, while each is validated only once
+	var b strings.Builder
+	b.WriteString(`{ book(id: 1) { ...f0 } }`)
+	for i := 0; i < 50; i++ {
+		fmt.Fprintf(&b, ` fragment f%d on Book { ...f%d ...f%d }`, i, i+1, i+1)
+	}
+	b.WriteString(` fragment f50 on Book { id }`)
+
+	done := make(chan graphql.Response, 1)
+	go func() {
+		done <- newSchema().Execute(context.Background(), graphql.Request{Query: b.String()})
+	}()
+	select {
+	case res := <-done:
+		assert.Nil(t, res.Data)
+		assert.Equal(t, []graphql.Error{{Message: fmt.Sprintf("query selects more than %d fields", graphql.MaxFields)}}, res.Errors)
+	case <-time.After(5 * time.Second):
+		t.Fatal("the fragments were expanded")
+	}
+}
+
+func TestFloat_NonFinite(t *testing.T) {
+	for _, value := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		_, err := graphql.Float.ParseValue(value)
+		assert.Error(t, err, "parse %v", value)
+		_, err = graphql.Float.Serialize(value)
+		assert.Error(t, err, "serialize %v", value)
+	}
+	got, err := graphql.Float.ParseValue(59.91)
+	assert.Nil(t, err)
+	assert.Equal(t, 59.91, got)
+}
@@ -0,0 +1,713 @@
+package graphql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Note: the parser supports the executable part of the GraphQL
+// language, i.e., operations, fragments, variables and directives,
+// the type system definitions are not supported, as the schema is
+// defined in Go.
+
+// The kinds of tokens produced by the lexer
+const (
+	tokenEOF = iota
+	tokenPunctuator
+	tokenName
+	tokenInt
+	tokenFloat
+	tokenString
+)
+
+type token struct {
+	kind  int
+	value string
+	pos   int
+}
+
+type lexer struct {
+	source string
+	pos    int
+}
+
+// syntaxError describes where the source could not be parsed
+func (l *lexer) syntaxError(pos int, format string, args ...interface{}) error {
+	line, column := 1, 1
+	for _, r := range l.source[:pos] {
+		if r == '\n' {
+			line, column = line+1, 1
+		} else {
+			column++
+		}
+	}
+	return fmt.Errorf("syntax error at %d:%d: %s", line, column, fmt.Sprintf(format, args...))
+}
+
+// next returns the next token, skipping whitespace,
+// commas and comments
+func (l *lexer) next() (token, error) {
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == ',':
+			l.pos++
+		case c == '#':
+			for l.pos < len(l.source) && l.source[l.pos] != '\n' {
+				l.pos++
+			}
+		case strings.HasPrefix(l.source[l.pos:], "\ufeff"):
+			l.pos += len("\ufeff")
+		default:
+			return l.read()
+		}
+	}
+	return token{kind: tokenEOF, pos: l.pos}, nil
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+func (l *lexer) read() (token, error) {
+	start := l.pos
+	c := l.source[l.pos]
+
+	switch {
+	case strings.HasPrefix(l.source[l.pos:], "..."):
+		l.pos += 3
+		return token{kind: tokenPunctuator, value: "...", pos: start}, nil
+	case strings.IndexByte("!$():=@[]{}|", c) >= 0:
+		l.pos++
+		return token{kind: tokenPunctuator, value: string(c), pos: start}, nil
+	case isNameStart(c):
+		for l.pos < len(l.source) && (isNameStart(l.source[l.pos]) || isDigit(l.source[l.pos])) {
+			l.pos++
+		}
+		return token{kind: tokenName, value: l.source[start:l.pos], pos: start}, nil
+	case c == '-' || isDigit(c):
+		return l.readNumber()
+	case c == '"':
+		return l.readString()
+	default:
+		r, _ := utf8.DecodeRuneInString(l.source[l.pos:])
+		return token{}, l.syntaxError(start, "unexpected character %q", r)
+	}
+}
+
+func (l *lexer) readNumber() (token, error) {
+	start := l.pos
+	kind := tokenInt
+	digits := func() int {
+		n := 0
+		for l.pos < len(l.source) && isDigit(l.source[l.pos]) {
+			l.pos++
+			n++
+		}
+		return n
+	}
+
+	if l.source[l.pos] == '-' {
+		l.pos++
+	}
+	if digits() == 0 {
+		return token{}, l.syntaxError(start, "invalid number")
+	}
+	if l.pos < len(l.source) && l.source[l.pos] == '.' {
+		kind = tokenFloat
+		l.pos++
+		if digits() == 0 {
+			return token{}, l.syntaxError(start, "invalid number")
+		}
+	}
+	if l.pos < len(l.source) && (l.source[l.pos] == 'e' || l.source[l.pos] == 'E') {
+		kind = tokenFloat
+		l.pos++
+		if l.pos < len(l.source) && (l.source[l.pos] == '+' || l.source[l.pos] == '-') {
+			l.pos++
+		}
+		if digits() == 0 {
+			return token{}, l.syntaxError(start, "invalid number")
+		}
+	}
+	return token{kind: kind, value: l.source[start:l.pos], pos: start}, nil
+}
+
+func (l *lexer) readString() (token, error) {
+	start := l.pos
+	if strings.HasPrefix(l.source[l.pos:], `"""`) {
+		return token{}, l.syntaxError(start, "block strings are not supported")
+	}
+
+	var sb strings.Builder
+	l.pos++
+	for l.pos < len(l.source) {
+		c := l.source[l.pos]
+		switch {
+		case c == '"':
+			l.pos++
+			return token{kind: tokenString, value: sb.String(), pos: start}, nil
+		case c == '\n' || c == '\r':
+			return token{}, l.syntaxError(start, "unterminated string")
+		case c == '\\':
+			if l.pos+1 >= len(l.source) {
+				return token{}, l.syntaxError(start, "unterminated string")
+			}
+			escaped := l.source[l.pos+1]
+			l.pos += 2
+			switch escaped {
+			case '"', '\\', '/':
+				sb.WriteByte(escaped)
+			case 'b':
+				sb.WriteByte('\b')
+			case 'f':
+				sb.WriteByte('\f')
+			case 'n':
+				sb.WriteByte('\n')
+			case 'r':
+				sb.WriteByte('\r')
+			case 't':
+				sb.WriteByte('\t')
+			case 'u':
+				if l.pos+4 > len(l.source) {
+					return token{}, l.syntaxError(start, "invalid unicode escape")
+				}
+				code, err := strconv.ParseUint(l.source[l.pos:l.pos+4], 16, 32)
+				if err != nil {
+					return token{}, l.syntaxError(start, "invalid unicode escape")
+				}
+				sb.WriteRune(rune(code))
+				l.pos += 4
+			default:
+				return token{}, l.syntaxError(start, "invalid escape \\%c", escaped)
+			}
+		default:
+			sb.WriteByte(c)
+			l.pos++
+		}
+	}
+	return token{}, l.syntaxError(start, "unterminated string")
+}
+
+// The values of the language, besides these, values are
+// represented by their Go types, i.e., int, float64, string,
+// bool, nil, []interface{} and map[string]interface{}
+type (
+	variable  string
+	enumValue string
+)
+
+// typeRef is a reference to a type, such as [Int!]!
+type typeRef struct {
+	name    string
+	ofType  *typeRef
+	nonNull bool
+}
+
+func (t *typeRef) String() string {
+	res := t.name
+	if t.ofType != nil {
+		res = "[" + t.ofType.String() + "]"
+	}
+	if t.nonNull {
+		res += "!"
+	}
+	return res
+}
+
+type document struct {
+	operations []*operation
+	fragments  map[string]*fragment
+}
+
+type operation struct {
+	kind       string
+	name       string
+	variables  []*variableDefinition
+	selections []selection
+}
+
+type variableDefinition struct {
+	name         string
+	typ          *typeRef
+	defaultValue interface{}
+	hasDefault   bool
+}
+
+type fragment struct {
+	name          string
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+}
+
+type selection interface{}
+
+type field struct {
+	alias      string
+	name       string
+	arguments  map[string]interface{}
+	directives []*directive
+	selections []selection
+}
+
+// responseKey is the name of the field in the response
+func (f *field) responseKey() string {
+	if len(f.alias) > 0 {
+		return f.alias
+	}
+	return f.name
+}
+
+type fragmentSpread struct {
+	name       string
+	directives []*directive
+}
+
+type inlineFragment struct {
+	typeCondition string
+	directives    []*directive
+	selections    []selection
+}
+
+type directive struct {
+	name      string
+	arguments map[string]interface{}
+}
+
+type parser struct {
+	lexer *lexer
+	token token
+}
+
+// parse parses the source into a document
+func parse(source string) (*document, error) {
+	p := &parser{lexer: &lexer{source: source}}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+
+	doc := &document{fragments: map[string]*fragment{}}
+	for p.token.kind != tokenEOF {
+		switch {
+		case p.peek(tokenPunctuator, "{"):
+			selections, err := p.parseSelectionSet()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, &operation{kind: "query", selections: selections})
+		case p.peek(tokenName, "query"), p.peek(tokenName, "mutation"), p.peek(tokenName, "subscription"):
+			op, err := p.parseOperation()
+			if err != nil {
+				return nil, err
+			}
+			doc.operations = append(doc.operations, op)
+		case p.peek(tokenName, "fragment"):
+			f, err := p.parseFragment()
+			if err != nil {
+				return nil, err
+			}
+			if _, hasKey := doc.fragments[f.name]; hasKey {
+				return nil, fmt.Errorf("there can be only one fragment named %q", f.name)
+			}
+			doc.fragments[f.name] = f
+		default:
+			return nil, p.unexpected()
+		}
+	}
+	if len(doc.operations) == 0 {
+		return nil, fmt.Errorf("document does not contain any operations")
+	}
+	return doc, nil
+}
+
+func (p *parser) advance() error {
+	t, err := p.lexer.next()
+	if err != nil {
+		return err
+	}
+	p.token = t
+	return nil
+}
+
+func (p *parser) peek(kind int, value string) bool {
+	return p.token.kind == kind && p.token.value == value
+}
+
+func (p *parser) unexpected() error {
+	if p.token.kind == tokenEOF {
+		return p.lexer.syntaxError(p.token.pos, "unexpected end of document")
+	}
+	return p.lexer.syntaxError(p.token.pos, "unexpected %q", p.token.value)
+}
+
+// skip advances past the punctuator, if it is the current token
+func (p *parser) skip(value string) (bool, error) {
+	if !p.peek(tokenPunctuator, value) {
+		return false, nil
+	}
+	return true, p.advance()
+}
+
+func (p *parser) expect(value string) error {
+	if !p.peek(tokenPunctuator, value) {
+		return p.unexpected()
+	}
+	return p.advance()
+}
+
+func (p *parser) expectName() (string, error) {
+	if p.token.kind != tokenName {
+		return "", p.unexpected()
+	}
+	name := p.token.value
+	return name, p.advance()
+}
+
+func (p *parser) parseOperation() (*operation, error) {
+	op := &operation{kind: p.token.value}
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	if p.token.kind == tokenName {
+		op.name = p.token.value
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if ok, err := p.skip("("); err != nil {
+		return nil, err
+	} else if ok {
+		for !p.peek(tokenPunctuator, ")") {
+			def, err := p.parseVariableDefinition()
+			if err != nil {
+				return nil, err
+			}
+			op.variables = append(op.variables, def)
+		}
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if p.peek(tokenPunctuator, "@") {
+		return nil, p.lexer.syntaxError(p.token.pos, "directives on operations are not supported")
+	}
+	op.selections, err = p.parseSelectionSet()
+	return op, err
+}
+
+func (p *parser) parseVariableDefinition() (*variableDefinition, error) {
+	err := p.expect("$")
+	if err != nil {
+		return nil, err
+	}
+	def := &variableDefinition{}
+	def.name, err = p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	err = p.expect(":")
+	if err != nil {
+		return nil, err
+	}
+	def.typ, err = p.parseType()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip("="); err != nil {
+		return nil, err
+	} else if ok {
+		def.defaultValue, err = p.parseValue(true)
+		if err != nil {
+			return nil, err
+		}
+		def.hasDefault = true
+	}
+	return def, nil
+}
+
+func (p *parser) parseType() (*typeRef, error) {
+	t := &typeRef{}
+	if ok, err := p.skip("["); err != nil {
+		return nil, err
+	} else if ok {
+		t.ofType, err = p.parseType()
+		if err != nil {
+			return nil, err
+		}
+		err = p.expect("]")
+		if err != nil {
+			return nil, err
+		}
+	} else {
+		t.name, err = p.expectName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	ok, err := p.skip("!")
+	t.nonNull = ok
+	return t, err
+}
+
+func (p *parser) parseFragment() (*fragment, error) {
+	err := p.advance()
+	if err != nil {
+		return nil, err
+	}
+	f := &fragment{}
+	f.name, err = p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if f.name == "on" {
+		return nil, p.lexer.syntaxError(p.token.pos, "a fragment can not be named on")
+	}
+	if !p.peek(tokenName, "on") {
+		return nil, p.unexpected()
+	}
+	err = p.advance()
+	if err != nil {
+		return nil, err
+	}
+	f.typeCondition, err = p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	f.directives, err = p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	f.selections, err = p.parseSelectionSet()
+	return f, err
+}
+
+func (p *parser) parseSelectionSet() ([]selection, error) {
+	err := p.expect("{")
+	if err != nil {
+		return nil, err
+	}
+
+	var selections []selection
+	for !p.peek(tokenPunctuator, "}") {
+		s, err := p.parseSelection()
+		if err != nil {
+			return nil, err
+		}
+		selections = append(selections, s)
+	}
+	if len(selections) == 0 {
+		return nil, p.unexpected()
+	}
+	return selections, p.advance()
+}
+
+func (p *parser) parseSelection() (selection, error) {
+	if ok, err := p.skip("..."); err != nil {
+		return nil, err
+	} else if ok {
+		return p.parseFragmentSelection()
+	}
+
+	f := &field{}
+	var err error
+	f.name, err = p.expectName()
+	if err != nil {
+		return nil, err
+	}
+	if ok, err := p.skip(":"); err != nil {
+		return nil, err
+	} else if ok {
+		f.alias = f.name
+		f.name, err = p.expectName()
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	f.arguments, err = p.parseArguments(false)
+	if err != nil {
+		return nil, err
+	}
+	f.directives, err = p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek(tokenPunctuator, "{") {
+		f.selections, err = p.parseSelectionSet()
+	}
+	return f, err
+}
+
+// parseFragmentSelection parses what follows the spread
+// operator, i.e., a fragment spread or an inline fragment
+func (p *parser) parseFragmentSelection() (selection, error) {
+	if p.token.kind == tokenName && p.token.value != "on" {
+		spread := &fragmentSpread{name: p.token.value}
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		spread.directives, err = p.parseDirectives()
+		return spread, err
+	}
+
+	inline := &inlineFragment{}
+	var err error
+	if p.peek(tokenName, "on") {
+		err = p.advance()
+		if err != nil {
+			return nil, err
+		}
+		inline.typeCondition, err = p.expectName()
+		if err != nil {
+			return nil, err
+		}
+	}
+	inline.directives, err = p.parseDirectives()
+	if err != nil {
+		return nil, err
+	}
+	inline.selections, err = p.parseSelectionSet()
+	return inline, err
+}
+
+func (p *parser) parseArguments(isConst bool) (map[string]interface{}, error) {
+	args := map[string]interface{}{}
+	ok, err := p.skip("(")
+	if err != nil || !ok {
+		return args, err
+	}
+
+	for !p.peek(tokenPunctuator, ")") {
+		name, err := p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		if _, hasKey := args[name]; hasKey {
+			return nil, fmt.Errorf("there can be only one argument named %q", name)
+		}
+		err = p.expect(":")
+		if err != nil {
+			return nil, err
+		}
+		args[name], err = p.parseValue(isConst)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return args, p.advance()
+}
+
+func (p *parser) parseDirectives() ([]*directive, error) {
+	var directives []*directive
+	for p.peek(tokenPunctuator, "@") {
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		d := &directive{}
+		d.name, err = p.expectName()
+		if err != nil {
+			return nil, err
+		}
+		d.arguments, err = p.parseArguments(false)
+		if err != nil {
+			return nil, err
+		}
+		directives = append(directives, d)
+	}
+	return directives, nil
+}
+
+// parseValue parses a value, variables are only
+// allowed if the value is not constant
+func (p *parser) parseValue(isConst bool) (interface{}, error) {
+	t := p.token
+	switch {
+	case t.kind == tokenPunctuator && t.value == "$" && !isConst:
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		name, err := p.expectName()
+		return variable(name), err
+	case t.kind == tokenPunctuator && t.value == "[":
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		values := []interface{}{}
+		for !p.peek(tokenPunctuator, "]") {
+			value, err := p.parseValue(isConst)
+			if err != nil {
+				return nil, err
+			}
+			values = append(values, value)
+		}
+		return values, p.advance()
+	case t.kind == tokenPunctuator && t.value == "{":
+		err := p.advance()
+		if err != nil {
+			return nil, err
+		}
+		values := map[string]interface{}{}
+		for !p.peek(tokenPunctuator, "}") {
+			name, err := p.expectName()
+			if err != nil {
+				return nil, err
+			}
+			err = p.expect(":")
+			if err != nil {
+				return nil, err
+			}
+			values[name], err = p.parseValue(isConst)
+			if err != nil {
+				return nil, err
+			}
+		}
+		return values, p.advance()
+	case t.kind == tokenInt:
+		value, err := strconv.Atoi(t.value)
+		if err != nil {
+			return nil, p.lexer.syntaxError(t.pos, "invalid int %s", t.value)
+		}
+		return value, p.advance()
+	case t.kind == tokenFloat:
+		value, err := strconv.ParseFloat(t.value, 64)
+		if err != nil {
+			return nil, p.lexer.syntaxError(t.pos, "invalid float %s", t.value)
+		}
+		return value, p.advance()
+	case t.kind == tokenString:
+		return t.value, p.advance()
+	case t.kind == tokenName:
+		var value interface{}
+		switch t.value {
+		case "true":
+			value = true
+		case "false":
+			value = false
+		case "null":
+			value = nil
+		default:
+			value = enumValue(t.value)
+		}
+		return value, p.advance()
+	default:
+		return nil, p.unexpected()
+	}
+}
@@ -0,0 +1,238 @@
+package graphql
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"reflect"
+)
+
+// Type is one of *Scalar, *Object, *List or *NonNull
+type Type interface {
+	String() string
+}
+
+// Scalar is a leaf value, it knows how to read
+// it from the input and write it to the output
+type Scalar struct {
+	Name string
+	// ParseValue converts an input value, i.e., a Go int, float64,
+	// string or bool from the query or the variables
+	ParseValue func(value interface{}) (interface{}, error)
+	// Serialize converts the resolved value for the response
+	Serialize func(value interface{}) (interface{}, error)
+}
+
+func (s *Scalar) String() string {
+	return s.Name
+}
+
+// Object is a type with fields
+type Object struct {
+	Name   string
+	Fields Fields
+}
+
+func (o *Object) String() string {
+	return o.Name
+}
+
+// Fields maps the names of the fields of an object to their definition
+type Fields map[string]*Field
+
+// ResolveFn resolves the value of a field, the source is the
+// resolved value of the object the field is part of
+type ResolveFn func(ctx context.Context, source interface{}, args map[string]interface{}) (interface{}, error)
+
+// Field is a field of an object
+type Field struct {
+	Type Type
+	Args Args
+	// Resolve is required for every field of the query, for other
+	// objects the value is read from a map[string]interface{} source
+	// by default
+	Resolve ResolveFn
+}
+
+// Args maps the names of the arguments of a field to their definition
+type Args map[string]*Argument
+
+// Argument is an argument of a field, the arguments that are
+// not provided and have no default are left out when resolving
+type Argument struct {
+	Type    Type
+	Default interface{}
+}
+
+// List is a list of another type
+type List struct {
+	OfType Type
+}
+
+func (l *List) String() string {
+	return "[" + l.OfType.String() + "]"
+}
+
+// NonNull is a type that can not be null
+type NonNull struct {
+	OfType Type
+}
+
+func (n *NonNull) String() string {
+	return n.OfType.String() + "!"
+}
+
+// Schema describes what can be queried
+type Schema struct {
+	Query *Object
+}
+
+// namedType unwraps the type from any lists and non-nulls
+func namedType(t Type) Type {
+	for {
+		switch w := t.(type) {
+		case *List:
+			t = w.OfType
+		case *NonNull:
+			t = w.OfType
+		default:
+			return t
+		}
+	}
+}
+
+func isNonNull(t Type) bool {
+	_, ok := t.(*NonNull)
+	return ok
+}
+
+// The built in scalars
+var (
+	Int = &Scalar{
+		Name: "Int",
+		ParseValue: func(value interface{}) (interface{}, error) {
+			return toInt(value)
+		},
+		Serialize: func(value interface{}) (interface{}, error) {
+			return toInt(value)
+		},
+	}
+	Float = &Scalar{
+		Name: "Float",
+		ParseValue: func(value interface{}) (interface{}, error) {
+			return toFloat(value)
+		},
+		Serialize: func(value interface{}) (interface{}, error) {
+			return toFloat(value)
+		},
+	}
+	String = &Scalar{
+		Name: "String",
+		ParseValue: func(value interface{}) (interface{}, error) {
+			if s, ok := value.(string); ok {
+				return s, nil
+			}
+			return nil, fmt.Errorf("expected a String, got %v", value)
+		},
+		Serialize: func(value interface{}) (interface{}, error) {
+			switch v := value.(type) {
+			case string:
+				return v, nil
+			case fmt.Stringer:
+				return v.String(), nil
+			}
+			return fmt.Sprint(value), nil
+		},
+	}
+	Boolean = &Scalar{
+		Name: "Boolean",
+		ParseValue: func(value interface{}) (interface{}, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("expected a Boolean, got %v", value)
+		},
+		Serialize: func(value interface{}) (interface{}, error) {
+			if b, ok := value.(bool); ok {
+				return b, nil
+			}
+			return nil, fmt.Errorf("expected a Boolean, got %v", value)
+		},
+	}
+)
+
+// scalars are the built in scalars that can be referred
+// to by name, i.e., by the types of the variables
+var scalars = map[string]*Scalar{
+	Int.Name:     Int,
+	Float.Name:   Float,
+	String.Name:  String,
+	Boolean.Name: Boolean,
+}
+
+func toInt(value interface{}) (interface{}, error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.Int() >= math.MinInt32 && v.Int() <= math.MaxInt32 {
+			return int(v.Int()), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		// Numbers in JSON variables are always floats
+		if f := v.Float(); f == math.Trunc(f) && f >= math.MinInt32 && f <= math.MaxInt32 {
+			return int(f), nil
+		}
+	}
+	return nil, fmt.Errorf("expected an Int, got %v", value)
+}
+
+func toFloat(value interface{}) (interface{}, error) {
+	switch v := reflect.ValueOf(value); v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), nil
+	case reflect.Float32, reflect.Float64:
+		// NaN and Inf are not numbers that can be represented
+		if f := v.Float(); !math.IsNaN(f) && !math.IsInf(f, 0) {
+			return f, nil
+		}
+	}
+	return nil, fmt.Errorf("expected a Float, got %v", value)
+}
+
+// coerceInput converts an input value to the type, the value must
+// not contain any variables, these must be replaced first
+func coerceInput(t Type, value interface{}) (interface{}, error) {
+	if n, ok := t.(*NonNull); ok {
+		if value == nil {
+			return nil, fmt.Errorf("expected a non-null %s", n.OfType)
+		}
+		return coerceInput(n.OfType, value)
+	}
+	if value == nil {
+		return nil, nil
+	}
+
+	switch typ := t.(type) {
+	case *List:
+		values, ok := value.([]interface{})
+		if !ok {
+			// A single value is coerced into a list of one
+			values = []interface{}{value}
+		}
+		res := make([]interface{}, 0, len(values))
+		for _, v := range values {
+			coerced, err := coerceInput(typ.OfType, v)
+			if err != nil {
+				return nil, err
+			}
+			res = append(res, coerced)
+		}
+		return res, nil
+	case *Scalar:
+		if e, ok := value.(enumValue); ok {
+			return nil, fmt.Errorf("expected a %s, got %s", typ.Name, e)
+		}
+		return typ.ParseValue(value)
+	default:
+		return nil, fmt.Errorf("%s can not be used as input", t)
+	}
+}
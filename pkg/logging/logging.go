@@ -0,0 +1,94 @@
+package logging
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/go-kit/kit/log"
+)
+
+// Note: the request ID is carried by the context, from the transport
+// through the services and stores into pedlar and its client, so what
+// they log can be correlated with the request that caused it.
+
+// RequestIDHeader is the header the request ID is received
+// in, echoed in, and forwarded to the upstream API in
+const RequestIDHeader = "X-Request-ID"
+
+// MaxRequestIDLength is the longest request ID that is accepted
+// from a client, longer ones are replaced
+var MaxRequestIDLength = 128
+
+// The formats a logger can be created with
+const (
+	FormatLogfmt = "logfmt"
+	FormatJSON   = "json"
+)
+
+// NewLogger creates a structured logger, that writes each entry
+// with a timestamp, in the provided format, to the writer
+func NewLogger(format string, w io.Writer) (log.Logger, error) {
+	var logger log.Logger
+	switch format {
+	case FormatLogfmt:
+		logger = log.NewLogfmtLogger(log.NewSyncWriter(w))
+	case FormatJSON:
+		logger = log.NewJSONLogger(log.NewSyncWriter(w))
+	default:
+		return nil, fmt.Errorf("unknown log format: %s, must be %s or %s", format, FormatLogfmt, FormatJSON)
+	}
+	return log.With(logger, "ts", log.DefaultTimestampUTC), nil
+}
+
+type contextKey int
+
+const requestIDKey contextKey = iota
+
+// WithRequestID returns a copy of the context with the request ID
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey, id)
+}
+
+// RequestID returns the request ID of the context, if any
+func RequestID(ctx context.Context) string {
+	id, _ := ctx.Value(requestIDKey).(string)
+	return id
+}
+
+// With adds the request ID of the context, if any, to the logger
+func With(ctx context.Context, logger log.Logger) log.Logger {
+	if id := RequestID(ctx); len(id) > 0 {
+		return log.With(logger, "request_id", id)
+	}
+	return logger
+}
+
+// NewRequestID generates a random request ID
+func NewRequestID() string {
+	b := make([]byte, 12)
+	_, err := rand.Read(b)
+	if err != nil {
+		panic(fmt.Sprintf("logging: failed to generate request ID: %s", err))
+	}
+	return hex.EncodeToString(b)
+}
+
+// RequestIDFromHeader returns the request ID provided by the client,
+// if it is valid, otherwise a new one is generated. Only printable
+// ASCII without spaces is accepted, so it is safe to log and echo.
+func RequestIDFromHeader(header http.Header) string {
+	id := header.Get(RequestIDHeader)
+	if len(id) == 0 || len(id) > MaxRequestIDLength {
+		return NewRequestID()
+	}
+	for i := 0; i < len(id); i++ {
+		if id[i] <= ' ' || id[i] > '~' {
+			return NewRequestID()
+		}
+	}
+	return id
+}
@@ -0,0 +1,93 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRequestIDFromHeader(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Header   string
+		Expect   string
+		Generate bool
+	}{
+		{Name: "Provided", Header: "abc-123_4.5:6", Expect: "abc-123_4.5:6"},
+		{Name: "Missing", Generate: true},
+		{Name: "Too long", Header: strings.Repeat("a", 129), Generate: true},
+		{Name: "Spaces", Header: "abc 123", Generate: true},
+		{Name: "Control characters", Header: "abc\x1b[31m", Generate: true},
+	}
+
+	for _, tc := range testCases {
+		header := http.Header{}
+		if len(tc.Header) > 0 {
+			header.Set(RequestIDHeader, tc.Header)
+		}
+		got := RequestIDFromHeader(header)
+		if tc.Generate {
+			assert.Len(t, got, 24, tc.Name)
+			assert.NotEqual(t, tc.Header, got, tc.Name)
+		} else {
+			assert.Equal(t, tc.Expect, got, tc.Name)
+		}
+	}
+	assert.NotEqual(t, NewRequestID(), NewRequestID())
+}
+
+func TestWith(t *testing.T) {
+	testCases := []struct {
+		Name   string
+		Format string
+		Ctx    context.Context
+		Expect interface{}
+	}{
+		{
+			Name:   "Logfmt with request ID",
+			Format: FormatLogfmt,
+			Ctx:    WithRequestID(context.Background(), "abc"),
+			Expect: "request_id=abc msg=hello\n",
+		},
+		{
+			Name:   "Logfmt without request ID",
+			Format: FormatLogfmt,
+			Ctx:    context.Background(),
+			Expect: "msg=hello\n",
+		},
+		{
+			Name:   "JSON with request ID",
+			Format: FormatJSON,
+			Ctx:    WithRequestID(context.Background(), "abc"),
+			Expect: map[string]interface{}{"msg": "hello", "request_id": "abc"},
+		},
+	}
+
+	for _, tc := range testCases {
+		var b bytes.Buffer
+		logger, err := NewLogger(tc.Format, &b)
+		assert.Nil(t, err, tc.Name)
+		With(tc.Ctx, logger).Log("msg", "hello")
+
+		// The timestamp differs between runs
+		if tc.Format == FormatJSON {
+			var got map[string]interface{}
+			err = json.Unmarshal(b.Bytes(), &got)
+			assert.Nil(t, err, tc.Name)
+			assert.NotEmpty(t, got["ts"], tc.Name)
+			delete(got, "ts")
+			assert.Equal(t, tc.Expect, got, tc.Name)
+			continue
+		}
+		assert.True(t, strings.HasPrefix(b.String(), "ts="), tc.Name)
+		assert.Equal(t, tc.Expect, b.String()[strings.Index(b.String(), " ")+1:], tc.Name)
+	}
+
+	_, err := NewLogger("xml", &bytes.Buffer{})
+	assert.Equal(t, "unknown log format: xml, must be logfmt or json", err.Error())
+}
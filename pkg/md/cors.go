@@ -2,12 +2,24 @@ package md
 
 import "net/http"
 
+// AllowedOrigins are the origins that may make cross origin
+// requests, * allows any origin
+var AllowedOrigins = []string{"*"}
+
 // Cors ensures that cross origin resource requests are allowed
 func Cors(h http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "GET, OPTIONS")
-		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type")
+		origin := allowedOrigin(r.Header.Get("Origin"))
+		if len(origin) > 0 {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+		}
+		// The header differs by origin, so caches must not share it
+		if origin != "*" {
+			w.Header().Add("Vary", "Origin")
+		}
+		w.Header().Set("Access-Control-Allow-Methods", "GET, POST, OPTIONS")
+		w.Header().Set("Access-Control-Allow-Headers", "Origin, Content-Type, Last-Event-ID, X-Request-ID, X-API-Key")
+		w.Header().Set("Access-Control-Expose-Headers", "X-Request-ID, Retry-After, X-Total-Count, ETag, Last-Modified")
 
 		if r.Method == http.MethodOptions {
 			return
@@ -16,3 +28,17 @@ func Cors(h http.Handler) http.Handler {
 		h.ServeHTTP(w, r)
 	})
 }
+
+// allowedOrigin returns the value of the allow origin header, the
+// origin of the request is echoed when only some origins are allowed
+func allowedOrigin(origin string) string {
+	for _, allowed := range AllowedOrigins {
+		if allowed == "*" {
+			return "*"
+		}
+		if allowed == origin {
+			return origin
+		}
+	}
+	return ""
+}
@@ -0,0 +1,73 @@
+package md
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCors(t *testing.T) {
+	testCases := []struct {
+		Name         string
+		Allowed      []string
+		Origin       string
+		Method       string
+		ExpectOrigin string
+		ExpectVary   string
+		ExpectCode   int
+	}{
+		{
+			Name:         "Any origin",
+			Allowed:      []string{"*"},
+			Origin:       "https://example.com",
+			Method:       http.MethodGet,
+			ExpectOrigin: "*",
+			ExpectCode:   http.StatusTeapot,
+		},
+		{
+			Name:         "Allowed origin is echoed",
+			Allowed:      []string{"https://example.com", "https://other.com"},
+			Origin:       "https://other.com",
+			Method:       http.MethodGet,
+			ExpectOrigin: "https://other.com",
+			ExpectVary:   "Origin",
+			ExpectCode:   http.StatusTeapot,
+		},
+		{
+			Name:       "Unknown origin",
+			Allowed:    []string{"https://example.com"},
+			Origin:     "https://evil.com",
+			Method:     http.MethodGet,
+			ExpectVary: "Origin",
+			ExpectCode: http.StatusTeapot,
+		},
+		{
+			Name:         "Preflight is not passed on",
+			Allowed:      []string{"*"},
+			Origin:       "https://example.com",
+			Method:       http.MethodOptions,
+			ExpectOrigin: "*",
+			ExpectCode:   http.StatusOK,
+		},
+	}
+
+	defer func(allowed []string) { AllowedOrigins = allowed }(AllowedOrigins)
+	for _, tc := range testCases {
+		AllowedOrigins = tc.Allowed
+		handler := Cors(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusTeapot)
+		}))
+
+		recorder := httptest.NewRecorder()
+		req := httptest.NewRequest(tc.Method, "/v1/stations", nil)
+		req.Header.Set("Origin", tc.Origin)
+		handler.ServeHTTP(recorder, req)
+
+		assert.Equal(t, tc.ExpectCode, recorder.Code, tc.Name)
+		assert.Equal(t, tc.ExpectOrigin, recorder.Header().Get("Access-Control-Allow-Origin"), tc.Name)
+		assert.Equal(t, tc.ExpectVary, recorder.Header().Get("Vary"), tc.Name)
+		assert.Equal(t, "X-Request-ID, Retry-After, X-Total-Count, ETag, Last-Modified", recorder.Header().Get("Access-Control-Expose-Headers"), tc.Name)
+	}
+}
@@ -0,0 +1,304 @@
+package metrics
+
+import (
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Note: the metrics follow the interfaces of the go-kit metrics package,
+// label values are provided to With as alternating name and value pairs,
+// and are exposed in the Prometheus text format by the registry.
+
+// Counter describes a metric that only goes up
+type Counter interface {
+	With(labelValues ...string) Counter
+	Add(delta float64)
+}
+
+// Gauge describes a metric that goes up and down
+type Gauge interface {
+	With(labelValues ...string) Gauge
+	Set(value float64)
+	Add(delta float64)
+}
+
+// Histogram describes a metric that counts the observed
+// values in buckets, e.g., the duration of requests
+type Histogram interface {
+	With(labelValues ...string) Histogram
+	Observe(value float64)
+}
+
+// DefaultBuckets are the upper bounds of the buckets of a
+// histogram, suitable for durations in seconds
+var DefaultBuckets = []float64{.005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// ContentType is the media type of the Prometheus text format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+// series contains the values of a metric with a set of label values
+type series struct {
+	labelValues []string
+	value       float64
+	counts      []uint64
+	sum         float64
+	count       uint64
+}
+
+// family contains every series of a metric
+type family struct {
+	name       string
+	help       string
+	kind       string
+	labelNames []string
+	buckets    []float64
+	series     map[string]*series
+	fn         func() float64
+}
+
+// Registry contains the metrics and exposes them in
+// the Prometheus text format
+type Registry struct {
+	mutex    sync.Mutex
+	families []*family
+}
+
+// NewRegistry creates an empty registry
+func NewRegistry() *Registry {
+	return &Registry{}
+}
+
+func (r *Registry) register(f *family) *family {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	for _, existing := range r.families {
+		if existing.name == f.name {
+			panic(fmt.Sprintf("metrics: %s is already registered", f.name))
+		}
+	}
+	f.series = map[string]*series{}
+	r.families = append(r.families, f)
+	return f
+}
+
+// NewCounter registers a counter with the provided label names
+func (r *Registry) NewCounter(name, help string, labelNames ...string) Counter {
+	f := r.register(&family{name: name, help: help, kind: "counter", labelNames: append([]string{}, labelNames...)})
+	return &counter{metric{registry: r, family: f}}
+}
+
+// NewGauge registers a gauge with the provided label names
+func (r *Registry) NewGauge(name, help string, labelNames ...string) Gauge {
+	f := r.register(&family{name: name, help: help, kind: "gauge", labelNames: append([]string{}, labelNames...)})
+	return &gauge{metric{registry: r, family: f}}
+}
+
+// NewGaugeFunc registers a gauge whose value is read
+// from the function whenever it is exposed
+func (r *Registry) NewGaugeFunc(name, help string, fn func() float64) {
+	r.register(&family{name: name, help: help, kind: "gauge", fn: fn})
+}
+
+// NewHistogram registers a histogram with the provided buckets,
+// the default buckets are used if none are provided
+func (r *Registry) NewHistogram(name, help string, buckets []float64, labelNames ...string) Histogram {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	f := r.register(&family{name: name, help: help, kind: "histogram", buckets: buckets, labelNames: append([]string{}, labelNames...)})
+	return &histogram{metric{registry: r, family: f}}
+}
+
+// metric is a family with some of its label values
+type metric struct {
+	registry    *Registry
+	family      *family
+	labelValues []string
+}
+
+// with adds the label values, unknown label names are ignored
+func (m metric) with(labelValues []string) metric {
+	values := make([]string, len(m.family.labelNames))
+	copy(values, m.labelValues)
+	for i := 0; i+1 < len(labelValues); i += 2 {
+		for j, name := range m.family.labelNames {
+			if name == labelValues[i] {
+				values[j] = labelValues[i+1]
+			}
+		}
+	}
+	m.labelValues = values
+	return m
+}
+
+// update applies the function to the series of the label values
+func (m metric) update(fn func(s *series)) {
+	m.registry.mutex.Lock()
+	defer m.registry.mutex.Unlock()
+
+	values := make([]string, len(m.family.labelNames))
+	copy(values, m.labelValues)
+	key := strings.Join(values, "\xff")
+	s, hasKey := m.family.series[key]
+	if !hasKey {
+		s = &series{
+			labelValues: values,
+			counts:      make([]uint64, len(m.family.buckets)),
+		}
+		m.family.series[key] = s
+	}
+	fn(s)
+}
+
+type counter struct {
+	metric
+}
+
+func (c *counter) With(labelValues ...string) Counter {
+	return &counter{c.with(labelValues)}
+}
+
+func (c *counter) Add(delta float64) {
+	c.update(func(s *series) {
+		s.value += delta
+	})
+}
+
+type gauge struct {
+	metric
+}
+
+func (g *gauge) With(labelValues ...string) Gauge {
+	return &gauge{g.with(labelValues)}
+}
+
+func (g *gauge) Set(value float64) {
+	g.update(func(s *series) {
+		s.value = value
+	})
+}
+
+func (g *gauge) Add(delta float64) {
+	g.update(func(s *series) {
+		s.value += delta
+	})
+}
+
+type histogram struct {
+	metric
+}
+
+func (h *histogram) With(labelValues ...string) Histogram {
+	return &histogram{h.with(labelValues)}
+}
+
+func (h *histogram) Observe(value float64) {
+	h.update(func(s *series) {
+		for i, upper := range h.family.buckets {
+			if value <= upper {
+				s.counts[i]++
+			}
+		}
+		s.sum += value
+		s.count++
+	})
+}
+
+// WriteTo writes every metric in the Prometheus text format,
+// the series of a metric are ordered by their label values
+func (r *Registry) WriteTo(w io.Writer) (int64, error) {
+	var b strings.Builder
+
+	// The functions are invoked outside of the lock,
+	// so they are free to use the registry
+	r.mutex.Lock()
+	families := append([]*family{}, r.families...)
+	r.mutex.Unlock()
+	values := make([]float64, len(families))
+	for i, f := range families {
+		if f.fn != nil {
+			values[i] = f.fn()
+		}
+	}
+
+	r.mutex.Lock()
+	for i, f := range families {
+		fmt.Fprintf(&b, "# HELP %s %s\n", f.name, escapeHelp(f.help))
+		fmt.Fprintf(&b, "# TYPE %s %s\n", f.name, f.kind)
+		if f.fn != nil {
+			fmt.Fprintf(&b, "%s %s\n", f.name, formatFloat(values[i]))
+			continue
+		}
+
+		keys := make([]string, 0, len(f.series))
+		for key := range f.series {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			s := f.series[key]
+			if f.kind != "histogram" {
+				fmt.Fprintf(&b, "%s%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues), formatFloat(s.value))
+				continue
+			}
+			names := append(append([]string{}, f.labelNames...), "le")
+			for i, upper := range f.buckets {
+				fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, formatLabels(names, append(append([]string{}, s.labelValues...), formatFloat(upper))), s.counts[i])
+			}
+			fmt.Fprintf(&b, "%s_bucket%s %d\n", f.name, formatLabels(names, append(append([]string{}, s.labelValues...), "+Inf")), s.count)
+			fmt.Fprintf(&b, "%s_sum%s %s\n", f.name, formatLabels(f.labelNames, s.labelValues), formatFloat(s.sum))
+			fmt.Fprintf(&b, "%s_count%s %d\n", f.name, formatLabels(f.labelNames, s.labelValues), s.count)
+		}
+	}
+	r.mutex.Unlock()
+
+	n, err := io.WriteString(w, b.String())
+	return int64(n), err
+}
+
+// ServeHTTP exposes the metrics to Prometheus
+func (r *Registry) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", ContentType)
+	r.WriteTo(w)
+}
+
+func formatLabels(names, values []string) string {
+	if len(names) == 0 {
+		return ""
+	}
+	pairs := make([]string, len(names))
+	for i, name := range names {
+		pairs[i] = fmt.Sprintf(`%s="%s"`, name, labelEscaper.Replace(values[i]))
+	}
+	return "{" + strings.Join(pairs, ",") + "}"
+}
+
+func formatFloat(value float64) string {
+	switch {
+	case math.IsInf(value, 1):
+		return "+Inf"
+	case math.IsInf(value, -1):
+		return "-Inf"
+	case math.IsNaN(value):
+		return "NaN"
+	}
+	return strconv.FormatFloat(value, 'g', -1, 64)
+}
+
+// labelEscaper escapes the backslashes, double quotes
+// and line feeds of label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeHelp escapes the backslashes and line feeds of the help text
+func escapeHelp(help string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(help)
+}
@@ -0,0 +1,110 @@
+package metrics
+
+import (
+	"bytes"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestRegistry_WriteTo(t *testing.T) {
+	testCases := []struct {
+		Name     string
+		Register func(r *Registry)
+		Expect   string
+	}{
+		{
+			Name: "Counter",
+			Register: func(r *Registry) {
+				c := r.NewCounter("requests_total", "The requests", "method", "code")
+				c.With("method", "GET", "code", "200").Add(1)
+				c.With("code", "200", "method", "GET").Add(2)
+				c.With("method", "GET", "code", "404").Add(1)
+			},
+			Expect: "# HELP requests_total The requests\n" +
+				"# TYPE requests_total counter\n" +
+				"requests_total{method=\"GET\",code=\"200\"} 3\n" +
+				"requests_total{method=\"GET\",code=\"404\"} 1\n",
+		},
+		{
+			Name: "Gauge, with partial and unknown labels",
+			Register: func(r *Registry) {
+				g := r.NewGauge("stations", "The stations", "state", "city")
+				open := g.With("state", "open")
+				open.Set(10)
+				open.Add(-2)
+				g.With("state", "closed", "country", "no").Set(1.5)
+			},
+			Expect: "# HELP stations The stations\n" +
+				"# TYPE stations gauge\n" +
+				"stations{state=\"closed\",city=\"\"} 1.5\n" +
+				"stations{state=\"open\",city=\"\"} 8\n",
+		},
+		{
+			Name: "Gauge function, without labels",
+			Register: func(r *Registry) {
+				r.NewGaugeFunc("age_seconds", "The age\nin seconds", func() float64 {
+					return 42.5
+				})
+			},
+			Expect: "# HELP age_seconds The age\\nin seconds\n" +
+				"# TYPE age_seconds gauge\n" +
+				"age_seconds 42.5\n",
+		},
+		{
+			Name: "Histogram",
+			Register: func(r *Registry) {
+				h := r.NewHistogram("duration_seconds", "The duration", []float64{1, 0.5}, "route")
+				h.With("route", "/a").Observe(0.25)
+				h.With("route", "/a").Observe(0.75)
+				h.With("route", "/a").Observe(2)
+			},
+			Expect: "# HELP duration_seconds The duration\n" +
+				"# TYPE duration_seconds histogram\n" +
+				"duration_seconds_bucket{route=\"/a\",le=\"0.5\"} 1\n" +
+				"duration_seconds_bucket{route=\"/a\",le=\"1\"} 2\n" +
+				"duration_seconds_bucket{route=\"/a\",le=\"+Inf\"} 3\n" +
+				"duration_seconds_sum{route=\"/a\"} 3\n" +
+				"duration_seconds_count{route=\"/a\"} 3\n",
+		},
+		{
+			Name: "Escaped label values",
+			Register: func(r *Registry) {
+				r.NewCounter("errors_total", "The errors", "reason").With("reason", "a \"quoted\"\\path\n").Add(1)
+			},
+			Expect: "# HELP errors_total The errors\n" +
+				"# TYPE errors_total counter\n" +
+				"errors_total{reason=\"a \\\"quoted\\\"\\\\path\\n\"} 1\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		r := NewRegistry()
+		tc.Register(r)
+		var b bytes.Buffer
+		_, err := r.WriteTo(&b)
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, tc.Expect, b.String(), tc.Name)
+	}
+}
+
+func TestRegistry_ServeHTTP(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "The requests").Add(1)
+
+	recorder := httptest.NewRecorder()
+	r.ServeHTTP(recorder, httptest.NewRequest(http.MethodGet, "/metrics", nil))
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, ContentType, recorder.Header().Get("Content-Type"))
+	assert.Equal(t, "# HELP requests_total The requests\n# TYPE requests_total counter\nrequests_total 1\n", recorder.Body.String())
+}
+
+func TestRegistry_RegisterTwice(t *testing.T) {
+	r := NewRegistry()
+	r.NewCounter("requests_total", "The requests")
+	assert.Panics(t, func() {
+		r.NewGauge("requests_total", "The requests")
+	})
+}
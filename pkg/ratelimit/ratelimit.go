@@ -0,0 +1,123 @@
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// Note: every caller has a bucket of tokens, that is refilled at the
+// rate of the limit. A request takes a token, and is rejected while
+// the bucket is empty, so a caller can make a burst of requests after
+// being quiet, but never more than the limit over time.
+
+// SweepInterval is how often the buckets that have been refilled
+// are removed, so callers that went away are forgotten
+var SweepInterval = time.Minute
+
+// Limit describes how many requests a caller may make
+type Limit struct {
+	// PerMinute is the number of requests allowed per minute, the
+	// requests are not limited if it is zero
+	PerMinute int `json:"requests_per_minute"`
+	// Burst is the number of requests that can be made at once,
+	// it defaults to the number of requests per minute
+	Burst int `json:"burst"`
+}
+
+// Unlimited returns true if the requests are not limited
+func (l Limit) Unlimited() bool {
+	return l.PerMinute <= 0
+}
+
+// burst returns the size of the bucket
+func (l Limit) burst() float64 {
+	if l.Burst <= 0 {
+		return float64(l.PerMinute)
+	}
+	return float64(l.Burst)
+}
+
+// rate returns the number of tokens added per second
+func (l Limit) rate() float64 {
+	return float64(l.PerMinute) / 60
+}
+
+// bucket contains the tokens left as of the last request
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  Limit
+}
+
+// refill adds the tokens earned since the last request
+func (b *bucket) refill(now time.Time) {
+	elapsed := now.Sub(b.last).Seconds()
+	if elapsed > 0 {
+		b.tokens = math.Min(b.limit.burst(), b.tokens+elapsed*b.limit.rate())
+	}
+	b.last = now
+}
+
+// Limiter limits the requests of every caller
+// by their own bucket
+type Limiter struct {
+	mutex     sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+	now       func() time.Time
+}
+
+// New creates a limiter without any callers
+func New() *Limiter {
+	return &Limiter{
+		buckets:   map[string]*bucket{},
+		lastSweep: time.Now(),
+		now:       time.Now,
+	}
+}
+
+// Allow takes a token from the bucket of the caller, if the bucket
+// is empty the request is not allowed, and the time until the next
+// token is added is returned
+func (l *Limiter) Allow(caller string, limit Limit) (bool, time.Duration) {
+	if limit.Unlimited() {
+		return true, 0
+	}
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+
+	now := l.now()
+	l.sweep(now)
+
+	b, hasKey := l.buckets[caller]
+	if !hasKey || b.limit != limit {
+		b = &bucket{tokens: limit.burst(), last: now, limit: limit}
+		l.buckets[caller] = b
+	}
+	b.refill(now)
+
+	if b.tokens < 1 {
+		wait := (1 - b.tokens) / limit.rate()
+		return false, time.Duration(wait * float64(time.Second))
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep removes the buckets that have been refilled, a new
+// bucket is full, so the callers will not notice
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < SweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for caller, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= b.limit.burst() {
+			delete(l.buckets, caller)
+		}
+	}
+}
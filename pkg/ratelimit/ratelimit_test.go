@@ -0,0 +1,111 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestLimiter_Allow(t *testing.T) {
+	epoch := time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC)
+
+	type request struct {
+		After       time.Duration
+		Caller      string
+		Limit       Limit
+		ExpectAllow bool
+		ExpectWait  time.Duration
+	}
+	perSecond := Limit{PerMinute: 60, Burst: 2}
+
+	testCases := []struct {
+		Name     string
+		Requests []request
+	}{
+		{
+			Name: "Unlimited",
+			Requests: []request{
+				{Caller: "a", ExpectAllow: true},
+				{Caller: "a", ExpectAllow: true},
+				{Caller: "a", ExpectAllow: true},
+			},
+		},
+		{
+			Name: "Burst, then wait for a token",
+			Requests: []request{
+				{Caller: "a", Limit: perSecond, ExpectAllow: true},
+				{Caller: "a", Limit: perSecond, ExpectAllow: true},
+				{Caller: "a", Limit: perSecond, ExpectWait: time.Second},
+				{After: 500 * time.Millisecond, Caller: "a", Limit: perSecond, ExpectWait: 500 * time.Millisecond},
+				{After: time.Second, Caller: "a", Limit: perSecond, ExpectAllow: true},
+				{Caller: "a", Limit: perSecond, ExpectWait: 500 * time.Millisecond},
+			},
+		},
+		{
+			Name: "Callers have their own buckets",
+			Requests: []request{
+				{Caller: "a", Limit: Limit{PerMinute: 1}, ExpectAllow: true},
+				{Caller: "a", Limit: Limit{PerMinute: 1}, ExpectWait: time.Minute},
+				{Caller: "b", Limit: Limit{PerMinute: 1}, ExpectAllow: true},
+			},
+		},
+		{
+			Name: "Burst defaults to the limit",
+			Requests: []request{
+				{Caller: "a", Limit: Limit{PerMinute: 2}, ExpectAllow: true},
+				{Caller: "a", Limit: Limit{PerMinute: 2}, ExpectAllow: true},
+				{Caller: "a", Limit: Limit{PerMinute: 2}, ExpectWait: 30 * time.Second},
+			},
+		},
+		{
+			Name: "Refilled up to the burst",
+			Requests: []request{
+				{Caller: "a", Limit: perSecond, ExpectAllow: true},
+				{After: time.Hour, Caller: "a", Limit: perSecond, ExpectAllow: true},
+				{Caller: "a", Limit: perSecond, ExpectAllow: true},
+				{Caller: "a", Limit: perSecond, ExpectWait: time.Second},
+			},
+		},
+		{
+			Name: "Changed limit starts over",
+			Requests: []request{
+				{Caller: "a", Limit: Limit{PerMinute: 1}, ExpectAllow: true},
+				{Caller: "a", Limit: Limit{PerMinute: 1}, ExpectWait: time.Minute},
+				{Caller: "a", Limit: perSecond, ExpectAllow: true},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		now := epoch
+		l := New()
+		l.now = func() time.Time { return now }
+		for i, r := range tc.Requests {
+			now = now.Add(r.After)
+			allowed, wait := l.Allow(r.Caller, r.Limit)
+			assert.Equal(t, r.ExpectAllow, allowed, "%s: request %d", tc.Name, i)
+			assert.Equal(t, r.ExpectWait, wait, "%s: request %d", tc.Name, i)
+		}
+	}
+}
+
+func TestLimiter_Sweep(t *testing.T) {
+	now := time.Date(2018, 10, 1, 8, 0, 0, 0, time.UTC)
+	l := New()
+	l.now = func() time.Time { return now }
+	l.lastSweep = now
+
+	l.Allow("quiet", Limit{PerMinute: 60})
+	l.Allow("busy", Limit{PerMinute: 1, Burst: 2})
+	l.Allow("busy", Limit{PerMinute: 1, Burst: 2})
+	assert.Len(t, l.buckets, 2)
+
+	// The quiet caller has been refilled, while the busy
+	// caller has only earned one of its tokens back
+	now = now.Add(SweepInterval)
+	l.Allow("other", Limit{PerMinute: 60})
+	assert.Len(t, l.buckets, 2)
+	assert.Contains(t, l.buckets, "busy")
+	assert.Contains(t, l.buckets, "other")
+}
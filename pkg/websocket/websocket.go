@@ -0,0 +1,469 @@
+package websocket
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Note: this is a minimal implementation of RFC 6455, it supports
+// what we need for pushing JSON messages, i.e., text and binary
+// messages, fragmentation and the control frames, but none of the
+// extensions, such as compression.
+
+// MaxMessageSize is the largest message that is read, a
+// peer that sends a larger message is disconnected
+var MaxMessageSize = 64 * 1024
+
+// The message types, as defined by their opcodes
+const (
+	continuationMessage = 0
+	TextMessage         = 1
+	BinaryMessage       = 2
+	CloseMessage        = 8
+	PingMessage         = 9
+	PongMessage         = 10
+)
+
+// The status codes sent with a close message
+const (
+	CloseNormalClosure   = 1000
+	CloseGoingAway       = 1001
+	CloseProtocolError   = 1002
+	CloseNoStatus        = 1005
+	ClosePolicyViolation = 1008
+	CloseMessageTooBig   = 1009
+	CloseInternalError   = 1011
+	CloseTryAgainLater   = 1013
+)
+
+// acceptGUID is appended to the key of the client, when
+// computing the accept value of the handshake
+const acceptGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// CloseError is returned by ReadMessage when the
+// peer closed the connection
+type CloseError struct {
+	Code   int
+	Reason string
+}
+
+func (e *CloseError) Error() string {
+	return fmt.Sprintf("websocket: closed with %d: %s", e.Code, e.Reason)
+}
+
+// Conn is a websocket connection, reading is only safe from a
+// single goroutine, while writing is safe from many goroutines
+type Conn struct {
+	conn     net.Conn
+	reader   *bufio.Reader
+	isServer bool
+
+	writeMutex sync.Mutex
+	closeSent  bool
+
+	// PingHandler is invoked with the payload of every ping, by
+	// default a pong is written with the same payload
+	PingHandler func(payload []byte) error
+	// PongHandler is invoked with the payload of every pong
+	PongHandler func(payload []byte) error
+}
+
+func newConn(conn net.Conn, reader *bufio.Reader, isServer bool) *Conn {
+	c := &Conn{
+		conn:     conn,
+		reader:   reader,
+		isServer: isServer,
+	}
+	c.PingHandler = func(payload []byte) error {
+		return c.WriteControl(PongMessage, payload, time.Now().Add(time.Second))
+	}
+	c.PongHandler = func([]byte) error {
+		return nil
+	}
+	return c
+}
+
+// headerContains reports if the comma separated header
+// contains the token, ignoring the case
+func headerContains(header http.Header, name, token string) bool {
+	for _, value := range header[name] {
+		for _, s := range strings.Split(value, ",") {
+			if strings.EqualFold(strings.TrimSpace(s), token) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// IsUpgrade reports if the request asks for a websocket connection
+func IsUpgrade(r *http.Request) bool {
+	return r.Method == http.MethodGet &&
+		headerContains(r.Header, "Connection", "upgrade") &&
+		headerContains(r.Header, "Upgrade", "websocket")
+}
+
+func acceptKey(key string) string {
+	h := sha1.New()
+	h.Write([]byte(key + acceptGUID))
+	return base64.StdEncoding.EncodeToString(h.Sum(nil))
+}
+
+// Upgrade performs the handshake and takes over the connection of
+// the request. Nothing has been written to the response if an error
+// is returned, so the caller is free to respond. The deadlines set
+// by the server, i.e., its read and write timeouts, are cleared.
+func Upgrade(w http.ResponseWriter, r *http.Request) (*Conn, error) {
+	if !IsUpgrade(r) {
+		return nil, fmt.Errorf("websocket: not an upgrade request")
+	}
+	if r.Header.Get("Sec-WebSocket-Version") != "13" {
+		return nil, fmt.Errorf("websocket: unsupported version: %s", r.Header.Get("Sec-WebSocket-Version"))
+	}
+	key := r.Header.Get("Sec-WebSocket-Key")
+	if len(key) == 0 {
+		return nil, fmt.Errorf("websocket: missing key")
+	}
+
+	hijacker, ok := w.(http.Hijacker)
+	if !ok {
+		return nil, fmt.Errorf("websocket: response does not support hijacking")
+	}
+	conn, rw, err := hijacker.Hijack()
+	if err != nil {
+		return nil, fmt.Errorf("websocket: failed to hijack: %s", err)
+	}
+	err = conn.SetDeadline(time.Time{})
+	if err == nil {
+		_, err = fmt.Fprintf(conn, "HTTP/1.1 101 Switching Protocols\r\n"+
+			"Upgrade: websocket\r\n"+
+			"Connection: Upgrade\r\n"+
+			"Sec-WebSocket-Accept: %s\r\n\r\n", acceptKey(key))
+	}
+	if err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: failed to complete handshake: %s", err)
+	}
+
+	return newConn(conn, rw.Reader, true), nil
+}
+
+// Dial opens a websocket connection to the ws:// or wss:// URL
+func Dial(rawurl string, timeout time.Duration) (*Conn, error) {
+	u, err := url.Parse(rawurl)
+	if err != nil {
+		return nil, err
+	}
+
+	dialer := &net.Dialer{Timeout: timeout}
+	var conn net.Conn
+	switch u.Scheme {
+	case "ws":
+		conn, err = dialer.Dial("tcp", hostPort(u, "80"))
+	case "wss":
+		conn, err = tls.DialWithDialer(dialer, "tcp", hostPort(u, "443"), &tls.Config{ServerName: u.Hostname()})
+	default:
+		return nil, fmt.Errorf("websocket: unsupported scheme: %s", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, 16)
+	_, err = io.ReadFull(rand.Reader, nonce)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	conn.SetDeadline(time.Now().Add(timeout))
+	_, err = fmt.Fprintf(conn, "GET %s HTTP/1.1\r\n"+
+		"Host: %s\r\n"+
+		"Upgrade: websocket\r\n"+
+		"Connection: Upgrade\r\n"+
+		"Sec-WebSocket-Key: %s\r\n"+
+		"Sec-WebSocket-Version: 13\r\n\r\n", u.RequestURI(), u.Host, key)
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+
+	reader := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(reader, &http.Request{Method: http.MethodGet})
+	if err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusSwitchingProtocols || resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		conn.Close()
+		return nil, fmt.Errorf("websocket: handshake failed with status: %s", resp.Status)
+	}
+	conn.SetDeadline(time.Time{})
+
+	return newConn(conn, reader, false), nil
+}
+
+func hostPort(u *url.URL, defaultPort string) string {
+	if len(u.Port()) > 0 {
+		return u.Host
+	}
+	return net.JoinHostPort(u.Hostname(), defaultPort)
+}
+
+// SetReadDeadline sets the deadline for reading the next message
+func (c *Conn) SetReadDeadline(t time.Time) error {
+	return c.conn.SetReadDeadline(t)
+}
+
+// WriteMessage writes a text or binary message, the write
+// fails if it has not completed before the deadline
+func (c *Conn) WriteMessage(messageType int, data []byte, deadline time.Time) error {
+	if messageType != TextMessage && messageType != BinaryMessage {
+		return fmt.Errorf("websocket: unsupported message type: %d", messageType)
+	}
+	return c.writeFrame(messageType, data, deadline)
+}
+
+// WriteControl writes a ping, pong or close message
+func (c *Conn) WriteControl(messageType int, data []byte, deadline time.Time) error {
+	if messageType != PingMessage && messageType != PongMessage && messageType != CloseMessage {
+		return fmt.Errorf("websocket: unsupported control type: %d", messageType)
+	}
+	if len(data) > 125 {
+		return fmt.Errorf("websocket: control message too long")
+	}
+	return c.writeFrame(messageType, data, deadline)
+}
+
+// WriteClose writes a close message with the status code and
+// reason, nothing else can be written after it
+func (c *Conn) WriteClose(code int, reason string, deadline time.Time) error {
+	data := make([]byte, 2, 2+len(reason))
+	binary.BigEndian.PutUint16(data, uint16(code))
+	data = append(data, reason...)
+	if len(data) > 125 {
+		data = data[:125]
+	}
+	return c.WriteControl(CloseMessage, data, deadline)
+}
+
+// Close closes the underlying connection, without
+// sending a close message
+func (c *Conn) Close() error {
+	return c.conn.Close()
+}
+
+// writeFrame writes a single, unfragmented frame, the
+// frames written by clients are masked
+func (c *Conn) writeFrame(opcode int, data []byte, deadline time.Time) error {
+	c.writeMutex.Lock()
+	defer c.writeMutex.Unlock()
+
+	if c.closeSent {
+		return fmt.Errorf("websocket: close already sent")
+	}
+	if opcode == CloseMessage {
+		c.closeSent = true
+	}
+
+	header := make([]byte, 2, 14)
+	header[0] = 0x80 | byte(opcode)
+	switch length := len(data); {
+	case length <= 125:
+		header[1] = byte(length)
+	case length <= 0xffff:
+		header[1] = 126
+		header = header[:4]
+		binary.BigEndian.PutUint16(header[2:], uint16(length))
+	default:
+		header[1] = 127
+		header = header[:10]
+		binary.BigEndian.PutUint64(header[2:], uint64(length))
+	}
+
+	payload := data
+	if !c.isServer {
+		header[1] |= 0x80
+		mask := make([]byte, 4)
+		_, err := io.ReadFull(rand.Reader, mask)
+		if err != nil {
+			return err
+		}
+		header = append(header, mask...)
+		payload = make([]byte, len(data))
+		for i := range data {
+			payload[i] = data[i] ^ mask[i%4]
+		}
+	}
+
+	err := c.conn.SetWriteDeadline(deadline)
+	if err != nil {
+		return err
+	}
+	_, err = c.conn.Write(append(header, payload...))
+	return err
+}
+
+// frame is a single frame as read from the connection
+type frame struct {
+	fin     bool
+	opcode  int
+	payload []byte
+}
+
+func (c *Conn) readFrame() (frame, error) {
+	var header [2]byte
+	_, err := io.ReadFull(c.reader, header[:])
+	if err != nil {
+		return frame{}, err
+	}
+
+	f := frame{
+		fin:    header[0]&0x80 != 0,
+		opcode: int(header[0] & 0x0f),
+	}
+	if header[0]&0x70 != 0 {
+		return f, c.fail(CloseProtocolError, "unexpected reserved bits")
+	}
+	masked := header[1]&0x80 != 0
+	if masked != c.isServer {
+		return f, c.fail(CloseProtocolError, "unexpected masking")
+	}
+
+	length := uint64(header[1] & 0x7f)
+	switch length {
+	case 126:
+		var extended [2]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = uint64(binary.BigEndian.Uint16(extended[:]))
+	case 127:
+		var extended [8]byte
+		_, err = io.ReadFull(c.reader, extended[:])
+		length = binary.BigEndian.Uint64(extended[:])
+	}
+	if err != nil {
+		return f, err
+	}
+
+	isControl := f.opcode >= CloseMessage
+	if isControl && (length > 125 || !f.fin) {
+		return f, c.fail(CloseProtocolError, "invalid control frame")
+	}
+	if !isControl && length > uint64(MaxMessageSize) {
+		return f, c.fail(CloseMessageTooBig, "message too big")
+	}
+
+	var mask [4]byte
+	if masked {
+		_, err = io.ReadFull(c.reader, mask[:])
+		if err != nil {
+			return f, err
+		}
+	}
+	f.payload = make([]byte, length)
+	_, err = io.ReadFull(c.reader, f.payload)
+	if err != nil {
+		return f, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// fail closes the connection because the peer misbehaved
+func (c *Conn) fail(code int, reason string) error {
+	c.WriteClose(code, reason, time.Now().Add(time.Second))
+	c.conn.Close()
+	return fmt.Errorf("websocket: %s", reason)
+}
+
+// validCloseCode reports if the code may be sent in a close message,
+// 1004 is reserved, and no status, abnormal closure and failed TLS
+// handshake only report why a connection closed
+func validCloseCode(code int) bool {
+	switch code {
+	case 1004, CloseNoStatus, 1006, 1015:
+		return false
+	}
+	return code >= CloseNormalClosure && code <= 4999
+}
+
+// ReadMessage returns the next text or binary message, the control
+// messages that arrive in the meantime are handled. A CloseError is
+// returned when the peer closes the connection, after the close has
+// been acknowledged.
+func (c *Conn) ReadMessage() (int, []byte, error) {
+	messageType := 0
+	var message []byte
+
+	for {
+		f, err := c.readFrame()
+		if err != nil {
+			return 0, nil, err
+		}
+
+		switch f.opcode {
+		case PingMessage:
+			err = c.PingHandler(f.payload)
+		case PongMessage:
+			err = c.PongHandler(f.payload)
+		case CloseMessage:
+			closeErr := &CloseError{Code: CloseNoStatus}
+			if len(f.payload) >= 2 {
+				closeErr.Code = int(binary.BigEndian.Uint16(f.payload))
+				closeErr.Reason = string(f.payload[2:])
+			}
+			// Acknowledge the close, unless we started it, with a normal
+			// closure when the peer gave no status, and a protocol error
+			// when it gave one that must not be sent, RFC 6455 section 7.4
+			switch {
+			case len(f.payload) == 0:
+				c.WriteClose(CloseNormalClosure, "", time.Now().Add(time.Second))
+			case len(f.payload) < 2 || !validCloseCode(closeErr.Code):
+				c.WriteClose(CloseProtocolError, "", time.Now().Add(time.Second))
+			default:
+				c.WriteClose(closeErr.Code, "", time.Now().Add(time.Second))
+			}
+			return 0, nil, closeErr
+		case TextMessage, BinaryMessage:
+			if messageType != 0 {
+				return 0, nil, c.fail(CloseProtocolError, "expected a continuation frame")
+			}
+			messageType = f.opcode
+			message = f.payload
+		case continuationMessage:
+			if messageType == 0 {
+				return 0, nil, c.fail(CloseProtocolError, "unexpected continuation frame")
+			}
+			if len(message)+len(f.payload) > MaxMessageSize {
+				return 0, nil, c.fail(CloseMessageTooBig, "message too big")
+			}
+			message = append(message, f.payload...)
+		default:
+			return 0, nil, c.fail(CloseProtocolError, "unknown opcode")
+		}
+		if err != nil {
+			return 0, nil, err
+		}
+
+		if messageType != 0 && f.fin && f.opcode < CloseMessage {
+			return messageType, message, nil
+		}
+	}
+}
@@ -0,0 +1,249 @@
+package websocket_test
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/paulbes/go-pedal/pkg/websocket"
+	"github.com/stretchr/testify/assert"
+)
+
+// echo writes every message back, until the client closes
+func echo(w http.ResponseWriter, r *http.Request) {
+	conn, err := websocket.Upgrade(w, r)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	defer conn.Close()
+
+	for {
+		messageType, message, err := conn.ReadMessage()
+		if err != nil {
+			return
+		}
+		if string(message) == "ping me" {
+			err = conn.WriteControl(websocket.PingMessage, []byte("hello"), time.Now().Add(time.Second))
+		} else {
+			err = conn.WriteMessage(messageType, message, time.Now().Add(time.Second))
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+func dial(t *testing.T, server *httptest.Server) *websocket.Conn {
+	conn, err := websocket.Dial("ws"+strings.TrimPrefix(server.URL, "http"), time.Second)
+	if err != nil {
+		t.Fatalf("failed to dial: %s", err)
+	}
+	return conn
+}
+
+func TestConn_Echo(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(echo))
+	defer server.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+
+	testCases := []struct {
+		Name        string
+		MessageType int
+		Message     string
+	}{
+		{Name: "Short text", MessageType: websocket.TextMessage, Message: "hello"},
+		{Name: "Medium binary", MessageType: websocket.BinaryMessage, Message: strings.Repeat("b", 300)},
+		{Name: "Long text", MessageType: websocket.TextMessage, Message: strings.Repeat("t", 65536)},
+	}
+
+	for _, tc := range testCases {
+		err := conn.WriteMessage(tc.MessageType, []byte(tc.Message), time.Now().Add(time.Second))
+		assert.Nil(t, err, tc.Name)
+		messageType, message, err := conn.ReadMessage()
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, tc.MessageType, messageType, tc.Name)
+		assert.Equal(t, tc.Message, string(message), tc.Name)
+	}
+}
+
+func TestConn_PingPong(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(echo))
+	defer server.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+
+	var pings []string
+	defaultHandler := conn.PingHandler
+	conn.PingHandler = func(payload []byte) error {
+		pings = append(pings, string(payload))
+		return defaultHandler(payload)
+	}
+
+	err := conn.WriteMessage(websocket.TextMessage, []byte("ping me"), time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	err = conn.WriteMessage(websocket.TextMessage, []byte("after"), time.Now().Add(time.Second))
+	assert.Nil(t, err)
+
+	// The ping is handled while waiting for the next message
+	_, message, err := conn.ReadMessage()
+	assert.Nil(t, err)
+	assert.Equal(t, "after", string(message))
+	assert.Equal(t, []string{"hello"}, pings)
+}
+
+func TestConn_Close(t *testing.T) {
+	closed := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		_, _, err = conn.ReadMessage()
+		closed <- err
+	}))
+	defer server.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+
+	err := conn.WriteClose(websocket.CloseGoingAway, "bye", time.Now().Add(time.Second))
+	assert.Nil(t, err)
+
+	// The server acknowledges the close
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, &websocket.CloseError{Code: websocket.CloseGoingAway}, err)
+	assert.Equal(t, &websocket.CloseError{Code: websocket.CloseGoingAway, Reason: "bye"}, <-closed)
+
+	err = conn.WriteMessage(websocket.TextMessage, []byte("too late"), time.Now().Add(time.Second))
+	assert.NotNil(t, err)
+}
+
+func TestConn_CloseReply(t *testing.T) {
+	testCases := []struct {
+		Name        string
+		Frame       []byte
+		ExpectReply []byte
+	}{
+		{
+			Name:        "Empty payload is acknowledged as a normal closure",
+			Frame:       []byte{0x88, 0x80, 0, 0, 0, 0},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xe8},
+		},
+		{
+			Name:        "Status is echoed",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xe9},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xe9},
+		},
+		{
+			Name:        "Private status is echoed",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x0f, 0xa0},
+			ExpectReply: []byte{0x88, 0x02, 0x0f, 0xa0},
+		},
+		{
+			Name:        "Truncated status is a protocol error",
+			Frame:       []byte{0x88, 0x81, 0, 0, 0, 0, 0x03},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+		{
+			Name:        "Reserved status is a protocol error",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xec},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+		{
+			Name:        "No status is a protocol error",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xed},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+		{
+			Name:        "Abnormal closure is a protocol error",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xee},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+		{
+			Name:        "Failed TLS handshake is a protocol error",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xf7},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+		{
+			Name:        "Status below the range is a protocol error",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x03, 0xe7},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+		{
+			Name:        "Status above the range is a protocol error",
+			Frame:       []byte{0x88, 0x82, 0, 0, 0, 0, 0x13, 0x88},
+			ExpectReply: []byte{0x88, 0x02, 0x03, 0xea},
+		},
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := websocket.Upgrade(w, r)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.ReadMessage()
+	}))
+	defer server.Close()
+
+	for _, tc := range testCases {
+		// The frames are written by hand, so we see exactly
+		// what the server replies with
+		conn, err := net.Dial("tcp", strings.TrimPrefix(server.URL, "http://"))
+		assert.Nil(t, err, tc.Name)
+		conn.SetDeadline(time.Now().Add(time.Second))
+		fmt.Fprintf(conn, "GET / HTTP/1.1\r\nHost: %s\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Key: dGhlIHNhbXBsZSBub25jZQ==\r\nSec-WebSocket-Version: 13\r\n\r\n", server.Listener.Addr())
+		reader := bufio.NewReader(conn)
+		res, err := http.ReadResponse(reader, nil)
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, http.StatusSwitchingProtocols, res.StatusCode, tc.Name)
+
+		_, err = conn.Write(tc.Frame)
+		assert.Nil(t, err, tc.Name)
+		reply := make([]byte, len(tc.ExpectReply))
+		_, err = io.ReadFull(reader, reply)
+		assert.Nil(t, err, tc.Name)
+		assert.Equal(t, tc.ExpectReply, reply, tc.Name)
+		conn.Close()
+	}
+}
+
+func TestConn_MessageTooBig(t *testing.T) {
+	websocket.MaxMessageSize = 10
+	defer func() { websocket.MaxMessageSize = 64 * 1024 }()
+
+	server := httptest.NewServer(http.HandlerFunc(echo))
+	defer server.Close()
+
+	conn := dial(t, server)
+	defer conn.Close()
+
+	err := conn.WriteMessage(websocket.TextMessage, []byte("more than ten bytes"), time.Now().Add(time.Second))
+	assert.Nil(t, err)
+	_, _, err = conn.ReadMessage()
+	assert.Equal(t, &websocket.CloseError{Code: websocket.CloseMessageTooBig, Reason: "message too big"}, err)
+}
+
+func TestUpgrade_NotWebsocket(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(echo))
+	defer server.Close()
+
+	resp, err := http.Get(server.URL)
+	assert.Nil(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	_, err = websocket.Dial("http"+strings.TrimPrefix(server.URL, "http"), time.Second)
+	assert.NotNil(t, err)
+}